@@ -7,13 +7,25 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
+	"github.com/therealutkarshpriyadarshi/dag/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/dag/internal/notify"
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
 	"github.com/therealutkarshpriyadarshi/dag/internal/scheduler"
+	"github.com/therealutkarshpriyadarshi/dag/internal/sla"
 	"github.com/therealutkarshpriyadarshi/dag/internal/state"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
 const version = "0.2.0"
@@ -38,6 +50,23 @@ var (
 	enableCatchup        = flag.Bool("enable-catchup", true, "Enable catchup for missed schedules")
 	maxCatchupRuns       = flag.Int("max-catchup-runs", 50, "Maximum number of catchup runs")
 	timezone             = flag.String("timezone", "UTC", "Default timezone for schedules")
+	leaseTimeout         = flag.Duration("lease-timeout", time.Minute, "How long a running DAG run or task may go without a heartbeat before it is recovered")
+
+	// Executor flags
+	executorType    = flag.String("executor", getEnv("EXECUTOR", "local"), "Executor type (local, sequential, distributed)")
+	natsURL         = flag.String("nats-url", getEnv("NATS_URL", "nats://localhost:4222"), "NATS server URL for the distributed executor")
+	executorWorkers = flag.Int("executor-workers", 5, "Number of local executor workers")
+	taskTimeout     = flag.Duration("task-timeout", 30*time.Minute, "Default task timeout")
+
+	// Result flags
+	resultsMaxValueSize = flag.Int("results-max-value-size", results.DefaultMaxValueSize, "Largest value a task may push stored inline, in bytes")
+	resultsBlobDir      = flag.String("results-blob-dir", getEnv("RESULTS_BLOB_DIR", ""), "Directory receiving values over the inline size limit (empty rejects such values)")
+
+	// Template flags
+	templateEnv = flag.String("template-env", getEnv("TEMPLATE_ENV", ""), "Comma-separated environment variables task command templates may read through .env")
+	renderFile  = flag.String("render", "", "Print the commands the tasks of the DAG defined in this YAML or JSON file would run, then exit")
+	renderDate  = flag.String("render-date", "", "Execution date the commands are rendered for (RFC3339 or YYYY-MM-DD, defaults to now)")
+	renderTask  = flag.String("render-task", "", "Only render the command of this task")
 
 	// Backfill flags
 	backfillMode         = flag.Bool("backfill", false, "Run in backfill mode")
@@ -46,11 +75,28 @@ var (
 	backfillEnd          = flag.String("backfill-end", "", "Backfill end date (RFC3339)")
 	backfillConcurrency  = flag.Int("backfill-concurrency", 5, "Backfill concurrency")
 	backfillDryRun       = flag.Bool("backfill-dry-run", false, "Backfill dry run")
+
+	// SLA flags
+	slaCheckInterval = flag.Duration("sla-check-interval", 30*time.Second, "How often task and DAG run SLAs are checked (0 disables the check)")
+	slaReference     = flag.String("sla-reference", string(storage.SLAFromExecutionDate), "What SLAs are measured from (execution_date, start_date)")
+
+	// Metrics flags
+	metricsAddr = flag.String("metrics-addr", getEnv("METRICS_ADDR", ":9091"), "Address serving Prometheus metrics on /metrics")
+
+	// Notification flags
+	notificationsConfig = flag.String("notifications-config", getEnv("NOTIFICATIONS_CONFIG", ""), "Path of the YAML file declaring notifiers (empty disables notifications)")
+	dlqAlertThreshold   = flag.Int("dlq-alert-threshold", 0, "Dead letter queue size at which the default notifiers are alerted (0 disables the alert)")
 )
 
 func main() {
 	flag.Parse()
 
+	// Previewing commands needs neither the database nor Redis
+	if *renderFile != "" {
+		runRender()
+		return
+	}
+
 	log.Printf("Starting Workflow Orchestrator Scheduler v%s", version)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -72,6 +118,7 @@ func main() {
 	dagRepo := storage.NewDAGRepository(db.DB)
 	dagRunRepo := storage.NewDAGRunRepository(db.DB, stateManager)
 	taskInstanceRepo := storage.NewTaskInstanceRepository(db.DB, stateManager)
+	taskLogRepo := storage.NewTaskLogRepository(db.DB)
 
 	// Check if running in backfill mode
 	if *backfillMode {
@@ -79,6 +126,23 @@ func main() {
 		return
 	}
 
+	// Notify about failures, successes, retries and SLA misses as the DAGs' notification rules ask
+	var dispatcher *notify.Dispatcher
+	if *notificationsConfig != "" {
+		dispatcher, err = initDispatcher(dagRepo, dagRunRepo, taskInstanceRepo)
+		if err != nil {
+			log.Fatalf("Failed to initialize notifications: %v", err)
+		}
+	}
+
+	// Deliver state change events written to the outbox by the repositories
+	statePublisher, closePublisher := initStatePublisher(redisClient)
+	defer closePublisher()
+	if dispatcher != nil {
+		statePublisher = state.NewMultiPublisher(statePublisher, dispatcher)
+	}
+	go state.NewOutboxRelay(db.DB, statePublisher, state.DefaultOutboxRelayConfig()).Run(ctx)
+
 	// Initialize concurrency manager
 	concurrencyConfig := &scheduler.ConcurrencyConfig{
 		MaxGlobalConcurrency:  *maxConcurrentRuns,
@@ -96,6 +160,21 @@ func main() {
 		DefaultTimezone:      *timezone,
 		EnableCatchup:        *enableCatchup,
 		MaxCatchupRuns:       *maxCatchupRuns,
+		LeaseTimeout:         *leaseTimeout,
+	}
+
+	// Initialize executor
+	resultManager, err := initResults(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize result store: %v", err)
+	}
+	exec, err := initExecutor(dagRepo, taskInstanceRepo, dagRunRepo, taskLogRepo, dlq.NewPostgresQueue(db.DB), resultManager, dispatcher)
+	if err != nil {
+		log.Fatalf("Failed to initialize executor: %v", err)
+	}
+
+	if err := exec.Start(ctx); err != nil {
+		log.Fatalf("Failed to start executor: %v", err)
 	}
 
 	sched := scheduler.New(
@@ -104,6 +183,7 @@ func main() {
 		dagRunRepo,
 		taskInstanceRepo,
 		concurrencyMgr,
+		exec,
 	)
 
 	// Start scheduler
@@ -111,7 +191,33 @@ func main() {
 		log.Fatalf("Failed to start scheduler: %v", err)
 	}
 
+	// Record and report SLA misses
+	if *slaCheckInterval > 0 {
+		switch storage.SLAReference(*slaReference) {
+		case storage.SLAFromExecutionDate, storage.SLAFromStartDate:
+		default:
+			log.Fatalf("Invalid SLA reference %q, expected execution_date or start_date", *slaReference)
+		}
+
+		slaMonitor := sla.NewMonitor(storage.NewSLAMissRepository(db.DB), sla.MonitorConfig{
+			CheckInterval: *slaCheckInterval,
+			Reference:     storage.SLAReference(*slaReference),
+		})
+		if dispatcher != nil {
+			slaMonitor.AddNotifier(dispatcher)
+		}
+		go slaMonitor.Run(ctx)
+	}
+
+	// Export metrics of the scheduler and its executor
+	metrics.RegisterGauge("scheduler_queue_depth", "DAG runs waiting in the scheduler's priority queue.", func() float64 {
+		return float64(sched.QueueDepth())
+	})
+	executor.RegisterMetrics(*executorType, exec)
+	go metrics.Serve(ctx, *metricsAddr)
+
 	log.Println("Scheduler started successfully")
+	log.Printf("Executor: %s", *executorType)
 	log.Printf("Schedule interval: %v", *scheduleInterval)
 	log.Printf("Max concurrent runs: %d", *maxConcurrentRuns)
 	log.Printf("Catchup enabled: %v", *enableCatchup)
@@ -130,6 +236,11 @@ func main() {
 		log.Printf("Error stopping scheduler: %v", err)
 	}
 
+	// Stop executor
+	if err := exec.Stop(context.Background()); err != nil {
+		log.Printf("Error stopping executor: %v", err)
+	}
+
 	// Close database connection
 	sqlDB, _ := db.DB.DB()
 	if sqlDB != nil {
@@ -223,6 +334,162 @@ func runBackfill(ctx context.Context, dagRepo storage.DAGRepository, dagRunRepo
 	}
 }
 
+// runRender prints the commands the tasks of a DAG file would run at an execution date
+func runRender() {
+	parser := dag.NewParser()
+	var dagModel *models.DAG
+	var err error
+	if filepath.Ext(*renderFile) == ".json" {
+		dagModel, err = parser.ParseJSONFile(*renderFile)
+	} else {
+		dagModel, err = parser.ParseYAMLFile(*renderFile)
+	}
+	if err != nil {
+		log.Fatalf("Failed to parse DAG file: %v", err)
+	}
+
+	executionDate := time.Now().UTC()
+	if *renderDate != "" {
+		if executionDate, err = render.ParseExecutionDate(*renderDate); err != nil {
+			log.Fatalf("Invalid render date: %v", err)
+		}
+	}
+
+	previews, err := render.NewRenderer(render.ParseEnvNames(*templateEnv)).Preview(dagModel, executionDate, *renderTask)
+	if err != nil {
+		log.Fatalf("Failed to render DAG %s: %v", dagModel.ID, err)
+	}
+
+	fmt.Printf("DAG %s at %s\n", dagModel.ID, executionDate.Format(time.RFC3339))
+	failed := false
+	for _, preview := range previews {
+		fmt.Printf("\n%s:\n", preview.TaskID)
+		if preview.Err != nil {
+			fmt.Printf("  error: %v\n", preview.Err)
+			failed = true
+			continue
+		}
+		fmt.Printf("  %s\n", preview.RenderedCommand)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// initResults creates the result manager storing the values tasks push for downstream tasks
+func initResults(db *storage.DB) (*results.Manager, error) {
+	config := results.DefaultConfig()
+	config.MaxValueSize = *resultsMaxValueSize
+	if *resultsBlobDir != "" {
+		blobStore, err := results.NewFileBlobStore(*resultsBlobDir)
+		if err != nil {
+			return nil, err
+		}
+		config.BlobStore = blobStore
+	}
+	return results.NewManager(results.NewPostgresStore(db.DB), config), nil
+}
+
+func initExecutor(dagRepo storage.DAGRepository, taskInstanceRepo storage.TaskInstanceRepository, dagRunRepo storage.DAGRunRepository, taskLogRepo storage.TaskLogRepository, dlqQueue dlq.Queue, resultManager *results.Manager, dispatcher *notify.Dispatcher) (executor.Executor, error) {
+	stateMachine := state.NewStateMachine()
+
+	config := executor.DefaultExecutorConfig()
+	config.WorkerCount = *executorWorkers
+	config.TaskTimeout = *taskTimeout
+	config.LeaseTimeout = *leaseTimeout
+	config.TemplateEnv = render.ParseEnvNames(*templateEnv)
+
+	// Tasks whose retries are exhausted end up in the dead letter queue, which the API server replays from
+	dlqManager := dlq.NewManager(dlqQueue, *dlqAlertThreshold)
+	dlqManager.OnEntryAdded(func(entry *dlq.Entry) {
+		log.Printf("Task %s of DAG run %s moved to dead letter queue after %d attempts", entry.TaskID, entry.DAGRunID, entry.Attempts)
+	})
+	if dispatcher != nil {
+		dlqManager.OnThresholdReached(dispatcher.DLQThresholdReached)
+	}
+	metrics.RegisterDLQ(dlqManager.GetQueue())
+
+	// Calls to external dependencies of tasks go through circuit breakers, exported as metrics
+	breakers := circuitbreaker.NewRegistry()
+	breakers.OnCreate(metrics.RegisterCircuitBreaker)
+	if dispatcher != nil {
+		breakers.OnStateChange(func(name string, from, to circuitbreaker.State) {
+			dispatcher.CircuitBreakerStateChanged(name)(from, to)
+		})
+	}
+	httpExecutor := executor.NewHTTPTaskExecutor(config.TaskTimeout)
+	httpExecutor.SetCircuitBreakers(breakers)
+
+	switch *executorType {
+	case "local":
+		localExecutor := executor.NewLocalExecutor(taskInstanceRepo, dagRunRepo, stateMachine, config)
+		localExecutor.RegisterTaskExecutor(executor.NewBashTaskExecutor())
+		localExecutor.RegisterTaskExecutor(httpExecutor)
+		localExecutor.RegisterTaskExecutor(executor.NewGoFuncTaskExecutor())
+		localExecutor.SetTaskLogRepository(taskLogRepo)
+		localExecutor.SetDLQ(dlqManager)
+		localExecutor.SetResults(resultManager)
+		return localExecutor, nil
+	case "sequential":
+		sequentialExecutor := executor.NewSequentialExecutor(taskInstanceRepo, dagRunRepo, stateMachine)
+		sequentialExecutor.RegisterTaskExecutor(executor.NewBashTaskExecutor())
+		sequentialExecutor.RegisterTaskExecutor(httpExecutor)
+		sequentialExecutor.RegisterTaskExecutor(executor.NewGoFuncTaskExecutor())
+		sequentialExecutor.SetTaskLogRepository(taskLogRepo)
+		sequentialExecutor.SetRetryStrategy(config.RetryStrategy)
+		sequentialExecutor.SetDLQ(dlqManager)
+		sequentialExecutor.SetResults(resultManager)
+		sequentialExecutor.SetTemplateEnv(config.TemplateEnv)
+		return sequentialExecutor, nil
+	case "distributed":
+		// Tasks are executed by workers (cmd/worker) consuming from NATS
+		distributedExecutor, err := executor.NewDistributedExecutor(*natsURL, taskInstanceRepo, dagRunRepo, stateMachine, config)
+		if err != nil {
+			return nil, err
+		}
+		distributedExecutor.SetTaskLogRepository(taskLogRepo)
+		distributedExecutor.SetDAGRepository(dagRepo)
+		distributedExecutor.SetDLQ(dlqManager)
+		distributedExecutor.SetResults(resultManager)
+		return distributedExecutor, nil
+	default:
+		return nil, fmt.Errorf("unknown executor type: %s", *executorType)
+	}
+}
+
+// initDispatcher creates the notification dispatcher from the notifications config file
+func initDispatcher(dagRepo storage.DAGRepository, dagRunRepo storage.DAGRunRepository, taskInstanceRepo storage.TaskInstanceRepository) (*notify.Dispatcher, error) {
+	config, err := notify.LoadConfig(*notificationsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatcher, err := notify.NewDispatcherFromConfig(config, dagRepo, dagRunRepo, taskInstanceRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Notifications enabled with %d notifiers", len(config.Notifiers))
+	return dispatcher, nil
+}
+
+// initStatePublisher returns the publisher of state change events: Redis pub/sub, and NATS
+// as well when the distributed executor is used. The returned function closes the publisher.
+func initStatePublisher(redisClient *redis.Client) (state.EventPublisher, func()) {
+	redisPublisher := state.NewRedisPublisher(redisClient)
+	if *executorType != "distributed" {
+		return redisPublisher, func() {}
+	}
+
+	nc, err := nats.Connect(*natsURL)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to NATS, state changes are only published to Redis: %v", err)
+		return redisPublisher, func() {}
+	}
+
+	return state.NewMultiPublisher(redisPublisher, state.NewNATSPublisher(nc)), nc.Close
+}
+
 func initDatabase() (*storage.DB, error) {
 	config := &storage.Config{
 		Host:        *dbHost,
@@ -5,18 +5,27 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
 	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
 	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
+	"github.com/therealutkarshpriyadarshi/dag/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/dag/internal/notify"
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
 	"github.com/therealutkarshpriyadarshi/dag/internal/state"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/middleware"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
 const version = "0.6.0"
@@ -83,30 +92,29 @@ func main() {
 		log.Printf("Warning: Failed to connect to Redis: %v", err)
 	}
 
-	// Initialize state management
+	// Initialize state management; repositories record state changes in the history and the outbox
+	// in the transaction of each change, and the outbox relay publishes them to Redis
 	redisPublisher := state.NewRedisPublisher(redisClient)
-	historyPublisher := state.NewHistoryPublisher(db.DB)
-	multiPublisher := state.NewMultiPublisher(redisPublisher, historyPublisher)
-	stateManager := state.NewManager(multiPublisher)
-	stateMachine := state.NewMachine()
+	stateManager := state.NewManager(nil)
+	stateMachine := state.NewStateMachine()
 
 	// Initialize repositories
 	dagRepo := storage.NewDAGRepository(db.DB)
 	dagRunRepo := storage.NewDAGRunRepository(db.DB, stateManager)
 	taskInstanceRepo := storage.NewTaskInstanceRepository(db.DB, stateManager)
 	taskLogRepo := storage.NewTaskLogRepository(db.DB)
+	slaMissRepo := storage.NewSLAMissRepository(db.DB)
 
-	// Initialize DAG engine
-	dagEngine := dag.NewEngine()
+	// Initialize DAG validator
+	dagValidator := dag.NewValidator()
 
-	// Initialize executor
+	// Initialize executor; task command templates may only read the environment variables listed in TEMPLATE_ENV
 	executorCfg := &executor.ExecutorConfig{
-		WorkerCount:  4,
-		QueueSize:    100,
-		MaxRetries:   3,
-		RetryDelay:   5 * time.Second,
-		TaskTimeout:  30 * time.Minute,
+		WorkerCount:     4,
+		QueueSize:       100,
+		TaskTimeout:     30 * time.Minute,
 		ShutdownTimeout: 1 * time.Minute,
+		TemplateEnv:     render.ParseEnvNames(os.Getenv("TEMPLATE_ENV")),
 	}
 
 	localExecutor := executor.NewLocalExecutor(
@@ -116,11 +124,57 @@ func main() {
 		executorCfg,
 	)
 
-	// Register task executors
+	// Register task executors; calls to external dependencies go through circuit breakers, exported as metrics
+	breakers := circuitbreaker.NewRegistry()
+	breakers.OnCreate(metrics.RegisterCircuitBreaker)
+	httpExecutor := executor.NewHTTPTaskExecutor(executorCfg.TaskTimeout)
+	httpExecutor.SetCircuitBreakers(breakers)
 	localExecutor.RegisterTaskExecutor(executor.NewBashTaskExecutor())
-	localExecutor.RegisterTaskExecutor(executor.NewHTTPTaskExecutor())
+	localExecutor.RegisterTaskExecutor(httpExecutor)
 	localExecutor.RegisterTaskExecutor(executor.NewGoFuncTaskExecutor())
 	// Note: DockerTaskExecutor requires Docker client setup
+	localExecutor.SetTaskLogRepository(taskLogRepo)
+	dlqAlertThreshold, err := strconv.Atoi(getEnv("DLQ_ALERT_THRESHOLD", "0"))
+	if err != nil {
+		log.Fatalf("Invalid DLQ_ALERT_THRESHOLD: %v", err)
+	}
+	dlqQueue := dlq.NewPostgresQueue(db.DB)
+	dlqManager := dlq.NewManager(dlqQueue, dlqAlertThreshold)
+	localExecutor.SetDLQ(dlqManager)
+
+	// Store the values tasks push for downstream tasks; values over the inline limit spill to files when a blob directory is set
+	resultsConfig := results.DefaultConfig()
+	resultsConfig.MaxValueSize, err = strconv.Atoi(getEnv("RESULTS_MAX_VALUE_SIZE", strconv.Itoa(results.DefaultMaxValueSize)))
+	if err != nil {
+		log.Fatalf("Invalid RESULTS_MAX_VALUE_SIZE: %v", err)
+	}
+	if dir := os.Getenv("RESULTS_BLOB_DIR"); dir != "" {
+		if resultsConfig.BlobStore, err = results.NewFileBlobStore(dir); err != nil {
+			log.Fatalf("Failed to initialize result blob store: %v", err)
+		}
+	}
+	resultManager := results.NewManager(results.NewPostgresStore(db.DB), resultsConfig)
+	localExecutor.SetResults(resultManager)
+
+	// Notify about failures, successes and retries as the DAGs' notification rules ask. The outbox relays of
+	// the server and the scheduler take turns, so both need the same notifications config.
+	var statePublisher state.EventPublisher = redisPublisher
+	if path := os.Getenv("NOTIFICATIONS_CONFIG"); path != "" {
+		notificationsConfig, err := notify.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load notifications config: %v", err)
+		}
+		dispatcher, err := notify.NewDispatcherFromConfig(notificationsConfig, dagRepo, dagRunRepo, taskInstanceRepo)
+		if err != nil {
+			log.Fatalf("Failed to initialize notifications: %v", err)
+		}
+		statePublisher = state.NewMultiPublisher(redisPublisher, dispatcher)
+		dlqManager.OnThresholdReached(dispatcher.DLQThresholdReached)
+		breakers.OnStateChange(func(name string, from, to circuitbreaker.State) {
+			dispatcher.CircuitBreakerStateChanged(name)(from, to)
+		})
+		log.Printf("Notifications enabled with %d notifiers", len(notificationsConfig.Notifiers))
+	}
 
 	// Start executor
 	executorCtx := context.Background()
@@ -129,8 +183,34 @@ func main() {
 	}
 	defer localExecutor.Stop(executorCtx)
 
+	// Resume DAG runs left running when the server last stopped
+	recoveryCtx, stopRecovery := context.WithCancel(executorCtx)
+	defer stopRecovery()
+	recovery := executor.NewRecovery(dagRepo, dagRunRepo, taskInstanceRepo, executorCfg.LeaseTimeout)
+	go recovery.Run(recoveryCtx, func(run *executor.OrphanedRun) {
+		if err := localExecutor.Resume(executorCtx, run.DAGRun, run.DAG, run.TaskInstances); err != nil {
+			log.Printf("Failed to resume DAG run %s: %v", run.DAGRun.ID, err)
+		}
+	})
+
+	// Replayed dead letters are scheduled by this server's executor, whichever process ran the DAG run before
+	replayer := executor.NewReplayer(dlqQueue, dagRepo, dagRunRepo, taskInstanceRepo,
+		func(dagRun *models.DAGRun, dagModel *models.DAG, taskInstances []*models.TaskInstance) error {
+			return localExecutor.Resume(executorCtx, dagRun, dagModel, taskInstances)
+		})
+
+	// Deliver state change events written to the outbox
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go state.NewOutboxRelay(db.DB, statePublisher, state.DefaultOutboxRelayConfig()).Run(relayCtx)
+
+	// Export metrics of the executor, the dead letter queue and the states stored in the database
+	executor.RegisterMetrics("local", localExecutor)
+	metrics.RegisterDLQ(dlqManager.GetQueue())
+	metrics.RegisterStateCounts(db.DB)
+
 	log.Printf("Database initialized successfully")
-	log.Printf("Repositories initialized: DAG, DAGRun, TaskInstance, TaskLog")
+	log.Printf("Repositories initialized: DAG, DAGRun, TaskInstance, TaskLog, SLAMiss")
 	log.Printf("Executor started with %d workers", executorCfg.WorkerCount)
 
 	// Set Gin mode based on environment
@@ -157,11 +237,27 @@ func main() {
 	router.Use(middleware.ErrorHandler())
 	router.Use(middleware.Logger(logger))
 	router.Use(middleware.CORS())
+	router.Use(middleware.Metrics())
 
 	// Initialize handlers
-	dagHandler := handlers.NewDAGHandler(dagRepo, dagEngine)
+	dagHandler := handlers.NewDAGHandler(dagRepo, dagValidator)
+	dagHandler.SetRenderer(render.NewRenderer(executorCfg.TemplateEnv))
 	dagRunHandler := handlers.NewDAGRunHandler(dagRepo, dagRunRepo, taskInstanceRepo, localExecutor)
+	// Running tasks of DAG runs handed to distributed workers are interrupted over NATS
+	if natsURL := os.Getenv("NATS_URL"); natsURL != "" {
+		if nc, err := nats.Connect(natsURL); err != nil {
+			log.Printf("Warning: Failed to connect to NATS, running tasks on workers will not be interrupted: %v", err)
+		} else {
+			defer nc.Close()
+			dagRunHandler.SetTaskCanceller(executor.NewNATSTaskCanceller(nc))
+		}
+	}
 	taskInstanceHandler := handlers.NewTaskInstanceHandler(taskInstanceRepo, taskLogRepo)
+	taskInstanceHandler.SetResults(resultManager)
+	eventHandler := handlers.NewEventHandler(redisPublisher, state.NewOutbox(db.DB))
+	slaHandler := handlers.NewSLAHandler(slaMissRepo)
+	dlqHandler := handlers.NewDLQHandler(dlqQueue, replayer)
+	circuitBreakerHandler := handlers.NewCircuitBreakerHandler(breakers)
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -203,6 +299,9 @@ func main() {
 		})
 	})
 
+	// Prometheus metrics endpoint
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// JWT configuration
 	jwtConfig := middleware.DefaultJWTConfig()
 
@@ -241,6 +340,11 @@ func main() {
 		dags.POST("/:id/pause", dagHandler.PauseDAG)
 		dags.POST("/:id/unpause", dagHandler.UnpauseDAG)
 		dags.POST("/:id/trigger", dagRunHandler.TriggerDAG)
+		dags.GET("/:id/versions", dagHandler.ListDAGVersions)
+		dags.GET("/:id/versions/:version", dagHandler.GetDAGVersion)
+		dags.POST("/:id/versions/:version/rollback", dagHandler.RollbackDAG)
+		dags.GET("/:id/diff", dagHandler.DiffDAGVersions)
+		dags.GET("/:id/render", dagHandler.RenderDAG)
 	}
 
 	// DAG Run routes
@@ -258,9 +362,37 @@ func main() {
 		taskInstances.GET("", taskInstanceHandler.ListTaskInstances)
 		taskInstances.GET("/:id", taskInstanceHandler.GetTaskInstance)
 		taskInstances.GET("/:id/logs", taskInstanceHandler.GetTaskInstanceLogs)
+		taskInstances.GET("/:id/logs/stream", taskInstanceHandler.StreamTaskInstanceLogs)
+		taskInstances.GET("/:id/results", taskInstanceHandler.GetTaskInstanceResults)
+		taskInstances.GET("/:id/results/:key", taskInstanceHandler.GetTaskInstanceResult)
 		taskInstances.POST("/:id/retry", taskInstanceHandler.RetryTaskInstance)
 	}
 
+	// State change events
+	api.GET("/events", eventHandler.StreamEvents)
+
+	// SLA misses
+	api.GET("/sla-misses", slaHandler.ListSLAMisses)
+
+	// Dead letter queue routes
+	deadLetters := api.Group("/dlq")
+	{
+		deadLetters.GET("", dlqHandler.ListDLQEntries)
+		deadLetters.DELETE("", dlqHandler.PurgeDLQ)
+		deadLetters.POST("/replay", dlqHandler.ReplayDLQEntries)
+		deadLetters.GET("/:id", dlqHandler.GetDLQEntry)
+		deadLetters.DELETE("/:id", dlqHandler.DeleteDLQEntry)
+		deadLetters.POST("/:id/replay", dlqHandler.ReplayDLQEntry)
+	}
+
+	// Admin routes
+	admin := api.Group("/admin")
+	{
+		admin.GET("/circuit-breakers", circuitBreakerHandler.ListCircuitBreakers)
+		admin.GET("/circuit-breakers/:name", circuitBreakerHandler.GetCircuitBreaker)
+		admin.POST("/circuit-breakers/:name/reset", circuitBreakerHandler.ResetCircuitBreaker)
+	}
+
 	// Start server
 	log.Printf("Server listening on port %s in %s mode", port, env)
 	log.Printf("Phase 6: REST API with authentication, rate limiting, and validation")
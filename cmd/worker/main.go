@@ -9,7 +9,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
 	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
+	"github.com/therealutkarshpriyadarshi/dag/internal/metrics"
 )
 
 const version = "0.4.0"
@@ -20,12 +22,16 @@ func main() {
 	workerCount := flag.Int("workers", 5, "Number of concurrent workers")
 	timeout := flag.Duration("timeout", 30*time.Minute, "Default task timeout")
 	enableDocker := flag.Bool("docker", false, "Enable Docker task executor")
+	metricsAddr := flag.String("metrics-addr", os.Getenv("METRICS_ADDR"), "Address serving Prometheus metrics on /metrics")
 	flag.Parse()
 
 	// Set default NATS URL if not provided
 	if *natsURL == "" {
 		*natsURL = "nats://localhost:4222"
 	}
+	if *metricsAddr == "" {
+		*metricsAddr = ":9092"
+	}
 
 	log.Printf("Starting Workflow Orchestrator Worker v%s", version)
 	log.Printf("NATS URL: %s", *natsURL)
@@ -49,13 +55,19 @@ func main() {
 		log.Fatalf("Failed to create worker: %v", err)
 	}
 
-	// Register task executors
+	// Register task executors; calls to external dependencies go through circuit breakers, exported as metrics
+	breakers := circuitbreaker.NewRegistry()
+	breakers.OnCreate(metrics.RegisterCircuitBreaker)
+	httpExecutor := executor.NewHTTPTaskExecutor(config.TaskTimeout)
+	httpExecutor.SetCircuitBreakers(breakers)
 	worker.RegisterTaskExecutor(executor.NewBashTaskExecutor())
-	worker.RegisterTaskExecutor(executor.NewHTTPTaskExecutor(config.TaskTimeout))
+	worker.RegisterTaskExecutor(httpExecutor)
 	worker.RegisterTaskExecutor(executor.NewGoFuncTaskExecutor())
 
 	if *enableDocker {
-		worker.RegisterTaskExecutor(executor.NewDockerTaskExecutor("python:3.11-slim"))
+		dockerExecutor := executor.NewDockerTaskExecutor("python:3.11-slim")
+		dockerExecutor.SetCircuitBreakers(breakers)
+		worker.RegisterTaskExecutor(dockerExecutor)
 		log.Println("Docker task executor registered")
 	}
 
@@ -73,6 +85,10 @@ func main() {
 
 	log.Printf("Worker %s started and ready to process tasks", worker.GetID())
 
+	// Export metrics of the worker
+	worker.RegisterMetrics()
+	go metrics.Serve(ctx, *metricsAddr)
+
 	// Wait for shutdown signal
 	sig := <-sigChan
 	log.Printf("Received signal %v, initiating graceful shutdown...", sig)
@@ -231,6 +231,21 @@ func (cb *CircuitBreaker) GetState() State {
 	return cb.state
 }
 
+// RetryAfter returns how long an open circuit breaker keeps rejecting requests before it lets a
+// trial request through, or zero if it is not open
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	if cb.state != StateOpen {
+		return 0
+	}
+	if remaining := cb.config.Timeout - time.Since(cb.lastFailureTime); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
 // Reset resets the circuit breaker to closed state
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
@@ -78,6 +78,26 @@ func TestCircuitBreaker_HalfOpenAfterTimeout(t *testing.T) {
 	}
 }
 
+func TestCircuitBreaker_RetryAfter(t *testing.T) {
+	cb := New(&Config{
+		MaxFailures:         1,
+		Timeout:             1 * time.Minute,
+		HalfOpenMaxRequests: 1,
+	})
+
+	if cb.RetryAfter() != 0 {
+		t.Errorf("A closed circuit should not ask to retry later, got %v", cb.RetryAfter())
+	}
+
+	cb.Execute(context.Background(), func() error {
+		return errors.New("error")
+	})
+
+	if retryAfter := cb.RetryAfter(); retryAfter <= 59*time.Second || retryAfter > time.Minute {
+		t.Errorf("Expected an open circuit to ask to retry in about a minute, got %v", retryAfter)
+	}
+}
+
 func TestCircuitBreaker_HalfOpenToClosedOnSuccess(t *testing.T) {
 	config := &Config{
 		MaxFailures:         2,
@@ -0,0 +1,106 @@
+package circuitbreaker
+
+import (
+	"sort"
+	"sync"
+)
+
+// Registry holds named circuit breakers, such as one per external dependency, creating each on first use
+type Registry struct {
+	mu            sync.RWMutex
+	breakers      map[string]*CircuitBreaker
+	onCreate      []func(name string, cb *CircuitBreaker)
+	onStateChange []func(name string, from, to State)
+}
+
+// NewRegistry creates an empty circuit breaker registry
+func NewRegistry() *Registry {
+	return &Registry{
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// OnCreate registers a callback invoked with every circuit breaker the registry creates from now on
+func (r *Registry) OnCreate(callback func(name string, cb *CircuitBreaker)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onCreate = append(r.onCreate, callback)
+}
+
+// OnStateChange registers a callback invoked when any circuit breaker of the registry changes state.
+// Like Config.OnStateChange, it is called while the breaker is locked and must not use it.
+func (r *Registry) OnStateChange(callback func(name string, from, to State)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onStateChange = append(r.onStateChange, callback)
+}
+
+// Get returns the circuit breaker with the given name, creating it from config if it does not exist yet.
+// The config of an existing breaker is not changed.
+func (r *Registry) Get(name string, config *Config) *CircuitBreaker {
+	r.mu.RLock()
+	cb, ok := r.breakers[name]
+	r.mu.RUnlock()
+	if ok {
+		return cb
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cb, ok := r.breakers[name]; ok {
+		return cb
+	}
+
+	if config == nil {
+		config = DefaultConfig()
+	}
+	breakerConfig := *config
+	breakerConfig.OnStateChange = func(from, to State) {
+		if config.OnStateChange != nil {
+			config.OnStateChange(from, to)
+		}
+		r.stateChanged(name, from, to)
+	}
+
+	cb = New(&breakerConfig)
+	r.breakers[name] = cb
+	for _, callback := range r.onCreate {
+		callback(name, cb)
+	}
+
+	return cb
+}
+
+// Lookup returns the circuit breaker with the given name, if it exists
+func (r *Registry) Lookup(name string) (*CircuitBreaker, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cb, ok := r.breakers[name]
+	return cb, ok
+}
+
+// Names returns the names of the circuit breakers in the registry, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.breakers))
+	for name := range r.breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stateChanged tells the registry's callbacks about a state change of the named breaker
+func (r *Registry) stateChanged(name string, from, to State) {
+	r.mu.RLock()
+	callbacks := r.onStateChange
+	r.mu.RUnlock()
+
+	for _, callback := range callbacks {
+		callback(name, from, to)
+	}
+}
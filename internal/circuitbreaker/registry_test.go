@@ -0,0 +1,64 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry_GetCreatesOnce(t *testing.T) {
+	registry := NewRegistry()
+
+	var created []string
+	registry.OnCreate(func(name string, cb *CircuitBreaker) {
+		created = append(created, name)
+	})
+
+	cb := registry.Get("http:api.example.com", &Config{MaxFailures: 1, Timeout: time.Minute, HalfOpenMaxRequests: 1})
+	if again := registry.Get("http:api.example.com", nil); again != cb {
+		t.Error("Get should return the existing circuit breaker")
+	}
+	registry.Get("docker", nil)
+
+	if len(created) != 2 {
+		t.Errorf("Expected 2 created circuit breakers, got %v", created)
+	}
+	if names := registry.Names(); len(names) != 2 || names[0] != "docker" || names[1] != "http:api.example.com" {
+		t.Errorf("Expected sorted names [docker http:api.example.com], got %v", names)
+	}
+	if _, ok := registry.Lookup("http:other.example.com"); ok {
+		t.Error("Lookup should not find a circuit breaker that was never created")
+	}
+}
+
+func TestRegistry_OnStateChange(t *testing.T) {
+	registry := NewRegistry()
+
+	var configChanges, registryChanges []State
+	registry.OnStateChange(func(name string, from, to State) {
+		if name != "docker" {
+			t.Errorf("Expected a state change of docker, got %s", name)
+		}
+		registryChanges = append(registryChanges, to)
+	})
+
+	cb := registry.Get("docker", &Config{
+		MaxFailures:         1,
+		Timeout:             time.Minute,
+		HalfOpenMaxRequests: 1,
+		OnStateChange: func(from, to State) {
+			configChanges = append(configChanges, to)
+		},
+	})
+	cb.Execute(context.Background(), func() error {
+		return errors.New("daemon unavailable")
+	})
+
+	if len(configChanges) != 1 || configChanges[0] != StateOpen {
+		t.Errorf("Expected the config's callback to see the breaker open, got %v", configChanges)
+	}
+	if len(registryChanges) != 1 || registryChanges[0] != StateOpen {
+		t.Errorf("Expected the registry's callback to see the breaker open, got %v", registryChanges)
+	}
+}
@@ -72,6 +72,30 @@ func (b *Builder) Paused(paused bool) *Builder {
 	return b
 }
 
+// SLA sets the time a DAG run may take before it misses its SLA
+func (b *Builder) SLA(duration time.Duration) *Builder {
+	b.dag.SLA = duration
+	return b
+}
+
+// Notifications sets the notifiers told about runs and tasks of the DAG
+func (b *Builder) Notifications(rules *models.NotificationRules) *Builder {
+	b.dag.Notifications = rules
+	return b
+}
+
+// CircuitBreaker sets the circuit breaker settings of tasks that do not set their own
+func (b *Builder) CircuitBreaker(config *models.CircuitBreakerConfig) *Builder {
+	b.dag.CircuitBreaker = config
+	return b
+}
+
+// Param adds a parameter to the DAG, available to task command templates as {{ .params.<name> }}
+func (b *Builder) Param(name string, defaultValue interface{}) *Builder {
+	b.dag.Params = append(b.dag.Params, models.Param{Name: name, Default: defaultValue})
+	return b
+}
+
 // Task adds a task to the DAG
 func (b *Builder) Task(id string, taskBuilder *TaskBuilder) *Builder {
 	task := taskBuilder.build(id)
@@ -107,13 +131,18 @@ func (b *Builder) MustBuild() *models.DAG {
 
 // TaskBuilder provides a fluent API for building tasks
 type TaskBuilder struct {
-	name         string
-	taskType     models.TaskType
-	command      string
-	dependencies []string
-	retries      int
-	timeout      time.Duration
-	sla          time.Duration
+	name           string
+	taskType       models.TaskType
+	command        string
+	dependencies   []string
+	retries        int
+	timeout        time.Duration
+	sla            time.Duration
+	retryPolicy    *models.RetryPolicy
+	circuitBreaker *models.CircuitBreakerConfig
+	triggerRule    models.TriggerRule
+	branch         *models.BranchConfig
+	resultPaths    map[string]string
 }
 
 // BashTask creates a new Bash task builder
@@ -182,6 +211,78 @@ func (tb *TaskBuilder) SLA(duration time.Duration) *TaskBuilder {
 	return tb
 }
 
+// RetryStrategy sets the backoff strategy used between retries
+func (tb *TaskBuilder) RetryStrategy(strategy models.RetryStrategy) *TaskBuilder {
+	tb.policy().Strategy = strategy
+	return tb
+}
+
+// RetryDelay sets the base and maximum delay between retries
+func (tb *TaskBuilder) RetryDelay(base, max time.Duration) *TaskBuilder {
+	policy := tb.policy()
+	policy.BaseDelay = base
+	policy.MaxDelay = max
+	return tb
+}
+
+// RetryJitter enables or disables randomized retry delays
+func (tb *TaskBuilder) RetryJitter(jitter bool) *TaskBuilder {
+	tb.policy().Jitter = jitter
+	return tb
+}
+
+// RetryOn limits retries to failures with the given error codes or exit codes
+func (tb *TaskBuilder) RetryOn(codes ...string) *TaskBuilder {
+	policy := tb.policy()
+	policy.RetryOn = append(policy.RetryOn, codes...)
+	return tb
+}
+
+// CircuitBreaker sets the circuit breaker settings of the task, overriding the DAG's
+func (tb *TaskBuilder) CircuitBreaker(config *models.CircuitBreakerConfig) *TaskBuilder {
+	tb.circuitBreaker = config
+	return tb
+}
+
+// TriggerRule sets when the task runs given the states of its dependencies
+func (tb *TaskBuilder) TriggerRule(rule models.TriggerRule) *TaskBuilder {
+	tb.triggerRule = rule
+	return tb
+}
+
+// Branch makes the task a branch that follows some of the given immediate downstream tasks, named by its result
+func (tb *TaskBuilder) Branch(targets ...string) *TaskBuilder {
+	tb.branch = &models.BranchConfig{Targets: targets}
+	return tb
+}
+
+// BranchField sets the field of the JSON response body naming the downstream tasks an HTTP branch task follows
+func (tb *TaskBuilder) BranchField(field string) *TaskBuilder {
+	if tb.branch == nil {
+		tb.branch = &models.BranchConfig{}
+	}
+	tb.branch.Field = field
+	return tb
+}
+
+// ResultPath makes an HTTP task push a field of its JSON response body under a result key,
+// given as a dot-separated path; "." pushes the whole body
+func (tb *TaskBuilder) ResultPath(key, path string) *TaskBuilder {
+	if tb.resultPaths == nil {
+		tb.resultPaths = make(map[string]string)
+	}
+	tb.resultPaths[key] = path
+	return tb
+}
+
+// policy returns the task's retry policy, creating an exponential one if unset
+func (tb *TaskBuilder) policy() *models.RetryPolicy {
+	if tb.retryPolicy == nil {
+		tb.retryPolicy = &models.RetryPolicy{Strategy: models.RetryStrategyExponential}
+	}
+	return tb.retryPolicy
+}
+
 // build constructs the final task
 func (tb *TaskBuilder) build(id string) *models.Task {
 	name := tb.name
@@ -190,13 +291,18 @@ func (tb *TaskBuilder) build(id string) *models.Task {
 	}
 
 	return &models.Task{
-		ID:           id,
-		Name:         name,
-		Type:         tb.taskType,
-		Command:      tb.command,
-		Dependencies: tb.dependencies,
-		Retries:      tb.retries,
-		Timeout:      tb.timeout,
-		SLA:          tb.sla,
+		ID:             id,
+		Name:           name,
+		Type:           tb.taskType,
+		Command:        tb.command,
+		Dependencies:   tb.dependencies,
+		Retries:        tb.retries,
+		Timeout:        tb.timeout,
+		SLA:            tb.sla,
+		RetryPolicy:    tb.retryPolicy,
+		CircuitBreaker: tb.circuitBreaker,
+		TriggerRule:    tb.triggerRule,
+		Branch:         tb.branch,
+		ResultPaths:    tb.resultPaths,
 	}
 }
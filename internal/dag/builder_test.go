@@ -285,6 +285,48 @@ func TestTaskBuilder_CustomName(t *testing.T) {
 	}
 }
 
+func TestTaskBuilder_RetryPolicy(t *testing.T) {
+	dag, err := NewBuilder("test").
+		Task("task-id", BashTask("exit 75").
+			Retries(3).
+			RetryStrategy(models.RetryStrategyFixed).
+			RetryDelay(5*time.Second, time.Minute).
+			RetryOn("75")).
+		Build()
+
+	if err != nil {
+		t.Fatalf("Failed to build DAG: %v", err)
+	}
+
+	policy := dag.Tasks[0].RetryPolicy
+	if policy == nil {
+		t.Fatal("Expected a retry policy, got nil")
+	}
+	if policy.Strategy != models.RetryStrategyFixed {
+		t.Errorf("Expected fixed strategy, got '%s'", policy.Strategy)
+	}
+	if policy.BaseDelay != 5*time.Second || policy.MaxDelay != time.Minute {
+		t.Errorf("Expected delays 5s/1m, got %v/%v", policy.BaseDelay, policy.MaxDelay)
+	}
+	if len(policy.RetryOn) != 1 || policy.RetryOn[0] != "75" {
+		t.Errorf("Expected retry_on [75], got %v", policy.RetryOn)
+	}
+}
+
+func TestTaskBuilder_NoRetryPolicy(t *testing.T) {
+	dag, err := NewBuilder("test").
+		Task("task-id", BashTask("echo hello").Retries(2)).
+		Build()
+
+	if err != nil {
+		t.Fatalf("Failed to build DAG: %v", err)
+	}
+
+	if dag.Tasks[0].RetryPolicy != nil {
+		t.Errorf("Expected no retry policy, got %+v", dag.Tasks[0].RetryPolicy)
+	}
+}
+
 func TestBuilder_OrphanedTask(t *testing.T) {
 	// DAG with multiple tasks where one is orphaned
 	_, err := NewBuilder("orphaned").
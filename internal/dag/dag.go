@@ -3,6 +3,9 @@ package dag
 import (
 	"fmt"
 
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/internal/retry"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
@@ -42,6 +45,71 @@ func (v *Validator) Validate(dag *models.DAG) error {
 		}
 	}
 
+	// Validate task retry policies
+	for _, task := range dag.Tasks {
+		if task.RetryPolicy == nil {
+			continue
+		}
+		if _, err := retry.NewStrategyFromPolicy(task.RetryPolicy); err != nil {
+			return fmt.Errorf("task %s has an invalid retry policy: %w", task.ID, err)
+		}
+	}
+
+	// Validate task trigger rules
+	for _, task := range dag.Tasks {
+		if !task.TriggerRule.IsValid() {
+			return fmt.Errorf("task %s has an unknown trigger rule: %s", task.ID, task.TriggerRule)
+		}
+	}
+
+	// Validate branch tasks, which may only follow tasks that depend on them directly
+	for _, task := range dag.Tasks {
+		if err := validateBranch(dag, &task); err != nil {
+			return err
+		}
+	}
+
+	// Validate the response body fields HTTP tasks push as results
+	for _, task := range dag.Tasks {
+		if err := validateResultPaths(&task); err != nil {
+			return err
+		}
+	}
+
+	// Validate the DAG's parameters and the command templates reading them
+	if err := validateParams(dag); err != nil {
+		return err
+	}
+	for _, task := range dag.Tasks {
+		if task.Type == models.TaskTypeGo {
+			continue
+		}
+		if err := render.Parse(task.Command); err != nil {
+			return fmt.Errorf("task %s: %w", task.ID, err)
+		}
+	}
+
+	// Validate circuit breaker settings
+	if err := validateCircuitBreaker(dag.CircuitBreaker); err != nil {
+		return fmt.Errorf("DAG has invalid circuit breaker settings: %w", err)
+	}
+	for _, task := range dag.Tasks {
+		if err := validateCircuitBreaker(task.CircuitBreaker); err != nil {
+			return fmt.Errorf("task %s has invalid circuit breaker settings: %w", task.ID, err)
+		}
+	}
+
+	// Validate notification rules
+	if rules := dag.Notifications; rules != nil {
+		for _, names := range [][]string{rules.OnFailure, rules.OnSuccess, rules.OnSLAMiss, rules.OnRetry} {
+			for _, name := range names {
+				if name == "" {
+					return fmt.Errorf("notification rules cannot name an empty notifier")
+				}
+			}
+		}
+	}
+
 	// Check for cycles
 	if err := v.detectCycle(dag); err != nil {
 		return err
@@ -55,6 +123,74 @@ func (v *Validator) Validate(dag *models.DAG) error {
 	return nil
 }
 
+// validateBranch checks the branch settings of a task, which may be unset
+func validateBranch(dag *models.DAG, task *models.Task) error {
+	if task.Branch == nil {
+		return nil
+	}
+
+	switch task.Type {
+	case models.TaskTypeBash, models.TaskTypeHTTP, models.TaskTypeGo:
+	default:
+		return fmt.Errorf("branch task %s must be a bash, http or go task", task.ID)
+	}
+
+	if len(task.Branch.Targets) == 0 {
+		return fmt.Errorf("branch task %s has no targets", task.ID)
+	}
+
+	children := make(map[string]bool)
+	for _, other := range dag.Tasks {
+		for _, depID := range other.Dependencies {
+			if depID == task.ID {
+				children[other.ID] = true
+			}
+		}
+	}
+	for _, target := range task.Branch.Targets {
+		if !children[target] {
+			return fmt.Errorf("branch task %s targets %s, which does not depend on it directly", task.ID, target)
+		}
+	}
+
+	return nil
+}
+
+// validateResultPaths checks the result paths of a task, which only HTTP tasks may have
+func validateResultPaths(task *models.Task) error {
+	if len(task.ResultPaths) == 0 {
+		return nil
+	}
+	if task.Type != models.TaskTypeHTTP {
+		return fmt.Errorf("task %s has result paths, which only http tasks support", task.ID)
+	}
+
+	for key, path := range task.ResultPaths {
+		if err := results.ValidateKey(key); err != nil {
+			return fmt.Errorf("task %s pushes an invalid result key: %w", task.ID, err)
+		}
+		if path == "" {
+			return fmt.Errorf("task %s has an empty result path for %s", task.ID, key)
+		}
+	}
+
+	return nil
+}
+
+// validateCircuitBreaker checks circuit breaker settings, which may be unset
+func validateCircuitBreaker(config *models.CircuitBreakerConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.MaxFailures < 0 {
+		return fmt.Errorf("max_failures cannot be negative")
+	}
+	if config.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	return nil
+}
+
 // checkOrphanedTasks verifies that all tasks are connected in the graph
 // A task is orphaned if it has no dependencies and no tasks depend on it (for multi-task DAGs)
 func (v *Validator) checkOrphanedTasks(dag *models.DAG) error {
@@ -225,3 +225,134 @@ func TestValidate_ComplexConnectedDAG(t *testing.T) {
 		t.Errorf("Expected no error for complex connected DAG, got: %v", err)
 	}
 }
+
+func TestValidate_BranchTask(t *testing.T) {
+	validator := NewValidator()
+	newDAG := func(task models.Task) *models.DAG {
+		return &models.DAG{
+			Name: "test-dag",
+			Tasks: []models.Task{
+				task,
+				{ID: "full", Name: "Full", Type: models.TaskTypeBash, Dependencies: []string{"choose"}},
+				{ID: "incremental", Name: "Incremental", Type: models.TaskTypeBash, Dependencies: []string{"choose"}},
+				{ID: "report", Name: "Report", Type: models.TaskTypeBash, Dependencies: []string{"full", "incremental"}},
+			},
+		}
+	}
+
+	valid := models.Task{ID: "choose", Name: "Choose", Type: models.TaskTypeBash,
+		Branch: &models.BranchConfig{Targets: []string{"full", "incremental"}}}
+	if err := validator.Validate(newDAG(valid)); err != nil {
+		t.Errorf("Expected no error for branch task, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		branch *models.BranchConfig
+		typ    models.TaskType
+	}{
+		{"no targets", &models.BranchConfig{}, models.TaskTypeBash},
+		{"indirect target", &models.BranchConfig{Targets: []string{"report"}}, models.TaskTypeBash},
+		{"unknown target", &models.BranchConfig{Targets: []string{"missing"}}, models.TaskTypeBash},
+		{"unsupported type", &models.BranchConfig{Targets: []string{"full"}}, models.TaskTypePython},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := models.Task{ID: "choose", Name: "Choose", Type: tt.typ, Branch: tt.branch}
+			if err := validator.Validate(newDAG(task)); err == nil {
+				t.Error("Expected error for invalid branch task, got nil")
+			}
+		})
+	}
+}
+
+func TestValidate_ResultPaths(t *testing.T) {
+	validator := NewValidator()
+	newDAG := func(typ models.TaskType, paths map[string]string) *models.DAG {
+		return &models.DAG{
+			Name:  "test-dag",
+			Tasks: []models.Task{{ID: "submit", Name: "Submit", Type: typ, ResultPaths: paths}},
+		}
+	}
+
+	if err := validator.Validate(newDAG(models.TaskTypeHTTP, map[string]string{"job_id": "job.id", "body": "."})); err != nil {
+		t.Errorf("Expected no error for result paths, got: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		typ   models.TaskType
+		paths map[string]string
+	}{
+		{"not an http task", models.TaskTypeBash, map[string]string{"job_id": "job.id"}},
+		{"invalid key", models.TaskTypeHTTP, map[string]string{"job id": "job.id"}},
+		{"empty path", models.TaskTypeHTTP, map[string]string{"job_id": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validator.Validate(newDAG(tt.typ, tt.paths)); err == nil {
+				t.Error("Expected error for invalid result paths, got nil")
+			}
+		})
+	}
+}
+
+func TestValidate_ParamsAndTemplates(t *testing.T) {
+	validator := NewValidator()
+	newDAG := func(params []models.Param, command string) *models.DAG {
+		return &models.DAG{
+			Name:   "test-dag",
+			Params: params,
+			Tasks:  []models.Task{{ID: "load", Name: "Load", Type: models.TaskTypeBash, Command: command}},
+		}
+	}
+
+	valid := newDAG([]models.Param{{Name: "table", Default: "events"}}, "load {{ .params.table }} --date {{ .ds }}")
+	if err := validator.Validate(valid); err != nil {
+		t.Errorf("Expected no error for a templated command, got: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		params  []models.Param
+		command string
+	}{
+		{"invalid parameter name", []models.Param{{Name: "table-name"}}, "echo"},
+		{"duplicate parameter", []models.Param{{Name: "table"}, {Name: "table"}}, "echo"},
+		{"unknown parameter type", []models.Param{{Name: "table", Type: "text"}}, "echo"},
+		{"default of the wrong type", []models.Param{{Name: "limit", Type: models.ParamTypeInteger, Default: "ten"}}, "echo"},
+		{"default outside the enum", []models.Param{{Name: "mode", Enum: []interface{}{"full", "incremental"}, Default: "partial"}}, "echo"},
+		{"enum value of the wrong type", []models.Param{{Name: "limit", Type: models.ParamTypeInteger, Enum: []interface{}{1, 2.5}}}, "echo"},
+		{"unclosed action", nil, "load {{ .ds"},
+		{"unknown function", nil, "load {{ upper .ds }}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validator.Validate(newDAG(tt.params, tt.command)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidate_RequiredParamsOfScheduledDAG(t *testing.T) {
+	validator := NewValidator()
+	dag := &models.DAG{
+		Name:   "test-dag",
+		Params: []models.Param{{Name: "table", Required: true}},
+		Tasks:  []models.Task{{ID: "load", Name: "Load", Type: models.TaskTypeBash, Command: "load {{ .params.table }}"}},
+	}
+	if err := validator.Validate(dag); err != nil {
+		t.Errorf("Expected a required parameter of a manually triggered DAG to be valid, got: %v", err)
+	}
+
+	// Scheduled runs have no conf and could never set it
+	dag.Schedule = "@daily"
+	if err := validator.Validate(dag); err == nil {
+		t.Error("Expected a required parameter without a default on a scheduled DAG to be rejected")
+	}
+	dag.Params[0].Default = "events"
+	if err := validator.Validate(dag); err != nil {
+		t.Errorf("Expected a required parameter with a default to be valid, got: %v", err)
+	}
+}
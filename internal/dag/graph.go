@@ -10,6 +10,7 @@ import (
 // Graph represents a DAG as an adjacency list with advanced graph algorithms
 type Graph struct {
 	tasks      map[string]*models.Task
+	order      []string            // Task IDs in definition order
 	adjList    map[string][]string // taskID -> list of dependent task IDs
 	revAdjList map[string][]string // taskID -> list of dependency task IDs
 }
@@ -26,6 +27,7 @@ func NewGraph(dag *models.DAG) *Graph {
 	for i := range dag.Tasks {
 		task := &dag.Tasks[i]
 		g.tasks[task.ID] = task
+		g.order = append(g.order, task.ID)
 		g.adjList[task.ID] = []string{}
 		g.revAdjList[task.ID] = task.Dependencies
 	}
@@ -68,6 +70,89 @@ func (g *Graph) GetParallelTasks(completed map[string]bool) []string {
 	return parallelTasks
 }
 
+// GetReadyTasks evaluates the trigger rules of the tasks that have not finished against the final states
+// of the finished ones, see EvaluateTriggerRule. It returns the tasks that can run and the state each task
+// that will not run ends in, including tasks that will not run because of those. Tasks still waiting for
+// dependencies are left out. Ready tasks are returned in definition order.
+func (g *Graph) GetReadyTasks(states map[string]models.State) (ready []string, notRun map[string]models.State) {
+	decided := make(map[string]models.State, len(states))
+	for taskID, state := range states {
+		decided[taskID] = state
+	}
+
+	// Tasks that will not run may decide the trigger rules of their dependents
+	notRun = make(map[string]models.State)
+	for changed := true; changed; {
+		changed = false
+		for _, taskID := range g.order {
+			if _, finished := decided[taskID]; finished {
+				continue
+			}
+			if run, state := EvaluateTriggerRule(g.tasks[taskID], decided); !run && state != "" {
+				decided[taskID] = state
+				notRun[taskID] = state
+				changed = true
+			}
+		}
+	}
+
+	for _, taskID := range g.order {
+		if _, finished := decided[taskID]; finished {
+			continue
+		}
+		if run, _ := EvaluateTriggerRule(g.tasks[taskID], decided); run {
+			ready = append(ready, taskID)
+		}
+	}
+
+	return ready, notRun
+}
+
+// GetBranchSkipSet returns the tasks skipped when a branch task follows only some of its immediate downstream
+// tasks: the others, and every task that can only be reached through them. Tasks that can also be reached
+// through a followed task or another path are left to their trigger rules. Tasks are in definition order.
+func (g *Graph) GetBranchSkipSet(taskID string, followed []string) ([]string, error) {
+	if _, exists := g.tasks[taskID]; !exists {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+
+	unfollowed := make(map[string]bool)
+	for _, childID := range g.adjList[taskID] {
+		unfollowed[childID] = true
+	}
+	for _, childID := range followed {
+		delete(unfollowed, childID)
+	}
+	if len(unfollowed) == 0 {
+		return nil, nil
+	}
+
+	// Find the tasks reachable from the roots without passing through an unfollowed task
+	reachable := make(map[string]bool)
+	var dfs func(string)
+	dfs = func(id string) {
+		if reachable[id] || unfollowed[id] {
+			return
+		}
+		reachable[id] = true
+		for _, dependentID := range g.adjList[id] {
+			dfs(dependentID)
+		}
+	}
+	for _, rootID := range g.GetRootTasks() {
+		dfs(rootID)
+	}
+
+	var skipped []string
+	for _, id := range g.order {
+		if !reachable[id] {
+			skipped = append(skipped, id)
+		}
+	}
+
+	return skipped, nil
+}
+
 // GetUpstreamTasks returns all tasks that this task depends on (directly or indirectly)
 func (g *Graph) GetUpstreamTasks(taskID string) ([]string, error) {
 	if _, exists := g.tasks[taskID]; !exists {
@@ -1,6 +1,7 @@
 package dag
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -115,6 +116,84 @@ func TestGetParallelTasks(t *testing.T) {
 	}
 }
 
+func TestGetReadyTasks(t *testing.T) {
+	graph := NewGraph(&models.DAG{
+		ID: "etl",
+		Tasks: []models.Task{
+			{ID: "extract"},
+			{ID: "transform", Dependencies: []string{"extract"}},
+			{ID: "load", Dependencies: []string{"transform"}},
+			{ID: "alert", Dependencies: []string{"extract", "transform"}, TriggerRule: models.TriggerRuleOneFailed},
+			{ID: "cleanup", Dependencies: []string{"load"}, TriggerRule: models.TriggerRuleAllDone},
+		},
+	})
+
+	ready, notRun := graph.GetReadyTasks(map[string]models.State{})
+	if len(ready) != 1 || ready[0] != "extract" || len(notRun) != 0 {
+		t.Errorf("Expected only extract to be ready, got %v and %v", ready, notRun)
+	}
+
+	// The failure cascades to transform and load, which lets alert and cleanup run
+	ready, notRun = graph.GetReadyTasks(map[string]models.State{"extract": models.StateFailed})
+	if len(ready) != 2 || ready[0] != "alert" || ready[1] != "cleanup" {
+		t.Errorf("Expected alert and cleanup to be ready, got %v", ready)
+	}
+	if notRun["transform"] != models.StateUpstreamFailed || notRun["load"] != models.StateUpstreamFailed || len(notRun) != 2 {
+		t.Errorf("Expected transform and load to be upstream_failed, got %v", notRun)
+	}
+
+	// Without failures alert is skipped
+	ready, notRun = graph.GetReadyTasks(map[string]models.State{
+		"extract":   models.StateSuccess,
+		"transform": models.StateSuccess,
+	})
+	if len(ready) != 1 || ready[0] != "load" {
+		t.Errorf("Expected load to be ready, got %v", ready)
+	}
+	if notRun["alert"] != models.StateSkipped || len(notRun) != 1 {
+		t.Errorf("Expected alert to be skipped, got %v", notRun)
+	}
+}
+
+func TestGetBranchSkipSet(t *testing.T) {
+	graph := NewGraph(&models.DAG{
+		ID: "etl",
+		Tasks: []models.Task{
+			{ID: "choose", Branch: &models.BranchConfig{Targets: []string{"full", "incremental"}}},
+			{ID: "full", Dependencies: []string{"choose"}},
+			{ID: "full_load", Dependencies: []string{"full"}},
+			{ID: "incremental", Dependencies: []string{"choose"}},
+			{ID: "report", Dependencies: []string{"full_load", "incremental"}, TriggerRule: models.TriggerRuleNoneFailed},
+			{ID: "audit", Dependencies: []string{"choose"}},
+		},
+	})
+
+	// The unfollowed path is skipped, but report can still be reached through incremental
+	skipped, err := graph.GetBranchSkipSet("choose", []string{"incremental"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{"full", "full_load", "audit"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("Expected %v to be skipped, got %v", want, skipped)
+	}
+
+	// Following no task skips everything downstream
+	skipped, _ = graph.GetBranchSkipSet("choose", nil)
+	if want := []string{"full", "full_load", "incremental", "report", "audit"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("Expected %v to be skipped, got %v", want, skipped)
+	}
+
+	// Following every task skips nothing
+	skipped, _ = graph.GetBranchSkipSet("choose", []string{"full", "incremental", "audit"})
+	if len(skipped) != 0 {
+		t.Errorf("Expected nothing to be skipped, got %v", skipped)
+	}
+
+	if _, err := graph.GetBranchSkipSet("missing", nil); err == nil {
+		t.Error("Expected error for unknown task")
+	}
+}
+
 func TestGetUpstreamTasks(t *testing.T) {
 	dag := createTestDAG()
 	graph := NewGraph(dag)
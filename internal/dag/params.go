@@ -0,0 +1,156 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// ErrInvalidConf is returned when the conf of a DAG run does not match the DAG's parameters
+var ErrInvalidConf = errors.New("invalid conf")
+
+// paramNamePattern matches parameter names, which templates refer to as {{ .params.<name> }}
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateParams checks the parameters of a DAG: their names, types, enums and defaults
+func validateParams(dag *models.DAG) error {
+	names := make(map[string]bool, len(dag.Params))
+	for _, param := range dag.Params {
+		if !paramNamePattern.MatchString(param.Name) {
+			return fmt.Errorf("invalid parameter name %q: names may only contain letters, digits and underscores", param.Name)
+		}
+		if names[param.Name] {
+			return fmt.Errorf("duplicate parameter: %s", param.Name)
+		}
+		names[param.Name] = true
+
+		if !param.Type.IsValid() {
+			return fmt.Errorf("parameter %s has an unknown type: %s", param.Name, param.Type)
+		}
+		for _, value := range param.Enum {
+			if !paramTypeMatches(param.Type, value) {
+				return fmt.Errorf("parameter %s has enum value %v, which is not of type %s", param.Name, value, param.Type)
+			}
+		}
+		if param.Default != nil {
+			if err := checkParamValue(param, param.Default); err != nil {
+				return fmt.Errorf("parameter %s has an invalid default: %w", param.Name, err)
+			}
+		}
+
+		// Scheduled runs have no conf, so they could never run
+		if param.Required && param.Default == nil && dag.Schedule != "" {
+			return fmt.Errorf("parameter %s of a scheduled DAG is required but has no default", param.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateConf checks the conf a DAG run is triggered with against the DAG's parameters: it may only set
+// parameters the DAG declares, to values of their type and enum, and required parameters must end up with
+// a value. A nil conf leaves every parameter to its default.
+func ValidateConf(dag *models.DAG, conf map[string]interface{}) error {
+	params := make(map[string]models.Param, len(dag.Params))
+	for _, param := range dag.Params {
+		params[param.Name] = param
+	}
+
+	// Keys are checked in order so that the error is the same every time
+	keys := make([]string, 0, len(conf))
+	for key := range conf {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		param, ok := params[key]
+		if !ok {
+			return fmt.Errorf("%w: unknown parameter %s", ErrInvalidConf, key)
+		}
+		if conf[key] == nil {
+			continue
+		}
+		if err := checkParamValue(param, conf[key]); err != nil {
+			return fmt.Errorf("%w: parameter %s: %v", ErrInvalidConf, key, err)
+		}
+	}
+
+	for name, value := range dag.ParamValues(conf) {
+		if value == nil && params[name].Required {
+			return fmt.Errorf("%w: parameter %s is required", ErrInvalidConf, name)
+		}
+	}
+	return nil
+}
+
+// checkParamValue checks that a value other than null is of the parameter's type and one of its enum values
+func checkParamValue(param models.Param, value interface{}) error {
+	if !paramTypeMatches(param.Type, value) {
+		return fmt.Errorf("expected a value of type %s, got %v", param.Type, value)
+	}
+	if len(param.Enum) == 0 {
+		return nil
+	}
+	for _, allowed := range param.Enum {
+		if sameParamValue(allowed, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("value %v is not one of %v", value, param.Enum)
+}
+
+// paramTypeMatches reports whether a value decoded from JSON or YAML is of a parameter type
+func paramTypeMatches(paramType models.ParamType, value interface{}) bool {
+	if paramType == "" {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return false
+	}
+	switch paramType {
+	case models.ParamTypeString:
+		return v.Kind() == reflect.String
+	case models.ParamTypeBoolean:
+		return v.Kind() == reflect.Bool
+	case models.ParamTypeNumber:
+		_, ok := toFloat(value)
+		return ok
+	case models.ParamTypeInteger:
+		number, ok := toFloat(value)
+		return ok && number == math.Trunc(number)
+	case models.ParamTypeArray:
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	case models.ParamTypeObject:
+		return v.Kind() == reflect.Map
+	}
+	return false
+}
+
+// sameParamValue compares parameter values, comparing numbers by value whatever their Go type
+func sameParamValue(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// toFloat converts a number decoded from JSON or YAML to a float64
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
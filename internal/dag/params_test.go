@@ -0,0 +1,50 @@
+package dag
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestValidateConf(t *testing.T) {
+	dag := &models.DAG{
+		Params: []models.Param{
+			{Name: "mode", Type: models.ParamTypeString, Default: "full", Enum: []interface{}{"full", "incremental"}},
+			{Name: "limit", Type: models.ParamTypeInteger, Default: 100},
+			{Name: "tables", Type: models.ParamTypeArray, Required: true},
+			{Name: "options", Type: models.ParamTypeObject},
+			{Name: "dry_run", Type: models.ParamTypeBoolean},
+			{Name: "extra"},
+		},
+	}
+
+	valid := []map[string]interface{}{
+		{"tables": []interface{}{"events"}},
+		{"tables": []interface{}{}, "mode": "incremental", "limit": 10.0, "dry_run": true},
+		{"tables": []interface{}{"events"}, "options": map[string]interface{}{"verbose": true}, "extra": 1.5},
+		{"tables": []interface{}{"events"}, "limit": nil},
+	}
+	for _, conf := range valid {
+		if err := ValidateConf(dag, conf); err != nil {
+			t.Errorf("ValidateConf(%v) failed: %v", conf, err)
+		}
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"missing required parameter": nil,
+		"null required parameter":    {"tables": nil},
+		"unknown parameter":          {"tables": []interface{}{}, "table": "events"},
+		"value outside the enum":     {"tables": []interface{}{}, "mode": "partial"},
+		"string for an integer":      {"tables": []interface{}{}, "limit": "10"},
+		"fraction for an integer":    {"tables": []interface{}{}, "limit": 10.5},
+		"string for a boolean":       {"tables": []interface{}{}, "dry_run": "true"},
+		"object for an array":        {"tables": map[string]interface{}{}},
+		"array for an object":        {"tables": []interface{}{}, "options": []interface{}{}},
+	}
+	for name, conf := range invalid {
+		if err := ValidateConf(dag, conf); !errors.Is(err, ErrInvalidConf) {
+			t.Errorf("%s: ValidateConf error = %v, want %v", name, err, ErrInvalidConf)
+		}
+	}
+}
@@ -24,27 +24,76 @@ func NewParser() *Parser {
 
 // dagFile represents the structure of a DAG definition file
 type dagFile struct {
-	ID          string         `json:"id" yaml:"id"`
-	Name        string         `json:"name" yaml:"name"`
-	Description string         `json:"description" yaml:"description"`
-	Schedule    string         `json:"schedule" yaml:"schedule"`
-	StartDate   string         `json:"start_date" yaml:"start_date"`
-	EndDate     string         `json:"end_date,omitempty" yaml:"end_date,omitempty"`
-	Tags        []string       `json:"tags" yaml:"tags"`
-	IsPaused    bool           `json:"is_paused" yaml:"is_paused"`
-	Tasks       []taskFile     `json:"tasks" yaml:"tasks"`
+	ID             string              `json:"id" yaml:"id"`
+	Name           string              `json:"name" yaml:"name"`
+	Description    string              `json:"description" yaml:"description"`
+	Schedule       string              `json:"schedule" yaml:"schedule"`
+	StartDate      string              `json:"start_date" yaml:"start_date"`
+	EndDate        string              `json:"end_date,omitempty" yaml:"end_date,omitempty"`
+	Tags           []string            `json:"tags" yaml:"tags"`
+	IsPaused       bool                `json:"is_paused" yaml:"is_paused"`
+	SLA            string              `json:"sla,omitempty" yaml:"sla,omitempty"`
+	Notifications  *notificationsFile  `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	CircuitBreaker *circuitBreakerFile `json:"circuit_breaker,omitempty" yaml:"circuit_breaker,omitempty"`
+	Params         []paramFile         `json:"params,omitempty" yaml:"params,omitempty"`
+	Tasks          []taskFile          `json:"tasks" yaml:"tasks"`
 }
 
 // taskFile represents the structure of a task in a DAG file
 type taskFile struct {
-	ID           string   `json:"id" yaml:"id"`
-	Name         string   `json:"name" yaml:"name"`
-	Type         string   `json:"type" yaml:"type"`
-	Command      string   `json:"command" yaml:"command"`
-	Dependencies []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
-	Retries      int      `json:"retries,omitempty" yaml:"retries,omitempty"`
-	Timeout      string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
-	SLA          string   `json:"sla,omitempty" yaml:"sla,omitempty"`
+	ID             string              `json:"id" yaml:"id"`
+	Name           string              `json:"name" yaml:"name"`
+	Type           string              `json:"type" yaml:"type"`
+	Command        string              `json:"command" yaml:"command"`
+	Dependencies   []string            `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	Retries        int                 `json:"retries,omitempty" yaml:"retries,omitempty"`
+	Timeout        string              `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	SLA            string              `json:"sla,omitempty" yaml:"sla,omitempty"`
+	Retry          *retryFile          `json:"retry,omitempty" yaml:"retry,omitempty"`
+	CircuitBreaker *circuitBreakerFile `json:"circuit_breaker,omitempty" yaml:"circuit_breaker,omitempty"`
+	TriggerRule    string              `json:"trigger_rule,omitempty" yaml:"trigger_rule,omitempty"`
+	Branch         *branchFile         `json:"branch,omitempty" yaml:"branch,omitempty"`
+	ResultPaths    map[string]string   `json:"result_paths,omitempty" yaml:"result_paths,omitempty"`
+}
+
+// paramFile represents a parameter in the params block of a DAG file
+type paramFile struct {
+	Name        string        `json:"name" yaml:"name"`
+	Type        string        `json:"type,omitempty" yaml:"type,omitempty"`
+	Default     interface{}   `json:"default,omitempty" yaml:"default,omitempty"`
+	Required    bool          `json:"required,omitempty" yaml:"required,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// branchFile represents the branch block of a task in a DAG file
+type branchFile struct {
+	Targets []string `json:"targets" yaml:"targets"`
+	Field   string   `json:"field,omitempty" yaml:"field,omitempty"`
+}
+
+// retryFile represents the retry policy block of a task in a DAG file
+type retryFile struct {
+	Strategy  string   `json:"strategy" yaml:"strategy"`
+	BaseDelay string   `json:"base_delay,omitempty" yaml:"base_delay,omitempty"`
+	MaxDelay  string   `json:"max_delay,omitempty" yaml:"max_delay,omitempty"`
+	Jitter    bool     `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	RetryOn   []string `json:"retry_on,omitempty" yaml:"retry_on,omitempty"`
+}
+
+// circuitBreakerFile represents the circuit breaker block of a DAG or task in a DAG file
+type circuitBreakerFile struct {
+	MaxFailures int    `json:"max_failures,omitempty" yaml:"max_failures,omitempty"`
+	Timeout     string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// notificationsFile represents the notification rules block of a DAG file
+type notificationsFile struct {
+	OnFailure []string `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	OnSuccess []string `json:"on_success,omitempty" yaml:"on_success,omitempty"`
+	OnSLAMiss []string `json:"on_sla_miss,omitempty" yaml:"on_sla_miss,omitempty"`
+	OnRetry   []string `json:"on_retry,omitempty" yaml:"on_retry,omitempty"`
 }
 
 // ParseYAMLFile parses a DAG definition from a YAML file
@@ -121,6 +170,45 @@ func (p *Parser) convertToDAG(df *dagFile) (*models.DAG, error) {
 		endDate = &ed
 	}
 
+	// Parse the SLA of DAG runs
+	var sla time.Duration
+	if df.SLA != "" {
+		sla, err = time.ParseDuration(df.SLA)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sla format: %w", err)
+		}
+	}
+
+	// Convert the notification rules
+	var notifications *models.NotificationRules
+	if nf := df.Notifications; nf != nil {
+		notifications = &models.NotificationRules{
+			OnFailure: nf.OnFailure,
+			OnSuccess: nf.OnSuccess,
+			OnSLAMiss: nf.OnSLAMiss,
+			OnRetry:   nf.OnRetry,
+		}
+	}
+
+	// Parse the circuit breaker settings of the DAG's tasks
+	circuitBreaker, err := parseCircuitBreaker(df.CircuitBreaker)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the parameters of the DAG
+	var params []models.Param
+	for _, pf := range df.Params {
+		params = append(params, models.Param{
+			Name:        pf.Name,
+			Type:        models.ParamType(pf.Type),
+			Default:     pf.Default,
+			Required:    pf.Required,
+			Enum:        pf.Enum,
+			Description: pf.Description,
+		})
+	}
+
 	// Convert tasks
 	tasks := make([]models.Task, 0, len(df.Tasks))
 	for _, tf := range df.Tasks {
@@ -132,17 +220,21 @@ func (p *Parser) convertToDAG(df *dagFile) (*models.DAG, error) {
 	}
 
 	dag := &models.DAG{
-		ID:          df.ID,
-		Name:        df.Name,
-		Description: df.Description,
-		Schedule:    df.Schedule,
-		Tasks:       tasks,
-		StartDate:   startDate,
-		EndDate:     endDate,
-		Tags:        df.Tags,
-		IsPaused:    df.IsPaused,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:             df.ID,
+		Name:           df.Name,
+		Description:    df.Description,
+		Schedule:       df.Schedule,
+		Tasks:          tasks,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		Tags:           df.Tags,
+		IsPaused:       df.IsPaused,
+		SLA:            sla,
+		Notifications:  notifications,
+		CircuitBreaker: circuitBreaker,
+		Params:         params,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
 	// Validate the DAG
@@ -179,20 +271,94 @@ func (p *Parser) convertToTask(tf *taskFile) (*models.Task, error) {
 		}
 	}
 
+	// Parse retry policy
+	var retryPolicy *models.RetryPolicy
+	if tf.Retry != nil {
+		retryPolicy, err = parseRetryPolicy(tf.Retry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse circuit breaker settings
+	circuitBreaker, err := parseCircuitBreaker(tf.CircuitBreaker)
+	if err != nil {
+		return nil, err
+	}
+
 	task := &models.Task{
-		ID:           tf.ID,
-		Name:         tf.Name,
-		Type:         taskType,
-		Command:      tf.Command,
-		Dependencies: tf.Dependencies,
-		Retries:      tf.Retries,
-		Timeout:      timeout,
-		SLA:          sla,
+		ID:             tf.ID,
+		Name:           tf.Name,
+		Type:           taskType,
+		Command:        tf.Command,
+		Dependencies:   tf.Dependencies,
+		Retries:        tf.Retries,
+		Timeout:        timeout,
+		SLA:            sla,
+		RetryPolicy:    retryPolicy,
+		CircuitBreaker: circuitBreaker,
+		TriggerRule:    models.TriggerRule(tf.TriggerRule),
+		ResultPaths:    tf.ResultPaths,
+	}
+
+	if tf.Branch != nil {
+		task.Branch = &models.BranchConfig{
+			Targets: tf.Branch.Targets,
+			Field:   tf.Branch.Field,
+		}
 	}
 
 	return task, nil
 }
 
+// parseRetryPolicy converts a retryFile to a models.RetryPolicy
+func parseRetryPolicy(rf *retryFile) (*models.RetryPolicy, error) {
+	policy := &models.RetryPolicy{
+		Strategy: models.RetryStrategy(rf.Strategy),
+		Jitter:   rf.Jitter,
+		RetryOn:  rf.RetryOn,
+	}
+
+	var err error
+	if rf.BaseDelay != "" {
+		policy.BaseDelay, err = time.ParseDuration(rf.BaseDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry base_delay format: %w", err)
+		}
+	}
+
+	if rf.MaxDelay != "" {
+		policy.MaxDelay, err = time.ParseDuration(rf.MaxDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry max_delay format: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// parseCircuitBreaker converts a circuitBreakerFile to a models.CircuitBreakerConfig
+func parseCircuitBreaker(cf *circuitBreakerFile) (*models.CircuitBreakerConfig, error) {
+	if cf == nil {
+		return nil, nil
+	}
+
+	config := &models.CircuitBreakerConfig{
+		MaxFailures: cf.MaxFailures,
+		Disabled:    cf.Disabled,
+	}
+
+	if cf.Timeout != "" {
+		timeout, err := time.ParseDuration(cf.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid circuit_breaker timeout format: %w", err)
+		}
+		config.Timeout = timeout
+	}
+
+	return config, nil
+}
+
 // parseTaskType converts a string to a TaskType
 func parseTaskType(typeStr string) (models.TaskType, error) {
 	switch typeStr {
@@ -16,6 +16,13 @@ name: Test DAG
 description: A test DAG for unit testing
 schedule: "0 0 * * *"
 start_date: "2024-01-01"
+sla: 1h
+notifications:
+  on_failure:
+    - ops-slack
+    - oncall-email
+  on_sla_miss:
+    - ops-slack
 tags:
   - test
   - example
@@ -59,6 +66,12 @@ tasks:
 	if len(dag.Tags) != 2 {
 		t.Errorf("Expected 2 tags, got %d", len(dag.Tags))
 	}
+	if dag.SLA != time.Hour {
+		t.Errorf("Expected 1h DAG SLA, got %v", dag.SLA)
+	}
+	if dag.Notifications == nil || len(dag.Notifications.OnFailure) != 2 || len(dag.Notifications.OnSLAMiss) != 1 {
+		t.Errorf("Expected notification rules for failures and SLA misses, got %+v", dag.Notifications)
+	}
 	if len(dag.Tasks) != 2 {
 		t.Fatalf("Expected 2 tasks, got %d", len(dag.Tasks))
 	}
@@ -262,6 +275,121 @@ tasks:
 	}
 }
 
+func TestParseYAML_RetryPolicy(t *testing.T) {
+	yamlData := []byte(`
+id: retry-policy
+name: Retry Policy
+start_date: "2024-01-01"
+tasks:
+  - id: task1
+    name: Task 1
+    type: bash
+    command: exit 75
+    retries: 3
+    retry:
+      strategy: linear
+      base_delay: 10s
+      max_delay: 2m
+      jitter: true
+      retry_on:
+        - timeout
+        - "75"
+`)
+
+	parser := NewParser()
+	dag, err := parser.ParseYAML(yamlData)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	policy := dag.Tasks[0].RetryPolicy
+	if policy == nil {
+		t.Fatal("Expected a retry policy, got nil")
+	}
+	if policy.Strategy != models.RetryStrategyLinear {
+		t.Errorf("Expected linear strategy, got '%s'", policy.Strategy)
+	}
+	if policy.BaseDelay != 10*time.Second {
+		t.Errorf("Expected 10s base delay, got %v", policy.BaseDelay)
+	}
+	if policy.MaxDelay != 2*time.Minute {
+		t.Errorf("Expected 2m max delay, got %v", policy.MaxDelay)
+	}
+	if !policy.Jitter {
+		t.Error("Expected jitter to be enabled")
+	}
+	if len(policy.RetryOn) != 2 || policy.RetryOn[0] != "timeout" || policy.RetryOn[1] != "75" {
+		t.Errorf("Expected retry_on [timeout 75], got %v", policy.RetryOn)
+	}
+}
+
+func TestParseJSON_RetryPolicy(t *testing.T) {
+	jsonData := []byte(`{
+		"id": "retry-policy",
+		"name": "Retry Policy",
+		"start_date": "2024-01-01",
+		"tasks": [
+			{
+				"id": "task1",
+				"name": "Task 1",
+				"type": "http",
+				"command": "GET https://example.com",
+				"retry": {"strategy": "fixed", "base_delay": "30s", "retry_on": ["http_503"]}
+			}
+		]
+	}`)
+
+	parser := NewParser()
+	dag, err := parser.ParseJSON(jsonData)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	policy := dag.Tasks[0].RetryPolicy
+	if policy == nil {
+		t.Fatal("Expected a retry policy, got nil")
+	}
+	if policy.Strategy != models.RetryStrategyFixed {
+		t.Errorf("Expected fixed strategy, got '%s'", policy.Strategy)
+	}
+	if policy.BaseDelay != 30*time.Second {
+		t.Errorf("Expected 30s base delay, got %v", policy.BaseDelay)
+	}
+}
+
+func TestParseYAML_InvalidRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name  string
+		retry string
+	}{
+		{name: "unknown strategy", retry: "strategy: random"},
+		{name: "invalid base delay", retry: "base_delay: soon"},
+		{name: "max delay below base delay", retry: "{base_delay: 1m, max_delay: 1s}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlData := []byte(`
+id: invalid-retry
+name: Invalid Retry
+start_date: "2024-01-01"
+tasks:
+  - id: task1
+    name: Task 1
+    type: bash
+    command: echo hello
+    retry:
+      ` + tt.retry + `
+`)
+
+			parser := NewParser()
+			if _, err := parser.ParseYAML(yamlData); err == nil {
+				t.Error("Expected error for invalid retry policy, got nil")
+			}
+		})
+	}
+}
+
 func TestParseYAML_InvalidSLA(t *testing.T) {
 	yamlData := []byte(`
 id: invalid-sla
@@ -283,6 +411,181 @@ tasks:
 	}
 }
 
+func TestParseYAML_CircuitBreaker(t *testing.T) {
+	yamlData := []byte(`
+id: circuit-breaker
+name: Circuit Breaker
+start_date: "2024-01-01"
+circuit_breaker:
+  max_failures: 3
+  timeout: 30s
+tasks:
+  - id: call_api
+    name: Call API
+    type: http
+    command: GET https://api.example.com/data
+  - id: call_flaky_api
+    name: Call Flaky API
+    type: http
+    command: GET https://flaky.example.com/data
+    dependencies: [call_api]
+    circuit_breaker:
+      disabled: true
+`)
+
+	parser := NewParser()
+	dag, err := parser.ParseYAML(yamlData)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	config := dag.TaskCircuitBreaker(&dag.Tasks[0])
+	if config == nil || config.MaxFailures != 3 || config.Timeout != 30*time.Second {
+		t.Errorf("Expected the DAG's circuit breaker settings for call_api, got %+v", config)
+	}
+	config = dag.TaskCircuitBreaker(&dag.Tasks[1])
+	if config == nil || !config.Disabled {
+		t.Errorf("Expected a disabled circuit breaker for call_flaky_api, got %+v", config)
+	}
+
+	for _, block := range []string{"timeout: soon", "max_failures: -1"} {
+		yamlData := []byte(`
+id: invalid-circuit-breaker
+name: Invalid Circuit Breaker
+start_date: "2024-01-01"
+tasks:
+  - id: task1
+    name: Task 1
+    type: http
+    command: GET https://api.example.com/data
+    circuit_breaker:
+      ` + block + `
+`)
+		if _, err := parser.ParseYAML(yamlData); err == nil {
+			t.Errorf("Expected error for circuit breaker %q, got nil", block)
+		}
+	}
+}
+
+func TestParseYAML_TriggerRule(t *testing.T) {
+	yamlData := []byte(`
+id: trigger-rules
+name: Trigger Rules
+start_date: "2024-01-01"
+tasks:
+  - id: extract
+    name: Extract
+    type: bash
+    command: ./extract.sh
+  - id: cleanup
+    name: Cleanup
+    type: bash
+    command: ./cleanup.sh
+    dependencies: [extract]
+    trigger_rule: all_done
+`)
+
+	parser := NewParser()
+	dag, err := parser.ParseYAML(yamlData)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	if dag.Tasks[0].TriggerRule != "" {
+		t.Errorf("Expected no trigger rule for extract, got %s", dag.Tasks[0].TriggerRule)
+	}
+	if dag.Tasks[1].TriggerRule != models.TriggerRuleAllDone {
+		t.Errorf("Expected all_done trigger rule for cleanup, got %s", dag.Tasks[1].TriggerRule)
+	}
+
+	invalid := []byte(`
+id: invalid-trigger-rule
+name: Invalid Trigger Rule
+start_date: "2024-01-01"
+tasks:
+  - id: task1
+    name: Task 1
+    type: bash
+    command: echo hello
+    trigger_rule: sometimes
+`)
+	if _, err := parser.ParseYAML(invalid); err == nil {
+		t.Error("Expected error for unknown trigger rule, got nil")
+	}
+}
+
+func TestParseYAML_Branch(t *testing.T) {
+	yamlData := []byte(`
+id: branching
+name: Branching
+start_date: "2024-01-01"
+tasks:
+  - id: choose
+    name: Choose
+    type: http
+    command: GET https://api.example.com/mode
+    branch:
+      targets: [full, incremental]
+      field: result.mode
+  - id: full
+    name: Full
+    type: bash
+    command: ./full.sh
+    dependencies: [choose]
+  - id: incremental
+    name: Incremental
+    type: bash
+    command: ./incremental.sh
+    dependencies: [choose]
+`)
+
+	parser := NewParser()
+	dag, err := parser.ParseYAML(yamlData)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	branch := dag.Tasks[0].Branch
+	if branch == nil {
+		t.Fatal("Expected choose to be a branch task")
+	}
+	if len(branch.Targets) != 2 || branch.Targets[0] != "full" || branch.Targets[1] != "incremental" {
+		t.Errorf("Expected targets [full incremental], got %v", branch.Targets)
+	}
+	if branch.Field != "result.mode" {
+		t.Errorf("Expected field result.mode, got %s", branch.Field)
+	}
+	if dag.Tasks[1].Branch != nil {
+		t.Error("Expected full not to be a branch task")
+	}
+
+	invalid := []byte(`
+id: invalid-branch
+name: Invalid Branch
+start_date: "2024-01-01"
+tasks:
+  - id: choose
+    name: Choose
+    type: bash
+    command: echo load
+    branch:
+      targets: [load]
+  - id: extract
+    name: Extract
+    type: bash
+    command: ./extract.sh
+    dependencies: [choose]
+  - id: load
+    name: Load
+    type: bash
+    command: ./load.sh
+    dependencies: [extract]
+`)
+	if _, err := parser.ParseYAML(invalid); err == nil {
+		t.Error("Expected error for branch target that is not a direct child, got nil")
+	}
+}
+
 func TestParseYAML_InvalidStartDate(t *testing.T) {
 	yamlData := []byte(`
 id: invalid-date
@@ -472,6 +775,112 @@ func TestParseJSON_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestParseYAML_ResultPaths(t *testing.T) {
+	yamlData := []byte(`
+id: results
+name: Results
+start_date: "2024-01-01"
+tasks:
+  - id: submit
+    name: Submit
+    type: http
+    command: POST https://api.example.com/jobs
+    result_paths:
+      job_id: job.id
+      response: "."
+`)
+
+	parser := NewParser()
+	dag, err := parser.ParseYAML(yamlData)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	paths := dag.Tasks[0].ResultPaths
+	if len(paths) != 2 || paths["job_id"] != "job.id" || paths["response"] != "." {
+		t.Errorf("Expected result paths job_id=job.id and response=., got %v", paths)
+	}
+}
+
+func TestParseYAML_Params(t *testing.T) {
+	yamlData := []byte(`
+id: params
+name: Params
+start_date: "2024-01-01"
+params:
+  - name: table
+    default: events
+    description: Table to load
+  - name: limit
+    default: 100
+tasks:
+  - id: load
+    name: Load
+    type: bash
+    command: load {{ .params.table }} --limit {{ .params.limit }} --date {{ .ds }}
+`)
+
+	parser := NewParser()
+	dag, err := parser.ParseYAML(yamlData)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	if len(dag.Params) != 2 {
+		t.Fatalf("Expected 2 params, got %d", len(dag.Params))
+	}
+	if dag.Params[0].Name != "table" || dag.Params[0].Default != "events" || dag.Params[0].Description != "Table to load" {
+		t.Errorf("Unexpected first param: %+v", dag.Params[0])
+	}
+	if values := dag.ParamValues(nil); values["table"] != "events" || values["limit"] == nil {
+		t.Errorf("Unexpected param values: %v", values)
+	}
+}
+
+func TestParseYAML_ParamSchema(t *testing.T) {
+	yamlData := []byte(`
+name: param_schema_dag
+params:
+  - name: mode
+    type: string
+    default: full
+    enum: [full, incremental]
+  - name: limit
+    type: integer
+    default: 100
+  - name: tables
+    type: array
+    required: true
+tasks:
+  - id: load
+    name: Load
+    type: bash
+    command: load --mode {{ .params.mode }} --limit {{ .params.limit }}
+`)
+
+	parser := NewParser()
+	dag, err := parser.ParseYAML(yamlData)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	mode, limit, tables := dag.Params[0], dag.Params[1], dag.Params[2]
+	if mode.Type != models.ParamTypeString || len(mode.Enum) != 2 || mode.Enum[1] != "incremental" {
+		t.Errorf("Unexpected mode param: %+v", mode)
+	}
+	if limit.Type != models.ParamTypeInteger {
+		t.Errorf("Unexpected limit param: %+v", limit)
+	}
+	if tables.Type != models.ParamTypeArray || !tables.Required {
+		t.Errorf("Unexpected tables param: %+v", tables)
+	}
+
+	// Values decoded from YAML are checked like those decoded from JSON
+	if err := ValidateConf(dag, map[string]interface{}{"limit": 10.0, "tables": []interface{}{"events"}}); err != nil {
+		t.Errorf("ValidateConf failed: %v", err)
+	}
+}
+
 func TestParseYAML_InvalidYAML(t *testing.T) {
 	invalidYAML := []byte(`
 invalid: yaml: content:
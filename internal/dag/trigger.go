@@ -0,0 +1,74 @@
+package dag
+
+import "github.com/therealutkarshpriyadarshi/dag/pkg/models"
+
+// EvaluateTriggerRule decides whether a task runs given the final states of the tasks that finished.
+// It returns true if the task can run. Otherwise it returns the state the task ends in without running,
+// skipped or upstream_failed, or an empty state while the decision depends on unfinished dependencies.
+// Tasks without dependencies always run.
+func EvaluateTriggerRule(task *models.Task, states map[string]models.State) (bool, models.State) {
+	if len(task.Dependencies) == 0 {
+		return true, ""
+	}
+
+	var done, succeeded, failed, skipped int
+	for _, depID := range task.Dependencies {
+		state, finished := states[depID]
+		if !finished {
+			continue
+		}
+		done++
+		switch state {
+		case models.StateSuccess:
+			succeeded++
+		case models.StateSkipped:
+			skipped++
+		default:
+			// Failed, upstream_failed and cancelled dependencies did not and will not succeed
+			failed++
+		}
+	}
+	allDone := done == len(task.Dependencies)
+
+	switch task.TriggerRule {
+	case models.TriggerRuleAllDone:
+		return allDone, ""
+
+	case models.TriggerRuleOneFailed:
+		if failed > 0 {
+			return true, ""
+		}
+		if allDone {
+			return false, models.StateSkipped
+		}
+
+	case models.TriggerRuleNoneFailed:
+		if failed > 0 {
+			return false, models.StateUpstreamFailed
+		}
+		return allDone, ""
+
+	case models.TriggerRuleOneSuccess:
+		if succeeded > 0 {
+			return true, ""
+		}
+		if allDone && failed > 0 {
+			return false, models.StateUpstreamFailed
+		}
+		if allDone {
+			return false, models.StateSkipped
+		}
+
+	default:
+		// All dependencies must succeed
+		if failed > 0 {
+			return false, models.StateUpstreamFailed
+		}
+		if skipped > 0 {
+			return false, models.StateSkipped
+		}
+		return allDone, ""
+	}
+
+	return false, ""
+}
@@ -0,0 +1,50 @@
+package dag
+
+import (
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestEvaluateTriggerRule(t *testing.T) {
+	tests := []struct {
+		name          string
+		rule          models.TriggerRule
+		states        map[string]models.State
+		expectedRun   bool
+		expectedState models.State
+	}{
+		{"all success waits", "", map[string]models.State{"a": models.StateSuccess}, false, ""},
+		{"all success runs", models.TriggerRuleAllSuccess, map[string]models.State{"a": models.StateSuccess, "b": models.StateSuccess}, true, ""},
+		{"all success upstream failed", "", map[string]models.State{"a": models.StateFailed}, false, models.StateUpstreamFailed},
+		{"all success skipped", "", map[string]models.State{"a": models.StateSkipped, "b": models.StateSuccess}, false, models.StateSkipped},
+		{"all done waits", models.TriggerRuleAllDone, map[string]models.State{"a": models.StateFailed}, false, ""},
+		{"all done runs after failures", models.TriggerRuleAllDone, map[string]models.State{"a": models.StateFailed, "b": models.StateUpstreamFailed}, true, ""},
+		{"one failed runs on first failure", models.TriggerRuleOneFailed, map[string]models.State{"a": models.StateFailed}, true, ""},
+		{"one failed skipped without failures", models.TriggerRuleOneFailed, map[string]models.State{"a": models.StateSuccess, "b": models.StateSkipped}, false, models.StateSkipped},
+		{"none failed runs after skips", models.TriggerRuleNoneFailed, map[string]models.State{"a": models.StateSuccess, "b": models.StateSkipped}, true, ""},
+		{"none failed upstream failed", models.TriggerRuleNoneFailed, map[string]models.State{"b": models.StateCancelled}, false, models.StateUpstreamFailed},
+		{"one success runs on first success", models.TriggerRuleOneSuccess, map[string]models.State{"b": models.StateSuccess}, true, ""},
+		{"one success upstream failed", models.TriggerRuleOneSuccess, map[string]models.State{"a": models.StateFailed, "b": models.StateSkipped}, false, models.StateUpstreamFailed},
+		{"one success skipped", models.TriggerRuleOneSuccess, map[string]models.State{"a": models.StateSkipped, "b": models.StateSkipped}, false, models.StateSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{ID: "c", Dependencies: []string{"a", "b"}, TriggerRule: tt.rule}
+
+			run, state := EvaluateTriggerRule(task, tt.states)
+			if run != tt.expectedRun || state != tt.expectedState {
+				t.Errorf("EvaluateTriggerRule() = %v, %q, want %v, %q", run, state, tt.expectedRun, tt.expectedState)
+			}
+		})
+	}
+}
+
+func TestEvaluateTriggerRule_RootTask(t *testing.T) {
+	task := &models.Task{ID: "a", TriggerRule: models.TriggerRuleOneFailed}
+
+	if run, _ := EvaluateTriggerRule(task, map[string]models.State{}); !run {
+		t.Error("Expected a task without dependencies to run")
+	}
+}
@@ -0,0 +1,196 @@
+package dag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// nonDefinitionFields are DAG fields that do not change what a DAG run executes
+var nonDefinitionFields = []string{"id", "is_paused", "version", "created_at", "updated_at"}
+
+// DAGDiff describes the differences between two DAG definitions
+type DAGDiff struct {
+	FromVersion   int          `json:"from_version"`
+	ToVersion     int          `json:"to_version"`
+	ChangedFields []string     `json:"changed_fields"`
+	AddedTasks    []string     `json:"added_tasks"`
+	RemovedTasks  []string     `json:"removed_tasks"`
+	ModifiedTasks []TaskChange `json:"modified_tasks"`
+}
+
+// TaskChange lists the fields that changed for a task present in both definitions
+type TaskChange struct {
+	TaskID string   `json:"task_id"`
+	Fields []string `json:"fields"`
+}
+
+// IsEmpty returns true if the two definitions are identical
+func (d *DAGDiff) IsEmpty() bool {
+	return len(d.ChangedFields) == 0 && len(d.AddedTasks) == 0 &&
+		len(d.RemovedTasks) == 0 && len(d.ModifiedTasks) == 0
+}
+
+// ContentHash returns a SHA-256 hash of the parts of a DAG that define what it executes
+// Identifiers, pause state and timestamps are excluded so that only definition changes alter the hash
+func ContentHash(dag *models.DAG) (string, error) {
+	fields, err := definitionFields(dag)
+	if err != nil {
+		return "", err
+	}
+
+	// encoding/json sorts map keys, giving a canonical encoding
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode DAG definition: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Diff compares two DAG definitions field by field and task by task
+func Diff(from, to *models.DAG) (*DAGDiff, error) {
+	fromFields, err := definitionFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := definitionFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &DAGDiff{
+		FromVersion:   from.Version,
+		ToVersion:     to.Version,
+		ChangedFields: []string{},
+		AddedTasks:    []string{},
+		RemovedTasks:  []string{},
+		ModifiedTasks: []TaskChange{},
+	}
+
+	delete(fromFields, "tasks")
+	delete(toFields, "tasks")
+	diff.ChangedFields = changedKeys(fromFields, toFields)
+
+	fromTasks, err := taskFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toTasks, err := taskFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range to.Tasks {
+		if _, exists := fromTasks[task.ID]; !exists {
+			diff.AddedTasks = append(diff.AddedTasks, task.ID)
+		}
+	}
+
+	for _, task := range from.Tasks {
+		toTask, exists := toTasks[task.ID]
+		if !exists {
+			diff.RemovedTasks = append(diff.RemovedTasks, task.ID)
+			continue
+		}
+
+		if fields := changedKeys(fromTasks[task.ID], toTask); len(fields) > 0 {
+			diff.ModifiedTasks = append(diff.ModifiedTasks, TaskChange{TaskID: task.ID, Fields: fields})
+		}
+	}
+
+	return diff, nil
+}
+
+// definitionFields returns the JSON fields of a normalized copy of the DAG definition
+func definitionFields(dag *models.DAG) (map[string]json.RawMessage, error) {
+	normalized := *dag
+	normalized.StartDate = normalizeTime(dag.StartDate)
+	if dag.EndDate != nil {
+		endDate := normalizeTime(*dag.EndDate)
+		normalized.EndDate = &endDate
+	}
+	if normalized.Tags == nil {
+		normalized.Tags = []string{}
+	}
+
+	normalized.Tasks = make([]models.Task, len(dag.Tasks))
+	for i, task := range dag.Tasks {
+		if task.Dependencies == nil {
+			task.Dependencies = []string{}
+		}
+		normalized.Tasks[i] = task
+	}
+
+	fields, err := toFields(&normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode DAG definition: %w", err)
+	}
+
+	for _, name := range nonDefinitionFields {
+		delete(fields, name)
+	}
+
+	return fields, nil
+}
+
+// taskFields returns the JSON fields of each task keyed by task ID
+func taskFields(dag *models.DAG) (map[string]map[string]json.RawMessage, error) {
+	tasks := make(map[string]map[string]json.RawMessage, len(dag.Tasks))
+	for i := range dag.Tasks {
+		task := dag.Tasks[i]
+		if task.Dependencies == nil {
+			task.Dependencies = []string{}
+		}
+
+		fields, err := toFields(&task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode task %s: %w", task.ID, err)
+		}
+		tasks[task.ID] = fields
+	}
+	return tasks, nil
+}
+
+// toFields encodes v as JSON and splits it into its top-level fields
+func toFields(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// changedKeys returns the sorted keys whose values differ between a and b
+func changedKeys(a, b map[string]json.RawMessage) []string {
+	changed := []string{}
+	for key, value := range a {
+		if other, exists := b[key]; !exists || !bytes.Equal(value, other) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range b {
+		if _, exists := a[key]; !exists {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// normalizeTime drops the location and sub-microsecond precision, which do not survive a database round trip
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,102 @@
+package dag
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func newVersionTestDAG() *models.DAG {
+	return &models.DAG{
+		ID:        "dag-1",
+		Name:      "etl",
+		Schedule:  "0 * * * *",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeBash, Command: "echo extract"},
+			{ID: "load", Type: models.TaskTypeBash, Command: "echo load", Dependencies: []string{"extract"}},
+		},
+	}
+}
+
+func TestContentHash_IgnoresNonDefinitionFields(t *testing.T) {
+	original := newVersionTestDAG()
+	hash1, err := ContentHash(original)
+	if err != nil {
+		t.Fatalf("ContentHash failed: %v", err)
+	}
+
+	changed := newVersionTestDAG()
+	changed.ID = "dag-2"
+	changed.IsPaused = true
+	changed.Version = 7
+	changed.UpdatedAt = time.Now()
+	changed.StartDate = original.StartDate.In(time.FixedZone("CET", 3600)).Add(300 * time.Nanosecond)
+
+	hash2, err := ContentHash(changed)
+	if err != nil {
+		t.Fatalf("ContentHash failed: %v", err)
+	}
+
+	if hash1 != hash2 {
+		t.Errorf("Expected identical hashes, got %s and %s", hash1, hash2)
+	}
+}
+
+func TestContentHash_ChangesWithDefinition(t *testing.T) {
+	original := newVersionTestDAG()
+	hash1, _ := ContentHash(original)
+
+	changed := newVersionTestDAG()
+	changed.Tasks[1].Command = "echo load --full"
+	hash2, _ := ContentHash(changed)
+
+	if hash1 == hash2 {
+		t.Error("Expected hash to change when a task command changes")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := newVersionTestDAG()
+	from.Version = 1
+
+	to := newVersionTestDAG()
+	to.Version = 2
+	to.Schedule = "0 0 * * *"
+	to.Tasks[1].Retries = 3
+	to.Tasks = append(to.Tasks[1:], models.Task{ID: "report", Type: models.TaskTypeHTTP, Command: "GET http://example.com", Dependencies: []string{"load"}})
+
+	diff, err := Diff(from, to)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	if diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Errorf("Unexpected versions: %d -> %d", diff.FromVersion, diff.ToVersion)
+	}
+	if !reflect.DeepEqual(diff.ChangedFields, []string{"schedule"}) {
+		t.Errorf("ChangedFields = %v, want [schedule]", diff.ChangedFields)
+	}
+	if !reflect.DeepEqual(diff.AddedTasks, []string{"report"}) {
+		t.Errorf("AddedTasks = %v, want [report]", diff.AddedTasks)
+	}
+	if !reflect.DeepEqual(diff.RemovedTasks, []string{"extract"}) {
+		t.Errorf("RemovedTasks = %v, want [extract]", diff.RemovedTasks)
+	}
+	if len(diff.ModifiedTasks) != 1 || diff.ModifiedTasks[0].TaskID != "load" ||
+		!reflect.DeepEqual(diff.ModifiedTasks[0].Fields, []string{"retries"}) {
+		t.Errorf("ModifiedTasks = %+v, want load: [retries]", diff.ModifiedTasks)
+	}
+}
+
+func TestDiff_Identical(t *testing.T) {
+	diff, err := Diff(newVersionTestDAG(), newVersionTestDAG())
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !diff.IsEmpty() {
+		t.Errorf("Expected empty diff, got %+v", diff)
+	}
+}
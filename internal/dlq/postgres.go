@@ -0,0 +1,245 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// entryModel is the database model of a DLQ entry
+type entryModel struct {
+	ID              uuid.UUID              `gorm:"type:uuid;primary_key"`
+	TaskInstanceID  uuid.UUID              `gorm:"type:uuid;not null"`
+	TaskID          string                 `gorm:"type:varchar(255);not null"`
+	DAGRunID        uuid.UUID              `gorm:"type:uuid;not null"`
+	DAGID           uuid.UUID              `gorm:"type:uuid;not null;index:idx_dead_letter_queue_dag_id"`
+	FailureReason   string                 `gorm:"type:varchar(255);not null"`
+	FailureTime     time.Time              `gorm:"not null;index:idx_dead_letter_queue_failure_time"`
+	Attempts        int                    `gorm:"not null"`
+	LastAttemptTime time.Time              `gorm:"not null"`
+	ErrorMessage    string                 `gorm:"type:text;not null"`
+	Metadata        map[string]interface{} `gorm:"type:jsonb;serializer:json"`
+	Replayed        bool                   `gorm:"not null"`
+	ReplayedAt      *time.Time
+}
+
+// TableName specifies the table name for entryModel
+func (entryModel) TableName() string {
+	return "dead_letter_queue"
+}
+
+// entry converts a model to an Entry
+func (m *entryModel) entry() *Entry {
+	return &Entry{
+		ID:              m.ID.String(),
+		TaskInstanceID:  m.TaskInstanceID.String(),
+		TaskID:          m.TaskID,
+		DAGRunID:        m.DAGRunID.String(),
+		DAGID:           m.DAGID.String(),
+		FailureReason:   m.FailureReason,
+		FailureTime:     m.FailureTime,
+		Attempts:        m.Attempts,
+		LastAttemptTime: m.LastAttemptTime,
+		ErrorMessage:    m.ErrorMessage,
+		Metadata:        m.Metadata,
+		Replayed:        m.Replayed,
+		ReplayedAt:      m.ReplayedAt,
+	}
+}
+
+// fromEntry converts an Entry to a model
+func fromEntry(entry *Entry) (*entryModel, error) {
+	ids := make([]uuid.UUID, 4)
+	for i, id := range []string{entry.ID, entry.TaskInstanceID, entry.DAGRunID, entry.DAGID} {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q: %w", id, err)
+		}
+		ids[i] = parsed
+	}
+
+	return &entryModel{
+		ID:              ids[0],
+		TaskInstanceID:  ids[1],
+		TaskID:          entry.TaskID,
+		DAGRunID:        ids[2],
+		DAGID:           ids[3],
+		FailureReason:   entry.FailureReason,
+		FailureTime:     entry.FailureTime,
+		Attempts:        entry.Attempts,
+		LastAttemptTime: entry.LastAttemptTime,
+		ErrorMessage:    entry.ErrorMessage,
+		Metadata:        entry.Metadata,
+		Replayed:        entry.Replayed,
+		ReplayedAt:      entry.ReplayedAt,
+	}, nil
+}
+
+// PostgresQueue is a DLQ stored in the dead_letter_queue table, so that entries outlive restarts
+// and are shared by every process using the database
+type PostgresQueue struct {
+	db *gorm.DB
+}
+
+// NewPostgresQueue creates a DLQ stored in Postgres
+func NewPostgresQueue(db *gorm.DB) *PostgresQueue {
+	return &PostgresQueue{db: db}
+}
+
+// parseID parses the ID of an entry; a malformed ID cannot name an entry
+func parseID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid ID %q", ErrNotFound, id)
+	}
+	return parsed, nil
+}
+
+// Add adds an entry to the DLQ. A replayed entry is replaced by the entry of its next failure.
+func (q *PostgresQueue) Add(ctx context.Context, entry *Entry) error {
+	model, err := fromEntry(entry)
+	if err != nil {
+		return fmt.Errorf("failed to convert DLQ entry to model: %w", err)
+	}
+
+	result := q.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"failure_reason", "failure_time", "attempts", "last_attempt_time",
+			"error_message", "metadata", "replayed", "replayed_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "dead_letter_queue.replayed"}}},
+	}).Create(model)
+	if result.Error != nil {
+		return fmt.Errorf("failed to add DLQ entry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// Get retrieves an entry by ID
+func (q *PostgresQueue) Get(ctx context.Context, id string) (*Entry, error) {
+	entryID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var model entryModel
+	if err := q.db.WithContext(ctx).Where("id = ?", entryID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get DLQ entry: %w", err)
+	}
+
+	return model.entry(), nil
+}
+
+// List lists entries matching the filters, most recent failures first
+func (q *PostgresQueue) List(ctx context.Context, filters *Filters) ([]*Entry, error) {
+	query := q.db.WithContext(ctx).Model(&entryModel{})
+
+	if filters != nil {
+		if filters.DAGID != "" {
+			dagID, err := uuid.Parse(filters.DAGID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DAG ID: %w", err)
+			}
+			query = query.Where("dag_id = ?", dagID)
+		}
+		if filters.TaskID != "" {
+			query = query.Where("task_id = ?", filters.TaskID)
+		}
+		if filters.Replayed != nil {
+			query = query.Where("replayed = ?", *filters.Replayed)
+		}
+		if filters.After != nil {
+			query = query.Where("failure_time >= ?", *filters.After)
+		}
+		if filters.Before != nil {
+			query = query.Where("failure_time <= ?", *filters.Before)
+		}
+		if filters.Limit > 0 {
+			query = query.Limit(filters.Limit)
+		}
+		if filters.Offset > 0 {
+			query = query.Offset(filters.Offset)
+		}
+	}
+
+	var entryModels []entryModel
+	if err := query.Order("failure_time DESC").Order("id").Find(&entryModels).Error; err != nil {
+		return nil, fmt.Errorf("failed to list DLQ entries: %w", err)
+	}
+
+	entries := make([]*Entry, len(entryModels))
+	for i := range entryModels {
+		entries[i] = entryModels[i].entry()
+	}
+
+	return entries, nil
+}
+
+// Replay marks an entry as replayed
+func (q *PostgresQueue) Replay(ctx context.Context, id string) error {
+	entryID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	result := q.db.WithContext(ctx).
+		Model(&entryModel{}).
+		Where("id = ?", entryID).
+		Updates(map[string]interface{}{"replayed": true, "replayed_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark DLQ entry as replayed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete removes an entry from the DLQ
+func (q *PostgresQueue) Delete(ctx context.Context, id string) error {
+	entryID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	result := q.db.WithContext(ctx).Where("id = ?", entryID).Delete(&entryModel{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete DLQ entry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Purge removes all entries from the DLQ
+func (q *PostgresQueue) Purge(ctx context.Context) error {
+	if err := q.db.WithContext(ctx).Where("1 = 1").Delete(&entryModel{}).Error; err != nil {
+		return fmt.Errorf("failed to purge DLQ: %w", err)
+	}
+	return nil
+}
+
+// Count returns the number of entries in the DLQ
+func (q *PostgresQueue) Count(ctx context.Context) (int, error) {
+	var count int64
+	if err := q.db.WithContext(ctx).Model(&entryModel{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count DLQ entries: %w", err)
+	}
+	return int(count), nil
+}
@@ -37,7 +37,8 @@ type Entry struct {
 
 // Queue represents a dead letter queue for failed tasks
 type Queue interface {
-	// Add adds an entry to the DLQ
+	// Add adds an entry to the DLQ. It returns ErrAlreadyExists if an entry with the same ID
+	// has not been replayed yet; a replayed entry is replaced, since its task failed again.
 	Add(ctx context.Context, entry *Entry) error
 
 	// Get retrieves an entry by ID
@@ -83,12 +84,12 @@ func NewMemoryQueue() *MemoryQueue {
 	}
 }
 
-// Add adds an entry to the DLQ
+// Add adds an entry to the DLQ. A replayed entry is replaced by the entry of its next failure.
 func (q *MemoryQueue) Add(ctx context.Context, entry *Entry) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if _, exists := q.entries[entry.ID]; exists {
+	if existing, exists := q.entries[entry.ID]; exists && !existing.Replayed {
 		return ErrAlreadyExists
 	}
 
@@ -230,6 +230,25 @@ func TestMemoryQueue_Replay(t *testing.T) {
 	if retrieved.ReplayedAt == nil {
 		t.Error("ReplayedAt should be set")
 	}
+
+	// The replayed task failed again
+	err = q.Add(ctx, &Entry{
+		ID:             "entry1",
+		TaskInstanceID: "ti1",
+		TaskID:         "task1",
+		DAGRunID:       "dr1",
+		DAGID:          "dag1",
+		FailureReason:  "max_retries_exceeded",
+		FailureTime:    time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to add entry of replayed task: %v", err)
+	}
+
+	retrieved, _ = q.Get(ctx, "entry1")
+	if retrieved.Replayed || retrieved.FailureReason != "max_retries_exceeded" {
+		t.Errorf("Expected the replayed entry to be replaced, got %+v", retrieved)
+	}
 }
 
 func TestMemoryQueue_Delete(t *testing.T) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
@@ -123,9 +124,21 @@ func (h *PropagationHandler) HandleDAGFailure(
 	return err
 }
 
-// ShouldMarkDownstreamFailed determines if downstream tasks should be marked as failed
-func (h *PropagationHandler) ShouldMarkDownstreamFailed(task *models.Task) bool {
-	return h.config.Policy == PropagationPolicySkipDownstream || h.config.Policy == PropagationPolicyFail
+// ShouldMarkDownstreamFailed determines if a task downstream of a failure should be marked upstream_failed,
+// given the final states of the tasks that finished, see ShouldMarkUpstreamFailed
+func (h *PropagationHandler) ShouldMarkDownstreamFailed(task *models.Task, states map[string]models.State) bool {
+	return h.ShouldMarkUpstreamFailed(task, states)
+}
+
+// ShouldMarkUpstreamFailed determines if a task that has not run should be marked upstream_failed, given the
+// final states of the tasks that finished. The task's trigger rule decides, so that all_done or one_failed
+// tasks still run after a dependency failed.
+func (h *PropagationHandler) ShouldMarkUpstreamFailed(task *models.Task, states map[string]models.State) bool {
+	if h.config.Policy == PropagationPolicyAllowPartial {
+		return false
+	}
+	_, state := dag.EvaluateTriggerRule(task, states)
+	return state == models.StateUpstreamFailed
 }
 
 // isTaskCritical checks if a task is marked as critical
@@ -143,7 +156,7 @@ func (h *PropagationHandler) CanDAGSucceed(taskInstances []*models.TaskInstance)
 	if !h.config.AllowPartialSuccess {
 		// All tasks must succeed
 		for _, ti := range taskInstances {
-			if ti.State == models.StateFailed {
+			if isFailure(ti.State) {
 				return false
 			}
 		}
@@ -160,6 +173,12 @@ func (h *PropagationHandler) CanDAGSucceed(taskInstances []*models.TaskInstance)
 	return true
 }
 
+// isFailure reports whether a task instance in the given state did not and will not succeed.
+// Tasks waiting for a retry or a reschedule may still succeed.
+func isFailure(state models.State) bool {
+	return state == models.StateFailed || state == models.StateUpstreamFailed || state == models.StateCancelled
+}
+
 // ErrorClassifier classifies errors for retry decisions
 type ErrorClassifier struct {
 	retryableErrors map[string]bool
@@ -103,14 +103,22 @@ func TestPropagationHandler_HandleTaskFailure_WithCallback(t *testing.T) {
 }
 
 func TestPropagationHandler_ShouldMarkDownstreamFailed(t *testing.T) {
+	states := map[string]models.State{"extract": models.StateFailed}
+
 	tests := []struct {
 		name     string
 		policy   PropagationPolicy
+		rule     models.TriggerRule
 		expected bool
 	}{
-		{"fail policy", PropagationPolicyFail, true},
-		{"skip downstream policy", PropagationPolicySkipDownstream, true},
-		{"allow partial policy", PropagationPolicyAllowPartial, false},
+		{"fail policy", PropagationPolicyFail, "", true},
+		{"fail policy with all done", PropagationPolicyFail, models.TriggerRuleAllDone, false},
+		{"fail policy with one failed", PropagationPolicyFail, models.TriggerRuleOneFailed, false},
+		{"skip downstream policy", PropagationPolicySkipDownstream, "", true},
+		{"all done", PropagationPolicySkipDownstream, models.TriggerRuleAllDone, false},
+		{"one failed", PropagationPolicySkipDownstream, models.TriggerRuleOneFailed, false},
+		{"none failed", PropagationPolicySkipDownstream, models.TriggerRuleNoneFailed, true},
+		{"allow partial policy", PropagationPolicyAllowPartial, "", false},
 	}
 
 	for _, tt := range tests {
@@ -118,8 +126,8 @@ func TestPropagationHandler_ShouldMarkDownstreamFailed(t *testing.T) {
 			config := &PropagationConfig{Policy: tt.policy}
 			handler := NewPropagationHandler(config)
 
-			task := &models.Task{ID: "task1"}
-			result := handler.ShouldMarkDownstreamFailed(task)
+			task := &models.Task{ID: "load", Dependencies: []string{"extract"}, TriggerRule: tt.rule}
+			result := handler.ShouldMarkDownstreamFailed(task, states)
 
 			if result != tt.expected {
 				t.Errorf("ShouldMarkDownstreamFailed() = %v, want %v", result, tt.expected)
@@ -157,6 +165,39 @@ func TestPropagationHandler_CanDAGSucceed(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "downstream task upstream failed, no partial success",
+			config: &PropagationConfig{
+				AllowPartialSuccess: false,
+			},
+			taskInstances: []*models.TaskInstance{
+				{TaskID: "task1", State: models.StateSuccess},
+				{TaskID: "task2", State: models.StateUpstreamFailed},
+			},
+			expected: false,
+		},
+		{
+			name: "task cancelled, no partial success",
+			config: &PropagationConfig{
+				AllowPartialSuccess: false,
+			},
+			taskInstances: []*models.TaskInstance{
+				{TaskID: "task1", State: models.StateSuccess},
+				{TaskID: "task2", State: models.StateCancelled},
+			},
+			expected: false,
+		},
+		{
+			name: "task waiting for reschedule, no partial success",
+			config: &PropagationConfig{
+				AllowPartialSuccess: false,
+			},
+			taskInstances: []*models.TaskInstance{
+				{TaskID: "task1", State: models.StateSuccess},
+				{TaskID: "task2", State: models.StateUpForReschedule},
+			},
+			expected: true,
+		},
 		{
 			name: "non-critical task failed, partial success allowed",
 			config: &PropagationConfig{
@@ -195,6 +236,34 @@ func TestPropagationHandler_CanDAGSucceed(t *testing.T) {
 	}
 }
 
+func TestPropagationHandler_ShouldMarkUpstreamFailed(t *testing.T) {
+	states := map[string]models.State{"extract": models.StateFailed}
+
+	tests := []struct {
+		name     string
+		policy   PropagationPolicy
+		rule     models.TriggerRule
+		expected bool
+	}{
+		{"all success", PropagationPolicySkipDownstream, "", true},
+		{"all done", PropagationPolicySkipDownstream, models.TriggerRuleAllDone, false},
+		{"one failed", PropagationPolicySkipDownstream, models.TriggerRuleOneFailed, false},
+		{"none failed", PropagationPolicyFail, models.TriggerRuleNoneFailed, true},
+		{"allow partial policy", PropagationPolicyAllowPartial, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPropagationHandler(&PropagationConfig{Policy: tt.policy})
+			task := &models.Task{ID: "load", Dependencies: []string{"extract"}, TriggerRule: tt.rule}
+
+			if result := handler.ShouldMarkUpstreamFailed(task, states); result != tt.expected {
+				t.Errorf("ShouldMarkUpstreamFailed() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestErrorClassifier_IsRetryable(t *testing.T) {
 	classifier := NewErrorClassifier()
 
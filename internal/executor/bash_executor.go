@@ -3,6 +3,8 @@ package executor
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -12,6 +14,9 @@ import (
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
+// processWaitDelay bounds how long a killed command may keep its output pipes open
+const processWaitDelay = 5 * time.Second
+
 // BashTaskExecutor executes bash commands
 type BashTaskExecutor struct {
 	workingDir string
@@ -58,21 +63,37 @@ func (e *BashTaskExecutor) Execute(ctx context.Context, task *models.Task, taskI
 	// Create command
 	cmd := exec.CommandContext(ctx, "bash", "-c", task.Command)
 
+	// Kill everything the command started once the task times out or is cancelled
+	killProcessGroupOnCancel(cmd)
+	cmd.WaitDelay = processWaitDelay
+
 	// Set working directory if specified
 	if e.workingDir != "" {
 		cmd.Dir = e.workingDir
 	}
 
+	// Tasks push results by writing key=value lines to the file named by DAG_RESULTS_FILE
+	resultsFile, err := os.CreateTemp("", "dag-results-*")
+	if err != nil {
+		result.EndTime = time.Now()
+		result.State = models.StateFailed
+		result.ErrorMessage = fmt.Sprintf("Failed to create results file: %v", err)
+		return result
+	}
+	resultsFile.Close()
+	defer os.Remove(resultsFile.Name())
+
 	// Set environment variables
-	cmd.Env = e.env
+	cmd.Env = append(e.env[:len(e.env):len(e.env)], ResultsFileEnv+"="+resultsFile.Name())
 
-	// Capture output
+	// Capture output, streaming it line by line to the log sink if one is attached
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var flushLines func()
+	cmd.Stdout, cmd.Stderr, flushLines = streamOutput(ctx, &stdout, &stderr)
 
 	// Execute command
-	err := cmd.Run()
+	err = cmd.Run()
+	flushLines()
 	result.EndTime = time.Now()
 
 	// Combine stdout and stderr
@@ -86,17 +107,48 @@ func (e *BashTaskExecutor) Execute(ctx context.Context, task *models.Task, taskI
 	if err != nil {
 		result.State = models.StateFailed
 		result.ErrorMessage = fmt.Sprintf("Command failed: %v\nOutput: %s", err, output)
+		result.ExitCode = exitCode(err)
 		log.Printf("Bash task %s failed: %v", task.ID, err)
 	} else {
 		log.Printf("Bash task %s completed successfully", task.ID)
+		if err := bashResults(resultsFile.Name(), stdout.String(), result); err != nil {
+			resultFailed(result, fmt.Sprintf("Invalid task results: %v", err))
+		} else if task.Branch != nil {
+			// Branch tasks name the downstream tasks to follow on the last line of their output
+			setBranches(task, result, lastLineBranches(withoutResultMarkers(stdout.String())))
+		}
 	}
 
 	// Check for context cancellation (timeout)
 	if ctx.Err() != nil {
 		result.State = models.StateFailed
 		result.ErrorMessage = fmt.Sprintf("Task timed out: %v\nOutput: %s", ctx.Err(), output)
+		result.ErrorCode = ErrorCodeTimeout
 		log.Printf("Bash task %s timed out", task.ID)
 	}
 
 	return result
 }
+
+// bashResults collects the results a succeeded command wrote to its results file or pushed
+// with "::result key=value" lines on stdout
+func bashResults(resultsFile string, stdout string, result *TaskResult) error {
+	// A command that removed its results file pushed nothing through it
+	data, err := os.ReadFile(resultsFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read results file: %w", err)
+	}
+
+	values := make(map[string]json.RawMessage)
+	if err := parseResultsFile(data, values); err != nil {
+		return err
+	}
+	if err := markerResults(stdout, values); err != nil {
+		return err
+	}
+
+	if len(values) > 0 {
+		result.Results = values
+	}
+	return nil
+}
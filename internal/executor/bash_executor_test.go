@@ -2,6 +2,7 @@ package executor
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -65,6 +66,10 @@ func TestBashTaskExecutor_Execute_Failure(t *testing.T) {
 	if result.ErrorMessage == "" {
 		t.Error("Expected error message, got empty string")
 	}
+
+	if result.ExitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", result.ExitCode)
+	}
 }
 
 func TestBashTaskExecutor_Execute_Timeout(t *testing.T) {
@@ -94,6 +99,147 @@ func TestBashTaskExecutor_Execute_Timeout(t *testing.T) {
 	if !strings.Contains(result.ErrorMessage, "timed out") {
 		t.Errorf("Expected timeout error message, got: %s", result.ErrorMessage)
 	}
+
+	if result.ErrorCode != ErrorCodeTimeout {
+		t.Errorf("Expected error code %s, got: %s", ErrorCodeTimeout, result.ErrorCode)
+	}
+}
+
+func TestBashTaskExecutor_Execute_CancelKillsChildProcesses(t *testing.T) {
+	executor := NewBashTaskExecutor()
+
+	// The background sleep inherits the output pipes, so the task only ends once it is killed too
+	task := &models.Task{
+		ID:      "test-task",
+		Type:    models.TaskTypeBash,
+		Command: "sleep 30 & wait",
+	}
+
+	taskInstance := &models.TaskInstance{
+		ID:     "test-instance",
+		TaskID: "test-task",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	result := executor.Execute(ctx, task, taskInstance)
+
+	if result.State != models.StateFailed {
+		t.Errorf("Expected state Failed after cancellation, got %s", result.State)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Cancelled task took %v to stop, its child process was not killed", elapsed)
+	}
+}
+
+func TestBashTaskExecutor_Execute_Branch(t *testing.T) {
+	executor := NewBashTaskExecutor()
+	branch := &models.BranchConfig{Targets: []string{"full", "incremental", "audit"}}
+
+	tests := []struct {
+		name      string
+		command   string
+		wantState models.State
+		want      []string
+	}{
+		{"last line names tasks", "echo 'checking mode'; echo 'incremental, audit'", models.StateSuccess, []string{"incremental", "audit"}},
+		{"empty output follows no task", "true", models.StateSuccess, []string{}},
+		{"unknown task fails", "echo reload", models.StateFailed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{ID: "choose", Type: models.TaskTypeBash, Command: tt.command, Branch: branch}
+			result := executor.Execute(context.Background(), task, &models.TaskInstance{ID: "test-instance", TaskID: "choose"})
+
+			if result.State != tt.wantState {
+				t.Fatalf("Expected state %s, got %s. Error: %s", tt.wantState, result.State, result.ErrorMessage)
+			}
+			if tt.wantState == models.StateFailed {
+				if result.ErrorCode != ErrorCodeInvalidBranch {
+					t.Errorf("Expected error code %s, got %s", ErrorCodeInvalidBranch, result.ErrorCode)
+				}
+				return
+			}
+			if !reflect.DeepEqual(result.Branches, tt.want) {
+				t.Errorf("Expected branches %v, got %v", tt.want, result.Branches)
+			}
+		})
+	}
+}
+
+func TestBashTaskExecutor_Execute_Results(t *testing.T) {
+	executor := NewBashTaskExecutor()
+
+	tests := []struct {
+		name      string
+		command   string
+		wantState models.State
+		want      map[string]string
+	}{
+		{
+			"results file",
+			`echo 'rows=42' >> "$DAG_RESULTS_FILE"; echo 'table=events' >> "$DAG_RESULTS_FILE"`,
+			models.StateSuccess,
+			map[string]string{"rows": `42`, "table": `"events"`},
+		},
+		{
+			"stdout markers",
+			`echo 'loading'; echo '::result stats={"rows": 42}'`,
+			models.StateSuccess,
+			map[string]string{"stats": `{"rows": 42}`},
+		},
+		{"no results", "echo done", models.StateSuccess, nil},
+		{"invalid key", `echo 'bad key=1' >> "$DAG_RESULTS_FILE"`, models.StateFailed, nil},
+		{"line without value", `echo 'rows' >> "$DAG_RESULTS_FILE"`, models.StateFailed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{ID: "load", Type: models.TaskTypeBash, Command: tt.command}
+			result := executor.Execute(context.Background(), task, &models.TaskInstance{ID: "test-instance", TaskID: "load"})
+
+			if result.State != tt.wantState {
+				t.Fatalf("Expected state %s, got %s. Error: %s", tt.wantState, result.State, result.ErrorMessage)
+			}
+			if tt.wantState == models.StateFailed {
+				if result.ErrorCode != ErrorCodeInvalidResult {
+					t.Errorf("Expected error code %s, got %s", ErrorCodeInvalidResult, result.ErrorCode)
+				}
+				return
+			}
+			if len(result.Results) != len(tt.want) {
+				t.Fatalf("Expected results %v, got %v", tt.want, result.Results)
+			}
+			for key, value := range tt.want {
+				if string(result.Results[key]) != value {
+					t.Errorf("Expected result %s = %s, got %s", key, value, result.Results[key])
+				}
+			}
+		})
+	}
+}
+
+func TestBashTaskExecutor_Execute_BranchIgnoresResultMarkers(t *testing.T) {
+	executor := NewBashTaskExecutor()
+	task := &models.Task{
+		ID:      "choose",
+		Type:    models.TaskTypeBash,
+		Command: "echo incremental; echo '::result mode=incremental'",
+		Branch:  &models.BranchConfig{Targets: []string{"full", "incremental"}},
+	}
+
+	result := executor.Execute(context.Background(), task, &models.TaskInstance{ID: "test-instance", TaskID: "choose"})
+	if result.State != models.StateSuccess {
+		t.Fatalf("Expected state success, got %s. Error: %s", result.State, result.ErrorMessage)
+	}
+	if !reflect.DeepEqual(result.Branches, []string{"incremental"}) {
+		t.Errorf("Expected branches [incremental], got %v", result.Branches)
+	}
+	if string(result.Results["mode"]) != `"incremental"` {
+		t.Errorf("Expected result mode = \"incremental\", got %s", result.Results["mode"])
+	}
 }
 
 func TestBashTaskExecutor_Type(t *testing.T) {
@@ -129,3 +275,46 @@ func TestBashTaskExecutor_WithConfig(t *testing.T) {
 		t.Errorf("Expected state Success, got %s", result.State)
 	}
 }
+
+func TestBashTaskExecutor_Execute_StreamsLogs(t *testing.T) {
+	executor := NewBashTaskExecutor()
+
+	task := &models.Task{
+		ID:      "test-task",
+		Type:    models.TaskTypeBash,
+		Command: "echo out1; echo err1 >&2; printf out2",
+		Timeout: 10 * time.Second,
+	}
+
+	taskInstance := &models.TaskInstance{
+		ID:     "test-instance",
+		TaskID: "test-task",
+	}
+
+	recorder := &recordingFlush{}
+	sink := NewBatchLogSink(recorder.flush, &LogSinkConfig{BatchSize: 100})
+
+	result := executor.Execute(WithLogSink(context.Background(), sink), task, taskInstance)
+	sink.Close()
+
+	if result.State != models.StateSuccess {
+		t.Fatalf("Expected state Success, got %s", result.State)
+	}
+
+	streams := map[string]LogStream{}
+	for _, line := range recorder.lines() {
+		streams[line.Text] = line.Stream
+	}
+
+	if streams["out1"] != LogStreamStdout || streams["out2"] != LogStreamStdout {
+		t.Errorf("Expected stdout lines out1 and out2, got %v", streams)
+	}
+	if streams["err1"] != LogStreamStderr {
+		t.Errorf("Expected stderr line err1, got %v", streams)
+	}
+
+	// Output is still returned in the result
+	if !strings.Contains(result.Output, "out1") {
+		t.Errorf("Expected output to contain 'out1', got: %s", result.Output)
+	}
+}
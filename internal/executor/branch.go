@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// defaultBranchField is the response body field naming the downstream tasks of HTTP branch tasks
+const defaultBranchField = "branches"
+
+// setBranches records the downstream tasks a succeeded branch task follows. The attempt fails
+// if the task named one that is not among its targets.
+func setBranches(task *models.Task, result *TaskResult, branches []string) {
+	targets := make(map[string]bool, len(task.Branch.Targets))
+	for _, target := range task.Branch.Targets {
+		targets[target] = true
+	}
+	for _, branch := range branches {
+		if !targets[branch] {
+			branchFailed(result, fmt.Sprintf("Branch task followed %s, which is not one of its targets %v", branch, task.Branch.Targets))
+			return
+		}
+	}
+
+	// Following no task is a decision too, unlike not deciding
+	if branches == nil {
+		branches = []string{}
+	}
+	result.Branches = branches
+	log.Printf("Branch task %s follows %v", task.ID, branches)
+}
+
+// branchFailed fails a branch task whose result did not name the downstream tasks to follow
+func branchFailed(result *TaskResult, message string) {
+	result.State = models.StateFailed
+	result.ErrorCode = ErrorCodeInvalidBranch
+	result.ErrorMessage = message
+}
+
+// splitBranches splits a list of task IDs separated by commas or whitespace
+func splitBranches(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// lastLineBranches returns the task IDs named on the last non-empty line of a command's output
+func lastLineBranches(output string) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return splitBranches(lines[len(lines)-1])
+}
+
+// fieldBranches returns the task IDs named by a field of a JSON document, given as a dot-separated path.
+// The field holds a task ID, comma-separated task IDs or a list of task IDs.
+func fieldBranches(body []byte, field string) ([]string, error) {
+	if field == "" {
+		field = defaultBranchField
+	}
+
+	document, err := decodeJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	value, err := jsonField(document, field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return splitBranches(v), nil
+	case []interface{}:
+		branches := make([]string, 0, len(v))
+		for _, item := range v {
+			branch, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("field %s must list task IDs, got %v", field, item)
+			}
+			branches = append(branches, branch)
+		}
+		return branches, nil
+	case nil:
+		return []string{}, nil
+	default:
+		return nil, fmt.Errorf("field %s must be a task ID or a list of task IDs, got %v", field, v)
+	}
+}
+
+// recordBranches persists the downstream tasks a succeeded branch task follows. They are written
+// before the task instance moves to success, so that a resumed run never sees a branch task
+// succeed without its decision.
+func recordBranches(ctx context.Context, taskRepo storage.TaskInstanceRepository, taskInstance *models.TaskInstance, result *TaskResult) error {
+	if result.State != models.StateSuccess || result.Branches == nil {
+		return nil
+	}
+
+	taskInstance.Branches = result.Branches
+	if err := taskRepo.Update(ctx, taskInstance); err != nil {
+		return fmt.Errorf("failed to record branches of task instance %s: %w", taskInstance.ID, err)
+	}
+	return nil
+}
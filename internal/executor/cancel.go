@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+var (
+	// ErrDAGRunCancelled is the cause of the context of tasks whose DAG run was cancelled
+	ErrDAGRunCancelled = errors.New("DAG run cancelled")
+	// ErrDAGRunNotActive is returned by Cancel when the executor is not scheduling the DAG run
+	ErrDAGRunNotActive = errors.New("DAG run is not active on this executor")
+)
+
+// activeRuns tracks the DAG runs an executor is scheduling so that they can be cancelled
+type activeRuns struct {
+	mu   sync.Mutex
+	runs map[string]*activeRun
+}
+
+// activeRun holds a context that is cancelled once its DAG run is
+type activeRun struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func newActiveRuns() *activeRuns {
+	return &activeRuns{runs: make(map[string]*activeRun)}
+}
+
+// add starts tracking a DAG run and returns a context that is done once the run is cancelled
+func (a *activeRuns) add(dagRunID string) context.Context {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	a.runs[dagRunID] = &activeRun{ctx: ctx, cancel: cancel}
+	return ctx
+}
+
+// remove stops tracking a DAG run added with the context whose Done channel is done.
+// Tasks of the run that are still running are not cancelled.
+func (a *activeRuns) remove(dagRunID string, done <-chan struct{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if run, ok := a.runs[dagRunID]; ok && run.ctx.Done() == done {
+		delete(a.runs, dagRunID)
+	}
+}
+
+// cancel cancels a DAG run, returning false if it is not tracked
+func (a *activeRuns) cancel(dagRunID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	run, ok := a.runs[dagRunID]
+	if ok {
+		run.cancel(ErrDAGRunCancelled)
+	}
+	return ok
+}
+
+// cancelled returns true if a tracked DAG run was cancelled
+func (a *activeRuns) cancelled(dagRunID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	run, ok := a.runs[dagRunID]
+	return ok && run.ctx.Err() != nil
+}
+
+// taskContext derives the context of a task from ctx that is also cancelled when its DAG run is.
+// The returned function releases the context once the task finished.
+func (a *activeRuns) taskContext(ctx context.Context, dagRunID string) (context.Context, func()) {
+	a.mu.Lock()
+	run, ok := a.runs[dagRunID]
+	a.mu.Unlock()
+
+	taskCtx, cancel := context.WithCancelCause(ctx)
+	if !ok {
+		return taskCtx, func() { cancel(nil) }
+	}
+
+	stop := context.AfterFunc(run.ctx, func() {
+		cancel(context.Cause(run.ctx))
+	})
+	return taskCtx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// cancelledElsewhere returns true if a DAG run was moved to cancelled by another process, such as the API
+// server cancelling a run it does not schedule
+func cancelledElsewhere(ctx context.Context, dagRunRepo storage.DAGRunRepository, dagRunID string) bool {
+	dagRun, err := dagRunRepo.Get(ctx, dagRunID)
+	return err == nil && dagRun.State == models.StateCancelled
+}
+
+// isCancelled returns true if ctx was cancelled because its DAG run was
+func isCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrDAGRunCancelled)
+}
+
+// markCancelled turns the result of a task interrupted by the cancellation of its DAG run into a cancelled result
+func markCancelled(ctx context.Context, result *TaskResult) {
+	if !isCancelled(ctx) {
+		return
+	}
+	result.State = models.StateCancelled
+	result.ErrorMessage = "Task interrupted because its DAG run was cancelled"
+	result.ErrorCode = ErrorCodeCancelled
+}
+
+// cancel moves every unfinished task of a cancelled DAG run that has not started yet to cancelled.
+// Running tasks are left alone; they report completion once they notice the cancellation.
+func (p *runProgress) cancel(ctx context.Context, taskRepo storage.TaskInstanceRepository) {
+	p.cancelled = true
+
+	for taskID, taskInstance := range p.taskInstances {
+		if p.completed[taskID] || p.failed[taskID] {
+			continue
+		}
+
+		current, err := taskRepo.Get(ctx, taskInstance.ID)
+		if err != nil {
+			log.Printf("Failed to get task instance %s: %v", taskInstance.ID, err)
+			continue
+		}
+
+		switch current.State {
+		case models.StateQueued, models.StateRetrying, models.StateScheduled, models.StateUpForReschedule:
+			if err := taskRepo.UpdateState(ctx, current.ID, current.State, models.StateCancelled); err != nil {
+				// Picked up in the meantime, so it is running with a cancelled context
+				log.Printf("Failed to cancel task %s: %v", taskID, err)
+				continue
+			}
+			current.State = models.StateCancelled
+		case models.StateRunning:
+			continue
+		}
+
+		p.taskInstances[taskID] = current
+		p.record(TaskCompletion{TaskID: taskID, State: current.State})
+	}
+}
@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/retry"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// blockingTaskExecutor runs until its context is done
+type blockingTaskExecutor struct {
+	started chan string
+}
+
+func (e *blockingTaskExecutor) Type() models.TaskType {
+	return models.TaskTypeBash
+}
+
+func (e *blockingTaskExecutor) Execute(ctx context.Context, task *models.Task, taskInstance *models.TaskInstance) *TaskResult {
+	result := &TaskResult{StartTime: time.Now()}
+	e.started <- task.ID
+	<-ctx.Done()
+	result.EndTime = time.Now()
+	result.State = models.StateFailed
+	result.ErrorMessage = ctx.Err().Error()
+	return result
+}
+
+func TestLocalExecutor_CancelInterruptsRunningTasks(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+
+	exec := NewLocalExecutor(taskRepo, &memDAGRunRepo{}, nil, DefaultExecutorConfig())
+	tasks := &blockingTaskExecutor{started: make(chan string, 1)}
+	exec.RegisterTaskExecutor(tasks)
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeBash, Retries: 2},
+			{ID: "load", Type: models.TaskTypeBash, Dependencies: []string{"extract"}},
+		},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+
+	if err := exec.Cancel(ctx, dagRun.ID); !errors.Is(err, ErrDAGRunNotActive) {
+		t.Errorf("Cancel of a DAG run that was not submitted = %v, want %v", err, ErrDAGRunNotActive)
+	}
+
+	if err := exec.Execute(ctx, dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	select {
+	case <-tasks.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Task did not start")
+	}
+
+	if err := exec.Cancel(ctx, dagRun.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+
+	select {
+	case finalState := <-done:
+		if finalState != models.StateCancelled {
+			t.Errorf("DAG run final state = %s, want %s", finalState, models.StateCancelled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancelled DAG run did not complete")
+	}
+
+	// The interrupted task is not retried and the task that never started is cancelled too
+	for _, instance := range taskRepo.instances {
+		if instance.State != models.StateCancelled {
+			t.Errorf("Task %s = %s, want %s", instance.TaskID, instance.State, models.StateCancelled)
+		}
+		if instance.TryNumber != 1 {
+			t.Errorf("Task %s ran %d attempts, want 1", instance.TaskID, instance.TryNumber)
+		}
+	}
+}
+
+func TestSequentialExecutor_CancelDuringRetryDelay(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	flaky := &flakyTaskExecutor{failures: 1}
+	exec.RegisterTaskExecutor(flaky)
+	exec.SetRetryStrategy(retry.NewFixedDelay(time.Hour, false))
+
+	dagModel, dagRun := newRetryTestDAG(2)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- exec.Execute(context.Background(), dagRun, dagModel)
+	}()
+
+	// Wait for the first attempt to fail and the task to wait for its next one
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if instance := taskRepo.only(); instance != nil && instance.State == models.StateRetrying {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Task did not wait for a retry")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := exec.Cancel(context.Background(), dagRun.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancelled DAG run kept waiting for the retry delay")
+	}
+
+	if dagRun.State != models.StateCancelled {
+		t.Errorf("DAG run state = %s, want %s", dagRun.State, models.StateCancelled)
+	}
+	if instance := taskRepo.only(); instance.State != models.StateCancelled || flaky.attempts != 1 {
+		t.Errorf("Task instance = %s after %d attempts, want %s after 1", instance.State, flaky.attempts, models.StateCancelled)
+	}
+}
+
+func TestLocalExecutor_CancelledElsewhere(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+	dagRunRepo := &statefulDAGRunRepo{run: models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}}
+
+	config := DefaultExecutorConfig()
+	config.CompletionResyncInterval = 20 * time.Millisecond
+	exec := NewLocalExecutor(taskRepo, dagRunRepo, nil, config)
+	tasks := &blockingTaskExecutor{started: make(chan string, 1)}
+	exec.RegisterTaskExecutor(tasks)
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	dagModel := &models.DAG{ID: "dag1", Tasks: []models.Task{{ID: "extract", Type: models.TaskTypeBash}}}
+	dagRun, _ := dagRunRepo.Get(ctx, "run1")
+	if err := exec.Execute(ctx, dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	select {
+	case <-tasks.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Task did not start")
+	}
+
+	// Another process, such as the API server, cancels the run in the database
+	if err := dagRunRepo.UpdateState(ctx, "run1", models.StateRunning, models.StateCancelled); err != nil {
+		t.Fatalf("UpdateState failed: %v", err)
+	}
+
+	select {
+	case finalState := <-done:
+		if finalState != models.StateCancelled {
+			t.Errorf("DAG run final state = %s, want %s", finalState, models.StateCancelled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Running task was not interrupted")
+	}
+
+	if instance := taskRepo.only(); instance.State != models.StateCancelled {
+		t.Errorf("Task instance state = %s, want %s", instance.State, models.StateCancelled)
+	}
+}
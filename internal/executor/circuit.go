@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// taskCircuitBreaker returns the circuit breaker guarding an external dependency of a task and its name,
+// or nil if the task disabled its circuit breakers. Tasks with the same settings share the breaker of a
+// dependency; settings other than the defaults get a breaker of their own, named after them.
+func taskCircuitBreaker(registry *circuitbreaker.Registry, dependency string, task *models.Task) (string, *circuitbreaker.CircuitBreaker) {
+	settings := task.CircuitBreaker
+	if settings != nil && settings.Disabled {
+		return "", nil
+	}
+
+	config := circuitbreaker.DefaultConfig()
+	name := dependency
+	if settings != nil && (settings.MaxFailures > 0 || settings.Timeout > 0) {
+		if settings.MaxFailures > 0 {
+			config.MaxFailures = settings.MaxFailures
+		}
+		if settings.Timeout > 0 {
+			config.Timeout = settings.Timeout
+		}
+		name = fmt.Sprintf("%s(max_failures=%d,timeout=%s)", dependency, config.MaxFailures, config.Timeout)
+	}
+
+	return name, registry.Get(name, config)
+}
+
+// callThrough calls a dependency through its circuit breaker, if any. call returns an error when the
+// dependency failed. It returns false without calling the dependency if the breaker rejects the call.
+func callThrough(ctx context.Context, cb *circuitbreaker.CircuitBreaker, call func() error) bool {
+	if cb == nil {
+		call()
+		return true
+	}
+
+	called := false
+	cb.Execute(ctx, func() error {
+		called = true
+		return call()
+	})
+	return called
+}
+
+// circuitOpenResult fails an attempt that did not run because the circuit breaker guarding its
+// dependency rejected it. The attempt may be retried once the breaker lets a trial request through.
+func circuitOpenResult(result *TaskResult, name string, cb *circuitbreaker.CircuitBreaker) *TaskResult {
+	result.EndTime = time.Now()
+	result.State = models.StateFailed
+	result.ErrorCode = ErrorCodeCircuitOpen
+	result.RetryAfter = cb.RetryAfter()
+	result.ErrorMessage = fmt.Sprintf("Circuit breaker %s is %s, not calling the dependency", name, cb.GetState())
+	return result
+}
+
+// taskWithDAGDefaults returns the task with the circuit breaker settings of its DAG if it has none of its own
+func taskWithDAGDefaults(dagModel *models.DAG, task *models.Task) *models.Task {
+	if dagModel == nil {
+		return task
+	}
+	settings := dagModel.TaskCircuitBreaker(task)
+	if settings == task.CircuitBreaker {
+		return task
+	}
+
+	withDefaults := *task
+	withDefaults.CircuitBreaker = settings
+	return &withDefaults
+}
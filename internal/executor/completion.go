@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// defaultCompletionResyncInterval is used when ExecutorConfig.CompletionResyncInterval is unset
+const defaultCompletionResyncInterval = 30 * time.Second
+
+// TaskCompletion signals that a task instance of a DAG run reached its final state
+type TaskCompletion struct {
+	DAGRunID       string       `json:"dag_run_id"`
+	TaskID         string       `json:"task_id"`
+	TaskInstanceID string       `json:"task_instance_id"`
+	State          models.State `json:"state"`
+	Branches       []string     `json:"branches"` // Downstream tasks a succeeded branch task follows; empty means none, nil means undecided
+}
+
+// completionRouter delivers task completions to the scheduling loop of their DAG run
+type completionRouter struct {
+	mu   sync.Mutex
+	runs map[string]chan TaskCompletion
+}
+
+func newCompletionRouter() *completionRouter {
+	return &completionRouter{runs: make(map[string]chan TaskCompletion)}
+}
+
+// register returns the channel on which completions of a DAG run with the given number of tasks are delivered
+func (r *completionRouter) register(dagRunID string, taskCount int) <-chan TaskCompletion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch := make(chan TaskCompletion, taskCount)
+	r.runs[dagRunID] = ch
+	return ch
+}
+
+// unregister stops delivering completions of a DAG run on ch. A run registered again
+// since, because it was resumed right after it finished, keeps its newer channel.
+func (r *completionRouter) unregister(dagRunID string, ch <-chan TaskCompletion) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.runs[dagRunID] == ch {
+		delete(r.runs, dagRunID)
+	}
+}
+
+// notify hands a completion to its DAG run without blocking.
+// Completions of runs that are not scheduled by this process are ignored.
+func (r *completionRouter) notify(completion TaskCompletion) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch, ok := r.runs[completion.DAGRunID]
+	if !ok {
+		return
+	}
+
+	select {
+	case ch <- completion:
+	default:
+		log.Printf("Dropped completion of task %s in DAG run %s, it will be picked up on resync", completion.TaskID, completion.DAGRunID)
+	}
+}
+
+// completionResyncInterval returns how often running DAG runs re-read task states in case a completion signal was lost
+func (c *ExecutorConfig) completionResyncInterval() time.Duration {
+	if c.CompletionResyncInterval <= 0 {
+		return defaultCompletionResyncInterval
+	}
+	return c.CompletionResyncInterval
+}
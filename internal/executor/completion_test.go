@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestLocalExecutor_SchedulesOnCompletion(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	config := DefaultExecutorConfig()
+	config.WorkerCount = 2
+	// Progress must come from completion signals, not from re-reading the repository
+	config.CompletionResyncInterval = time.Hour
+
+	exec := NewLocalExecutor(taskRepo, &memDAGRunRepo{}, nil, config)
+	exec.RegisterTaskExecutor(&flakyTaskExecutor{})
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+
+	ctx := context.Background()
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeBash},
+			{ID: "transform", Type: models.TaskTypeBash, Dependencies: []string{"extract"}},
+			{ID: "load", Type: models.TaskTypeBash, Dependencies: []string{"transform"}},
+		},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+
+	start := time.Now()
+	if err := exec.Execute(ctx, dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	select {
+	case finalState := <-done:
+		if finalState != models.StateSuccess {
+			t.Errorf("DAG run final state = %s, want %s", finalState, models.StateSuccess)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DAG run did not complete")
+	}
+
+	// Three dependent levels used to take at least three polling ticks
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DAG run took %s, want completion-driven scheduling", elapsed)
+	}
+}
+
+func TestCompletionRouter_IgnoresUnknownRuns(t *testing.T) {
+	router := newCompletionRouter()
+	completions := router.register("run1", 1)
+
+	router.notify(TaskCompletion{DAGRunID: "run2", TaskID: "task1", State: models.StateSuccess})
+	router.notify(TaskCompletion{DAGRunID: "run1", TaskID: "task1", State: models.StateSuccess})
+
+	select {
+	case completion := <-completions:
+		if completion.DAGRunID != "run1" || completion.TaskID != "task1" {
+			t.Errorf("Completion = %+v, want task1 of run1", completion)
+		}
+	default:
+		t.Fatal("Expected a completion for run1")
+	}
+
+	router.unregister("run1", completions)
+	router.notify(TaskCompletion{DAGRunID: "run1", TaskID: "task2", State: models.StateSuccess})
+	if len(completions) != 0 {
+		t.Error("Expected no completions after unregistering the run")
+	}
+
+	// A run resumed before its previous scheduling loop unregistered keeps receiving completions
+	previous := router.register("run1", 1)
+	resumed := router.register("run1", 1)
+	router.unregister("run1", previous)
+	router.notify(TaskCompletion{DAGRunID: "run1", TaskID: "task3", State: models.StateSuccess})
+	if len(resumed) != 1 {
+		t.Error("Expected the resumed run to receive the completion")
+	}
+}
@@ -3,6 +3,7 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -10,6 +11,9 @@ import (
 
 	"github.com/nats-io/nats.go"
 	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
 	"github.com/therealutkarshpriyadarshi/dag/internal/state"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
@@ -21,27 +25,60 @@ const (
 	TasksResultsStream = "TASKS_RESULTS"
 
 	// Subject names
-	TasksPendingSubject = "tasks.pending"
-	TasksResultsSubject = "tasks.results"
+	TasksPendingSubject    = "tasks.pending"
+	TasksResultsSubject    = "tasks.results"
 	WorkerHeartbeatSubject = "workers.heartbeat"
+	// TaskLogsSubjectPrefix is followed by the task instance ID
+	TaskLogsSubjectPrefix = "tasks.logs."
+	// TaskCompletedSubjectPrefix is followed by the DAG run ID
+	TaskCompletedSubjectPrefix = "tasks.completed."
+	// TaskCancelSubjectPrefix is followed by the ID of the task instance to cancel
+	TaskCancelSubjectPrefix = "tasks.cancel."
+	// ResultsPullSubject receives requests of workers for values pushed by tasks
+	ResultsPullSubject = "results.pull"
+	// TaskRedeliveredSubject receives requests of workers asking what to do with a redelivered task message
+	TaskRedeliveredSubject = "tasks.redelivered"
+)
+
+// Actions a worker takes on a redelivered task message
+const (
+	RedeliveryRun  = "run"  // No worker started the attempt, so the message was only waiting in a queue
+	RedeliveryLost = "lost" // The worker that started the attempt died; the attempt is reported as lost
+	RedeliveryDrop = "drop" // The attempt is concluded, or its worker is alive and reports it
 )
 
 // DistributedExecutor executes tasks across multiple workers using NATS
 type DistributedExecutor struct {
-	nc            *nats.Conn
-	js            nats.JetStreamContext
-	taskRepo      storage.TaskInstanceRepository
-	dagRunRepo    storage.DAGRunRepository
-	stateMachine  *state.Machine
-	config        *ExecutorConfig
+	nc           *nats.Conn
+	js           nats.JetStreamContext
+	taskRepo     storage.TaskInstanceRepository
+	dagRunRepo   storage.DAGRunRepository
+	dagRepo      storage.DAGRepository
+	stateMachine *state.StateMachine
+	config       *ExecutorConfig
+	onComplete   DAGRunCompleteFunc
+	taskLogRepo  storage.TaskLogRepository
+	dlq          *dlq.Manager
+	results      *results.Manager
+	renderer     *render.Renderer
+	completions  *completionRouter
+	active       *activeRuns
+
+	// Tasks handed to workers, kept so failed attempts can be published again
+	inflight   map[string]*TaskExecution
+	inflightMu sync.Mutex
 
 	// Worker management
-	workers     map[string]*WorkerInfo
-	workersMu   sync.RWMutex
+	workers   map[string]*WorkerInfo
+	workersMu sync.RWMutex
 
 	// Subscriptions
-	resultSub   *nats.Subscription
+	resultSub    *nats.Subscription
 	heartbeatSub *nats.Subscription
+	logSub       *nats.Subscription
+	completedSub *nats.Subscription
+	pullSub      *nats.Subscription
+	redeliverSub *nats.Subscription
 
 	running bool
 	mu      sync.RWMutex
@@ -52,42 +89,105 @@ type DistributedExecutor struct {
 
 // WorkerInfo represents information about a worker
 type WorkerInfo struct {
-	ID           string
-	Hostname     string
-	LastHeartbeat time.Time
-	ActiveTasks  int
+	ID              string
+	Hostname        string
+	LastHeartbeat   time.Time
+	ActiveTasks     int
+	TaskInstanceIDs []string // Task instances the worker was running at its last heartbeat
 }
 
 // TaskMessage represents a task to be executed
 type TaskMessage struct {
-	TaskInstanceID string        `json:"task_instance_id"`
-	TaskID         string        `json:"task_id"`
-	DAGRunID       string        `json:"dag_run_id"`
-	DAGID          string        `json:"dag_id"`
-	TaskType       string        `json:"task_type"`
-	Command        string        `json:"command"`
-	Timeout        time.Duration `json:"timeout"`
-	Retries        int           `json:"retries"`
+	TaskInstanceID string                       `json:"task_instance_id"`
+	TaskID         string                       `json:"task_id"`
+	DAGRunID       string                       `json:"dag_run_id"`
+	DAGID          string                       `json:"dag_id"`
+	TaskType       string                       `json:"task_type"`
+	Command        string                       `json:"command"`
+	Timeout        time.Duration                `json:"timeout"`
+	Retries        int                          `json:"retries"`
+	TryNumber      int                          `json:"try_number"`
+	CircuitBreaker *models.CircuitBreakerConfig `json:"circuit_breaker,omitempty"` // Circuit breaker settings of the task or its DAG
+	Branch         *models.BranchConfig         `json:"branch,omitempty"`          // Branch settings of branch tasks
+	ResultPaths    map[string]string            `json:"result_paths,omitempty"`    // Response body fields HTTP tasks push as results
+	Params         map[string]interface{}       `json:"params,omitempty"`          // Parameters of the DAG run by name
 }
 
 // TaskResultMessage represents the result of a task execution
 type TaskResultMessage struct {
-	TaskInstanceID string        `json:"task_instance_id"`
-	WorkerID       string        `json:"worker_id"`
-	State          string        `json:"state"`
-	Output         string        `json:"output"`
-	ErrorMessage   string        `json:"error_message"`
-	StartTime      time.Time     `json:"start_time"`
-	EndTime        time.Time     `json:"end_time"`
-	Hostname       string        `json:"hostname"`
+	TaskInstanceID string                     `json:"task_instance_id"`
+	WorkerID       string                     `json:"worker_id"`
+	State          string                     `json:"state"`
+	Output         string                     `json:"output"`
+	ErrorMessage   string                     `json:"error_message"`
+	ExitCode       int                        `json:"exit_code,omitempty"`
+	ErrorCode      string                     `json:"error_code,omitempty"`
+	RetryAfter     time.Duration              `json:"retry_after,omitempty"` // Minimum delay before the attempt is retried
+	TryNumber      int                        `json:"try_number,omitempty"`  // Attempt the result belongs to; results of superseded attempts are ignored
+	Branches       []string                   `json:"branches"`              // Downstream tasks a succeeded branch task follows; empty means none
+	Results        map[string]json.RawMessage `json:"results,omitempty"`     // Values the task pushed, stored by the control plane
+	StartTime      time.Time                  `json:"start_time"`
+	EndTime        time.Time                  `json:"end_time"`
+	Hostname       string                     `json:"hostname"`
+}
+
+// taskResult converts a result message to the TaskResult fields used for retry decisions
+func (m *TaskResultMessage) taskResult() *TaskResult {
+	return &TaskResult{
+		State:        models.State(m.State),
+		Output:       m.Output,
+		ErrorMessage: m.ErrorMessage,
+		ExitCode:     m.ExitCode,
+		ErrorCode:    m.ErrorCode,
+		RetryAfter:   m.RetryAfter,
+		Branches:     m.Branches,
+		Results:      m.Results,
+		StartTime:    m.StartTime,
+		EndTime:      m.EndTime,
+		Hostname:     m.Hostname,
+	}
+}
+
+// ResultPullRequest asks the control plane for a value a task of a DAG run pushed
+type ResultPullRequest struct {
+	DAGRunID string `json:"dag_run_id"`
+	TaskID   string `json:"task_id"`
+	Key      string `json:"key"`
+}
+
+// ResultPullReply carries a pulled value, or why it could not be pulled
+type ResultPullReply struct {
+	Value    json.RawMessage `json:"value,omitempty"`
+	NotFound bool            `json:"not_found,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// RedeliveryCheck asks the control plane what to do with a task message JetStream delivered again
+type RedeliveryCheck struct {
+	TaskInstanceID string `json:"task_instance_id"`
+	TryNumber      int    `json:"try_number"`
+}
+
+// RedeliveryReply tells a worker which Redelivery action to take on a redelivered task message
+type RedeliveryReply struct {
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
 }
 
 // WorkerHeartbeat represents a worker heartbeat message
 type WorkerHeartbeat struct {
-	WorkerID    string    `json:"worker_id"`
-	Hostname    string    `json:"hostname"`
-	ActiveTasks int       `json:"active_tasks"`
-	Timestamp   time.Time `json:"timestamp"`
+	WorkerID        string    `json:"worker_id"`
+	Hostname        string    `json:"hostname"`
+	ActiveTasks     int       `json:"active_tasks"`
+	TaskInstanceIDs []string  `json:"task_instance_ids,omitempty"` // Task instances the worker is running
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// TaskLogMessage carries a batch of task output lines streamed by a worker
+type TaskLogMessage struct {
+	TaskInstanceID string    `json:"task_instance_id"`
+	WorkerID       string    `json:"worker_id"`
+	Lines          []LogLine `json:"lines"`
 }
 
 // NewDistributedExecutor creates a new distributed executor
@@ -95,7 +195,7 @@ func NewDistributedExecutor(
 	natsURL string,
 	taskRepo storage.TaskInstanceRepository,
 	dagRunRepo storage.DAGRunRepository,
-	stateMachine *state.Machine,
+	stateMachine *state.StateMachine,
 	config *ExecutorConfig,
 ) (*DistributedExecutor, error) {
 	if config == nil {
@@ -122,15 +222,19 @@ func NewDistributedExecutor(
 		dagRunRepo:   dagRunRepo,
 		stateMachine: stateMachine,
 		config:       config,
+		renderer:     render.NewRenderer(config.TemplateEnv),
 		workers:      make(map[string]*WorkerInfo),
+		inflight:     make(map[string]*TaskExecution),
+		completions:  newCompletionRouter(),
+		active:       newActiveRuns(),
 		running:      false,
 		status: ExecutorStatus{
-			Running:       false,
-			ActiveTasks:   0,
+			Running:        false,
+			ActiveTasks:    0,
 			CompletedTasks: 0,
-			FailedTasks:   0,
-			WorkerCount:   0,
-			QueueDepth:    0,
+			FailedTasks:    0,
+			WorkerCount:    0,
+			QueueDepth:     0,
 		},
 	}
 
@@ -170,6 +274,36 @@ func (e *DistributedExecutor) initStreams() error {
 	return nil
 }
 
+// SetTaskLogRepository enables persisting task output streamed by workers
+func (e *DistributedExecutor) SetTaskLogRepository(repo storage.TaskLogRepository) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.taskLogRepo = repo
+}
+
+// SetDAGRepository enables concluding the results of tasks this process did not hand out, such as tasks
+// published before a restart or by another control plane: their DAG is loaded from the version their run executes
+// so that they are retried or sent to the dead letter queue
+func (e *DistributedExecutor) SetDAGRepository(repo storage.DAGRepository) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dagRepo = repo
+}
+
+// SetDLQ sets the dead letter queue that receives tasks whose attempts are exhausted
+func (e *DistributedExecutor) SetDLQ(manager *dlq.Manager) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dlq = manager
+}
+
+// SetResults sets the result manager that stores the values tasks push and serves the values workers pull
+func (e *DistributedExecutor) SetResults(manager *results.Manager) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results = manager
+}
+
 // Start initializes the executor and starts listening for results
 func (e *DistributedExecutor) Start(ctx context.Context) error {
 	e.mu.Lock()
@@ -199,10 +333,62 @@ func (e *DistributedExecutor) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to subscribe to heartbeats: %w", err)
 	}
 
+	// Subscribe to task logs
+	if e.taskLogRepo != nil {
+		e.logSub, err = e.nc.Subscribe(TaskLogsSubjectPrefix+"*", e.handleTaskLogs)
+		if err != nil {
+			e.resultSub.Unsubscribe()
+			e.heartbeatSub.Unsubscribe()
+			return fmt.Errorf("failed to subscribe to task logs: %w", err)
+		}
+	}
+
+	// Subscribe to task completions, which may be reported by any control plane consuming results
+	e.completedSub, err = e.nc.Subscribe(TaskCompletedSubjectPrefix+"*", e.handleTaskCompleted)
+	if err != nil {
+		e.resultSub.Unsubscribe()
+		e.heartbeatSub.Unsubscribe()
+		if e.logSub != nil {
+			e.logSub.Unsubscribe()
+		}
+		return fmt.Errorf("failed to subscribe to task completions: %w", err)
+	}
+
+	// Tell workers whether redelivered task messages were started, spreading requests across control planes
+	e.redeliverSub, err = e.nc.QueueSubscribe(TaskRedeliveredSubject, "tasks-redelivered", e.handleRedelivery)
+	if err != nil {
+		e.resultSub.Unsubscribe()
+		e.heartbeatSub.Unsubscribe()
+		if e.logSub != nil {
+			e.logSub.Unsubscribe()
+		}
+		e.completedSub.Unsubscribe()
+		return fmt.Errorf("failed to subscribe to task redeliveries: %w", err)
+	}
+
+	// Serve the values workers pull, spreading requests across control planes
+	if e.results != nil {
+		e.pullSub, err = e.nc.QueueSubscribe(ResultsPullSubject, "results-pull", e.handleResultPull)
+		if err != nil {
+			e.resultSub.Unsubscribe()
+			e.heartbeatSub.Unsubscribe()
+			if e.logSub != nil {
+				e.logSub.Unsubscribe()
+			}
+			e.completedSub.Unsubscribe()
+			e.redeliverSub.Unsubscribe()
+			return fmt.Errorf("failed to subscribe to result pulls: %w", err)
+		}
+	}
+
 	// Start worker monitoring
 	e.wg.Add(1)
 	go e.monitorWorkers(ctx)
 
+	// Renew the leases of tasks handed to workers
+	e.wg.Add(1)
+	go e.heartbeatInflight(ctx)
+
 	log.Println("Distributed executor started")
 	return nil
 }
@@ -226,6 +412,18 @@ func (e *DistributedExecutor) Stop(ctx context.Context) error {
 	if e.heartbeatSub != nil {
 		e.heartbeatSub.Unsubscribe()
 	}
+	if e.logSub != nil {
+		e.logSub.Unsubscribe()
+	}
+	if e.completedSub != nil {
+		e.completedSub.Unsubscribe()
+	}
+	if e.pullSub != nil {
+		e.pullSub.Unsubscribe()
+	}
+	if e.redeliverSub != nil {
+		e.redeliverSub.Unsubscribe()
+	}
 
 	// Wait for goroutines to finish
 	done := make(chan struct{})
@@ -271,8 +469,15 @@ func (e *DistributedExecutor) GetStatus() ExecutorStatus {
 	return status
 }
 
+// OnDAGRunComplete sets a callback invoked when a DAG run reaches a terminal state
+func (e *DistributedExecutor) OnDAGRunComplete(callback DAGRunCompleteFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onComplete = callback
+}
+
 // Execute submits a DAG run for execution
-func (e *DistributedExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG) error {
+func (e *DistributedExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dagModel *models.DAG) error {
 	if !e.running {
 		return fmt.Errorf("executor is not running")
 	}
@@ -283,13 +488,11 @@ func (e *DistributedExecutor) Execute(ctx context.Context, dagRun *models.DAGRun
 	if err := e.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateQueued, models.StateRunning); err != nil {
 		return fmt.Errorf("failed to update DAG run state: %w", err)
 	}
-
-	// Build dependency graph
-	graph := dag.NewGraph(dag)
+	dagRun.State = models.StateRunning
 
 	// Create task instances for all tasks
 	taskInstances := make(map[string]*models.TaskInstance)
-	for _, task := range dag.Tasks {
+	for _, task := range dagModel.Tasks {
 		taskInstance := &models.TaskInstance{
 			TaskID:    task.ID,
 			DAGRunID:  dagRun.ID,
@@ -298,112 +501,204 @@ func (e *DistributedExecutor) Execute(ctx context.Context, dagRun *models.DAGRun
 			MaxTries:  task.Retries + 1,
 		}
 
-		created, err := e.taskRepo.Create(ctx, taskInstance)
-		if err != nil {
+		if err := e.taskRepo.Create(ctx, taskInstance); err != nil {
 			return fmt.Errorf("failed to create task instance for %s: %w", task.ID, err)
 		}
-		taskInstances[task.ID] = created
+		taskInstances[task.ID] = taskInstance
 	}
 
 	// Start a goroutine to manage task scheduling for this DAG run
+	completions := e.completions.register(dagRun.ID, len(dagModel.Tasks))
+	cancelled := e.active.add(dagRun.ID).Done()
 	e.wg.Add(1)
-	go e.scheduleTasks(ctx, dagRun, dag, graph, taskInstances)
+	go e.scheduleTasks(ctx, dagRun, dagModel, newRunProgress(taskInstances), completions, cancelled)
 
 	return nil
 }
 
-// scheduleTasks manages the scheduling of tasks for a DAG run
+// Resume continues scheduling a running DAG run from its persisted task instances.
+// Results of tasks still running on workers are picked up as usual; tasks whose
+// lease expires first are retried or failed.
+func (e *DistributedExecutor) Resume(ctx context.Context, dagRun *models.DAGRun, dagModel *models.DAG, taskInstances []*models.TaskInstance) error {
+	if !e.running {
+		return fmt.Errorf("executor is not running")
+	}
+
+	progress, err := resumeRunProgress(ctx, e.taskRepo, dagRun, dagModel, taskInstances)
+	if err != nil {
+		return fmt.Errorf("failed to resume DAG run %s: %w", dagRun.ID, err)
+	}
+	dagRun.State = models.StateRunning
+
+	completions := e.completions.register(dagRun.ID, len(dagModel.Tasks))
+	cancelled := e.active.add(dagRun.ID).Done()
+	e.wg.Add(1)
+	go e.scheduleTasks(ctx, dagRun, dagModel, progress, completions, cancelled)
+
+	log.Printf("Resumed DAG run %s", dagRun.ID)
+	return nil
+}
+
+// Cancel cancels the tasks of a DAG run that have not started and asks the workers to interrupt
+// the running ones. The DAG run ends in the cancelled state once the workers reported back.
+func (e *DistributedExecutor) Cancel(ctx context.Context, dagRunID string) error {
+	if !e.active.cancel(dagRunID) {
+		return ErrDAGRunNotActive
+	}
+
+	log.Printf("Cancelling DAG run %s", dagRunID)
+	return nil
+}
+
+// scheduleTasks manages the scheduling of tasks for a DAG run.
+// It publishes every task whose trigger rule lets it run and wakes up again whenever a task completion is received.
 func (e *DistributedExecutor) scheduleTasks(
 	ctx context.Context,
 	dagRun *models.DAGRun,
-	dag *models.DAG,
-	graph *dag.Graph,
-	taskInstances map[string]*models.TaskInstance,
+	dagModel *models.DAG,
+	progress *runProgress,
+	completions <-chan TaskCompletion,
+	cancelled <-chan struct{},
 ) {
 	defer e.wg.Done()
+	defer e.completions.unregister(dagRun.ID, completions)
+	defer e.active.remove(dagRun.ID, cancelled)
+
+	// Renew the lease on the DAG run so that recovery leaves it alone while this process schedules it
+	stopHeartbeat := startHeartbeat(ctx, e.config.heartbeatInterval(), "DAG run "+dagRun.ID, func(ctx context.Context) error {
+		return e.dagRunRepo.Heartbeat(ctx, dagRun.ID)
+	})
+	defer stopHeartbeat()
 
-	completedTasks := make(map[string]bool)
-	failedTasks := make(map[string]bool)
-	submittedTasks := make(map[string]bool)
+	// Completion messages are not durable, so task states are re-read from the repository occasionally
+	resync := time.NewTicker(e.config.completionResyncInterval())
+	defer resync.Stop()
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	// Resumed runs may have tasks left running by a dead worker
+	progress.resync(ctx, e.taskRepo, e.config.leaseTimeout())
 
+	graph := dag.NewGraph(dagModel)
 	for {
+		// Find tasks ready to execute; trigger rules may decide that others will not run
+		readyTasks, notRun := progress.next(graph)
+		progress.skip(ctx, e.taskRepo, notRun)
+
+		for _, task := range readyTasks {
+			taskInstance := progress.taskInstances[task.ID]
+
+			execution := &TaskExecution{
+				Task:         task,
+				TaskInstance: taskInstance,
+				DAGRun:       dagRun,
+				DAG:          dagModel,
+			}
+
+			// Resumed and reaped tasks start from retrying rather than queued
+			if err := e.dispatch(ctx, execution, taskInstance.State); err != nil {
+				log.Printf("Failed to dispatch task %s: %v", task.ID, err)
+				progress.finish(task.ID, models.StateFailed)
+				continue
+			}
+
+			progress.submitted[task.ID] = true
+			log.Printf("Published task %s to NATS", task.ID)
+		}
+
+		// Check if all tasks are done
+		if progress.done() {
+			e.finalizeDagRun(ctx, dagRun, progress.finalState())
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			log.Printf("DAG run %s scheduling cancelled", dagRun.ID)
 			return
-		case <-ticker.C:
-			// Check if all tasks are done
-			if len(completedTasks)+len(failedTasks) == len(dag.Tasks) {
-				e.finalizeDagRun(ctx, dagRun, len(failedTasks) > 0)
-				return
+		case <-cancelled:
+			// Workers report the interrupted tasks as cancelled
+			cancelled = nil
+			progress.cancel(ctx, e.taskRepo)
+			e.cancelRunningTasks(progress)
+		case completion := <-completions:
+			progress.record(completion)
+		case <-resync.C:
+			if cancelled != nil && cancelledElsewhere(ctx, e.dagRunRepo, dagRun.ID) {
+				// Handled like a cancellation through this executor once the loop wakes up again
+				e.active.cancel(dagRun.ID)
 			}
+			progress.resync(ctx, e.taskRepo, e.config.leaseTimeout())
+			if progress.cancelled {
+				// Reaped tasks must not be handed out again
+				progress.cancel(ctx, e.taskRepo)
+			}
+		}
+	}
+}
 
-			// Find tasks ready to execute
-			readyTasks := graph.GetParallelTasks(completedTasks)
-
-			for _, taskID := range readyTasks {
-				if submittedTasks[taskID] || failedTasks[taskID] {
-					continue
-				}
-
-				task := graph.GetTask(taskID)
-				if task == nil {
-					continue
-				}
+// dispatch marks a task instance as running and publishes it for the workers.
+// If publishing fails, the task instance is marked failed.
+func (e *DistributedExecutor) dispatch(ctx context.Context, execution *TaskExecution, fromState models.State) error {
+	taskInstance := execution.TaskInstance
 
-				// Check if upstream tasks failed
-				upstreamFailed := false
-				for _, depID := range task.Dependencies {
-					if failedTasks[depID] {
-						upstreamFailed = true
-						break
-					}
-				}
+	// Mark the task as running before handing it to a worker
+	if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, fromState, models.StateRunning); err != nil {
+		return fmt.Errorf("failed to update task state to running: %w", err)
+	}
+	// Mirror the repository, which starts the lease of the attempt without a worker, so that later
+	// updates of the whole task instance do not bring back the worker of an earlier attempt
+	now := time.Now()
+	taskInstance.State = models.StateRunning
+	taskInstance.WorkerID = ""
+	taskInstance.LastHeartbeatAt = &now
 
-				taskInstance := taskInstances[taskID]
+	e.inflightMu.Lock()
+	e.inflight[taskInstance.ID] = execution
+	e.inflightMu.Unlock()
 
-				if upstreamFailed {
-					e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateQueued, models.StateUpstreamFailed)
-					failedTasks[taskID] = true
-					continue
-				}
-
-				// Publish task to NATS
-				if err := e.publishTask(task, taskInstance, dagRun); err != nil {
-					log.Printf("Failed to publish task %s: %v", taskID, err)
-					continue
-				}
+	// Commands are rendered here rather than on workers, which have neither the DAG's parameters nor the result store
+	e.mu.RLock()
+	resultManager := e.results
+	e.mu.RUnlock()
+	task, err := renderCommand(ctx, e.renderer, e.taskRepo, resultManager, taskWithDAGDefaults(execution.DAG, execution.Task), taskInstance, execution.DAGRun, execution.DAG)
+	if err != nil {
+		// The attempt fails without reaching a worker, and is retried like any other failed attempt
+		attempt := templateFailed(err)
+		return e.applyResult(ctx, &TaskResultMessage{
+			TaskInstanceID: taskInstance.ID,
+			State:          string(attempt.State),
+			ErrorMessage:   attempt.ErrorMessage,
+			ErrorCode:      attempt.ErrorCode,
+			TryNumber:      taskInstance.TryNumber,
+			StartTime:      attempt.StartTime,
+			EndTime:        attempt.EndTime,
+			Hostname:       attempt.Hostname,
+		})
+	}
 
-				submittedTasks[taskID] = true
-				log.Printf("Published task %s to NATS", taskID)
-			}
+	if err := e.publishTask(task, taskInstance, execution.DAGRun, runParams(execution.DAGRun, execution.DAG)); err != nil {
+		e.removeInflight(taskInstance.ID)
+		if updateErr := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRunning, models.StateFailed); updateErr != nil {
+			log.Printf("Failed to mark unpublished task instance %s as failed: %v", taskInstance.ID, updateErr)
+		}
+		taskInstance.State = models.StateFailed
+		return err
+	}
 
-			// Update completed/failed tasks status
-			for taskID := range submittedTasks {
-				if completedTasks[taskID] || failedTasks[taskID] {
-					continue
-				}
+	return nil
+}
 
-				taskInstance, err := e.taskRepo.GetByID(ctx, taskInstances[taskID].ID)
-				if err != nil {
-					continue
-				}
+// removeInflight forgets a task instance handed to the workers and returns its execution, if known
+func (e *DistributedExecutor) removeInflight(taskInstanceID string) *TaskExecution {
+	e.inflightMu.Lock()
+	defer e.inflightMu.Unlock()
 
-				if taskInstance.State == models.StateSuccess {
-					completedTasks[taskID] = true
-				} else if taskInstance.State.IsTerminal() {
-					failedTasks[taskID] = true
-				}
-			}
-		}
-	}
+	execution := e.inflight[taskInstanceID]
+	delete(e.inflight, taskInstanceID)
+	return execution
 }
 
 // publishTask publishes a task to NATS for execution
-func (e *DistributedExecutor) publishTask(task *models.Task, taskInstance *models.TaskInstance, dagRun *models.DAGRun) error {
+func (e *DistributedExecutor) publishTask(task *models.Task, taskInstance *models.TaskInstance, dagRun *models.DAGRun, params map[string]interface{}) error {
 	msg := &TaskMessage{
 		TaskInstanceID: taskInstance.ID,
 		TaskID:         task.ID,
@@ -413,6 +708,11 @@ func (e *DistributedExecutor) publishTask(task *models.Task, taskInstance *model
 		Command:        task.Command,
 		Timeout:        task.Timeout,
 		Retries:        task.Retries,
+		TryNumber:      taskInstance.TryNumber,
+		CircuitBreaker: task.CircuitBreaker,
+		Branch:         task.Branch,
+		ResultPaths:    task.ResultPaths,
+		Params:         params,
 	}
 
 	data, err := json.Marshal(msg)
@@ -437,30 +737,98 @@ func (e *DistributedExecutor) handleTaskResult(msg *nats.Msg) {
 		return
 	}
 
-	ctx := context.Background()
-
-	// Update task instance
-	taskInstance, err := e.taskRepo.GetByID(ctx, result.TaskInstanceID)
-	if err != nil {
-		log.Printf("Failed to get task instance %s: %v", result.TaskInstanceID, err)
+	if err := e.applyResult(context.Background(), &result); err != nil {
+		log.Printf("Failed to process result of task instance %s: %v", result.TaskInstanceID, err)
 		msg.Nak()
 		return
 	}
 
+	msg.Ack()
+}
+
+// applyResult records the outcome of a task attempt, retrying it if its retry policy allows.
+// Results of attempts that are no longer running are ignored, so that an attempt is only
+// concluded once whether its result comes from a worker or from zombie detection.
+func (e *DistributedExecutor) applyResult(ctx context.Context, result *TaskResultMessage) error {
+	taskInstance, err := e.taskRepo.Get(ctx, result.TaskInstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get task instance: %w", err)
+	}
+
+	if taskInstance.State != models.StateRunning || (result.TryNumber > 0 && result.TryNumber != taskInstance.TryNumber) {
+		log.Printf("Ignoring result of task instance %s attempt %d, it is %s on attempt %d",
+			taskInstance.ID, result.TryNumber, taskInstance.State, taskInstance.TryNumber)
+		return nil
+	}
+
+	// Values the task pushed are stored before retries are decided, since an attempt whose values
+	// cannot be stored fails
+	if len(result.Results) > 0 {
+		e.mu.RLock()
+		resultManager := e.results
+		e.mu.RUnlock()
+
+		attempt := result.taskResult()
+		storeResults(ctx, resultManager, taskInstance, attempt)
+		result.State, result.ErrorCode, result.ErrorMessage = string(attempt.State), attempt.ErrorCode, attempt.ErrorMessage
+	}
+
 	// Update state
-	taskInstance.State = models.State(result.State)
 	taskInstance.StartDate = &result.StartTime
 	taskInstance.EndDate = &result.EndTime
 	taskInstance.Duration = result.EndTime.Sub(result.StartTime)
 	taskInstance.Hostname = result.Hostname
 	taskInstance.ErrorMessage = result.ErrorMessage
+	taskInstance.WorkerID = result.WorkerID
+
+	execution := e.removeInflight(taskInstance.ID)
+	if execution == nil {
+		execution = e.loadExecution(ctx, taskInstance)
+	}
+	e.observeResult(ctx, execution, taskInstance, result)
 
+	// Failed attempts are published again once the retry delay has elapsed. Without the execution,
+	// the policy comes from the task instance, but the task cannot be published again.
+	var task *models.Task
+	if execution != nil {
+		task = execution.Task
+	}
+	retryConfig := taskRetryConfig(task, taskInstance, e.config.retryStrategy())
+	retryable := shouldRetry(retryConfig, taskInstance, result.taskResult())
+	if retryable && execution == nil {
+		log.Printf("Cannot retry task instance %s, the DAG of its run could not be loaded", taskInstance.ID)
+	}
+	if retryable && execution != nil {
+		delay, err := markRetrying(ctx, e.taskRepo, taskInstance, retryConfig.Strategy, result.RetryAfter)
+		if err != nil {
+			e.inflightMu.Lock()
+			e.inflight[taskInstance.ID] = execution
+			e.inflightMu.Unlock()
+			return fmt.Errorf("failed to schedule retry: %w", err)
+		}
+
+		execution.TaskInstance = taskInstance
+		time.AfterFunc(delay, func() { e.retryTask(execution) })
+		return nil
+	}
+
+	if err := recordBranches(ctx, e.taskRepo, taskInstance, result.taskResult()); err != nil {
+		return err
+	}
+	taskInstance.State = models.State(result.State)
 	if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRunning, models.State(result.State)); err != nil {
-		log.Printf("Failed to update task state: %v", err)
-		msg.Nak()
-		return
+		return fmt.Errorf("failed to update task state: %w", err)
+	}
+
+	if taskInstance.State == models.StateFailed && execution != nil {
+		e.mu.RLock()
+		dlqManager := e.dlq
+		e.mu.RUnlock()
+		sendToDLQ(ctx, dlqManager, taskInstance, execution.DAG, result.ErrorMessage)
 	}
 
+	e.publishCompletion(taskInstance)
+
 	// Update statistics
 	e.mu.Lock()
 	if result.State == string(models.StateSuccess) {
@@ -471,7 +839,213 @@ func (e *DistributedExecutor) handleTaskResult(msg *nats.Msg) {
 	e.mu.Unlock()
 
 	log.Printf("Task %s completed with state %s", result.TaskInstanceID, result.State)
-	msg.Ack()
+	return nil
+}
+
+// loadExecution rebuilds the execution of a task instance this process did not hand out from its DAG run
+// and the DAG version the run executes. It returns nil if they cannot be loaded.
+func (e *DistributedExecutor) loadExecution(ctx context.Context, taskInstance *models.TaskInstance) *TaskExecution {
+	e.mu.RLock()
+	dagRepo := e.dagRepo
+	e.mu.RUnlock()
+	if dagRepo == nil {
+		return nil
+	}
+
+	dagRun, err := e.dagRunRepo.Get(ctx, taskInstance.DAGRunID)
+	if err != nil {
+		log.Printf("Failed to get DAG run of task instance %s: %v", taskInstance.ID, err)
+		return nil
+	}
+	dagVersion, err := dagRepo.GetVersion(ctx, dagRun.DAGID, dagRun.DAGVersion)
+	if err != nil {
+		log.Printf("Failed to get DAG version %d of task instance %s: %v", dagRun.DAGVersion, taskInstance.ID, err)
+		return nil
+	}
+
+	for i := range dagVersion.DAG.Tasks {
+		if dagVersion.DAG.Tasks[i].ID == taskInstance.TaskID {
+			return &TaskExecution{
+				Task:         &dagVersion.DAG.Tasks[i],
+				TaskInstance: taskInstance,
+				DAGRun:       dagRun,
+				DAG:          dagVersion.DAG,
+			}
+		}
+	}
+	log.Printf("Task %s of task instance %s is not in DAG version %d", taskInstance.TaskID, taskInstance.ID, dagRun.DAGVersion)
+	return nil
+}
+
+// retryTask publishes a task again once its retry delay has elapsed, unless its DAG run was cancelled meanwhile
+func (e *DistributedExecutor) retryTask(execution *TaskExecution) {
+	e.mu.RLock()
+	running := e.running
+	e.mu.RUnlock()
+
+	if !running {
+		log.Printf("Executor stopped, task %s left in retrying state", execution.Task.ID)
+		return
+	}
+
+	ctx := context.Background()
+	taskInstance := execution.TaskInstance
+	if e.active.cancelled(taskInstance.DAGRunID) || cancelledElsewhere(ctx, e.dagRunRepo, taskInstance.DAGRunID) {
+		if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRetrying, models.StateCancelled); err != nil {
+			// The scheduling loop of the DAG run cancelled it already
+			log.Printf("Did not cancel retry of task %s: %v", execution.Task.ID, err)
+			return
+		}
+		taskInstance.State = models.StateCancelled
+		e.publishCompletion(taskInstance)
+		return
+	}
+
+	if err := e.dispatch(ctx, execution, models.StateRetrying); err != nil {
+		log.Printf("Failed to dispatch retry of task %s: %v", execution.Task.ID, err)
+		// The task instance may have been cancelled or failed meanwhile; report whatever it ended in
+		current, err := e.taskRepo.Get(ctx, taskInstance.ID)
+		if err != nil {
+			log.Printf("Failed to get task instance %s: %v", taskInstance.ID, err)
+			return
+		}
+		if current.State.IsTerminal() {
+			taskInstance.State = current.State
+			e.publishCompletion(current)
+		}
+	}
+}
+
+// publishCompletion announces that a task instance reached its final state so the
+// scheduling loop of its DAG run wakes up, whichever control plane runs it
+func (e *DistributedExecutor) publishCompletion(taskInstance *models.TaskInstance) {
+	completion := TaskCompletion{
+		DAGRunID:       taskInstance.DAGRunID,
+		TaskID:         taskInstance.TaskID,
+		TaskInstanceID: taskInstance.ID,
+		State:          taskInstance.State,
+		Branches:       taskInstance.Branches,
+	}
+
+	data, err := json.Marshal(completion)
+	if err != nil {
+		log.Printf("Failed to marshal task completion: %v", err)
+		return
+	}
+
+	if err := e.nc.Publish(TaskCompletedSubjectPrefix+completion.DAGRunID, data); err != nil {
+		log.Printf("Failed to publish completion of task instance %s: %v", taskInstance.ID, err)
+	}
+}
+
+// handleTaskCompleted wakes the scheduling loop of a DAG run running in this process
+func (e *DistributedExecutor) handleTaskCompleted(msg *nats.Msg) {
+	var completion TaskCompletion
+	if err := json.Unmarshal(msg.Data, &completion); err != nil {
+		log.Printf("Failed to unmarshal task completion: %v", err)
+		return
+	}
+
+	e.completions.notify(completion)
+}
+
+// handleTaskLogs persists task output streamed by workers
+func (e *DistributedExecutor) handleTaskLogs(msg *nats.Msg) {
+	var logMsg TaskLogMessage
+	if err := json.Unmarshal(msg.Data, &logMsg); err != nil {
+		log.Printf("Failed to unmarshal task logs: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := e.taskLogRepo.CreateBatch(ctx, logMsg.TaskInstanceID, toTaskLogModels(logMsg.Lines)); err != nil {
+		log.Printf("Failed to persist logs for task instance %s: %v", logMsg.TaskInstanceID, err)
+	}
+}
+
+// handleResultPull replies to a worker with a value a task pushed
+func (e *DistributedExecutor) handleResultPull(msg *nats.Msg) {
+	var request ResultPullRequest
+	if err := json.Unmarshal(msg.Data, &request); err != nil {
+		log.Printf("Failed to unmarshal result pull: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reply ResultPullReply
+	value, err := e.results.Pull(ctx, request.DAGRunID, request.TaskID, request.Key)
+	switch {
+	case errors.Is(err, results.ErrNotFound):
+		reply.NotFound = true
+	case err != nil:
+		reply.Error = err.Error()
+	default:
+		reply.Value = value
+	}
+
+	data, err := json.Marshal(&reply)
+	if err != nil {
+		log.Printf("Failed to marshal result pull reply: %v", err)
+		return
+	}
+	if int64(len(data)) > e.nc.MaxPayload() {
+		data, _ = json.Marshal(&ResultPullReply{
+			Error: fmt.Sprintf("result %s of task %s is too large to pull over NATS", request.Key, request.TaskID),
+		})
+	}
+
+	if err := msg.Respond(data); err != nil {
+		log.Printf("Failed to reply to result pull: %v", err)
+	}
+}
+
+// handleRedelivery replies to a worker with what to do with a task message JetStream delivered again
+func (e *DistributedExecutor) handleRedelivery(msg *nats.Msg) {
+	var check RedeliveryCheck
+	if err := json.Unmarshal(msg.Data, &check); err != nil {
+		log.Printf("Failed to unmarshal redelivery check: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reply RedeliveryReply
+	taskInstance, err := e.taskRepo.Get(ctx, check.TaskInstanceID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		reply.Action = RedeliveryDrop
+	case err != nil:
+		reply.Error = err.Error()
+	default:
+		reply.Action = redeliveryAction(taskInstance, check.TryNumber, e.config.leaseTimeout())
+	}
+
+	data, _ := json.Marshal(&reply)
+	if err := msg.Respond(data); err != nil {
+		log.Printf("Failed to reply to redelivery check: %v", err)
+	}
+}
+
+// redeliveryAction decides what a worker does with a redelivered message of a task attempt. Messages also
+// expire while they wait behind other tasks, so an attempt only counts as lost once a worker recorded
+// that it started it and that worker's lease expired.
+func redeliveryAction(taskInstance *models.TaskInstance, tryNumber int, leaseTimeout time.Duration) string {
+	switch {
+	case taskInstance.State != models.StateRunning || taskInstance.TryNumber != tryNumber:
+		return RedeliveryDrop
+	case taskInstance.WorkerID == "":
+		// Workers report the tasks they start right away, so none started this one
+		return RedeliveryRun
+	case leaseExpired(taskInstance, leaseTimeout):
+		return RedeliveryLost
+	default:
+		return RedeliveryDrop
+	}
 }
 
 // handleWorkerHeartbeat processes worker heartbeat messages
@@ -484,19 +1058,28 @@ func (e *DistributedExecutor) handleWorkerHeartbeat(msg *nats.Msg) {
 
 	e.workersMu.Lock()
 	e.workers[heartbeat.WorkerID] = &WorkerInfo{
-		ID:           heartbeat.WorkerID,
-		Hostname:     heartbeat.Hostname,
-		LastHeartbeat: heartbeat.Timestamp,
-		ActiveTasks:  heartbeat.ActiveTasks,
+		ID:              heartbeat.WorkerID,
+		Hostname:        heartbeat.Hostname,
+		LastHeartbeat:   heartbeat.Timestamp,
+		ActiveTasks:     heartbeat.ActiveTasks,
+		TaskInstanceIDs: heartbeat.TaskInstanceIDs,
 	}
 	e.workersMu.Unlock()
+
+	// Renew the leases of the worker's tasks and record which worker runs them
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := e.taskRepo.HeartbeatWorkerTasks(ctx, heartbeat.WorkerID, heartbeat.TaskInstanceIDs); err != nil {
+		log.Printf("Failed to record heartbeat of worker %s: %v", heartbeat.WorkerID, err)
+	}
 }
 
-// monitorWorkers monitors worker health and removes dead workers
+// monitorWorkers monitors worker health, removes dead workers and concludes the tasks they were running
 func (e *DistributedExecutor) monitorWorkers(ctx context.Context) {
 	defer e.wg.Done()
 
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(e.config.heartbeatInterval())
 	defer ticker.Stop()
 
 	for {
@@ -504,32 +1087,179 @@ func (e *DistributedExecutor) monitorWorkers(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			var dead []string
 			e.workersMu.Lock()
 			now := time.Now()
 			for id, worker := range e.workers {
-				if now.Sub(worker.LastHeartbeat) > 30*time.Second {
+				if now.Sub(worker.LastHeartbeat) > e.config.leaseTimeout() {
 					log.Printf("Worker %s is dead, removing", id)
 					delete(e.workers, id)
+					dead = append(dead, id)
 				}
 			}
 			e.workersMu.Unlock()
+
+			for _, id := range dead {
+				e.recoverWorkerTasks(ctx, id)
+			}
 		}
 	}
 }
 
+// recoverWorkerTasks fails the current attempt of every task a dead worker was running.
+// Each attempt then goes through the task's retry policy like any other failure, so it is
+// either rescheduled on another worker or marked failed. If the worker's task message is
+// redelivered by JetStream later, the control plane tells the receiving worker to drop it,
+// because the attempt is already concluded.
+func (e *DistributedExecutor) recoverWorkerTasks(ctx context.Context, workerID string) {
+	running := models.StateRunning
+	taskInstances, err := e.taskRepo.List(ctx, storage.TaskInstanceFilters{WorkerID: workerID, State: &running})
+	if err != nil {
+		log.Printf("Failed to list tasks of dead worker %s: %v", workerID, err)
+		return
+	}
+
+	for _, taskInstance := range taskInstances {
+		e.applyLostResult(ctx, taskInstance, workerID, fmt.Sprintf("worker %s stopped sending heartbeats", workerID))
+	}
+}
+
+// applyLostResult concludes an attempt whose worker is gone as failed with ErrorCodeWorkerLost
+func (e *DistributedExecutor) applyLostResult(ctx context.Context, taskInstance *models.TaskInstance, workerID, reason string) {
+	now := time.Now()
+	startTime := now
+	if taskInstance.StartDate != nil {
+		startTime = *taskInstance.StartDate
+	}
+
+	result := &TaskResultMessage{
+		TaskInstanceID: taskInstance.ID,
+		WorkerID:       workerID,
+		State:          string(models.StateFailed),
+		ErrorMessage:   reason,
+		ErrorCode:      ErrorCodeWorkerLost,
+		TryNumber:      taskInstance.TryNumber,
+		StartTime:      startTime,
+		EndTime:        now,
+		Hostname:       taskInstance.Hostname,
+	}
+
+	if err := e.applyResult(ctx, result); err != nil {
+		log.Printf("Failed to conclude lost task instance %s: %v", taskInstance.ID, err)
+	}
+}
+
+// heartbeatInflight renews the leases of task instances published by this process that no worker
+// has picked up yet. Once a worker runs a task, its own heartbeats renew the lease.
+func (e *DistributedExecutor) heartbeatInflight(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.heartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.mu.RLock()
+			running := e.running
+			e.mu.RUnlock()
+			if !running {
+				return
+			}
+
+			claimed := make(map[string]bool)
+			e.workersMu.RLock()
+			for _, worker := range e.workers {
+				for _, id := range worker.TaskInstanceIDs {
+					claimed[id] = true
+				}
+			}
+			e.workersMu.RUnlock()
+
+			e.inflightMu.Lock()
+			taskInstanceIDs := make([]string, 0, len(e.inflight))
+			for id := range e.inflight {
+				if !claimed[id] {
+					taskInstanceIDs = append(taskInstanceIDs, id)
+				}
+			}
+			e.inflightMu.Unlock()
+
+			for _, id := range taskInstanceIDs {
+				if err := e.taskRepo.Heartbeat(ctx, id); err != nil && err != storage.ErrNotFound {
+					log.Printf("Failed to record heartbeat of task instance %s: %v", id, err)
+				}
+			}
+		}
+	}
+}
+
+// cancelRunningTasks asks the workers to interrupt the tasks of a cancelled DAG run that are still running
+func (e *DistributedExecutor) cancelRunningTasks(progress *runProgress) {
+	for taskID, taskInstance := range progress.taskInstances {
+		if progress.completed[taskID] || progress.failed[taskID] {
+			continue
+		}
+
+		if err := e.nc.Publish(TaskCancelSubjectPrefix+taskInstance.ID, nil); err != nil {
+			log.Printf("Failed to publish cancellation of task instance %s: %v", taskInstance.ID, err)
+		}
+	}
+}
+
+// NATSTaskCanceller asks distributed workers to interrupt task instances. Processes that do not run
+// the DistributedExecutor use it to cancel the tasks of DAG runs scheduled elsewhere.
+type NATSTaskCanceller struct {
+	nc *nats.Conn
+}
+
+// NewNATSTaskCanceller creates a task canceller publishing on the given connection
+func NewNATSTaskCanceller(nc *nats.Conn) *NATSTaskCanceller {
+	return &NATSTaskCanceller{nc: nc}
+}
+
+// CancelTask signals the worker running a task instance on tasks.cancel.<task_instance_id>
+func (c *NATSTaskCanceller) CancelTask(ctx context.Context, taskInstanceID string) error {
+	if err := c.nc.Publish(TaskCancelSubjectPrefix+taskInstanceID, nil); err != nil {
+		return fmt.Errorf("failed to publish cancellation of task instance %s: %w", taskInstanceID, err)
+	}
+	return nil
+}
+
+// observeResult records the duration of a task attempt reported by a worker.
+// The DAG of tasks handed out before a restart is looked up from their DAG run.
+func (e *DistributedExecutor) observeResult(ctx context.Context, execution *TaskExecution, taskInstance *models.TaskInstance, result *TaskResultMessage) {
+	var dagID string
+	if execution != nil {
+		dagID = execution.DAGRun.DAGID
+	} else if dagRun, err := e.dagRunRepo.Get(ctx, taskInstance.DAGRunID); err == nil {
+		dagID = dagRun.DAGID
+	} else {
+		return
+	}
+	observeTaskResult(dagID, result.taskResult())
+}
+
 // finalizeDagRun updates the final state of a DAG run
-func (e *DistributedExecutor) finalizeDagRun(ctx context.Context, dagRun *models.DAGRun, failed bool) {
+func (e *DistributedExecutor) finalizeDagRun(ctx context.Context, dagRun *models.DAGRun, finalState models.State) {
 	endTime := time.Now()
 	dagRun.EndDate = &endTime
 
-	finalState := models.StateSuccess
-	if failed {
-		finalState = models.StateFailed
-	}
-
 	if err := e.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateRunning, finalState); err != nil {
 		log.Printf("Failed to update final DAG run state: %v", err)
 	}
+	dagRun.State = finalState
+	observeDAGRun(dagRun, finalState)
 
 	log.Printf("DAG run %s completed with state %s", dagRun.ID, finalState)
+
+	e.mu.RLock()
+	onComplete := e.onComplete
+	e.mu.RUnlock()
+
+	if onComplete != nil {
+		onComplete(dagRun, finalState)
+	}
 }
@@ -0,0 +1,224 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/retry"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestDistributedExecutor_IgnoresSupersededResults(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+
+	e := &DistributedExecutor{
+		taskRepo: taskRepo,
+		config:   DefaultExecutorConfig(),
+		inflight: make(map[string]*TaskExecution),
+	}
+
+	tests := []struct {
+		name     string
+		instance *models.TaskInstance
+		result   TaskResultMessage
+	}{
+		{
+			name:     "earlier attempt",
+			instance: &models.TaskInstance{TaskID: "task1", State: models.StateRunning, TryNumber: 2, MaxTries: 3},
+			result:   TaskResultMessage{State: string(models.StateSuccess), TryNumber: 1},
+		},
+		{
+			name:     "attempt already concluded",
+			instance: &models.TaskInstance{TaskID: "task1", State: models.StateRetrying, TryNumber: 2, MaxTries: 3},
+			result:   TaskResultMessage{State: string(models.StateFailed), ErrorCode: ErrorCodeWorkerLost, TryNumber: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := taskRepo.Create(ctx, tt.instance); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			execution := &TaskExecution{Task: &models.Task{ID: "task1"}, TaskInstance: tt.instance}
+			e.inflight[tt.instance.ID] = execution
+
+			tt.result.TaskInstanceID = tt.instance.ID
+			tt.result.StartTime = time.Now()
+			tt.result.EndTime = time.Now()
+			if err := e.applyResult(ctx, &tt.result); err != nil {
+				t.Fatalf("applyResult failed: %v", err)
+			}
+
+			got, _ := taskRepo.Get(ctx, tt.instance.ID)
+			if got.State != tt.instance.State || got.TryNumber != tt.instance.TryNumber {
+				t.Errorf("Task instance = %s (try %d), want it untouched", got.State, got.TryNumber)
+			}
+			if e.inflight[tt.instance.ID] != execution {
+				t.Error("Superseded result should not release the current attempt")
+			}
+		})
+	}
+}
+
+func TestDistributedExecutor_AppliesResultsOfTasksNotInFlight(t *testing.T) {
+	ctx := context.Background()
+	dagModel := &models.DAG{ID: "dag1", Tasks: []models.Task{{ID: "task1", Type: models.TaskTypeBash}}}
+	dagRunRepo := &statefulDAGRunRepo{run: models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateRunning, DAGVersion: 1}}
+
+	tests := []struct {
+		name          string
+		tryNumber     int
+		wantState     models.State
+		wantTryNumber int
+		wantDLQ       int
+	}{
+		{name: "attempts left", tryNumber: 1, wantState: models.StateRetrying, wantTryNumber: 2},
+		{name: "attempts exhausted", tryNumber: 2, wantState: models.StateFailed, wantTryNumber: 2, wantDLQ: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskRepo := newMemTaskInstanceRepo()
+			queue := dlq.NewMemoryQueue()
+			config := DefaultExecutorConfig()
+			config.RetryStrategy = retry.NewFixedDelay(time.Hour, false)
+
+			// Nothing is in flight, as after a restart of the control plane
+			e := &DistributedExecutor{
+				taskRepo:   taskRepo,
+				dagRunRepo: dagRunRepo,
+				config:     config,
+				inflight:   make(map[string]*TaskExecution),
+			}
+			e.SetDAGRepository(&versionedDAGRepo{dag: dagModel})
+			e.SetDLQ(dlq.NewManager(queue, 0))
+
+			instance := &models.TaskInstance{TaskID: "task1", DAGRunID: "run1", State: models.StateRunning, TryNumber: tt.tryNumber, MaxTries: 2}
+			if err := taskRepo.Create(ctx, instance); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+
+			result := &TaskResultMessage{
+				TaskInstanceID: instance.ID,
+				State:          string(models.StateFailed),
+				ErrorMessage:   "exit status 1",
+				TryNumber:      tt.tryNumber,
+				StartTime:      time.Now(),
+				EndTime:        time.Now(),
+			}
+			if err := e.applyResult(ctx, result); err != nil {
+				t.Fatalf("applyResult failed: %v", err)
+			}
+
+			got, _ := taskRepo.Get(ctx, instance.ID)
+			if got.State != tt.wantState || got.TryNumber != tt.wantTryNumber {
+				t.Errorf("Task instance = %s (try %d), want %s (try %d)", got.State, got.TryNumber, tt.wantState, tt.wantTryNumber)
+			}
+			if entries, _ := queue.List(ctx, nil); len(entries) != tt.wantDLQ {
+				t.Errorf("DLQ has %d entries, want %d", len(entries), tt.wantDLQ)
+			}
+		})
+	}
+}
+
+func TestRedeliveryAction(t *testing.T) {
+	leaseTimeout := time.Minute
+	fresh := time.Now()
+	stale := time.Now().Add(-2 * leaseTimeout)
+
+	tests := []struct {
+		name     string
+		instance *models.TaskInstance
+		try      int
+		want     string
+	}{
+		{
+			// The first of two queued tasks runs for longer than the ack wait on a live worker
+			name:     "long task on a live worker",
+			instance: &models.TaskInstance{State: models.StateRunning, TryNumber: 1, WorkerID: "worker1", LastHeartbeatAt: &fresh},
+			try:      1,
+			want:     RedeliveryDrop,
+		},
+		{
+			// The second task expired while it waited behind the first one, no worker started it
+			name:     "task queued behind a long task",
+			instance: &models.TaskInstance{State: models.StateRunning, TryNumber: 1, LastHeartbeatAt: &stale},
+			try:      1,
+			want:     RedeliveryRun,
+		},
+		{
+			name:     "worker died",
+			instance: &models.TaskInstance{State: models.StateRunning, TryNumber: 1, WorkerID: "worker1", LastHeartbeatAt: &stale},
+			try:      1,
+			want:     RedeliveryLost,
+		},
+		{
+			name:     "attempt already concluded",
+			instance: &models.TaskInstance{State: models.StateRetrying, TryNumber: 2, WorkerID: "worker1", LastHeartbeatAt: &stale},
+			try:      1,
+			want:     RedeliveryDrop,
+		},
+		{
+			name:     "earlier attempt",
+			instance: &models.TaskInstance{State: models.StateRunning, TryNumber: 2, LastHeartbeatAt: &fresh},
+			try:      1,
+			want:     RedeliveryDrop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redeliveryAction(tt.instance, tt.try, leaseTimeout); got != tt.want {
+				t.Errorf("redeliveryAction() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistributedExecutor_RetryOfCancelledRun(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		instanceState models.State
+	}{
+		{name: "waiting for its retry", instanceState: models.StateRetrying},
+		{name: "cancelled by the scheduling loop", instanceState: models.StateCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskRepo := newMemTaskInstanceRepo()
+			dagRunRepo := &statefulDAGRunRepo{run: models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateCancelled}}
+			e := &DistributedExecutor{
+				taskRepo:   taskRepo,
+				dagRunRepo: dagRunRepo,
+				config:     DefaultExecutorConfig(),
+				inflight:   make(map[string]*TaskExecution),
+				active:     newActiveRuns(),
+				running:    true,
+			}
+
+			instance := &models.TaskInstance{TaskID: "task1", DAGRunID: "run1", State: tt.instanceState, TryNumber: 2, MaxTries: 3}
+			if err := taskRepo.Create(ctx, instance); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			e.retryTask(&TaskExecution{
+				Task:         &models.Task{ID: "task1"},
+				TaskInstance: instance,
+				DAGRun:       &dagRunRepo.run,
+				DAG:          &models.DAG{ID: "dag1"},
+			})
+
+			if got, _ := taskRepo.Get(ctx, instance.ID); got.State != models.StateCancelled {
+				t.Errorf("Task instance state = %s, want %s", got.State, models.StateCancelled)
+			}
+			if tt.instanceState == models.StateRetrying && instance.State != models.StateCancelled {
+				t.Errorf("Retried task instance = %s, want %s", instance.State, models.StateCancelled)
+			}
+		})
+	}
+}
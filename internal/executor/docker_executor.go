@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -11,10 +12,19 @@ import (
 	"strings"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
-// DockerTaskExecutor executes tasks in Docker containers
+// dockerDaemonErrorExitCode is the exit code of docker run when the Docker daemon failed to run the
+// container, rather than the command in the container failing
+const dockerDaemonErrorExitCode = 125
+
+// errDockerUnavailable is the failure recorded by the Docker daemon's circuit breaker when it cannot be reached
+var errDockerUnavailable = errors.New("docker is not available")
+
+// DockerTaskExecutor executes tasks in Docker containers. The Docker daemon is called through
+// a circuit breaker, so that tasks fail fast while it keeps failing.
 type DockerTaskExecutor struct {
 	defaultImage  string
 	network       string
@@ -22,6 +32,7 @@ type DockerTaskExecutor struct {
 	removeOnExit  bool
 	memoryLimitMB int64
 	cpuQuota      int
+	breakers      *circuitbreaker.Registry
 }
 
 // DockerTaskConfig represents Docker task configuration
@@ -46,6 +57,7 @@ func NewDockerTaskExecutor(defaultImage string) *DockerTaskExecutor {
 		removeOnExit:  true,
 		memoryLimitMB: 1024,
 		cpuQuota:      100,
+		breakers:      circuitbreaker.NewRegistry(),
 	}
 }
 
@@ -58,9 +70,16 @@ func NewDockerTaskExecutorWithConfig(config *DockerTaskConfig) *DockerTaskExecut
 		removeOnExit:  config.RemoveOnExit,
 		memoryLimitMB: config.MemoryMB,
 		cpuQuota:      config.CPUQuota,
+		breakers:      circuitbreaker.NewRegistry(),
 	}
 }
 
+// SetCircuitBreakers sets the registry holding the circuit breaker of the Docker daemon,
+// which may be shared with other executors
+func (e *DockerTaskExecutor) SetCircuitBreakers(registry *circuitbreaker.Registry) {
+	e.breakers = registry
+}
+
 // Type returns the task type this executor handles
 func (e *DockerTaskExecutor) Type() models.TaskType {
 	// Docker executor can handle Python tasks by running them in containers
@@ -80,14 +99,6 @@ func (e *DockerTaskExecutor) Execute(ctx context.Context, task *models.Task, tas
 
 	log.Printf("Executing Docker task: %s", task.ID)
 
-	// Check if Docker is available
-	if !e.isDockerAvailable() {
-		result.EndTime = time.Now()
-		result.State = models.StateFailed
-		result.ErrorMessage = "Docker is not available or not running"
-		return result
-	}
-
 	// Parse task configuration
 	config, err := e.parseTaskConfig(task)
 	if err != nil {
@@ -97,19 +108,53 @@ func (e *DockerTaskExecutor) Execute(ctx context.Context, task *models.Task, tas
 		return result
 	}
 
+	// Parameters of the DAG run reach the container as DAG_PARAM_<name>, unless the task sets them itself
+	config.Env = addParamEnv(config.Env, ParamsFromContext(ctx))
+
 	// Build docker run command
 	args := e.buildDockerCommand(config, task.ID)
 
 	// Execute Docker command
 	cmd := exec.CommandContext(ctx, "docker", args...)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Killing the docker client leaves the container running, so stop it as well
+	cmd.Cancel = func() error {
+		e.stopContainer(task.ID)
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = processWaitDelay
 
-	log.Printf("Running Docker command: docker %s", strings.Join(args, " "))
+	var stdout, stderr bytes.Buffer
+	var flushLines func()
+	cmd.Stdout, cmd.Stderr, flushLines = streamOutput(ctx, &stdout, &stderr)
+
+	// Check if Docker is available and run the container, both through the circuit breaker of the Docker daemon
+	name, breaker := taskCircuitBreaker(e.breakers, "docker", task)
+	available := true
+	called := callThrough(ctx, breaker, func() error {
+		if !e.isDockerAvailable() {
+			available = false
+			return errDockerUnavailable
+		}
 
-	err = cmd.Run()
+		log.Printf("Running Docker command: docker %s", strings.Join(args, " "))
+		err = cmd.Run()
+		if exitCode(err) == dockerDaemonErrorExitCode {
+			return err
+		}
+		return nil
+	})
+	flushLines()
+	if !called {
+		log.Printf("Docker task %s not run, circuit breaker %s rejected it", task.ID, name)
+		return circuitOpenResult(result, name, breaker)
+	}
+	if !available {
+		result.EndTime = time.Now()
+		result.State = models.StateFailed
+		result.ErrorMessage = "Docker is not available or not running"
+		return result
+	}
 	result.EndTime = time.Now()
 
 	// Combine output
@@ -122,6 +167,7 @@ func (e *DockerTaskExecutor) Execute(ctx context.Context, task *models.Task, tas
 	if err != nil {
 		result.State = models.StateFailed
 		result.ErrorMessage = fmt.Sprintf("Docker execution failed: %v\nOutput: %s", err, output)
+		result.ExitCode = exitCode(err)
 		log.Printf("Docker task %s failed: %v", task.ID, err)
 	} else {
 		log.Printf("Docker task %s completed successfully", task.ID)
@@ -131,8 +177,7 @@ func (e *DockerTaskExecutor) Execute(ctx context.Context, task *models.Task, tas
 	if ctx.Err() != nil {
 		result.State = models.StateFailed
 		result.ErrorMessage = fmt.Sprintf("Task timed out: %v", ctx.Err())
-		// Try to stop the container
-		e.stopContainer(task.ID)
+		result.ErrorCode = ErrorCodeTimeout
 	}
 
 	return result
@@ -2,8 +2,12 @@ package executor
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/dag/internal/retry"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
@@ -20,8 +24,23 @@ type Executor interface {
 
 	// GetStatus returns the current status of the executor
 	GetStatus() ExecutorStatus
+
+	// OnDAGRunComplete sets a callback invoked when a DAG run reaches a terminal state
+	OnDAGRunComplete(callback DAGRunCompleteFunc)
+
+	// Resume continues scheduling a running DAG run whose previous owner stopped,
+	// starting from the task instances it already persisted
+	Resume(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG, taskInstances []*models.TaskInstance) error
+
+	// Cancel stops a DAG run scheduled by this executor: running tasks are interrupted,
+	// tasks that have not started are cancelled and the run ends in the cancelled state.
+	// It returns ErrDAGRunNotActive if the executor is not scheduling the DAG run.
+	Cancel(ctx context.Context, dagRunID string) error
 }
 
+// DAGRunCompleteFunc is called with the DAG run and its final state once all of its tasks are done
+type DAGRunCompleteFunc func(dagRun *models.DAGRun, finalState models.State)
+
 // TaskExecutor executes individual tasks
 type TaskExecutor interface {
 	// Execute runs a single task and returns the result
@@ -31,11 +50,34 @@ type TaskExecutor interface {
 	Type() models.TaskType
 }
 
+const (
+	// ErrorCodeTimeout is the error code reported when a task exceeds its timeout
+	ErrorCodeTimeout = "timeout"
+	// ErrorCodeWorkerLost is the error code reported when the distributed worker running a task died
+	ErrorCodeWorkerLost = "worker_lost"
+	// ErrorCodeCancelled is the error code reported when a task was interrupted because its DAG run was cancelled
+	ErrorCodeCancelled = "cancelled"
+	// ErrorCodeCircuitOpen is the error code reported when a task failed fast because the circuit breaker
+	// guarding one of its external dependencies is open
+	ErrorCodeCircuitOpen = "circuit_open"
+	// ErrorCodeInvalidBranch is the error code reported when a branch task named a task it cannot follow
+	ErrorCodeInvalidBranch = "invalid_branch"
+	// ErrorCodeInvalidResult is the error code reported when the results a task pushed could not be extracted or stored
+	ErrorCodeInvalidResult = "invalid_result"
+	// ErrorCodeInvalidTemplate is the error code reported when a task's command template could not be rendered
+	ErrorCodeInvalidTemplate = "invalid_template"
+)
+
 // TaskResult represents the result of a task execution
 type TaskResult struct {
 	State        models.State
 	Output       string
 	ErrorMessage string
+	ExitCode     int           // Exit code of the task's process, if it ran one
+	ErrorCode    string        // Machine-readable failure reason such as "timeout" or "http_503"
+	RetryAfter   time.Duration // Minimum delay before a failed attempt is retried, such as until an open circuit breaker lets requests through
+	Branches     []string      // Downstream tasks a succeeded branch task follows
+	Results      map[string]json.RawMessage // Values the task pushed for downstream tasks, by key
 	StartTime    time.Time
 	EndTime      time.Time
 	Hostname     string
@@ -60,6 +102,18 @@ type ExecutorConfig struct {
 	EnableDocker     bool
 	MaxMemoryMB      int64
 	MaxCPUPercent    int
+	LogSink          *LogSinkConfig
+	RetryStrategy    retry.Strategy
+	// CompletionResyncInterval is how often running DAG runs re-read task states from
+	// the repository in case a completion signal was lost
+	CompletionResyncInterval time.Duration
+	// HeartbeatInterval is how often running DAG runs and tasks renew their lease
+	HeartbeatInterval time.Duration
+	// LeaseTimeout is how long a DAG run or task may go without a heartbeat before
+	// its owner is presumed dead and the work is recovered
+	LeaseTimeout time.Duration
+	// TemplateEnv names the environment variables task command templates may read through .env
+	TemplateEnv []string
 }
 
 // DefaultExecutorConfig returns default configuration
@@ -72,5 +126,20 @@ func DefaultExecutorConfig() *ExecutorConfig {
 		EnableDocker:    false,
 		MaxMemoryMB:     1024,
 		MaxCPUPercent:   100,
+		LogSink:         DefaultLogSinkConfig(),
+		RetryStrategy:   retry.DefaultExponentialBackoff(),
+
+		CompletionResyncInterval: defaultCompletionResyncInterval,
+		HeartbeatInterval:        defaultHeartbeatInterval,
+		LeaseTimeout:             defaultLeaseTimeout,
+	}
+}
+
+// exitCode returns the exit code of a command that failed with err, or 0 if it did not exit on its own
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
 	}
+	return 0
 }
@@ -14,16 +14,21 @@ import (
 // GoTaskFunc is a function type for Go task execution
 type GoTaskFunc func(ctx context.Context) error
 
+// GoBranchFunc is a function type for Go branch tasks, returning the downstream task IDs to follow
+type GoBranchFunc func(ctx context.Context) ([]string, error)
+
 // GoFuncTaskExecutor executes Go functions
 type GoFuncTaskExecutor struct {
-	functions map[string]GoTaskFunc
-	mu        sync.RWMutex
+	functions       map[string]GoTaskFunc
+	branchFunctions map[string]GoBranchFunc
+	mu              sync.RWMutex
 }
 
 // NewGoFuncTaskExecutor creates a new Go function task executor
 func NewGoFuncTaskExecutor() *GoFuncTaskExecutor {
 	return &GoFuncTaskExecutor{
-		functions: make(map[string]GoTaskFunc),
+		functions:       make(map[string]GoTaskFunc),
+		branchFunctions: make(map[string]GoBranchFunc),
 	}
 }
 
@@ -34,6 +39,13 @@ func (e *GoFuncTaskExecutor) RegisterFunction(taskID string, fn GoTaskFunc) {
 	e.functions[taskID] = fn
 }
 
+// RegisterBranchFunction registers a Go function that can be executed by branch tasks by task ID
+func (e *GoFuncTaskExecutor) RegisterBranchFunction(taskID string, fn GoBranchFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.branchFunctions[taskID] = fn
+}
+
 // Type returns the task type this executor handles
 func (e *GoFuncTaskExecutor) Type() models.TaskType {
 	return models.TaskTypeGo
@@ -53,34 +65,25 @@ func (e *GoFuncTaskExecutor) Execute(ctx context.Context, task *models.Task, tas
 	log.Printf("Executing Go function task: %s", task.ID)
 
 	// Get the function
-	e.mu.RLock()
-	fn, exists := e.functions[task.ID]
-	e.mu.RUnlock()
-
+	fn, exists := e.lookup(task)
 	if !exists {
-		// Try using command as function name
-		e.mu.RLock()
-		fn, exists = e.functions[task.Command]
-		e.mu.RUnlock()
-
-		if !exists {
-			result.EndTime = time.Now()
-			result.State = models.StateFailed
-			result.ErrorMessage = fmt.Sprintf("No function registered for task ID: %s or command: %s", task.ID, task.Command)
-			log.Printf("Go function task %s failed: no function registered", task.ID)
-			return result
-		}
+		result.EndTime = time.Now()
+		result.State = models.StateFailed
+		result.ErrorMessage = fmt.Sprintf("No function registered for task ID: %s or command: %s", task.ID, task.Command)
+		log.Printf("Go function task %s failed: no function registered", task.ID)
+		return result
 	}
 
 	// Execute the function with panic recovery
 	var err error
+	var branches []string
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
 				err = fmt.Errorf("panic occurred: %v", r)
 			}
 		}()
-		err = fn(ctx)
+		branches, err = fn(ctx)
 	}()
 
 	result.EndTime = time.Now()
@@ -93,7 +96,32 @@ func (e *GoFuncTaskExecutor) Execute(ctx context.Context, task *models.Task, tas
 	} else {
 		result.Output = "Function executed successfully"
 		log.Printf("Go function task %s completed successfully", task.ID)
+		if task.Branch != nil {
+			setBranches(task, result, branches)
+		}
 	}
 
 	return result
 }
+
+// lookup returns the function registered for a task by task ID, or by its command as function name.
+// Branch tasks run branch functions only.
+func (e *GoFuncTaskExecutor) lookup(task *models.Task) (GoBranchFunc, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, name := range []string{task.ID, task.Command} {
+		if task.Branch != nil {
+			if fn, exists := e.branchFunctions[name]; exists {
+				return fn, true
+			}
+			continue
+		}
+		if fn, exists := e.functions[name]; exists {
+			return func(ctx context.Context) ([]string, error) {
+				return nil, fn(ctx)
+			}, true
+		}
+	}
+	return nil, false
+}
@@ -133,6 +133,41 @@ func TestGoFuncTaskExecutor_Execute_NoFunction(t *testing.T) {
 	}
 }
 
+func TestGoFuncTaskExecutor_Execute_Branch(t *testing.T) {
+	executor := NewGoFuncTaskExecutor()
+	executor.RegisterBranchFunction("choose", func(ctx context.Context) ([]string, error) {
+		return []string{"incremental"}, nil
+	})
+	executor.RegisterFunction("plain", func(ctx context.Context) error {
+		return nil
+	})
+
+	task := &models.Task{
+		ID:     "choose",
+		Type:   models.TaskTypeGo,
+		Branch: &models.BranchConfig{Targets: []string{"full", "incremental"}},
+	}
+	result := executor.Execute(context.Background(), task, &models.TaskInstance{ID: "test-instance", TaskID: "choose"})
+	if result.State != models.StateSuccess {
+		t.Fatalf("Expected state Success, got %s. Error: %s", result.State, result.ErrorMessage)
+	}
+	if len(result.Branches) != 1 || result.Branches[0] != "incremental" {
+		t.Errorf("Expected branches [incremental], got %v", result.Branches)
+	}
+
+	// Branch tasks only run branch functions
+	task = &models.Task{
+		ID:      "choose-plain",
+		Type:    models.TaskTypeGo,
+		Command: "plain",
+		Branch:  &models.BranchConfig{Targets: []string{"full"}},
+	}
+	result = executor.Execute(context.Background(), task, &models.TaskInstance{ID: "test-instance", TaskID: "choose-plain"})
+	if result.State != models.StateFailed {
+		t.Errorf("Expected branch task without a branch function to fail, got %s", result.State)
+	}
+}
+
 func TestGoFuncTaskExecutor_Type(t *testing.T) {
 	executor := NewGoFuncTaskExecutor()
 
@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+const (
+	// defaultHeartbeatInterval is used when ExecutorConfig.HeartbeatInterval is unset
+	defaultHeartbeatInterval = 10 * time.Second
+	// defaultLeaseTimeout is used when ExecutorConfig.LeaseTimeout is unset
+	defaultLeaseTimeout = time.Minute
+)
+
+// heartbeatInterval returns how often running DAG runs and tasks renew their lease
+func (c *ExecutorConfig) heartbeatInterval() time.Duration {
+	if c.HeartbeatInterval <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return c.HeartbeatInterval
+}
+
+// leaseTimeout returns how long a DAG run or task may go without a heartbeat before it is considered orphaned
+func (c *ExecutorConfig) leaseTimeout() time.Duration {
+	if c.LeaseTimeout <= 0 {
+		return defaultLeaseTimeout
+	}
+	return c.LeaseTimeout
+}
+
+// startHeartbeat calls beat every interval until ctx is done or the returned stop function is called
+func startHeartbeat(ctx context.Context, interval time.Duration, name string, beat func(ctx context.Context) error) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := beat(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("Failed to record heartbeat of %s: %v", name, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// leaseExpired returns true if a running task instance has not reported a heartbeat within leaseTimeout
+func leaseExpired(taskInstance *models.TaskInstance, leaseTimeout time.Duration) bool {
+	if taskInstance.State != models.StateRunning {
+		return false
+	}
+	if taskInstance.LastHeartbeatAt == nil {
+		return true
+	}
+	return time.Since(*taskInstance.LastHeartbeatAt) > leaseTimeout
+}
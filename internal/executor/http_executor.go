@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,13 +13,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
-// HTTPTaskExecutor executes HTTP requests
+// HTTPTaskExecutor executes HTTP requests. Requests to each host go through a circuit breaker,
+// so that tasks fail fast while the host keeps failing.
 type HTTPTaskExecutor struct {
-	client  *http.Client
-	timeout time.Duration
+	client   *http.Client
+	timeout  time.Duration
+	breakers *circuitbreaker.Registry
 }
 
 // HTTPTaskConfig represents HTTP task configuration
@@ -38,10 +42,17 @@ func NewHTTPTaskExecutor(timeout time.Duration) *HTTPTaskExecutor {
 		client: &http.Client{
 			Timeout: timeout,
 		},
-		timeout: timeout,
+		timeout:  timeout,
+		breakers: circuitbreaker.NewRegistry(),
 	}
 }
 
+// SetCircuitBreakers sets the registry holding the circuit breakers of the target hosts,
+// which may be shared with other executors
+func (e *HTTPTaskExecutor) SetCircuitBreakers(registry *circuitbreaker.Registry) {
+	e.breakers = registry
+}
+
 // Type returns the task type this executor handles
 func (e *HTTPTaskExecutor) Type() models.TaskType {
 	return models.TaskTypeHTTP
@@ -93,13 +104,25 @@ func (e *HTTPTaskExecutor) Execute(ctx context.Context, task *models.Task, taskI
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	// Execute request
-	resp, err := e.client.Do(req)
+	// Execute request through the circuit breaker of the target host
+	name, breaker := taskCircuitBreaker(e.breakers, "http:"+req.URL.Host, task)
+	var resp *http.Response
+	called := callThrough(ctx, breaker, func() error {
+		resp, err = e.client.Do(req)
+		return hostFailure(ctx, resp, err)
+	})
+	if !called {
+		log.Printf("HTTP task %s not run, circuit breaker %s rejected it", task.ID, name)
+		return circuitOpenResult(result, name, breaker)
+	}
 	result.EndTime = time.Now()
 
 	if err != nil {
 		result.State = models.StateFailed
 		result.ErrorMessage = fmt.Sprintf("HTTP request failed: %v", err)
+		if ctx.Err() != nil {
+			result.ErrorCode = ErrorCodeTimeout
+		}
 		log.Printf("HTTP task %s failed: %v", task.ID, err)
 		return result
 	}
@@ -119,14 +142,48 @@ func (e *HTTPTaskExecutor) Execute(ctx context.Context, task *models.Task, taskI
 	if resp.StatusCode >= 400 {
 		result.State = models.StateFailed
 		result.ErrorMessage = fmt.Sprintf("HTTP request returned error status: %d", resp.StatusCode)
+		result.ErrorCode = fmt.Sprintf("http_%d", resp.StatusCode)
 		log.Printf("HTTP task %s failed with status %d", task.ID, resp.StatusCode)
 	} else {
 		log.Printf("HTTP task %s completed successfully with status %d", task.ID, resp.StatusCode)
+		if len(task.ResultPaths) > 0 {
+			// Tasks push fields of the response body as results
+			values, err := pathResults(body, task.ResultPaths)
+			if err != nil {
+				resultFailed(result, fmt.Sprintf("Failed to read results: %v", err))
+				return result
+			}
+			result.Results = values
+		}
+		if task.Branch != nil {
+			// Branch tasks name the downstream tasks to follow in a field of the response body
+			branches, err := fieldBranches(body, task.Branch.Field)
+			if err != nil {
+				branchFailed(result, fmt.Sprintf("Failed to read branches: %v", err))
+			} else {
+				setBranches(task, result, branches)
+			}
+		}
 	}
 
 	return result
 }
 
+// hostFailure returns an error if a request failed because of the host: it could not be reached,
+// did not respond in time or responded with a server error. A cancelled task is not the host's failure.
+func hostFailure(ctx context.Context, resp *http.Response, err error) error {
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil
+		}
+		return err
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("server error status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // parseCommand parses the command string into HTTP configuration
 // Format: METHOD URL [BODY]
 func (e *HTTPTaskExecutor) parseCommand(command string) (*HTTPTaskConfig, error) {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -117,6 +119,162 @@ func TestHTTPTaskExecutor_Execute_Error_Status(t *testing.T) {
 	if !strings.Contains(result.ErrorMessage, "404") {
 		t.Errorf("Expected error message to contain 404, got: %s", result.ErrorMessage)
 	}
+
+	if result.ErrorCode != "http_404" {
+		t.Errorf("Expected error code http_404, got: %s", result.ErrorCode)
+	}
+}
+
+func TestHTTPTaskExecutor_CircuitBreaker(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	executor := NewHTTPTaskExecutor(10 * time.Second)
+	task := &models.Task{
+		ID:             "test-task",
+		Type:           models.TaskTypeHTTP,
+		Command:        "GET " + server.URL,
+		CircuitBreaker: &models.CircuitBreakerConfig{MaxFailures: 2, Timeout: time.Minute},
+	}
+	taskInstance := &models.TaskInstance{ID: "test-instance", TaskID: "test-task"}
+
+	// Server errors open the breaker of the host
+	for i := 0; i < 2; i++ {
+		result := executor.Execute(context.Background(), task, taskInstance)
+		if result.ErrorCode != "http_503" {
+			t.Errorf("Expected error code http_503, got %q", result.ErrorCode)
+		}
+	}
+
+	// Tasks then fail fast without calling the host until the breaker's timeout elapsed
+	result := executor.Execute(context.Background(), task, taskInstance)
+	if result.State != models.StateFailed || result.ErrorCode != ErrorCodeCircuitOpen {
+		t.Errorf("Expected a failure with error code %s, got %s with %q", ErrorCodeCircuitOpen, result.State, result.ErrorCode)
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Minute {
+		t.Errorf("Expected a retry within the breaker timeout, got %v", result.RetryAfter)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected the host to be called twice, got %d", requests.Load())
+	}
+
+	// Tasks that disable their circuit breakers still call the host
+	task.CircuitBreaker = &models.CircuitBreakerConfig{Disabled: true}
+	if result := executor.Execute(context.Background(), task, taskInstance); result.ErrorCode != "http_503" {
+		t.Errorf("Expected error code http_503 without a circuit breaker, got %q", result.ErrorCode)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("Expected the host to be called three times, got %d", requests.Load())
+	}
+}
+
+func TestHTTPTaskExecutor_Execute_Branch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"branches": ["full"], "result": {"mode": "incremental,audit"}, "count": 2}`))
+	}))
+	defer server.Close()
+
+	executor := NewHTTPTaskExecutor(10 * time.Second)
+	targets := []string{"full", "incremental", "audit"}
+
+	tests := []struct {
+		name      string
+		field     string
+		wantState models.State
+		want      []string
+	}{
+		{"default field", "", models.StateSuccess, []string{"full"}},
+		{"nested field", "result.mode", models.StateSuccess, []string{"incremental", "audit"}},
+		{"missing field", "result.missing", models.StateFailed, nil},
+		{"field of the wrong type", "count", models.StateFailed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{
+				ID:      "choose",
+				Type:    models.TaskTypeHTTP,
+				Command: "GET " + server.URL,
+				Branch:  &models.BranchConfig{Targets: targets, Field: tt.field},
+			}
+			result := executor.Execute(context.Background(), task, &models.TaskInstance{ID: "test-instance", TaskID: "choose"})
+
+			if result.State != tt.wantState {
+				t.Fatalf("Expected state %s, got %s. Error: %s", tt.wantState, result.State, result.ErrorMessage)
+			}
+			if tt.wantState == models.StateFailed {
+				if result.ErrorCode != ErrorCodeInvalidBranch {
+					t.Errorf("Expected error code %s, got %s", ErrorCodeInvalidBranch, result.ErrorCode)
+				}
+				return
+			}
+			if !reflect.DeepEqual(result.Branches, tt.want) {
+				t.Errorf("Expected branches %v, got %v", tt.want, result.Branches)
+			}
+		})
+	}
+}
+
+func TestHTTPTaskExecutor_Execute_ResultPaths(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"job": {"id": "job-7", "rows": 12345678901234567890}, "ok": true}`))
+	}))
+	defer server.Close()
+
+	executor := NewHTTPTaskExecutor(10 * time.Second)
+
+	tests := []struct {
+		name      string
+		paths     map[string]string
+		wantState models.State
+		want      map[string]string
+	}{
+		{
+			"fields",
+			map[string]string{"job_id": "job.id", "rows": "job.rows", "ok": "ok"},
+			models.StateSuccess,
+			map[string]string{"job_id": `"job-7"`, "rows": `12345678901234567890`, "ok": `true`},
+		},
+		{
+			"whole body",
+			map[string]string{"response": "."},
+			models.StateSuccess,
+			map[string]string{"response": `{"job":{"id":"job-7","rows":12345678901234567890},"ok":true}`},
+		},
+		{"missing field", map[string]string{"job_id": "job.missing"}, models.StateFailed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{
+				ID:          "submit",
+				Type:        models.TaskTypeHTTP,
+				Command:     "GET " + server.URL,
+				ResultPaths: tt.paths,
+			}
+			result := executor.Execute(context.Background(), task, &models.TaskInstance{ID: "test-instance", TaskID: "submit"})
+
+			if result.State != tt.wantState {
+				t.Fatalf("Expected state %s, got %s. Error: %s", tt.wantState, result.State, result.ErrorMessage)
+			}
+			if tt.wantState == models.StateFailed {
+				if result.ErrorCode != ErrorCodeInvalidResult {
+					t.Errorf("Expected error code %s, got %s", ErrorCodeInvalidResult, result.ErrorCode)
+				}
+				return
+			}
+			if len(result.Results) != len(tt.want) {
+				t.Fatalf("Expected results %v, got %v", tt.want, result.Results)
+			}
+			for key, value := range tt.want {
+				if string(result.Results[key]) != value {
+					t.Errorf("Expected result %s = %s, got %s", key, value, result.Results[key])
+				}
+			}
+		})
+	}
 }
 
 func TestHTTPTaskExecutor_ParseCommand(t *testing.T) {
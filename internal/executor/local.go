@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
 	"github.com/therealutkarshpriyadarshi/dag/internal/state"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
@@ -25,15 +28,25 @@ type TaskExecution struct {
 type LocalExecutor struct {
 	taskRepo      storage.TaskInstanceRepository
 	dagRunRepo    storage.DAGRunRepository
-	stateMachine  *state.Machine
+	stateMachine  *state.StateMachine
 	taskExecutors map[models.TaskType]TaskExecutor
 	config        *ExecutorConfig
+	onComplete    DAGRunCompleteFunc
+	taskLogRepo   storage.TaskLogRepository
+	dlq           *dlq.Manager
+	results       *results.Manager
+	renderer      *render.Renderer
+	completions   *completionRouter
+	active        *activeRuns
 
 	taskQueue chan *TaskExecution
-	workers   []*worker
-	running   bool
-	mu        sync.RWMutex
-	wg        sync.WaitGroup
+	stopChan  chan struct{}
+	// retryMu keeps retry timers from sending on the task queue after it is closed
+	retryMu sync.RWMutex
+	workers []*worker
+	running bool
+	mu      sync.RWMutex
+	wg      sync.WaitGroup
 
 	status ExecutorStatus
 }
@@ -49,7 +62,7 @@ type worker struct {
 func NewLocalExecutor(
 	taskRepo storage.TaskInstanceRepository,
 	dagRunRepo storage.DAGRunRepository,
-	stateMachine *state.Machine,
+	stateMachine *state.StateMachine,
 	config *ExecutorConfig,
 ) *LocalExecutor {
 	if config == nil {
@@ -62,16 +75,20 @@ func NewLocalExecutor(
 		stateMachine:  stateMachine,
 		taskExecutors: make(map[models.TaskType]TaskExecutor),
 		config:        config,
+		renderer:      render.NewRenderer(config.TemplateEnv),
+		completions:   newCompletionRouter(),
+		active:        newActiveRuns(),
 		taskQueue:     make(chan *TaskExecution, config.QueueSize),
+		stopChan:      make(chan struct{}),
 		workers:       make([]*worker, 0, config.WorkerCount),
 		running:       false,
 		status: ExecutorStatus{
-			Running:       false,
-			ActiveTasks:   0,
+			Running:        false,
+			ActiveTasks:    0,
 			CompletedTasks: 0,
-			FailedTasks:   0,
-			WorkerCount:   config.WorkerCount,
-			QueueDepth:    0,
+			FailedTasks:    0,
+			WorkerCount:    config.WorkerCount,
+			QueueDepth:     0,
 		},
 	}
 }
@@ -83,6 +100,27 @@ func (e *LocalExecutor) RegisterTaskExecutor(executor TaskExecutor) {
 	e.taskExecutors[executor.Type()] = executor
 }
 
+// SetTaskLogRepository enables streaming task output into the task log repository
+func (e *LocalExecutor) SetTaskLogRepository(repo storage.TaskLogRepository) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.taskLogRepo = repo
+}
+
+// SetDLQ sets the dead letter queue that receives tasks whose attempts are exhausted
+func (e *LocalExecutor) SetDLQ(manager *dlq.Manager) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dlq = manager
+}
+
+// SetResults sets the result manager that stores the values tasks push and serves the values they pull
+func (e *LocalExecutor) SetResults(manager *results.Manager) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results = manager
+}
+
 // Start initializes the executor and starts worker goroutines
 func (e *LocalExecutor) Start(ctx context.Context) error {
 	e.mu.Lock()
@@ -123,7 +161,8 @@ func (e *LocalExecutor) Stop(ctx context.Context) error {
 
 	log.Println("Stopping local executor...")
 
-	// Stop all workers
+	// Stop all workers and pending retries
+	close(e.stopChan)
 	for _, w := range e.workers {
 		close(w.stopChan)
 	}
@@ -142,7 +181,9 @@ func (e *LocalExecutor) Stop(ctx context.Context) error {
 		log.Println("Shutdown timeout reached, forcing stop")
 	}
 
+	e.retryMu.Lock()
 	close(e.taskQueue)
+	e.retryMu.Unlock()
 
 	e.mu.Lock()
 	e.status.Running = false
@@ -162,8 +203,15 @@ func (e *LocalExecutor) GetStatus() ExecutorStatus {
 	return status
 }
 
+// OnDAGRunComplete sets a callback invoked when a DAG run reaches a terminal state
+func (e *LocalExecutor) OnDAGRunComplete(callback DAGRunCompleteFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onComplete = callback
+}
+
 // Execute submits a DAG run for execution
-func (e *LocalExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG) error {
+func (e *LocalExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dagModel *models.DAG) error {
 	if !e.running {
 		return fmt.Errorf("executor is not running")
 	}
@@ -174,13 +222,11 @@ func (e *LocalExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag
 	if err := e.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateQueued, models.StateRunning); err != nil {
 		return fmt.Errorf("failed to update DAG run state: %w", err)
 	}
-
-	// Build dependency graph
-	graph := dag.NewGraph(dag)
+	dagRun.State = models.StateRunning
 
 	// Create task instances for all tasks
 	taskInstances := make(map[string]*models.TaskInstance)
-	for _, task := range dag.Tasks {
+	for _, task := range dagModel.Tasks {
 		taskInstance := &models.TaskInstance{
 			TaskID:    task.ID,
 			DAGRunID:  dagRun.ID,
@@ -189,131 +235,153 @@ func (e *LocalExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag
 			MaxTries:  task.Retries + 1,
 		}
 
-		created, err := e.taskRepo.Create(ctx, taskInstance)
-		if err != nil {
+		if err := e.taskRepo.Create(ctx, taskInstance); err != nil {
 			return fmt.Errorf("failed to create task instance for %s: %w", task.ID, err)
 		}
-		taskInstances[task.ID] = created
+		taskInstances[task.ID] = taskInstance
 	}
 
 	// Start a goroutine to manage task scheduling for this DAG run
-	go e.scheduleTasks(ctx, dagRun, dag, graph, taskInstances)
+	completions := e.completions.register(dagRun.ID, len(dagModel.Tasks))
+	cancelled := e.active.add(dagRun.ID).Done()
+	go e.scheduleTasks(ctx, dagRun, dagModel, newRunProgress(taskInstances), completions, cancelled)
 
 	return nil
 }
 
-// scheduleTasks manages the scheduling of tasks for a DAG run
+// Resume continues scheduling a running DAG run from its persisted task instances.
+// Tasks left running by the previous owner are retried or failed once their lease expires.
+func (e *LocalExecutor) Resume(ctx context.Context, dagRun *models.DAGRun, dagModel *models.DAG, taskInstances []*models.TaskInstance) error {
+	if !e.running {
+		return fmt.Errorf("executor is not running")
+	}
+
+	progress, err := resumeRunProgress(ctx, e.taskRepo, dagRun, dagModel, taskInstances)
+	if err != nil {
+		return fmt.Errorf("failed to resume DAG run %s: %w", dagRun.ID, err)
+	}
+	dagRun.State = models.StateRunning
+
+	completions := e.completions.register(dagRun.ID, len(dagModel.Tasks))
+	cancelled := e.active.add(dagRun.ID).Done()
+	go e.scheduleTasks(ctx, dagRun, dagModel, progress, completions, cancelled)
+
+	log.Printf("Resumed DAG run %s", dagRun.ID)
+	return nil
+}
+
+// Cancel interrupts the running tasks of a DAG run and cancels the tasks that have not started.
+// The DAG run ends in the cancelled state once its running tasks stopped.
+func (e *LocalExecutor) Cancel(ctx context.Context, dagRunID string) error {
+	if !e.active.cancel(dagRunID) {
+		return ErrDAGRunNotActive
+	}
+
+	log.Printf("Cancelling DAG run %s", dagRunID)
+	return nil
+}
+
+// scheduleTasks manages the scheduling of tasks for a DAG run.
+// It submits every task whose trigger rule lets it run and wakes up again whenever a worker reports a completion.
 func (e *LocalExecutor) scheduleTasks(
 	ctx context.Context,
 	dagRun *models.DAGRun,
-	dag *models.DAG,
-	graph *dag.Graph,
-	taskInstances map[string]*models.TaskInstance,
+	dagModel *models.DAG,
+	progress *runProgress,
+	completions <-chan TaskCompletion,
+	cancelled <-chan struct{},
 ) {
-	completedTasks := make(map[string]bool)
-	failedTasks := make(map[string]bool)
-	submittedTasks := make(map[string]bool)
+	defer e.completions.unregister(dagRun.ID, completions)
+	defer e.active.remove(dagRun.ID, cancelled)
+
+	// Renew the lease on the DAG run so that recovery leaves it alone while this process schedules it
+	stopHeartbeat := startHeartbeat(ctx, e.config.heartbeatInterval(), "DAG run "+dagRun.ID, func(ctx context.Context) error {
+		return e.dagRunRepo.Heartbeat(ctx, dagRun.ID)
+	})
+	defer stopHeartbeat()
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	// Completion signals are not durable, so task states are re-read from the repository occasionally
+	resync := time.NewTicker(e.config.completionResyncInterval())
+	defer resync.Stop()
 
+	// Resumed runs may have tasks left running by a dead process
+	progress.resync(ctx, e.taskRepo, e.config.leaseTimeout())
+
+	graph := dag.NewGraph(dagModel)
 	for {
+		// Find tasks ready to execute; trigger rules may decide that others will not run
+		readyTasks, notRun := progress.next(graph)
+		progress.skip(ctx, e.taskRepo, notRun)
+
+		for _, task := range readyTasks {
+			// Submit task for execution
+			execution := &TaskExecution{
+				Task:         task,
+				TaskInstance: progress.taskInstances[task.ID],
+				DAGRun:       dagRun,
+				DAG:          dagModel,
+			}
+
+			select {
+			case e.taskQueue <- execution:
+				progress.submitted[task.ID] = true
+				log.Printf("Submitted task %s for execution", task.ID)
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Check if all tasks are done
+		if progress.done() {
+			// Update final DAG run state
+			e.finalizeDagRun(ctx, dagRun, progress.finalState())
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			log.Printf("DAG run %s scheduling cancelled", dagRun.ID)
 			return
-		case <-ticker.C:
-			// Check if all tasks are done
-			if len(completedTasks)+len(failedTasks) == len(dag.Tasks) {
-				// Update final DAG run state
-				e.finalizeDagRun(ctx, dagRun, len(failedTasks) > 0)
-				return
+		case <-cancelled:
+			// Running tasks see the cancellation through their context and report completion
+			cancelled = nil
+			progress.cancel(ctx, e.taskRepo)
+		case completion := <-completions:
+			progress.record(completion)
+		case <-resync.C:
+			if cancelled != nil && cancelledElsewhere(ctx, e.dagRunRepo, dagRun.ID) {
+				// Handled like a cancellation through this executor once the loop wakes up again
+				e.active.cancel(dagRun.ID)
 			}
-
-			// Find tasks ready to execute
-			readyTasks := graph.GetParallelTasks(completedTasks)
-
-			for _, taskID := range readyTasks {
-				if submittedTasks[taskID] || failedTasks[taskID] {
-					continue
-				}
-
-				task := graph.GetTask(taskID)
-				if task == nil {
-					continue
-				}
-
-				// Check if upstream tasks failed
-				upstreamFailed := false
-				for _, depID := range task.Dependencies {
-					if failedTasks[depID] {
-						upstreamFailed = true
-						break
-					}
-				}
-
-				taskInstance := taskInstances[taskID]
-
-				if upstreamFailed {
-					// Mark as upstream failed
-					e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateQueued, models.StateUpstreamFailed)
-					failedTasks[taskID] = true
-					continue
-				}
-
-				// Submit task for execution
-				execution := &TaskExecution{
-					Task:         task,
-					TaskInstance: taskInstance,
-					DAGRun:       dagRun,
-					DAG:          dag,
-				}
-
-				select {
-				case e.taskQueue <- execution:
-					submittedTasks[taskID] = true
-					log.Printf("Submitted task %s for execution", taskID)
-				case <-ctx.Done():
-					return
-				}
-			}
-
-			// Update completed/failed tasks status
-			for taskID := range submittedTasks {
-				if completedTasks[taskID] || failedTasks[taskID] {
-					continue
-				}
-
-				taskInstance, err := e.taskRepo.GetByID(ctx, taskInstances[taskID].ID)
-				if err != nil {
-					continue
-				}
-
-				if taskInstance.State == models.StateSuccess {
-					completedTasks[taskID] = true
-				} else if taskInstance.State.IsTerminal() {
-					failedTasks[taskID] = true
-				}
+			progress.resync(ctx, e.taskRepo, e.config.leaseTimeout())
+			if progress.cancelled {
+				// Reaped tasks must not be handed out again
+				progress.cancel(ctx, e.taskRepo)
 			}
 		}
 	}
 }
 
 // finalizeDagRun updates the final state of a DAG run
-func (e *LocalExecutor) finalizeDagRun(ctx context.Context, dagRun *models.DAGRun, failed bool) {
+func (e *LocalExecutor) finalizeDagRun(ctx context.Context, dagRun *models.DAGRun, finalState models.State) {
 	endTime := time.Now()
 	dagRun.EndDate = &endTime
 
-	finalState := models.StateSuccess
-	if failed {
-		finalState = models.StateFailed
-	}
-
 	if err := e.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateRunning, finalState); err != nil {
 		log.Printf("Failed to update final DAG run state: %v", err)
 	}
+	dagRun.State = finalState
+	observeDAGRun(dagRun, finalState)
 
 	log.Printf("DAG run %s completed with state %s", dagRun.ID, finalState)
+
+	e.mu.RLock()
+	onComplete := e.onComplete
+	e.mu.RUnlock()
+
+	if onComplete != nil {
+		onComplete(dagRun, finalState)
+	}
 }
 
 // worker.run executes tasks from the queue
@@ -344,56 +412,162 @@ func (w *worker) executeTask(ctx context.Context, execution *TaskExecution) {
 
 	w.executor.mu.Lock()
 	executor, ok := w.executor.taskExecutors[execution.Task.Type]
+	taskLogRepo := w.executor.taskLogRepo
+	dlqManager := w.executor.dlq
+	resultManager := w.executor.results
 	w.executor.mu.Unlock()
 
+	// Retried attempts start from retrying rather than queued
+	fromState := execution.TaskInstance.State
+	if fromState == "" {
+		fromState = models.StateQueued
+	}
+
 	if !ok {
-		log.Printf("No executor registered for task type %s", execution.Task.Type)
-		w.executor.taskRepo.UpdateState(ctx, execution.TaskInstance.ID, models.StateQueued, models.StateFailed)
+		errorMessage := fmt.Sprintf("no executor registered for task type %s", execution.Task.Type)
+		log.Printf("Failed to execute task %s: %s", execution.Task.ID, errorMessage)
+		if err := w.executor.taskRepo.UpdateState(ctx, execution.TaskInstance.ID, fromState, models.StateFailed); err != nil {
+			log.Printf("Failed to update task state: %v", err)
+			w.notifyUnstarted(ctx, execution)
+			return
+		}
+		execution.TaskInstance.State = models.StateFailed
+		execution.TaskInstance.ErrorMessage = errorMessage
+		if err := w.executor.taskRepo.Update(ctx, execution.TaskInstance); err != nil {
+			log.Printf("Failed to record error of task %s: %v", execution.Task.ID, err)
+		}
+		sendToDLQ(ctx, dlqManager, execution.TaskInstance, execution.DAG, errorMessage)
+		w.executor.notifyCompletion(execution, models.StateFailed)
 		return
 	}
 
 	// Update task state to running
-	if err := w.executor.taskRepo.UpdateState(ctx, execution.TaskInstance.ID, models.StateQueued, models.StateRunning); err != nil {
+	if err := w.executor.taskRepo.UpdateState(ctx, execution.TaskInstance.ID, fromState, models.StateRunning); err != nil {
 		log.Printf("Failed to update task state to running: %v", err)
+		w.notifyUnstarted(ctx, execution)
 		return
 	}
+	execution.TaskInstance.State = models.StateRunning
 
 	w.executor.mu.Lock()
 	w.executor.status.ActiveTasks++
 	w.executor.mu.Unlock()
 
-	// Execute with timeout
-	taskCtx := ctx
+	// Execute with timeout, interrupting the task if its DAG run is cancelled
+	taskCtx, releaseTask := w.executor.active.taskContext(ctx, execution.DAGRun.ID)
+	defer releaseTask()
 	if execution.Task.Timeout > 0 {
 		var cancel context.CancelFunc
-		taskCtx, cancel = context.WithTimeout(ctx, execution.Task.Timeout)
+		taskCtx, cancel = context.WithTimeout(taskCtx, execution.Task.Timeout)
 		defer cancel()
 	}
 
-	// Execute the task
-	result := executor.Execute(taskCtx, execution.Task, execution.TaskInstance)
+	// Renew the task's lease while it runs so that it is not reaped as a zombie
+	taskInstanceID := execution.TaskInstance.ID
+	stopHeartbeat := startHeartbeat(ctx, w.executor.config.heartbeatInterval(), "task instance "+taskInstanceID, func(ctx context.Context) error {
+		return w.executor.taskRepo.Heartbeat(ctx, taskInstanceID)
+	})
+
+	// Execute the task, streaming its output to the task logs
+	taskCtx, closeLogs := attachLogSink(taskCtx, taskLogRepo, taskInstanceID, w.executor.config.LogSink)
+	taskCtx, taskResults := attachResults(taskCtx, resultManager, execution.TaskInstance)
+	taskCtx = WithParams(taskCtx, runParams(execution.DAGRun, execution.DAG))
+	var result *TaskResult
+	task, err := renderCommand(ctx, w.executor.renderer, w.executor.taskRepo, resultManager, taskWithDAGDefaults(execution.DAG, execution.Task), execution.TaskInstance, execution.DAGRun, execution.DAG)
+	if err != nil {
+		result = templateFailed(err)
+	} else {
+		result = executor.Execute(taskCtx, task, execution.TaskInstance)
+	}
+	closeLogs()
+	stopHeartbeat()
+	markCancelled(taskCtx, result)
+	taskResults.collect(result)
+	storeResults(ctx, resultManager, execution.TaskInstance, result)
+	observeTaskResult(execution.DAGRun.DAGID, result)
 
 	w.executor.mu.Lock()
 	w.executor.status.ActiveTasks--
-	if result.State == models.StateSuccess {
-		w.executor.status.CompletedTasks++
-	} else {
-		w.executor.status.FailedTasks++
-	}
 	w.executor.mu.Unlock()
 
 	// Update task instance with result
-	execution.TaskInstance.State = result.State
 	execution.TaskInstance.StartDate = &result.StartTime
 	execution.TaskInstance.EndDate = &result.EndTime
 	execution.TaskInstance.Duration = result.EndTime.Sub(result.StartTime)
 	execution.TaskInstance.Hostname = result.Hostname
 	execution.TaskInstance.ErrorMessage = result.ErrorMessage
 
+	// Failed attempts are re-queued after the retry delay without holding this worker
+	retryConfig := taskRetryConfig(execution.Task, execution.TaskInstance, w.executor.config.retryStrategy())
+	if shouldRetry(retryConfig, execution.TaskInstance, result) {
+		delay, err := markRetrying(ctx, w.executor.taskRepo, execution.TaskInstance, retryConfig.Strategy, result.RetryAfter)
+		if err == nil {
+			w.executor.requeueAfter(ctx, execution, delay)
+			return
+		}
+		log.Printf("Failed to schedule retry of task %s: %v", execution.Task.ID, err)
+	}
+
+	w.executor.mu.Lock()
+	if result.State == models.StateSuccess {
+		w.executor.status.CompletedTasks++
+	} else {
+		w.executor.status.FailedTasks++
+	}
+	w.executor.mu.Unlock()
+
 	// Update state in database
+	if err := recordBranches(ctx, w.executor.taskRepo, execution.TaskInstance, result); err != nil {
+		log.Printf("Failed to record branches of task %s: %v", execution.Task.ID, err)
+	}
+	execution.TaskInstance.State = result.State
 	if err := w.executor.taskRepo.UpdateState(ctx, execution.TaskInstance.ID, models.StateRunning, result.State); err != nil {
 		log.Printf("Failed to update task state: %v", err)
 	}
 
+	if result.State == models.StateFailed {
+		sendToDLQ(ctx, dlqManager, execution.TaskInstance, execution.DAG, result.ErrorMessage)
+	}
+
+	w.executor.notifyCompletion(execution, result.State)
+
 	log.Printf("Worker %d completed task %s with state %s", w.id, execution.Task.ID, result.State)
 }
+
+// notifyUnstarted reports a task whose state could not be updated before it ran, so that the scheduling
+// loop of its DAG run does not wait for it. The task counts as failed unless it already finished, e.g.
+// because its DAG run was cancelled meanwhile.
+func (w *worker) notifyUnstarted(ctx context.Context, execution *TaskExecution) {
+	state := models.StateFailed
+	if current, err := w.executor.taskRepo.Get(ctx, execution.TaskInstance.ID); err == nil && current.State.IsTerminal() {
+		state = current.State
+	}
+	execution.TaskInstance.State = state
+	w.executor.notifyCompletion(execution, state)
+}
+
+// notifyCompletion wakes the scheduling loop of the task's DAG run
+func (e *LocalExecutor) notifyCompletion(execution *TaskExecution, state models.State) {
+	e.completions.notify(TaskCompletion{
+		DAGRunID:       execution.DAGRun.ID,
+		TaskID:         execution.Task.ID,
+		TaskInstanceID: execution.TaskInstance.ID,
+		State:          state,
+		Branches:       execution.TaskInstance.Branches,
+	})
+}
+
+// requeueAfter puts a task back on the queue once the retry delay has elapsed
+func (e *LocalExecutor) requeueAfter(ctx context.Context, execution *TaskExecution, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		e.retryMu.RLock()
+		defer e.retryMu.RUnlock()
+
+		select {
+		case <-e.stopChan:
+			log.Printf("Executor stopped, task %s left in retrying state", execution.Task.ID)
+		case <-ctx.Done():
+		case e.taskQueue <- execution:
+		}
+	})
+}
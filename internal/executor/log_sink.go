@@ -0,0 +1,295 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+)
+
+// LogStream identifies the output stream a log line was written to
+type LogStream string
+
+const (
+	LogStreamStdout LogStream = "stdout"
+	LogStreamStderr LogStream = "stderr"
+)
+
+// maxLogLineBytes bounds a single line so output without newlines is still emitted
+const maxLogLineBytes = 64 * 1024
+
+// LogLine is a single line of task output
+type LogLine struct {
+	Stream     LogStream `json:"stream"`
+	LineNumber int       `json:"line_number"`
+	Text       string    `json:"text"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// LogSink receives task output line by line while a task runs
+type LogSink interface {
+	WriteLine(stream LogStream, text string)
+}
+
+type logSinkKey struct{}
+
+// WithLogSink returns a context that carries a log sink for task executors
+func WithLogSink(ctx context.Context, sink LogSink) context.Context {
+	return context.WithValue(ctx, logSinkKey{}, sink)
+}
+
+// LogSinkFromContext returns the log sink carried by ctx, or nil if there is none
+func LogSinkFromContext(ctx context.Context) LogSink {
+	sink, _ := ctx.Value(logSinkKey{}).(LogSink)
+	return sink
+}
+
+// LogSinkConfig controls how task output is batched and capped
+type LogSinkConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxBytes      int64
+}
+
+// DefaultLogSinkConfig returns default log sink configuration
+func DefaultLogSinkConfig() *LogSinkConfig {
+	return &LogSinkConfig{
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		MaxBytes:      10 * 1024 * 1024,
+	}
+}
+
+// LogFlushFunc persists or forwards a batch of log lines
+type LogFlushFunc func(lines []LogLine) error
+
+// BatchLogSink numbers log lines and hands them to a flush function in batches.
+// Once MaxBytes of output have been written, a truncation marker is emitted and further lines are dropped.
+type BatchLogSink struct {
+	flush  LogFlushFunc
+	config *LogSinkConfig
+
+	mu         sync.Mutex
+	pending    []LogLine
+	lineNumber int
+	written    int64
+	truncated  bool
+	closed     bool
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewBatchLogSink creates a log sink that flushes when a batch fills up or the flush interval elapses
+func NewBatchLogSink(flush LogFlushFunc, config *LogSinkConfig) *BatchLogSink {
+	if config == nil {
+		config = DefaultLogSinkConfig()
+	}
+
+	s := &BatchLogSink{
+		flush:    flush,
+		config:   config,
+		stopChan: make(chan struct{}),
+	}
+
+	if config.FlushInterval > 0 {
+		s.wg.Add(1)
+		go s.flushPeriodically()
+	}
+
+	return s
+}
+
+// NewStorageLogSink creates a log sink that persists lines of a task instance to the task log repository
+func NewStorageLogSink(repo storage.TaskLogRepository, taskInstanceID string, config *LogSinkConfig) *BatchLogSink {
+	return NewBatchLogSink(func(lines []LogLine) error {
+		// Logs are still persisted after the task context is cancelled or times out
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return repo.CreateBatch(ctx, taskInstanceID, toTaskLogModels(lines))
+	}, config)
+}
+
+// WriteLine records a line of output
+func (s *BatchLogSink) WriteLine(stream LogStream, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.truncated {
+		return
+	}
+
+	if s.config.MaxBytes > 0 && s.written+int64(len(text)) > s.config.MaxBytes {
+		s.truncated = true
+		s.appendLine(stream, fmt.Sprintf("[log truncated: output exceeded %d bytes]", s.config.MaxBytes))
+	} else {
+		s.written += int64(len(text))
+		s.appendLine(stream, text)
+	}
+
+	if len(s.pending) >= s.config.BatchSize || s.truncated {
+		s.flushLocked()
+	}
+}
+
+// Close flushes any pending lines and stops the flush loop
+func (s *BatchLogSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// Truncated returns true if output was dropped because the size cap was reached
+func (s *BatchLogSink) Truncated() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.truncated
+}
+
+func (s *BatchLogSink) appendLine(stream LogStream, text string) {
+	s.lineNumber++
+	s.pending = append(s.pending, LogLine{
+		Stream:     stream,
+		LineNumber: s.lineNumber,
+		Text:       text,
+		Timestamp:  time.Now().UTC(),
+	})
+}
+
+// flushLocked hands pending lines to the flush function; the caller must hold s.mu
+func (s *BatchLogSink) flushLocked() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	lines := s.pending
+	s.pending = nil
+
+	if err := s.flush(lines); err != nil {
+		log.Printf("Failed to flush %d log lines: %v", len(lines), err)
+		return err
+	}
+	return nil
+}
+
+func (s *BatchLogSink) flushPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flushLocked()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// toTaskLogModels converts log lines to task log database models
+func toTaskLogModels(lines []LogLine) []storage.TaskLogModel {
+	logs := make([]storage.TaskLogModel, len(lines))
+	for i, line := range lines {
+		logs[i] = storage.TaskLogModel{
+			Stream:     string(line.Stream),
+			LineNumber: line.LineNumber,
+			LogData:    line.Text,
+			Timestamp:  line.Timestamp,
+		}
+	}
+	return logs
+}
+
+// lineWriter is an io.Writer that splits output into lines and forwards them to a log sink
+type lineWriter struct {
+	sink   LogSink
+	stream LogStream
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func newLineWriter(sink LogSink, stream LogStream) *lineWriter {
+	return &lineWriter{sink: sink, stream: stream}
+}
+
+// Write forwards every complete line in p and buffers the remainder
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.sink.WriteLine(w.stream, string(bytes.TrimSuffix(w.buf[:i], []byte("\r"))))
+		w.buf = w.buf[i+1:]
+	}
+
+	if len(w.buf) >= maxLogLineBytes {
+		w.sink.WriteLine(w.stream, string(w.buf))
+		w.buf = nil
+	}
+
+	return len(p), nil
+}
+
+// Flush forwards a trailing line that was not terminated by a newline
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.sink.WriteLine(w.stream, string(w.buf))
+		w.buf = nil
+	}
+}
+
+// streamOutput tees command output into the log sink carried by ctx, if there is one.
+// The returned function forwards partial trailing lines and must be called once the command has exited.
+func streamOutput(ctx context.Context, stdout, stderr io.Writer) (io.Writer, io.Writer, func()) {
+	sink := LogSinkFromContext(ctx)
+	if sink == nil {
+		return stdout, stderr, func() {}
+	}
+
+	stdoutLines := newLineWriter(sink, LogStreamStdout)
+	stderrLines := newLineWriter(sink, LogStreamStderr)
+
+	return io.MultiWriter(stdout, stdoutLines), io.MultiWriter(stderr, stderrLines), func() {
+		stdoutLines.Flush()
+		stderrLines.Flush()
+	}
+}
+
+// attachLogSink attaches a sink persisting the output of a task instance to ctx when repo is set.
+// The returned function flushes the remaining lines and must be called when the task has finished.
+func attachLogSink(ctx context.Context, repo storage.TaskLogRepository, taskInstanceID string, config *LogSinkConfig) (context.Context, func()) {
+	if repo == nil {
+		return ctx, func() {}
+	}
+
+	sink := NewStorageLogSink(repo, taskInstanceID, config)
+	return WithLogSink(ctx, sink), func() { sink.Close() }
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingFlush collects every batch handed to a flush function
+type recordingFlush struct {
+	mu      sync.Mutex
+	batches [][]LogLine
+}
+
+func (r *recordingFlush) flush(lines []LogLine) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, lines)
+	return nil
+}
+
+func (r *recordingFlush) lines() []LogLine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []LogLine
+	for _, batch := range r.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func TestBatchLogSink_FlushesInBatches(t *testing.T) {
+	recorder := &recordingFlush{}
+	sink := NewBatchLogSink(recorder.flush, &LogSinkConfig{BatchSize: 2})
+
+	sink.WriteLine(LogStreamStdout, "one")
+	sink.WriteLine(LogStreamStderr, "two")
+	sink.WriteLine(LogStreamStdout, "three")
+
+	if len(recorder.batches) != 1 || len(recorder.batches[0]) != 2 {
+		t.Fatalf("Expected one full batch before close, got %v", recorder.batches)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := recorder.lines()
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if line.LineNumber != i+1 {
+			t.Errorf("Line %d has number %d", i, line.LineNumber)
+		}
+	}
+	if lines[1].Stream != LogStreamStderr || lines[1].Text != "two" {
+		t.Errorf("Unexpected second line: %+v", lines[1])
+	}
+
+	// Lines written after close are dropped
+	sink.WriteLine(LogStreamStdout, "late")
+	if len(recorder.lines()) != 3 {
+		t.Error("Expected lines written after close to be dropped")
+	}
+}
+
+func TestBatchLogSink_FlushesOnInterval(t *testing.T) {
+	recorder := &recordingFlush{}
+	sink := NewBatchLogSink(recorder.flush, &LogSinkConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer sink.Close()
+
+	sink.WriteLine(LogStreamStdout, "hello")
+
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.lines()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Pending line was not flushed by the flush loop")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatchLogSink_TruncatesAtMaxBytes(t *testing.T) {
+	recorder := &recordingFlush{}
+	sink := NewBatchLogSink(recorder.flush, &LogSinkConfig{BatchSize: 100, MaxBytes: 10})
+
+	sink.WriteLine(LogStreamStdout, "12345")
+	sink.WriteLine(LogStreamStdout, "67890")
+	sink.WriteLine(LogStreamStdout, "overflow")
+	sink.WriteLine(LogStreamStdout, "dropped")
+	sink.Close()
+
+	lines := recorder.lines()
+	if len(lines) != 3 {
+		t.Fatalf("Expected 2 lines and a truncation marker, got %+v", lines)
+	}
+	if !strings.Contains(lines[2].Text, "truncated") {
+		t.Errorf("Expected truncation marker, got %q", lines[2].Text)
+	}
+	if !sink.Truncated() {
+		t.Error("Expected sink to report truncation")
+	}
+}
+
+func TestLineWriter_SplitsLines(t *testing.T) {
+	recorder := &recordingFlush{}
+	sink := NewBatchLogSink(recorder.flush, &LogSinkConfig{BatchSize: 100})
+
+	w := newLineWriter(sink, LogStreamStdout)
+	w.Write([]byte("first\r\nsec"))
+	w.Write([]byte("ond\nthird"))
+	w.Flush()
+	sink.Close()
+
+	lines := recorder.lines()
+	want := []string{"first", "second", "third"}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %+v", len(want), lines)
+	}
+	for i, text := range want {
+		if lines[i].Text != text {
+			t.Errorf("Line %d = %q, want %q", i, lines[i].Text, text)
+		}
+	}
+}
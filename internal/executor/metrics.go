@@ -0,0 +1,123 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/therealutkarshpriyadarshi/dag/internal/metrics"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// statusCollector exports the ExecutorStatus of an executor, and for a distributed executor
+// the heartbeat age of its workers and the tasks waiting in NATS
+type statusCollector struct {
+	executor Executor
+
+	running     *prometheus.Desc
+	activeTasks *prometheus.Desc
+	completed   *prometheus.Desc
+	failed      *prometheus.Desc
+	workers     *prometheus.Desc
+	queueDepth  *prometheus.Desc
+	heartbeat   *prometheus.Desc
+	natsPending *prometheus.Desc
+}
+
+// RegisterMetrics exports the status of an executor, labelled with its kind (local, sequential or distributed)
+func RegisterMetrics(kind string, e Executor) {
+	labels := prometheus.Labels{"executor": kind}
+	desc := func(name, help string, variableLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "executor", name), help, variableLabels, labels)
+	}
+
+	metrics.Register(&statusCollector{
+		executor:    e,
+		running:     desc("running", "Whether the executor is running."),
+		activeTasks: desc("active_tasks", "Tasks being executed."),
+		completed:   desc("completed_tasks_total", "Tasks that completed successfully since the executor started."),
+		failed:      desc("failed_tasks_total", "Tasks that failed since the executor started."),
+		workers:     desc("workers", "Workers available to the executor."),
+		queueDepth:  desc("queue_depth", "Tasks waiting to be executed."),
+		heartbeat:   desc("worker_heartbeat_age_seconds", "Time since the last heartbeat of a worker.", "worker_id"),
+		natsPending: desc("nats_pending_tasks", "Tasks in NATS not yet delivered to or acknowledged by a worker."),
+	})
+}
+
+func (c *statusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.running
+	ch <- c.activeTasks
+	ch <- c.completed
+	ch <- c.failed
+	ch <- c.workers
+	ch <- c.queueDepth
+	ch <- c.heartbeat
+	ch <- c.natsPending
+}
+
+func (c *statusCollector) Collect(ch chan<- prometheus.Metric) {
+	status := c.executor.GetStatus()
+
+	running := 0.0
+	if status.Running {
+		running = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, running)
+	ch <- prometheus.MustNewConstMetric(c.activeTasks, prometheus.GaugeValue, float64(status.ActiveTasks))
+	ch <- prometheus.MustNewConstMetric(c.completed, prometheus.CounterValue, float64(status.CompletedTasks))
+	ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(status.FailedTasks))
+	ch <- prometheus.MustNewConstMetric(c.workers, prometheus.GaugeValue, float64(status.WorkerCount))
+	ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(status.QueueDepth))
+
+	distributed, ok := c.executor.(*DistributedExecutor)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	distributed.workersMu.RLock()
+	for id, worker := range distributed.workers {
+		ch <- prometheus.MustNewConstMetric(c.heartbeat, prometheus.GaugeValue, now.Sub(worker.LastHeartbeat).Seconds(), id)
+	}
+	distributed.workersMu.RUnlock()
+
+	if pending, err := natsPending(distributed.js); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.natsPending, prometheus.GaugeValue, float64(pending))
+	}
+}
+
+// natsPending returns the number of pending tasks not yet delivered to or acknowledged by a worker
+func natsPending(js nats.JetStreamContext) (int, error) {
+	info, err := js.ConsumerInfo(TasksPendingStream, "workers")
+	if err != nil {
+		return 0, err
+	}
+	return int(info.NumPending) + info.NumAckPending, nil
+}
+
+// RegisterMetrics exports the tasks the worker is running and the tasks waiting in NATS
+func (w *Worker) RegisterMetrics() {
+	metrics.RegisterGauge("worker_active_tasks", "Tasks being executed by this worker.", func() float64 {
+		return float64(w.GetActiveTasks())
+	})
+	metrics.RegisterGauge("worker_nats_pending_tasks", "Tasks in NATS not yet delivered to or acknowledged by a worker.", func() float64 {
+		pending, err := natsPending(w.js)
+		if err != nil {
+			return 0
+		}
+		return float64(pending)
+	})
+}
+
+// observeDAGRun records the duration of a DAG run that reached its final state
+func observeDAGRun(dagRun *models.DAGRun, finalState models.State) {
+	if dagRun.StartDate == nil || dagRun.EndDate == nil {
+		return
+	}
+	metrics.ObserveDAGRun(dagRun.DAGID, finalState, dagRun.EndDate.Sub(*dagRun.StartDate))
+}
+
+// observeTaskResult records the duration of a finished task attempt
+func observeTaskResult(dagID string, result *TaskResult) {
+	metrics.ObserveTaskInstance(dagID, result.State, result.EndTime.Sub(result.StartTime))
+}
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// ParamEnvPrefix prefixes the environment variables through which Docker tasks read the parameters of their DAG run
+const ParamEnvPrefix = "DAG_PARAM_"
+
+type paramsKey struct{}
+
+// WithParams returns a context that carries the parameters of the DAG run a task belongs to
+func WithParams(ctx context.Context, params map[string]interface{}) context.Context {
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// ParamsFromContext returns the parameters of the DAG run of a running task by name, or nil outside of
+// a task run by an executor. Go tasks read the values their run was triggered with this way.
+func ParamsFromContext(ctx context.Context) map[string]interface{} {
+	params, _ := ctx.Value(paramsKey{}).(map[string]interface{})
+	return params
+}
+
+// runParams returns the parameters of a DAG run by name: the values of its conf and the defaults of the others
+func runParams(dagRun *models.DAGRun, dagModel *models.DAG) map[string]interface{} {
+	if dagModel == nil {
+		return nil
+	}
+	var conf map[string]interface{}
+	if dagRun != nil {
+		conf = dagRun.Conf
+	}
+	return dagModel.ParamValues(conf)
+}
+
+// addParamEnv adds environment variables exposing parameters to Docker tasks as DAG_PARAM_<name> to env,
+// which may be nil, keeping the variables it already sets. Strings are passed as is and other values as
+// JSON; parameters without a value are left unset.
+func addParamEnv(env map[string]string, params map[string]interface{}) map[string]string {
+	for name, value := range params {
+		key := ParamEnvPrefix + name
+		if _, ok := env[key]; ok || value == nil {
+			continue
+		}
+
+		var text string
+		if s, ok := value.(string); ok {
+			text = s
+		} else {
+			data, err := json.Marshal(value)
+			if err != nil {
+				log.Printf("Failed to encode parameter %s: %v", name, err)
+				continue
+			}
+			text = string(data)
+		}
+
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env[key] = text
+	}
+	return env
+}
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestSequentialExecutor_RunParams(t *testing.T) {
+	var seen map[string]interface{}
+	goExecutor := NewGoFuncTaskExecutor()
+	goExecutor.RegisterFunction("extract", func(ctx context.Context) error {
+		seen = ParamsFromContext(ctx)
+		return nil
+	})
+
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(goExecutor)
+	exec.RegisterTaskExecutor(NewBashTaskExecutor())
+
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Params: []models.Param{
+			{Name: "table", Default: "events"},
+			{Name: "limit", Default: 100},
+		},
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeGo, Command: "extract"},
+			{ID: "load", Type: models.TaskTypeBash, Command: "echo {{ .params.table }} {{ .params.limit }}", Dependencies: []string{"extract"}},
+		},
+	}
+	dagRun := &models.DAGRun{
+		ID:            "run1",
+		DAGID:         "dag1",
+		State:         models.StateQueued,
+		ExecutionDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		Conf:          map[string]interface{}{"table": "users"},
+	}
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// The conf overrides defaults, which fill in the parameters it leaves out
+	if seen["table"] != "users" || seen["limit"] != 100 {
+		t.Errorf("Params seen by the Go task = %v, want table users and limit 100", seen)
+	}
+	instances, _ := taskRepo.ListByDAGRun(context.Background(), "run1")
+	for _, instance := range instances {
+		if instance.TaskID == "load" && instance.RenderedCommand != "echo users 100" {
+			t.Errorf("Rendered command of load = %q, want %q", instance.RenderedCommand, "echo users 100")
+		}
+	}
+}
+
+func TestAddParamEnv(t *testing.T) {
+	params := map[string]interface{}{
+		"table":  "events",
+		"limit":  100,
+		"tables": []interface{}{"a", "b"},
+		"mode":   "full",
+		"unset":  nil,
+	}
+	env := addParamEnv(map[string]string{"DAG_PARAM_mode": "incremental"}, params)
+
+	want := map[string]string{
+		"DAG_PARAM_table":  "events",
+		"DAG_PARAM_limit":  "100",
+		"DAG_PARAM_tables": `["a","b"]`,
+		"DAG_PARAM_mode":   "incremental",
+	}
+	if len(env) != len(want) {
+		t.Errorf("Env = %v, want %v", env, want)
+	}
+	for key, value := range want {
+		if env[key] != value {
+			t.Errorf("Env %s = %q, want %q", key, env[key], value)
+		}
+	}
+
+	if env := addParamEnv(nil, nil); env != nil {
+		t.Errorf("Env without parameters = %v, want none", env)
+	}
+}
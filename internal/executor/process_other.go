@@ -0,0 +1,9 @@
+//go:build !unix
+
+package executor
+
+import "os/exec"
+
+// killProcessGroupOnCancel is a no-op on platforms without process groups;
+// cancelling the context of cmd only kills the command itself
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,239 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// errLeaseExpired is recorded on task instances whose executor stopped sending heartbeats
+const errLeaseExpired = "task heartbeat lost, the executor running it is presumed dead"
+
+// runProgress tracks which tasks of a DAG run have been handed out and how they finished
+type runProgress struct {
+	taskInstances map[string]*models.TaskInstance // Task ID -> task instance
+	states        map[string]models.State         // Task ID -> final state of finished tasks, for trigger rules
+	branches      map[string][]string             // Task ID -> downstream tasks followed by finished branch tasks
+	completed     map[string]bool                 // Finished tasks that succeeded or were skipped
+	failed        map[string]bool
+	submitted     map[string]bool
+	cancelled     bool // The DAG run was cancelled
+}
+
+// newRunProgress returns the progress of a DAG run whose task instances were just created
+func newRunProgress(taskInstances map[string]*models.TaskInstance) *runProgress {
+	return &runProgress{
+		taskInstances: taskInstances,
+		states:        make(map[string]models.State),
+		branches:      make(map[string][]string),
+		completed:     make(map[string]bool),
+		failed:        make(map[string]bool),
+		submitted:     make(map[string]bool),
+	}
+}
+
+// resumeRunProgress rebuilds the progress of a DAG run from its persisted task instances.
+// Task instances that are missing, e.g. because the previous owner crashed while creating them, are created.
+// Running task instances count as submitted; the next resync decides whether they are still alive.
+func resumeRunProgress(
+	ctx context.Context,
+	taskRepo storage.TaskInstanceRepository,
+	dagRun *models.DAGRun,
+	dagModel *models.DAG,
+	taskInstances []*models.TaskInstance,
+) (*runProgress, error) {
+	byTaskID := make(map[string]*models.TaskInstance, len(taskInstances))
+	for _, taskInstance := range taskInstances {
+		byTaskID[taskInstance.TaskID] = taskInstance
+	}
+
+	progress := newRunProgress(make(map[string]*models.TaskInstance, len(dagModel.Tasks)))
+	for _, task := range dagModel.Tasks {
+		taskInstance, ok := byTaskID[task.ID]
+		if !ok {
+			taskInstance = &models.TaskInstance{
+				TaskID:    task.ID,
+				DAGRunID:  dagRun.ID,
+				State:     models.StateQueued,
+				TryNumber: 1,
+				MaxTries:  task.Retries + 1,
+			}
+			if err := taskRepo.Create(ctx, taskInstance); err != nil {
+				return nil, fmt.Errorf("failed to create task instance for %s: %w", task.ID, err)
+			}
+		}
+		progress.taskInstances[task.ID] = taskInstance
+
+		switch taskInstance.State {
+		case models.StateRunning:
+			progress.submitted[task.ID] = true
+		case models.StateQueued, models.StateRetrying, models.StateScheduled, models.StateUpForReschedule:
+			// Not handed to an executor yet, or waiting for its next attempt
+		default:
+			progress.record(TaskCompletion{TaskID: task.ID, State: taskInstance.State, Branches: taskInstance.Branches})
+		}
+	}
+
+	return progress, nil
+}
+
+// record marks a task as finished once it reached its final state, along with the downstream tasks it follows
+func (p *runProgress) record(completion TaskCompletion) {
+	if completion.State.IsTerminal() {
+		if completion.Branches != nil {
+			p.branches[completion.TaskID] = completion.Branches
+		}
+		p.finish(completion.TaskID, completion.State)
+	}
+}
+
+// finish records the final state of a task. Skipped tasks count as completed, since they did not fail.
+func (p *runProgress) finish(taskID string, state models.State) {
+	p.states[taskID] = state
+	if state == models.StateSuccess || state == models.StateSkipped {
+		p.completed[taskID] = true
+	} else {
+		p.failed[taskID] = true
+	}
+}
+
+// next returns the tasks of a DAG run that have not been handed out and whose trigger rule lets them run,
+// and the state each task that will not run ends in: skipped if a branch task did not follow it, or
+// whatever its trigger rule decided
+func (p *runProgress) next(graph *dag.Graph) (ready []*models.Task, notRun map[string]models.State) {
+	states := p.states
+	unfollowed := p.unfollowed(graph)
+	if len(unfollowed) > 0 {
+		states = make(map[string]models.State, len(p.states)+len(unfollowed))
+		for taskID, state := range p.states {
+			states[taskID] = state
+		}
+		for _, taskID := range unfollowed {
+			states[taskID] = models.StateSkipped
+		}
+	}
+
+	readyIDs, notRun := graph.GetReadyTasks(states)
+	for _, taskID := range unfollowed {
+		notRun[taskID] = models.StateSkipped
+	}
+	for _, taskID := range readyIDs {
+		if p.submitted[taskID] {
+			continue
+		}
+		task, err := graph.GetTask(taskID)
+		if err != nil {
+			continue
+		}
+		ready = append(ready, task)
+	}
+	return ready, notRun
+}
+
+// unfollowed returns the tasks that have not been handed out and that succeeded branch tasks did not follow,
+// see dag.Graph.GetBranchSkipSet. Branch tasks that did not record a decision follow all of their targets.
+func (p *runProgress) unfollowed(graph *dag.Graph) []string {
+	var unfollowed []string
+	for taskID, branches := range p.branches {
+		if p.states[taskID] != models.StateSuccess {
+			continue
+		}
+		skipped, err := graph.GetBranchSkipSet(taskID, branches)
+		if err != nil {
+			continue
+		}
+		for _, skippedID := range skipped {
+			if _, finished := p.states[skippedID]; !finished && !p.submitted[skippedID] {
+				unfollowed = append(unfollowed, skippedID)
+			}
+		}
+	}
+	return unfollowed
+}
+
+// skip moves the tasks that will not run, see next, to their final state
+func (p *runProgress) skip(ctx context.Context, taskRepo storage.TaskInstanceRepository, notRun map[string]models.State) {
+	for taskID, state := range notRun {
+		if err := taskRepo.UpdateState(ctx, p.taskInstances[taskID].ID, models.StateQueued, state); err != nil {
+			log.Printf("Failed to update task %s state to %s: %v", taskID, state, err)
+		}
+		p.finish(taskID, state)
+	}
+}
+
+// done returns true once every task of the DAG run finished
+func (p *runProgress) done() bool {
+	return len(p.completed)+len(p.failed) == len(p.taskInstances)
+}
+
+// finalState returns the state a DAG run ends in once all of its tasks are done
+func (p *runProgress) finalState() models.State {
+	switch {
+	case p.cancelled:
+		return models.StateCancelled
+	case len(p.failed) > 0:
+		return models.StateFailed
+	default:
+		return models.StateSuccess
+	}
+}
+
+// resync reads the state of submitted tasks that have not reported completion from the repository.
+// Running tasks whose lease expired are reaped: they are retried if attempts remain and failed otherwise.
+func (p *runProgress) resync(ctx context.Context, taskRepo storage.TaskInstanceRepository, leaseTimeout time.Duration) {
+	for taskID := range p.submitted {
+		if p.completed[taskID] || p.failed[taskID] {
+			continue
+		}
+
+		taskInstance, err := taskRepo.Get(ctx, p.taskInstances[taskID].ID)
+		if err != nil {
+			continue
+		}
+
+		if leaseExpired(taskInstance, leaseTimeout) {
+			if err := reapZombie(ctx, taskRepo, taskInstance); err != nil {
+				log.Printf("Failed to reap task %s: %v", taskID, err)
+				continue
+			}
+			p.taskInstances[taskID] = taskInstance
+			if taskInstance.State == models.StateRetrying {
+				// Hand the task out again
+				delete(p.submitted, taskID)
+				continue
+			}
+		}
+
+		p.record(TaskCompletion{TaskID: taskID, State: taskInstance.State, Branches: taskInstance.Branches})
+	}
+}
+
+// reapZombie ends the attempt of a running task instance whose executor stopped sending heartbeats.
+// The task instance moves to retrying if it has attempts left and to failed otherwise.
+func reapZombie(ctx context.Context, taskRepo storage.TaskInstanceRepository, taskInstance *models.TaskInstance) error {
+	newState := models.StateFailed
+	if taskInstance.TryNumber < taskInstance.MaxTries {
+		newState = models.StateRetrying
+	}
+
+	if err := taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRunning, newState); err != nil {
+		return fmt.Errorf("failed to update task state to %s: %w", newState, err)
+	}
+
+	taskInstance.State = newState
+	taskInstance.ErrorMessage = errLeaseExpired
+	if newState == models.StateRetrying {
+		taskInstance.TryNumber++
+	}
+	if err := taskRepo.Update(ctx, taskInstance); err != nil {
+		log.Printf("Failed to record reaping of task instance %s: %v", taskInstance.ID, err)
+	}
+
+	log.Printf("Reaped task %s of DAG run %s, lease expired (now %s)", taskInstance.TaskID, taskInstance.DAGRunID, newState)
+	return nil
+}
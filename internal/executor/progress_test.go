@@ -0,0 +1,506 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestRunProgress_ResyncReapsExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+
+	fresh := time.Now()
+	stale := time.Now().Add(-time.Hour)
+	instances := map[string]*models.TaskInstance{
+		"alive":     {TaskID: "alive", State: models.StateRunning, TryNumber: 1, MaxTries: 1, LastHeartbeatAt: &fresh},
+		"retryable": {TaskID: "retryable", State: models.StateRunning, TryNumber: 1, MaxTries: 2, LastHeartbeatAt: &stale},
+		"exhausted": {TaskID: "exhausted", State: models.StateRunning, TryNumber: 2, MaxTries: 2, LastHeartbeatAt: &stale},
+	}
+	for _, instance := range instances {
+		if err := taskRepo.Create(ctx, instance); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	progress := newRunProgress(instances)
+	for taskID := range instances {
+		progress.submitted[taskID] = true
+	}
+
+	progress.resync(ctx, taskRepo, time.Minute)
+
+	if !progress.submitted["alive"] || progress.failed["alive"] {
+		t.Error("Task with a fresh heartbeat should still be running")
+	}
+
+	if progress.submitted["retryable"] {
+		t.Error("Reaped task with attempts left should be handed out again")
+	}
+	if retried := progress.taskInstances["retryable"]; retried.State != models.StateRetrying || retried.TryNumber != 2 {
+		t.Errorf("Reaped task = %s (try %d), want retrying (try 2)", retried.State, retried.TryNumber)
+	}
+
+	if !progress.failed["exhausted"] {
+		t.Error("Reaped task without attempts left should have failed")
+	}
+	if failed, _ := taskRepo.Get(ctx, instances["exhausted"].ID); failed.State != models.StateFailed || failed.ErrorMessage != errLeaseExpired {
+		t.Errorf("Exhausted task = %s (%q), want failed with lease error", failed.State, failed.ErrorMessage)
+	}
+}
+
+func TestLocalExecutor_ResumesOrphanedRun(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+
+	config := DefaultExecutorConfig()
+	config.WorkerCount = 2
+	config.CompletionResyncInterval = time.Hour
+
+	exec := NewLocalExecutor(taskRepo, &memDAGRunRepo{}, nil, config)
+	tasks := &flakyTaskExecutor{}
+	exec.RegisterTaskExecutor(tasks)
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeBash},
+			{ID: "transform", Type: models.TaskTypeBash, Dependencies: []string{"extract"}, Retries: 1},
+			{ID: "load", Type: models.TaskTypeBash, Dependencies: []string{"transform"}},
+		},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateRunning}
+
+	// The previous owner finished extract and died while running transform; load was never created
+	stale := time.Now().Add(-time.Hour)
+	persisted := []*models.TaskInstance{
+		{TaskID: "extract", DAGRunID: "run1", State: models.StateSuccess, TryNumber: 1, MaxTries: 1},
+		{TaskID: "transform", DAGRunID: "run1", State: models.StateRunning, TryNumber: 1, MaxTries: 2, LastHeartbeatAt: &stale},
+	}
+	for _, instance := range persisted {
+		if err := taskRepo.Create(ctx, instance); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	if err := exec.Resume(ctx, dagRun, dagModel, persisted); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	select {
+	case finalState := <-done:
+		if finalState != models.StateSuccess {
+			t.Errorf("DAG run final state = %s, want %s", finalState, models.StateSuccess)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Resumed DAG run did not complete")
+	}
+
+	// Only transform and load run again; extract already succeeded
+	tasks.mu.Lock()
+	attempts := tasks.attempts
+	tasks.mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("Tasks ran %d times, want 2", attempts)
+	}
+
+	transform, _ := taskRepo.Get(ctx, persisted[1].ID)
+	if transform.State != models.StateSuccess || transform.TryNumber != 2 {
+		t.Errorf("transform = %s (try %d), want success on try 2", transform.State, transform.TryNumber)
+	}
+}
+
+func TestResumeRunProgress_KeepsWaitingTasksPending(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "retry", Type: models.TaskTypeBash},
+			{ID: "sensor", Type: models.TaskTypeBash},
+			{ID: "cancelled", Type: models.TaskTypeBash},
+		},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateRunning}
+
+	persisted := []*models.TaskInstance{
+		{TaskID: "retry", DAGRunID: "run1", State: models.StateScheduled, TryNumber: 2, MaxTries: 3},
+		{TaskID: "sensor", DAGRunID: "run1", State: models.StateUpForReschedule, TryNumber: 1, MaxTries: 1},
+		{TaskID: "cancelled", DAGRunID: "run1", State: models.StateCancelled, TryNumber: 1, MaxTries: 1},
+	}
+	for _, instance := range persisted {
+		if err := taskRepo.Create(ctx, instance); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	progress, err := resumeRunProgress(ctx, taskRepo, dagRun, dagModel, persisted)
+	if err != nil {
+		t.Fatalf("resumeRunProgress failed: %v", err)
+	}
+
+	for _, taskID := range []string{"retry", "sensor"} {
+		if progress.failed[taskID] || progress.completed[taskID] || progress.submitted[taskID] {
+			t.Errorf("Task %s waiting for its next attempt should still be pending", taskID)
+		}
+	}
+	if !progress.failed["cancelled"] {
+		t.Error("Cancelled task should count as finished without success")
+	}
+}
+
+// commandTaskExecutor fails tasks whose command is "false" and succeeds the others.
+// Branch tasks follow the tasks named by their command.
+type commandTaskExecutor struct{}
+
+func (e *commandTaskExecutor) Type() models.TaskType {
+	return models.TaskTypeBash
+}
+
+func (e *commandTaskExecutor) Execute(ctx context.Context, task *models.Task, taskInstance *models.TaskInstance) *TaskResult {
+	result := &TaskResult{State: models.StateSuccess, StartTime: time.Now(), EndTime: time.Now()}
+	if task.Command == "false" {
+		result.State = models.StateFailed
+		result.ErrorMessage = "exit status 1"
+	} else if task.Branch != nil {
+		setBranches(task, result, splitBranches(task.Command))
+	}
+	return result
+}
+
+// newTriggerRuleTestDAG returns a DAG whose extract task fails, with tasks downstream of it for each outcome
+func newTriggerRuleTestDAG() (*models.DAG, *models.DAGRun) {
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeBash, Command: "false"},
+			{ID: "transform", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"extract"}},
+			{ID: "load", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"transform"}},
+			{ID: "alert", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"extract"}, TriggerRule: models.TriggerRuleOneFailed},
+			{ID: "report", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"alert"}, TriggerRule: models.TriggerRuleOneSuccess},
+			{ID: "cleanup", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"load"}, TriggerRule: models.TriggerRuleAllDone},
+		},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+	return dagModel, dagRun
+}
+
+// assertTriggerRuleStates checks the task states of a DAG run of newTriggerRuleTestDAG
+func assertTriggerRuleStates(t *testing.T, taskRepo *memTaskInstanceRepo) {
+	t.Helper()
+
+	instances, _ := taskRepo.ListByDAGRun(context.Background(), "run1")
+	states := make(map[string]models.State, len(instances))
+	for _, instance := range instances {
+		states[instance.TaskID] = instance.State
+	}
+
+	want := map[string]models.State{
+		"extract":   models.StateFailed,
+		"transform": models.StateUpstreamFailed,
+		"load":      models.StateUpstreamFailed,
+		"alert":     models.StateSuccess,
+		"report":    models.StateSuccess,
+		"cleanup":   models.StateSuccess,
+	}
+	for taskID, state := range want {
+		if states[taskID] != state {
+			t.Errorf("Task %s state = %s, want %s", taskID, states[taskID], state)
+		}
+	}
+}
+
+func TestSequentialExecutor_TriggerRules(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(&commandTaskExecutor{})
+
+	dagModel, dagRun := newTriggerRuleTestDAG()
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if dagRun.State != models.StateFailed {
+		t.Errorf("DAG run state = %s, want %s", dagRun.State, models.StateFailed)
+	}
+	assertTriggerRuleStates(t, taskRepo)
+}
+
+func TestSequentialExecutor_FailsTasksWithoutExecutor(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	queue := dlq.NewMemoryQueue()
+	exec.SetDLQ(dlq.NewManager(queue, 0))
+
+	dagModel, dagRun := newRetryTestDAG(0)
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if dagRun.State != models.StateFailed {
+		t.Errorf("DAG run state = %s, want %s", dagRun.State, models.StateFailed)
+	}
+	if instance := taskRepo.only(); instance.State != models.StateFailed || instance.ErrorMessage == "" {
+		t.Errorf("Task instance = %s (%q), want failed with an error message", instance.State, instance.ErrorMessage)
+	}
+	if entries, _ := queue.List(context.Background(), nil); len(entries) != 1 {
+		t.Errorf("DLQ has %d entries, want 1", len(entries))
+	}
+}
+
+// unstartableTaskInstanceRepo fails every attempt to move a task instance to running
+type unstartableTaskInstanceRepo struct {
+	*memTaskInstanceRepo
+}
+
+func (r *unstartableTaskInstanceRepo) UpdateState(ctx context.Context, id string, oldState, newState models.State) error {
+	if newState == models.StateRunning {
+		return fmt.Errorf("task instance %s was modified", id)
+	}
+	return r.memTaskInstanceRepo.UpdateState(ctx, id, oldState, newState)
+}
+
+func TestLocalExecutor_FailsTasksThatCannotStart(t *testing.T) {
+	tests := []struct {
+		name        string
+		register    bool
+		unstartable bool
+		wantState   models.State
+		wantDLQ     int
+	}{
+		{name: "no executor registered", wantState: models.StateFailed, wantDLQ: 1},
+		// The task instance is left to whoever modified it
+		{name: "state update conflict", register: true, unstartable: true, wantState: models.StateQueued},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			memRepo := newMemTaskInstanceRepo()
+			var taskRepo storage.TaskInstanceRepository = memRepo
+			if tt.unstartable {
+				taskRepo = &unstartableTaskInstanceRepo{memRepo}
+			}
+
+			exec := NewLocalExecutor(taskRepo, &memDAGRunRepo{}, nil, DefaultExecutorConfig())
+			if tt.register {
+				exec.RegisterTaskExecutor(&commandTaskExecutor{})
+			}
+			queue := dlq.NewMemoryQueue()
+			exec.SetDLQ(dlq.NewManager(queue, 0))
+
+			done := make(chan models.State, 1)
+			exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+				done <- finalState
+			})
+
+			if err := exec.Start(ctx); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			defer exec.Stop(ctx)
+
+			dagModel, dagRun := newRetryTestDAG(0)
+			if err := exec.Execute(ctx, dagRun, dagModel); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			select {
+			case finalState := <-done:
+				if finalState != models.StateFailed {
+					t.Errorf("DAG run final state = %s, want %s", finalState, models.StateFailed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("DAG run waited for a task that never started")
+			}
+
+			if instance := memRepo.only(); instance.State != tt.wantState {
+				t.Errorf("Task instance state = %s, want %s", instance.State, tt.wantState)
+			} else if tt.wantState == models.StateFailed && instance.ErrorMessage == "" {
+				t.Error("Expected the failed task instance to have an error message")
+			}
+			if entries, _ := queue.List(ctx, nil); len(entries) != tt.wantDLQ {
+				t.Errorf("DLQ has %d entries, want %d", len(entries), tt.wantDLQ)
+			}
+		})
+	}
+}
+
+func TestLocalExecutor_TriggerRules(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	config := DefaultExecutorConfig()
+	// Tasks that will not run must not hold up their dependents until the next resync
+	config.CompletionResyncInterval = time.Hour
+
+	exec := NewLocalExecutor(taskRepo, &memDAGRunRepo{}, nil, config)
+	exec.RegisterTaskExecutor(&commandTaskExecutor{})
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+
+	ctx := context.Background()
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	dagModel, dagRun := newTriggerRuleTestDAG()
+	if err := exec.Execute(ctx, dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	select {
+	case finalState := <-done:
+		if finalState != models.StateFailed {
+			t.Errorf("DAG run final state = %s, want %s", finalState, models.StateFailed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DAG run did not complete")
+	}
+	assertTriggerRuleStates(t, taskRepo)
+}
+
+// newBranchTestDAG returns a DAG whose choose task follows the incremental path, with joins of both paths
+func newBranchTestDAG() (*models.DAG, *models.DAGRun) {
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "choose", Type: models.TaskTypeBash, Command: "incremental", Branch: &models.BranchConfig{Targets: []string{"full", "incremental"}}},
+			{ID: "full", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"choose"}},
+			{ID: "full_load", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"full"}},
+			{ID: "incremental", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"choose"}},
+			{ID: "report", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"full_load", "incremental"}, TriggerRule: models.TriggerRuleNoneFailed},
+			{ID: "summary", Type: models.TaskTypeBash, Command: "true", Dependencies: []string{"full_load", "incremental"}},
+		},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+	return dagModel, dagRun
+}
+
+// assertBranchStates checks the task states of a DAG run of newBranchTestDAG
+func assertBranchStates(t *testing.T, taskRepo *memTaskInstanceRepo) {
+	t.Helper()
+
+	instances, _ := taskRepo.ListByDAGRun(context.Background(), "run1")
+	byTaskID := make(map[string]*models.TaskInstance, len(instances))
+	for _, instance := range instances {
+		byTaskID[instance.TaskID] = instance
+	}
+
+	want := map[string]models.State{
+		"choose":      models.StateSuccess,
+		"full":        models.StateSkipped,
+		"full_load":   models.StateSkipped,
+		"incremental": models.StateSuccess,
+		"report":      models.StateSuccess,
+		"summary":     models.StateSkipped,
+	}
+	for taskID, state := range want {
+		if byTaskID[taskID].State != state {
+			t.Errorf("Task %s state = %s, want %s", taskID, byTaskID[taskID].State, state)
+		}
+	}
+
+	if branches := byTaskID["choose"].Branches; len(branches) != 1 || branches[0] != "incremental" {
+		t.Errorf("Branches of choose = %v, want [incremental]", branches)
+	}
+}
+
+func TestSequentialExecutor_Branches(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(&commandTaskExecutor{})
+
+	dagModel, dagRun := newBranchTestDAG()
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if dagRun.State != models.StateSuccess {
+		t.Errorf("DAG run state = %s, want %s", dagRun.State, models.StateSuccess)
+	}
+	assertBranchStates(t, taskRepo)
+}
+
+func TestLocalExecutor_Branches(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	config := DefaultExecutorConfig()
+	config.CompletionResyncInterval = time.Hour
+
+	exec := NewLocalExecutor(taskRepo, &memDAGRunRepo{}, nil, config)
+	exec.RegisterTaskExecutor(&commandTaskExecutor{})
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+
+	ctx := context.Background()
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	dagModel, dagRun := newBranchTestDAG()
+	if err := exec.Execute(ctx, dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	select {
+	case finalState := <-done:
+		if finalState != models.StateSuccess {
+			t.Errorf("DAG run final state = %s, want %s", finalState, models.StateSuccess)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DAG run did not complete")
+	}
+	assertBranchStates(t, taskRepo)
+}
+
+func TestResumeRunProgress_RestoresBranches(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+
+	dagModel, dagRun := newBranchTestDAG()
+	persisted := []*models.TaskInstance{
+		{TaskID: "choose", DAGRunID: "run1", State: models.StateSuccess, TryNumber: 1, MaxTries: 1, Branches: []string{"incremental"}},
+	}
+	for _, instance := range persisted {
+		if err := taskRepo.Create(ctx, instance); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	progress, err := resumeRunProgress(ctx, taskRepo, dagRun, dagModel, persisted)
+	if err != nil {
+		t.Fatalf("resumeRunProgress failed: %v", err)
+	}
+
+	ready, notRun := progress.next(dag.NewGraph(dagModel))
+	if len(ready) != 1 || ready[0].ID != "incremental" {
+		t.Errorf("Expected only incremental to be ready, got %v", ready)
+	}
+	for _, taskID := range []string{"full", "full_load"} {
+		if notRun[taskID] != models.StateSkipped {
+			t.Errorf("Task %s = %s, want skipped", taskID, notRun[taskID])
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// OrphanedRun is a running DAG run whose owner stopped renewing its lease, claimed by this process
+type OrphanedRun struct {
+	DAGRun        *models.DAGRun
+	DAG           *models.DAG
+	TaskInstances []*models.TaskInstance
+}
+
+// Recovery finds DAG runs left running by a process that crashed or restarted and claims them
+type Recovery struct {
+	dagRepo      storage.DAGRepository
+	dagRunRepo   storage.DAGRunRepository
+	taskRepo     storage.TaskInstanceRepository
+	leaseTimeout time.Duration
+}
+
+// NewRecovery creates a recovery that treats running DAG runs without a heartbeat for leaseTimeout as orphaned
+func NewRecovery(
+	dagRepo storage.DAGRepository,
+	dagRunRepo storage.DAGRunRepository,
+	taskRepo storage.TaskInstanceRepository,
+	leaseTimeout time.Duration,
+) *Recovery {
+	if leaseTimeout <= 0 {
+		leaseTimeout = defaultLeaseTimeout
+	}
+
+	return &Recovery{
+		dagRepo:      dagRepo,
+		dagRunRepo:   dagRunRepo,
+		taskRepo:     taskRepo,
+		leaseTimeout: leaseTimeout,
+	}
+}
+
+// ClaimOrphanedRuns claims every orphaned DAG run and loads what is needed to resume it.
+// A run claimed by another process first is skipped.
+func (r *Recovery) ClaimOrphanedRuns(ctx context.Context) ([]*OrphanedRun, error) {
+	running := models.StateRunning
+	dagRuns, err := r.dagRunRepo.List(ctx, storage.DAGRunFilters{State: &running})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list running DAG runs: %w", err)
+	}
+
+	var orphaned []*OrphanedRun
+	for _, dagRun := range dagRuns {
+		claimed, err := r.dagRunRepo.ClaimOrphaned(ctx, dagRun.ID, r.leaseTimeout)
+		if err != nil {
+			log.Printf("Failed to claim DAG run %s: %v", dagRun.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		run, err := r.load(ctx, dagRun)
+		if err != nil {
+			// The lease was renewed by the claim, so the run is retried once it expires again
+			log.Printf("Failed to load orphaned DAG run %s: %v", dagRun.ID, err)
+			continue
+		}
+
+		log.Printf("Claimed orphaned DAG run %s (DAG: %s)", dagRun.ID, dagRun.DAGID)
+		orphaned = append(orphaned, run)
+	}
+
+	return orphaned, nil
+}
+
+// load reads the DAG definition version and the task instances of a claimed DAG run
+func (r *Recovery) load(ctx context.Context, dagRun *models.DAGRun) (*OrphanedRun, error) {
+	dagVersion, err := r.dagRepo.GetVersion(ctx, dagRun.DAGID, dagRun.DAGVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DAG version %d: %w", dagRun.DAGVersion, err)
+	}
+
+	taskInstances, err := r.taskRepo.ListByDAGRun(ctx, dagRun.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task instances: %w", err)
+	}
+
+	return &OrphanedRun{
+		DAGRun:        dagRun,
+		DAG:           dagVersion.DAG,
+		TaskInstances: taskInstances,
+	}, nil
+}
+
+// Run claims orphaned DAG runs right away and then every lease timeout until ctx is done,
+// handing each of them to resume
+func (r *Recovery) Run(ctx context.Context, resume func(run *OrphanedRun)) {
+	ticker := time.NewTicker(r.leaseTimeout)
+	defer ticker.Stop()
+
+	for {
+		orphaned, err := r.ClaimOrphanedRuns(ctx)
+		if err != nil {
+			log.Printf("Failed to recover orphaned DAG runs: %v", err)
+		}
+		for _, run := range orphaned {
+			resume(run)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// ErrNotReplayable is returned when a DLQ entry cannot be replayed because of the state
+// of the entry, its task instance or its DAG run
+var ErrNotReplayable = errors.New("dlq entry is not replayable")
+
+// ResumeFunc hands a DAG run to an executor, which continues it from its persisted task instances
+type ResumeFunc func(dagRun *models.DAGRun, dagModel *models.DAG, taskInstances []*models.TaskInstance) error
+
+// ReplayResult is the outcome of replaying one DLQ entry
+type ReplayResult struct {
+	EntryID      string
+	TaskInstance *models.TaskInstance // The new try of the task, if the entry was replayed
+	Err          error
+}
+
+// Replayer replays dead letters: the failed task instance of an entry gets a new try, the tasks
+// that did not run because of it are queued again, and the failed DAG run is resumed.
+type Replayer struct {
+	queue      dlq.Queue
+	dagRepo    storage.DAGRepository
+	dagRunRepo storage.DAGRunRepository
+	taskRepo   storage.TaskInstanceRepository
+	resume     ResumeFunc
+}
+
+// NewReplayer creates a replayer handing replayed DAG runs to resume
+func NewReplayer(
+	queue dlq.Queue,
+	dagRepo storage.DAGRepository,
+	dagRunRepo storage.DAGRunRepository,
+	taskRepo storage.TaskInstanceRepository,
+	resume ResumeFunc,
+) *Replayer {
+	return &Replayer{
+		queue:      queue,
+		dagRepo:    dagRepo,
+		dagRunRepo: dagRunRepo,
+		taskRepo:   taskRepo,
+		resume:     resume,
+	}
+}
+
+// Replay replays a DLQ entry and returns the new try of its task instance
+func (r *Replayer) Replay(ctx context.Context, id string) (*models.TaskInstance, error) {
+	result := r.ReplayAll(ctx, []string{id})[0]
+	return result.TaskInstance, result.Err
+}
+
+// ReplayAll replays DLQ entries, returning a result per entry in the order of ids.
+// Entries of the same DAG run are replayed together, so that the run is resumed once.
+func (r *Replayer) ReplayAll(ctx context.Context, ids []string) []ReplayResult {
+	results := make([]ReplayResult, len(ids))
+	byRun := make(map[string][]*replayTarget)
+	var runOrder []string
+
+	for i, id := range ids {
+		results[i].EntryID = id
+
+		target, err := r.load(ctx, id)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		target.result = &results[i]
+
+		if _, ok := byRun[target.taskInstance.DAGRunID]; !ok {
+			runOrder = append(runOrder, target.taskInstance.DAGRunID)
+		}
+		byRun[target.taskInstance.DAGRunID] = append(byRun[target.taskInstance.DAGRunID], target)
+	}
+
+	for _, dagRunID := range runOrder {
+		r.replayRun(ctx, dagRunID, byRun[dagRunID])
+	}
+
+	return results
+}
+
+// replayTarget is a DLQ entry being replayed and the task instance it refers to
+type replayTarget struct {
+	entry        *dlq.Entry
+	taskInstance *models.TaskInstance
+	result       *ReplayResult
+}
+
+// load reads a DLQ entry and its task instance and checks that the entry can be replayed
+func (r *Replayer) load(ctx context.Context, id string) (*replayTarget, error) {
+	entry, err := r.queue.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Replayed {
+		return nil, fmt.Errorf("%w: entry was already replayed", ErrNotReplayable)
+	}
+
+	taskInstance, err := r.taskRepo.Get(ctx, entry.TaskInstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task instance %s: %w", entry.TaskInstanceID, err)
+	}
+	if taskInstance.State != models.StateFailed {
+		return nil, fmt.Errorf("%w: task instance is %s", ErrNotReplayable, taskInstance.State)
+	}
+
+	return &replayTarget{entry: entry, taskInstance: taskInstance}, nil
+}
+
+// replayRun gives the failed tasks of a DAG run a new try and resumes the run
+func (r *Replayer) replayRun(ctx context.Context, dagRunID string, targets []*replayTarget) {
+	fail := func(err error) {
+		for _, target := range targets {
+			target.result.Err = err
+		}
+	}
+
+	dagRun, err := r.dagRunRepo.Get(ctx, dagRunID)
+	if err != nil {
+		fail(fmt.Errorf("failed to get DAG run %s: %w", dagRunID, err))
+		return
+	}
+	if dagRun.State != models.StateFailed {
+		// The executor scheduling the run still acts on the failure
+		fail(fmt.Errorf("%w: DAG run is %s, replay once it failed", ErrNotReplayable, dagRun.State))
+		return
+	}
+
+	dagVersion, err := r.dagRepo.GetVersion(ctx, dagRun.DAGID, dagRun.DAGVersion)
+	if err != nil {
+		fail(fmt.Errorf("failed to get DAG version %d: %w", dagRun.DAGVersion, err))
+		return
+	}
+
+	// Entering running claims the run and starts its lease, so concurrent replays of the run conflict
+	// and recovery resumes it should this process stop before the executor took it over
+	if err := r.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateFailed, models.StateRunning); err != nil {
+		fail(fmt.Errorf("failed to update DAG run state: %w", err))
+		return
+	}
+	dagRun.State = models.StateRunning
+
+	graph := dag.NewGraph(dagVersion.DAG)
+	downstream := make(map[string]bool)
+	for _, target := range targets {
+		if err := r.retryTask(ctx, target.taskInstance); err != nil {
+			target.result.Err = err
+			continue
+		}
+		target.result.TaskInstance = target.taskInstance
+
+		taskIDs, err := graph.GetDownstreamTasks(target.taskInstance.TaskID)
+		if err != nil {
+			log.Printf("Failed to find tasks downstream of %s: %v", target.taskInstance.TaskID, err)
+		}
+		for _, taskID := range taskIDs {
+			downstream[taskID] = true
+		}
+
+		if err := r.queue.Replay(ctx, target.entry.ID); err != nil {
+			log.Printf("Failed to mark DLQ entry %s as replayed: %v", target.entry.ID, err)
+		}
+	}
+
+	taskInstances, err := r.taskRepo.ListByDAGRun(ctx, dagRun.ID)
+	if err != nil {
+		log.Printf("Failed to list task instances of replayed DAG run %s, it is resumed once its lease expires: %v", dagRun.ID, err)
+		return
+	}
+
+	// Tasks that did not run because a replayed task failed wait for it again
+	for _, taskInstance := range taskInstances {
+		if !downstream[taskInstance.TaskID] || taskInstance.State != models.StateUpstreamFailed {
+			continue
+		}
+		if err := r.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateUpstreamFailed, models.StateQueued); err != nil {
+			log.Printf("Failed to queue task instance %s again: %v", taskInstance.ID, err)
+			continue
+		}
+		taskInstance.State = models.StateQueued
+	}
+
+	if err := r.resume(dagRun, dagVersion.DAG, taskInstances); err != nil {
+		log.Printf("Failed to resume replayed DAG run %s, it is resumed once its lease expires: %v", dagRun.ID, err)
+		return
+	}
+
+	log.Printf("Replayed %d task(s) of DAG run %s", len(targets), dagRun.ID)
+}
+
+// retryTask moves a failed task instance to retrying and starts its next try.
+// The try gets a single attempt, since the attempts the task was configured with are exhausted.
+func (r *Replayer) retryTask(ctx context.Context, taskInstance *models.TaskInstance) error {
+	if err := r.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateFailed, models.StateRetrying); err != nil {
+		return fmt.Errorf("failed to update task state to retrying: %w", err)
+	}
+
+	taskInstance.State = models.StateRetrying
+	taskInstance.TryNumber++
+	if taskInstance.MaxTries < taskInstance.TryNumber {
+		taskInstance.MaxTries = taskInstance.TryNumber
+	}
+	taskInstance.ErrorMessage = ""
+	taskInstance.StartDate = nil
+	taskInstance.EndDate = nil
+
+	if err := r.taskRepo.Update(ctx, taskInstance); err != nil {
+		// The task is retried anyway; only the recorded attempt details are stale
+		log.Printf("Failed to record replay of task instance %s: %v", taskInstance.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// statefulDAGRunRepo stores the state of one DAG run
+type statefulDAGRunRepo struct {
+	memDAGRunRepo
+	mu  sync.Mutex
+	run models.DAGRun
+}
+
+func (r *statefulDAGRunRepo) Get(ctx context.Context, id string) (*models.DAGRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id != r.run.ID {
+		return nil, storage.ErrNotFound
+	}
+	copied := r.run
+	return &copied, nil
+}
+
+func (r *statefulDAGRunRepo) UpdateState(ctx context.Context, id string, oldState, newState models.State) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id != r.run.ID || r.run.State != oldState {
+		return fmt.Errorf("DAG run %s is not in state %s", id, oldState)
+	}
+	r.run.State = newState
+	return nil
+}
+
+// versionedDAGRepo returns the same DAG for every version
+type versionedDAGRepo struct {
+	storage.DAGRepository
+	dag *models.DAG
+}
+
+func (r *versionedDAGRepo) GetVersion(ctx context.Context, dagID string, version int) (*models.DAGVersion, error) {
+	return &models.DAGVersion{DAGID: dagID, Version: version, DAG: r.dag}, nil
+}
+
+func TestReplayer_ReplaysFailedTask(t *testing.T) {
+	ctx := context.Background()
+	taskRepo := newMemTaskInstanceRepo()
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeBash},
+			{ID: "load", Type: models.TaskTypeBash, Dependencies: []string{"extract"}},
+		},
+	}
+	dagRunRepo := &statefulDAGRunRepo{run: models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}}
+
+	config := DefaultExecutorConfig()
+	config.WorkerCount = 1
+	exec := NewLocalExecutor(taskRepo, dagRunRepo, nil, config)
+	flaky := &flakyTaskExecutor{failures: 1}
+	exec.RegisterTaskExecutor(flaky)
+
+	queue := dlq.NewMemoryQueue()
+	exec.SetDLQ(dlq.NewManager(queue, 0))
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+	waitFor := func(want models.State) {
+		t.Helper()
+		select {
+		case finalState := <-done:
+			if finalState != want {
+				t.Fatalf("DAG run final state = %s, want %s", finalState, want)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("DAG run did not complete")
+		}
+	}
+
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	run, _ := dagRunRepo.Get(ctx, "run1")
+	if err := exec.Execute(ctx, run, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	waitFor(models.StateFailed)
+
+	entries, err := queue.List(ctx, nil)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one DLQ entry, got %+v (%v)", entries, err)
+	}
+
+	replayer := NewReplayer(queue, &versionedDAGRepo{dag: dagModel}, dagRunRepo, taskRepo,
+		func(dagRun *models.DAGRun, dagModel *models.DAG, taskInstances []*models.TaskInstance) error {
+			return exec.Resume(ctx, dagRun, dagModel, taskInstances)
+		})
+
+	taskInstance, err := replayer.Replay(ctx, entries[0].ID)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if taskInstance.TryNumber != 2 || taskInstance.MaxTries != 2 {
+		t.Errorf("Replayed task = try %d of %d, want try 2 of 2", taskInstance.TryNumber, taskInstance.MaxTries)
+	}
+
+	// The new try of extract succeeds and load, skipped by the failure, runs
+	waitFor(models.StateSuccess)
+	flaky.mu.Lock()
+	attempts := flaky.attempts
+	flaky.mu.Unlock()
+	if attempts != 3 {
+		t.Errorf("Tasks ran %d times, want 3", attempts)
+	}
+
+	entry, _ := queue.Get(ctx, entries[0].ID)
+	if !entry.Replayed {
+		t.Error("Expected the DLQ entry to be marked as replayed")
+	}
+	if _, err := replayer.Replay(ctx, entries[0].ID); !errors.Is(err, ErrNotReplayable) {
+		t.Errorf("Expected ErrNotReplayable for a replayed entry, got %v", err)
+	}
+	if _, err := replayer.Replay(ctx, "missing"); !errors.Is(err, dlq.ErrNotFound) {
+		t.Errorf("Expected dlq.ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+const (
+	// ResultsFileEnv names the environment variable holding the file bash tasks write results to, one key=value per line
+	ResultsFileEnv = "DAG_RESULTS_FILE"
+
+	// resultMarker prefixes the stdout lines through which bash tasks push results
+	resultMarker = "::result "
+)
+
+// ErrNoTaskResults is returned when a task pushes or pulls results outside of a task run by an executor
+var ErrNoTaskResults = errors.New("no task results in context")
+
+// ResultPuller returns the JSON value a task of the current DAG run pushed under a key
+type ResultPuller func(ctx context.Context, taskID, key string) (json.RawMessage, error)
+
+// TaskResults collects the values a running task pushes and gives it access to the values
+// other tasks of its DAG run pushed
+type TaskResults struct {
+	pull ResultPuller
+
+	mu     sync.Mutex
+	pushed map[string]json.RawMessage
+}
+
+// NewTaskResults creates the results of a running task, pulling values through pull
+func NewTaskResults(pull ResultPuller) *TaskResults {
+	return &TaskResults{
+		pull:   pull,
+		pushed: make(map[string]json.RawMessage),
+	}
+}
+
+type taskResultsKey struct{}
+
+// WithTaskResults returns a context that carries the results of a running task
+func WithTaskResults(ctx context.Context, taskResults *TaskResults) context.Context {
+	return context.WithValue(ctx, taskResultsKey{}, taskResults)
+}
+
+// TaskResultsFromContext returns the task results carried by ctx, or nil if there are none
+func TaskResultsFromContext(ctx context.Context) *TaskResults {
+	taskResults, _ := ctx.Value(taskResultsKey{}).(*TaskResults)
+	return taskResults
+}
+
+// PushResult records a value for downstream tasks under a key, encoded as JSON. The value is
+// stored once the task attempt finishes, replacing any value pushed earlier under the key.
+func PushResult(ctx context.Context, key string, value interface{}) error {
+	taskResults := TaskResultsFromContext(ctx)
+	if taskResults == nil {
+		return ErrNoTaskResults
+	}
+	if err := results.ValidateKey(key); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode result %s: %w", key, err)
+	}
+
+	taskResults.mu.Lock()
+	defer taskResults.mu.Unlock()
+	taskResults.pushed[key] = data
+	return nil
+}
+
+// PullResult decodes the value a task of the same DAG run pushed under a key into value.
+// It returns an error wrapping results.ErrNotFound if the task pushed no such value.
+func PullResult(ctx context.Context, taskID, key string, value interface{}) error {
+	taskResults := TaskResultsFromContext(ctx)
+	if taskResults == nil {
+		return ErrNoTaskResults
+	}
+	if taskResults.pull == nil {
+		return fmt.Errorf("failed to pull result %s of task %s: no result store is configured", key, taskID)
+	}
+
+	data, err := taskResults.pull(ctx, taskID, key)
+	if err != nil {
+		return fmt.Errorf("failed to pull result %s of task %s: %w", key, taskID, err)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to decode result %s of task %s: %w", key, taskID, err)
+	}
+	return nil
+}
+
+// collect adds the values the task pushed through its context to the result of its attempt.
+// Values the task executor extracted itself take precedence.
+func (r *TaskResults) collect(result *TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, value := range r.pushed {
+		if _, exists := result.Results[key]; exists {
+			continue
+		}
+		if result.Results == nil {
+			result.Results = make(map[string]json.RawMessage, len(r.pushed))
+		}
+		result.Results[key] = value
+	}
+}
+
+// attachResults attaches the results of a task instance to ctx, pulling values from the result
+// manager, which may be nil
+func attachResults(ctx context.Context, manager *results.Manager, taskInstance *models.TaskInstance) (context.Context, *TaskResults) {
+	var pull ResultPuller
+	if manager != nil {
+		dagRunID := taskInstance.DAGRunID
+		pull = func(ctx context.Context, taskID, key string) (json.RawMessage, error) {
+			return manager.Pull(ctx, dagRunID, taskID, key)
+		}
+	}
+
+	taskResults := NewTaskResults(pull)
+	return WithTaskResults(ctx, taskResults), taskResults
+}
+
+// storeResults stores the values a task attempt pushed. They are stored before retries are
+// decided, since an attempt whose values cannot be stored fails.
+func storeResults(ctx context.Context, manager *results.Manager, taskInstance *models.TaskInstance, result *TaskResult) {
+	if len(result.Results) == 0 {
+		return
+	}
+	if manager == nil {
+		log.Printf("Dropping %d results of task %s: no result store is configured", len(result.Results), taskInstance.TaskID)
+		return
+	}
+
+	err := manager.PushAll(ctx, taskInstance.DAGRunID, taskInstance.TaskID, result.Results)
+	if err == nil {
+		return
+	}
+
+	log.Printf("Failed to store results of task %s: %v", taskInstance.TaskID, err)
+	if result.State == models.StateSuccess {
+		resultFailed(result, fmt.Sprintf("Failed to store results: %v", err))
+	}
+}
+
+// resultFailed fails a task attempt whose results could not be extracted or stored
+func resultFailed(result *TaskResult, message string) {
+	result.State = models.StateFailed
+	result.ErrorCode = ErrorCodeInvalidResult
+	result.ErrorMessage = message
+}
+
+// parseResultLine parses a result written as key=value. Values that are not JSON are stored as strings.
+func parseResultLine(line string) (string, json.RawMessage, error) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", nil, fmt.Errorf("result %q is not written as key=value", line)
+	}
+	key = strings.TrimSpace(key)
+	if err := results.ValidateKey(key); err != nil {
+		return "", nil, err
+	}
+
+	value = strings.TrimSpace(value)
+	if value != "" && json.Valid([]byte(value)) {
+		return key, json.RawMessage(value), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode result %s: %w", key, err)
+	}
+	return key, data, nil
+}
+
+// parseResultsFile parses the results a bash task wrote to its results file, skipping empty lines
+func parseResultsFile(data []byte, values map[string]json.RawMessage) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), results.DefaultMaxBlobSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, value, err := parseResultLine(line)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read results file: %w", err)
+	}
+	return nil
+}
+
+// markerResults parses the results a command pushed on stdout with "::result key=value" lines
+func markerResults(output string, values map[string]json.RawMessage) error {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, resultMarker) {
+			continue
+		}
+		key, value, err := parseResultLine(strings.TrimPrefix(line, resultMarker))
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	return nil
+}
+
+// withoutResultMarkers removes the result marker lines from a command's output
+func withoutResultMarkers(output string) string {
+	if !strings.Contains(output, resultMarker) {
+		return output
+	}
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), resultMarker) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// pathResults extracts the values of an HTTP task from fields of its JSON response body,
+// given by result key as dot-separated paths
+func pathResults(body []byte, paths map[string]string) (map[string]json.RawMessage, error) {
+	document, err := decodeJSON(body)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]json.RawMessage, len(paths))
+	for _, key := range keys {
+		value, err := jsonField(document, paths[key])
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode result %s: %w", key, err)
+		}
+		values[key] = data
+	}
+	return values, nil
+}
+
+// decodeJSON decodes a JSON response body, keeping numbers as written
+func decodeJSON(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("response body is not JSON: %w", err)
+	}
+	return document, nil
+}
+
+// jsonField returns the field of a decoded JSON document at a dot-separated path; "." is the whole document
+func jsonField(document interface{}, path string) (interface{}, error) {
+	if path == "." {
+		return document, nil
+	}
+
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("response body has no field %s", path)
+		}
+		if value, ok = object[key]; !ok {
+			return nil, fmt.Errorf("response body has no field %s", path)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestPushResult_WithoutTaskResults(t *testing.T) {
+	if err := PushResult(context.Background(), "rows", 1); !errors.Is(err, ErrNoTaskResults) {
+		t.Errorf("PushResult error = %v, want %v", err, ErrNoTaskResults)
+	}
+
+	var rows int
+	if err := PullResult(context.Background(), "extract", "rows", &rows); !errors.Is(err, ErrNoTaskResults) {
+		t.Errorf("PullResult error = %v, want %v", err, ErrNoTaskResults)
+	}
+}
+
+func TestPushResult_InvalidKey(t *testing.T) {
+	ctx := WithTaskResults(context.Background(), NewTaskResults(nil))
+	if err := PushResult(ctx, "row count", 1); !errors.Is(err, results.ErrInvalidKey) {
+		t.Errorf("PushResult error = %v, want %v", err, results.ErrInvalidKey)
+	}
+}
+
+func TestSequentialExecutor_Results(t *testing.T) {
+	goExecutor := NewGoFuncTaskExecutor()
+	goExecutor.RegisterFunction("extract", func(ctx context.Context) error {
+		return PushResult(ctx, "rows", []int{1, 2, 3})
+	})
+	goExecutor.RegisterFunction("transform", func(ctx context.Context) error {
+		var rows []int
+		if err := PullResult(ctx, "extract", "rows", &rows); err != nil {
+			return err
+		}
+		total := 0
+		for _, row := range rows {
+			total += row
+		}
+		return PushResult(ctx, "total", total)
+	})
+	goExecutor.RegisterFunction("missing", func(ctx context.Context) error {
+		var value string
+		err := PullResult(ctx, "extract", "missing", &value)
+		if !errors.Is(err, results.ErrNotFound) {
+			t.Errorf("PullResult error = %v, want %v", err, results.ErrNotFound)
+		}
+		return nil
+	})
+
+	taskRepo := newMemTaskInstanceRepo()
+	manager := results.NewManager(results.NewMemoryStore(), nil)
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(goExecutor)
+	exec.SetResults(manager)
+
+	dagModel := &models.DAG{
+		ID: "dag1",
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeGo},
+			{ID: "transform", Type: models.TaskTypeGo, Dependencies: []string{"extract"}},
+			{ID: "missing", Type: models.TaskTypeGo, Dependencies: []string{"extract"}},
+		},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if dagRun.State != models.StateSuccess {
+		t.Fatalf("DAG run state = %s, want %s", dagRun.State, models.StateSuccess)
+	}
+
+	total, err := manager.Pull(context.Background(), "run1", "transform", "total")
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if string(total) != "6" {
+		t.Errorf("Result total = %s, want 6", total)
+	}
+}
+
+func TestSequentialExecutor_ResultTooLargeFailsTask(t *testing.T) {
+	goExecutor := NewGoFuncTaskExecutor()
+	goExecutor.RegisterFunction("extract", func(ctx context.Context) error {
+		return PushResult(ctx, "rows", strings.Repeat("x", 100))
+	})
+
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(goExecutor)
+	manager := results.NewManager(results.NewMemoryStore(), &results.Config{MaxValueSize: 64})
+	exec.SetResults(manager)
+
+	dagModel := &models.DAG{
+		ID:    "dag1",
+		Tasks: []models.Task{{ID: "extract", Type: models.TaskTypeGo}},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	instances, _ := taskRepo.ListByDAGRun(context.Background(), "run1")
+	if len(instances) != 1 || instances[0].State != models.StateFailed {
+		t.Fatalf("Task instances = %+v, want a failed extract task", instances)
+	}
+	if _, err := manager.Pull(context.Background(), "run1", "extract", "rows"); !errors.Is(err, results.ErrNotFound) {
+		t.Errorf("Pull error = %v, want %v", err, results.ErrNotFound)
+	}
+}
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/retry"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// retryStrategy returns the configured retry strategy, defaulting to exponential backoff
+func (c *ExecutorConfig) retryStrategy() retry.Strategy {
+	if c.RetryStrategy == nil {
+		return retry.DefaultExponentialBackoff()
+	}
+	return c.RetryStrategy
+}
+
+// taskRetryConfig returns the retry config of a task instance.
+// The task's own retry policy takes precedence over the executor's fallback strategy.
+func taskRetryConfig(task *models.Task, taskInstance *models.TaskInstance, fallback retry.Strategy) *retry.Config {
+	if task != nil && task.RetryPolicy != nil {
+		config, err := retry.NewConfigFromPolicy(task.RetryPolicy, taskInstance.MaxTries)
+		if err == nil {
+			return config
+		}
+		log.Printf("Ignoring invalid retry policy of task %s: %v", task.ID, err)
+	}
+	return retry.NewConfig(taskInstance.MaxTries, fallback)
+}
+
+// shouldRetry returns true if a failed attempt of the task instance may be retried.
+// Attempts rejected by an open circuit breaker did not run, so they are retried whatever the retry_on list.
+func shouldRetry(config *retry.Config, taskInstance *models.TaskInstance, result *TaskResult) bool {
+	if result.State != models.StateFailed || !config.ShouldRetry(taskInstance.TryNumber) {
+		return false
+	}
+
+	if len(config.RetryOnErrorCodes) == 0 || result.ErrorCode == ErrorCodeCircuitOpen {
+		return true
+	}
+	for _, code := range resultErrorCodes(result) {
+		if config.ShouldRetryError(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// resultErrorCodes returns the codes a retry_on list is matched against: the error code and the exit code
+func resultErrorCodes(result *TaskResult) []string {
+	var codes []string
+	if result.ErrorCode != "" {
+		codes = append(codes, result.ErrorCode)
+	}
+	if result.ExitCode != 0 {
+		codes = append(codes, strconv.Itoa(result.ExitCode))
+	}
+	return codes
+}
+
+// markRetrying moves a failed attempt from running to retrying and bumps its try number.
+// It returns how long to wait before the next attempt: the strategy's delay, but no less than minDelay.
+func markRetrying(ctx context.Context, taskRepo storage.TaskInstanceRepository, taskInstance *models.TaskInstance, strategy retry.Strategy, minDelay time.Duration) (time.Duration, error) {
+	if err := taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRunning, models.StateRetrying); err != nil {
+		return 0, fmt.Errorf("failed to update task state to retrying: %w", err)
+	}
+
+	delay := max(strategy.NextDelay(taskInstance.TryNumber), minDelay)
+
+	taskInstance.State = models.StateRetrying
+	taskInstance.TryNumber++
+	if err := taskRepo.Update(ctx, taskInstance); err != nil {
+		// The retry still happens; only the recorded attempt details are stale
+		log.Printf("Failed to record retry of task instance %s: %v", taskInstance.ID, err)
+	}
+
+	log.Printf("Retrying task %s (attempt %d of %d) in %s", taskInstance.TaskID, taskInstance.TryNumber, taskInstance.MaxTries, delay)
+	return delay, nil
+}
+
+// sendToDLQ records a task instance whose attempts are exhausted in the dead letter queue.
+// Only failed task instances are recorded; cancelled or rescheduled ones are not dead letters.
+func sendToDLQ(ctx context.Context, manager *dlq.Manager, taskInstance *models.TaskInstance, dagModel *models.DAG, errorMessage string) {
+	if manager == nil || taskInstance.State != models.StateFailed {
+		return
+	}
+
+	if err := manager.AddFailedTask(ctx, taskInstance, dagModel, errors.New(errorMessage)); err != nil {
+		log.Printf("Failed to add task instance %s to the dead letter queue: %v", taskInstance.ID, err)
+	}
+}
@@ -0,0 +1,311 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/retry"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// memTaskInstanceRepo stores task instances in memory and records state transitions
+type memTaskInstanceRepo struct {
+	storage.TaskInstanceRepository
+	mu          sync.Mutex
+	instances   map[string]*models.TaskInstance
+	transitions []string
+}
+
+func newMemTaskInstanceRepo() *memTaskInstanceRepo {
+	return &memTaskInstanceRepo{instances: make(map[string]*models.TaskInstance)}
+}
+
+func (r *memTaskInstanceRepo) Create(ctx context.Context, instance *models.TaskInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instance.ID = uuid.New().String()
+	copied := *instance
+	r.instances[instance.ID] = &copied
+	return nil
+}
+
+func (r *memTaskInstanceRepo) Get(ctx context.Context, id string) (*models.TaskInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instance, ok := r.instances[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	copied := *instance
+	return &copied, nil
+}
+
+func (r *memTaskInstanceRepo) Update(ctx context.Context, instance *models.TaskInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *instance
+	r.instances[instance.ID] = &copied
+	return nil
+}
+
+func (r *memTaskInstanceRepo) UpdateState(ctx context.Context, id string, oldState, newState models.State) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instance, ok := r.instances[id]
+	if !ok || instance.State != oldState {
+		return fmt.Errorf("task instance %s is not in state %s", id, oldState)
+	}
+	instance.State = newState
+	if newState == models.StateRunning {
+		now := time.Now()
+		instance.LastHeartbeatAt = &now
+	}
+	r.transitions = append(r.transitions, fmt.Sprintf("%s->%s", oldState, newState))
+	return nil
+}
+
+func (r *memTaskInstanceRepo) Heartbeat(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instance, ok := r.instances[id]
+	if !ok || instance.State != models.StateRunning {
+		return storage.ErrNotFound
+	}
+	now := time.Now()
+	instance.LastHeartbeatAt = &now
+	return nil
+}
+
+func (r *memTaskInstanceRepo) ListByDAGRun(ctx context.Context, dagRunID string) ([]*models.TaskInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var instances []*models.TaskInstance
+	for _, instance := range r.instances {
+		if instance.DAGRunID == dagRunID {
+			copied := *instance
+			instances = append(instances, &copied)
+		}
+	}
+	return instances, nil
+}
+
+func (r *memTaskInstanceRepo) only() *models.TaskInstance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, instance := range r.instances {
+		copied := *instance
+		return &copied
+	}
+	return nil
+}
+
+// memDAGRunRepo only tracks DAG run state
+type memDAGRunRepo struct {
+	storage.DAGRunRepository
+}
+
+func (r *memDAGRunRepo) Get(ctx context.Context, id string) (*models.DAGRun, error) {
+	return nil, storage.ErrNotFound
+}
+
+func (r *memDAGRunRepo) UpdateState(ctx context.Context, id string, oldState, newState models.State) error {
+	return nil
+}
+
+func (r *memDAGRunRepo) Heartbeat(ctx context.Context, id string) error {
+	return nil
+}
+
+// flakyTaskExecutor fails a fixed number of attempts before succeeding
+type flakyTaskExecutor struct {
+	mu         sync.Mutex
+	failures   int
+	exitCode   int
+	errorCode  string
+	retryAfter time.Duration
+	attempts   int
+}
+
+func (e *flakyTaskExecutor) Type() models.TaskType {
+	return models.TaskTypeBash
+}
+
+func (e *flakyTaskExecutor) Execute(ctx context.Context, task *models.Task, taskInstance *models.TaskInstance) *TaskResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.attempts++
+	result := &TaskResult{State: models.StateSuccess, StartTime: time.Now(), EndTime: time.Now()}
+	if e.attempts <= e.failures {
+		result.State = models.StateFailed
+		result.ErrorMessage = fmt.Sprintf("attempt %d failed", e.attempts)
+		result.ExitCode = e.exitCode
+		result.ErrorCode = e.errorCode
+		result.RetryAfter = e.retryAfter
+	}
+	return result
+}
+
+func newRetryTestDAG(retries int) (*models.DAG, *models.DAGRun) {
+	dagModel := &models.DAG{
+		ID:    "dag1",
+		Tasks: []models.Task{{ID: "task1", Type: models.TaskTypeBash, Command: "true", Retries: retries}},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+	return dagModel, dagRun
+}
+
+func TestSequentialExecutor_RetriesFailedTask(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(&flakyTaskExecutor{failures: 1})
+	exec.SetRetryStrategy(retry.NewFixedDelay(time.Millisecond, false))
+
+	dagModel, dagRun := newRetryTestDAG(2)
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if dagRun.State != models.StateSuccess {
+		t.Errorf("DAG run state = %s, want %s", dagRun.State, models.StateSuccess)
+	}
+
+	instance := taskRepo.only()
+	if instance.State != models.StateSuccess || instance.TryNumber != 2 {
+		t.Errorf("Task instance = %s on try %d, want success on try 2", instance.State, instance.TryNumber)
+	}
+
+	want := []string{"queued->running", "running->retrying", "retrying->running", "running->success"}
+	if fmt.Sprint(taskRepo.transitions) != fmt.Sprint(want) {
+		t.Errorf("Transitions = %v, want %v", taskRepo.transitions, want)
+	}
+}
+
+func TestSequentialExecutor_TaskRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		exitCode     int
+		wantAttempts int
+		wantState    models.State
+	}{
+		{name: "retries matching exit code", exitCode: 75, wantAttempts: 2, wantState: models.StateSuccess},
+		{name: "does not retry other exit codes", exitCode: 1, wantAttempts: 1, wantState: models.StateFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskRepo := newMemTaskInstanceRepo()
+			exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+			flaky := &flakyTaskExecutor{failures: 1, exitCode: tt.exitCode}
+			exec.RegisterTaskExecutor(flaky)
+			// The task's own policy must win over the executor's slow default
+			exec.SetRetryStrategy(retry.NewFixedDelay(time.Hour, false))
+
+			dagModel, dagRun := newRetryTestDAG(2)
+			dagModel.Tasks[0].RetryPolicy = &models.RetryPolicy{
+				Strategy:  models.RetryStrategyFixed,
+				BaseDelay: time.Millisecond,
+				RetryOn:   []string{ErrorCodeTimeout, "75"},
+			}
+
+			if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			if flaky.attempts != tt.wantAttempts {
+				t.Errorf("Task ran %d times, want %d", flaky.attempts, tt.wantAttempts)
+			}
+			if instance := taskRepo.only(); instance.State != tt.wantState {
+				t.Errorf("Task instance state = %s, want %s", instance.State, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestSequentialExecutor_RetriesAfterOpenCircuit(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	flaky := &flakyTaskExecutor{failures: 1, errorCode: ErrorCodeCircuitOpen, retryAfter: 50 * time.Millisecond}
+	exec.RegisterTaskExecutor(flaky)
+
+	// Attempts rejected by an open circuit breaker are retried whatever the retry_on list,
+	// once the breaker lets requests through again
+	dagModel, dagRun := newRetryTestDAG(2)
+	dagModel.Tasks[0].RetryPolicy = &models.RetryPolicy{
+		Strategy:  models.RetryStrategyFixed,
+		BaseDelay: time.Millisecond,
+		RetryOn:   []string{"75"},
+	}
+
+	start := time.Now()
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if flaky.attempts != 2 {
+		t.Errorf("Task ran %d times, want 2", flaky.attempts)
+	}
+	if instance := taskRepo.only(); instance.State != models.StateSuccess {
+		t.Errorf("Task instance state = %s, want %s", instance.State, models.StateSuccess)
+	}
+	if elapsed := time.Since(start); elapsed < flaky.retryAfter {
+		t.Errorf("Task was retried after %s, want at least %s", elapsed, flaky.retryAfter)
+	}
+}
+
+func TestLocalExecutor_SendsExhaustedTaskToDLQ(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	config := DefaultExecutorConfig()
+	config.WorkerCount = 1
+	config.RetryStrategy = retry.NewFixedDelay(time.Millisecond, false)
+
+	exec := NewLocalExecutor(taskRepo, &memDAGRunRepo{}, nil, config)
+	flaky := &flakyTaskExecutor{failures: 10}
+	exec.RegisterTaskExecutor(flaky)
+
+	queue := dlq.NewMemoryQueue()
+	exec.SetDLQ(dlq.NewManager(queue, 0))
+
+	done := make(chan models.State, 1)
+	exec.OnDAGRunComplete(func(dagRun *models.DAGRun, finalState models.State) {
+		done <- finalState
+	})
+
+	ctx := context.Background()
+	if err := exec.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer exec.Stop(ctx)
+
+	dagModel, dagRun := newRetryTestDAG(1)
+	if err := exec.Execute(ctx, dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	select {
+	case finalState := <-done:
+		if finalState != models.StateFailed {
+			t.Errorf("DAG run final state = %s, want %s", finalState, models.StateFailed)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("DAG run did not complete")
+	}
+
+	if flaky.attempts != 2 {
+		t.Errorf("Task ran %d times, want 2", flaky.attempts)
+	}
+
+	entries, err := queue.List(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to list DLQ entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Attempts != 2 || entries[0].ErrorMessage != "attempt 2 failed" {
+		t.Errorf("DLQ entries = %+v, want one entry after 2 attempts", entries)
+	}
+}
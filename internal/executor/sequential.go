@@ -2,12 +2,17 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/internal/retry"
 	"github.com/therealutkarshpriyadarshi/dag/internal/state"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
@@ -18,8 +23,15 @@ import (
 type SequentialExecutor struct {
 	taskRepo         storage.TaskInstanceRepository
 	dagRunRepo       storage.DAGRunRepository
-	stateMachine     *state.Machine
+	stateMachine     *state.StateMachine
 	taskExecutors    map[models.TaskType]TaskExecutor
+	onComplete       DAGRunCompleteFunc
+	taskLogRepo      storage.TaskLogRepository
+	retryStrategy    retry.Strategy
+	dlq              *dlq.Manager
+	results          *results.Manager
+	renderer         *render.Renderer
+	active           *activeRuns
 	status           ExecutorStatus
 	mu               sync.RWMutex
 }
@@ -28,13 +40,16 @@ type SequentialExecutor struct {
 func NewSequentialExecutor(
 	taskRepo storage.TaskInstanceRepository,
 	dagRunRepo storage.DAGRunRepository,
-	stateMachine *state.Machine,
+	stateMachine *state.StateMachine,
 ) *SequentialExecutor {
 	return &SequentialExecutor{
 		taskRepo:      taskRepo,
 		dagRunRepo:    dagRunRepo,
 		stateMachine:  stateMachine,
 		taskExecutors: make(map[models.TaskType]TaskExecutor),
+		retryStrategy: retry.DefaultExponentialBackoff(),
+		renderer:      render.NewRenderer(nil),
+		active:        newActiveRuns(),
 		status: ExecutorStatus{
 			Running:       false,
 			ActiveTasks:   0,
@@ -53,6 +68,41 @@ func (e *SequentialExecutor) RegisterTaskExecutor(executor TaskExecutor) {
 	e.taskExecutors[executor.Type()] = executor
 }
 
+// SetTaskLogRepository enables streaming task output into the task log repository
+func (e *SequentialExecutor) SetTaskLogRepository(repo storage.TaskLogRepository) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.taskLogRepo = repo
+}
+
+// SetRetryStrategy sets the strategy used to delay retries of failed tasks
+func (e *SequentialExecutor) SetRetryStrategy(strategy retry.Strategy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retryStrategy = strategy
+}
+
+// SetDLQ sets the dead letter queue that receives tasks whose attempts are exhausted
+func (e *SequentialExecutor) SetDLQ(manager *dlq.Manager) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dlq = manager
+}
+
+// SetResults sets the result manager that stores the values tasks push and serves the values they pull
+func (e *SequentialExecutor) SetResults(manager *results.Manager) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results = manager
+}
+
+// SetTemplateEnv sets the environment variables task command templates may read through .env
+func (e *SequentialExecutor) SetTemplateEnv(names []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.renderer = render.NewRenderer(names)
+}
+
 // Start initializes the executor
 func (e *SequentialExecutor) Start(ctx context.Context) error {
 	e.mu.Lock()
@@ -78,21 +128,26 @@ func (e *SequentialExecutor) GetStatus() ExecutorStatus {
 	return e.status
 }
 
+// OnDAGRunComplete sets a callback invoked when a DAG run reaches a terminal state
+func (e *SequentialExecutor) OnDAGRunComplete(callback DAGRunCompleteFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onComplete = callback
+}
+
 // Execute executes a DAG run sequentially
-func (e *SequentialExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG) error {
+func (e *SequentialExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dagModel *models.DAG) error {
 	// Update DAG run state to running
 	now := time.Now()
 	dagRun.StartDate = &now
 	if err := e.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateQueued, models.StateRunning); err != nil {
 		return fmt.Errorf("failed to update DAG run state: %w", err)
 	}
-
-	// Build dependency graph
-	graph := dag.NewGraph(dag)
+	dagRun.State = models.StateRunning
 
 	// Create task instances for all tasks
 	taskInstances := make(map[string]*models.TaskInstance)
-	for _, task := range dag.Tasks {
+	for _, task := range dagModel.Tasks {
 		taskInstance := &models.TaskInstance{
 			TaskID:    task.ID,
 			DAGRunID:  dagRun.ID,
@@ -101,136 +156,286 @@ func (e *SequentialExecutor) Execute(ctx context.Context, dagRun *models.DAGRun,
 			MaxTries:  task.Retries + 1,
 		}
 
-		created, err := e.taskRepo.Create(ctx, taskInstance)
-		if err != nil {
+		if err := e.taskRepo.Create(ctx, taskInstance); err != nil {
 			return fmt.Errorf("failed to create task instance for %s: %w", task.ID, err)
 		}
-		taskInstances[task.ID] = created
+		taskInstances[task.ID] = taskInstance
 	}
 
+	return e.run(ctx, dagRun, dagModel, newRunProgress(taskInstances))
+}
+
+// Resume executes the remaining tasks of a running DAG run from its persisted task instances.
+// Tasks left running by the previous owner are retried or failed right away, since this executor
+// runs every task itself.
+func (e *SequentialExecutor) Resume(ctx context.Context, dagRun *models.DAGRun, dagModel *models.DAG, taskInstances []*models.TaskInstance) error {
+	progress, err := resumeRunProgress(ctx, e.taskRepo, dagRun, dagModel, taskInstances)
+	if err != nil {
+		return fmt.Errorf("failed to resume DAG run %s: %w", dagRun.ID, err)
+	}
+	dagRun.State = models.StateRunning
+
+	for taskID := range progress.submitted {
+		taskInstance := progress.taskInstances[taskID]
+		if err := reapZombie(ctx, e.taskRepo, taskInstance); err != nil {
+			return fmt.Errorf("failed to reap task %s: %w", taskID, err)
+		}
+		if taskInstance.State != models.StateRetrying {
+			progress.finish(taskID, taskInstance.State)
+		}
+		delete(progress.submitted, taskID)
+	}
+
+	return e.run(ctx, dagRun, dagModel, progress)
+}
+
+// Cancel interrupts the task of a DAG run that is executing and cancels the remaining ones
+func (e *SequentialExecutor) Cancel(ctx context.Context, dagRunID string) error {
+	if !e.active.cancel(dagRunID) {
+		return ErrDAGRunNotActive
+	}
+
+	log.Printf("Cancelling DAG run %s", dagRunID)
+	return nil
+}
+
+// run executes the tasks of a DAG run that have not finished yet in topological order
+func (e *SequentialExecutor) run(ctx context.Context, dagRun *models.DAGRun, dagModel *models.DAG, progress *runProgress) error {
+	cancelled := e.active.add(dagRun.ID)
+	defer e.active.remove(dagRun.ID, cancelled.Done())
+
+	// Renew the lease on the DAG run so that recovery leaves it alone while it executes
+	stopHeartbeat := startHeartbeat(ctx, defaultHeartbeatInterval, "DAG run "+dagRun.ID, func(ctx context.Context) error {
+		return e.dagRunRepo.Heartbeat(ctx, dagRun.ID)
+	})
+	defer stopHeartbeat()
+
+	// Build dependency graph
+	graph := dag.NewGraph(dagModel)
+
 	// Get topological order
-	order, err := dag.TopologicalSort(dag)
+	order, err := dag.NewValidator().GetTopologicalOrder(dagModel)
 	if err != nil {
 		return fmt.Errorf("failed to get topological order: %w", err)
 	}
 
 	// Execute tasks in topological order
-	completedTasks := make(map[string]bool)
-	failedDAGRun := false
-
 	for _, taskID := range order {
-		// Check if upstream tasks failed
-		task := graph.GetTask(taskID)
-		if task == nil {
-			return fmt.Errorf("task %s not found in graph", taskID)
+		if cancelled.Err() == nil && cancelledElsewhere(ctx, e.dagRunRepo, dagRun.ID) {
+			e.active.cancel(dagRun.ID)
 		}
-
-		upstreamFailed := false
-		for _, depID := range task.Dependencies {
-			if !completedTasks[depID] {
-				upstreamFailed = true
-				break
-			}
+		if cancelled.Err() != nil {
+			break
 		}
 
-		taskInstance := taskInstances[taskID]
-
-		if upstreamFailed {
-			// Skip this task due to upstream failure
-			if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateQueued, models.StateUpstreamFailed); err != nil {
-				log.Printf("Failed to update task %s state to upstream_failed: %v", taskID, err)
-			}
+		// Dependencies all finished, so branch tasks and trigger rules decide whether the task runs
+		_, notRun := progress.next(graph)
+		progress.skip(ctx, e.taskRepo, notRun)
+		if progress.completed[taskID] || progress.failed[taskID] {
 			continue
 		}
 
-		// Execute the task
-		if err := e.executeTask(ctx, task, taskInstance); err != nil {
-			log.Printf("Failed to execute task %s: %v", taskID, err)
-			failedDAGRun = true
-			continue
+		task, err := graph.GetTask(taskID)
+		if err != nil {
+			return fmt.Errorf("task %s not found in graph", taskID)
 		}
 
-		// Check if task succeeded
-		updated, err := e.taskRepo.GetByID(ctx, taskInstance.ID)
-		if err != nil {
-			log.Printf("Failed to get updated task instance %s: %v", taskID, err)
+		taskInstance := progress.taskInstances[taskID]
+
+		// Execute the task
+		if err := e.executeTask(ctx, task, taskInstance, dagRun, dagModel); err != nil {
+			log.Printf("Failed to execute task %s: %v", taskID, err)
+			progress.finish(taskID, models.StateFailed)
 			continue
 		}
 
-		if updated.State == models.StateSuccess {
-			completedTasks[taskID] = true
-		} else {
-			failedDAGRun = true
-		}
+		progress.record(TaskCompletion{TaskID: taskID, State: taskInstance.State, Branches: taskInstance.Branches})
 	}
 
 	// Update DAG run final state
 	endTime := time.Now()
 	dagRun.EndDate = &endTime
 
-	finalState := models.StateSuccess
-	if failedDAGRun {
-		finalState = models.StateFailed
+	if cancelled.Err() != nil {
+		progress.cancel(ctx, e.taskRepo)
 	}
+	finalState := progress.finalState()
 
 	if err := e.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateRunning, finalState); err != nil {
-		return fmt.Errorf("failed to update final DAG run state: %w", err)
+		// A run cancelled by another process already is in its final state
+		if finalState != models.StateCancelled || !cancelledElsewhere(ctx, e.dagRunRepo, dagRun.ID) {
+			return fmt.Errorf("failed to update final DAG run state: %w", err)
+		}
+	}
+	dagRun.State = finalState
+	observeDAGRun(dagRun, finalState)
+
+	e.mu.RLock()
+	onComplete := e.onComplete
+	e.mu.RUnlock()
+
+	if onComplete != nil {
+		onComplete(dagRun, finalState)
 	}
 
 	return nil
 }
 
-// executeTask executes a single task
-func (e *SequentialExecutor) executeTask(ctx context.Context, task *models.Task, taskInstance *models.TaskInstance) error {
+// executeTask executes a single task, retrying failed attempts until the retry strategy gives up
+func (e *SequentialExecutor) executeTask(ctx context.Context, task *models.Task, taskInstance *models.TaskInstance, dagRun *models.DAGRun, dagModel *models.DAG) error {
 	e.mu.Lock()
 	executor, ok := e.taskExecutors[task.Type]
+	taskLogRepo := e.taskLogRepo
+	strategy := e.retryStrategy
+	dlqManager := e.dlq
+	resultManager := e.results
+	renderer := e.renderer
 	e.mu.Unlock()
 
+	// Resumed tasks may start from retrying rather than queued
+	fromState := taskInstance.State
+	if fromState == "" {
+		fromState = models.StateQueued
+	}
+
 	if !ok {
-		return fmt.Errorf("no executor registered for task type %s", task.Type)
+		err := fmt.Errorf("no executor registered for task type %s", task.Type)
+		if updateErr := e.taskRepo.UpdateState(ctx, taskInstance.ID, fromState, models.StateFailed); updateErr != nil {
+			log.Printf("Failed to update task state: %v", updateErr)
+			return err
+		}
+		taskInstance.State = models.StateFailed
+		taskInstance.ErrorMessage = err.Error()
+		if updateErr := e.taskRepo.Update(ctx, taskInstance); updateErr != nil {
+			log.Printf("Failed to record error of task %s: %v", task.ID, updateErr)
+		}
+		sendToDLQ(ctx, dlqManager, taskInstance, dagModel, taskInstance.ErrorMessage)
+		return err
+	}
+	for {
+		// Update task state to running
+		if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, fromState, models.StateRunning); err != nil {
+			return fmt.Errorf("failed to update task state to running: %w", err)
+		}
+		taskInstance.State = models.StateRunning
+
+		// Attempts whose command template cannot be rendered fail without running
+		var result *TaskResult
+		attemptTask, err := renderCommand(ctx, renderer, e.taskRepo, resultManager, taskWithDAGDefaults(dagModel, task), taskInstance, dagRun, dagModel)
+		if err != nil {
+			result = templateFailed(err)
+		} else {
+			result = e.runAttempt(ctx, executor, attemptTask, taskInstance, taskLogRepo, resultManager, runParams(dagRun, dagModel))
+		}
+		storeResults(ctx, resultManager, taskInstance, result)
+		observeTaskResult(dagModel.ID, result)
+
+		// Update task instance with result
+		taskInstance.StartDate = &result.StartTime
+		taskInstance.EndDate = &result.EndTime
+		taskInstance.Duration = result.EndTime.Sub(result.StartTime)
+		taskInstance.Hostname = result.Hostname
+		taskInstance.ErrorMessage = result.ErrorMessage
+
+		if retryConfig := taskRetryConfig(task, taskInstance, strategy); shouldRetry(retryConfig, taskInstance, result) {
+			delay, err := markRetrying(ctx, e.taskRepo, taskInstance, retryConfig.Strategy, result.RetryAfter)
+			if err != nil {
+				return err
+			}
+
+			if err := e.waitForRetry(ctx, taskInstance, delay); err != nil {
+				if !errors.Is(err, ErrDAGRunCancelled) {
+					return fmt.Errorf("retry of task %s cancelled: %w", task.ID, err)
+				}
+				// The DAG run was cancelled while the task waited for its next attempt
+				if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRetrying, models.StateCancelled); err != nil {
+					return fmt.Errorf("failed to update task state: %w", err)
+				}
+				taskInstance.State = models.StateCancelled
+				return nil
+			}
+
+			fromState = models.StateRetrying
+			continue
+		}
+
+		e.mu.Lock()
+		if result.State == models.StateSuccess {
+			e.status.CompletedTasks++
+		} else {
+			e.status.FailedTasks++
+		}
+		e.mu.Unlock()
+
+		// Update state in database
+		if err := recordBranches(ctx, e.taskRepo, taskInstance, result); err != nil {
+			log.Printf("Failed to record branches of task %s: %v", task.ID, err)
+		}
+		taskInstance.State = result.State
+		if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRunning, result.State); err != nil {
+			return fmt.Errorf("failed to update task state: %w", err)
+		}
+
+		if result.State == models.StateFailed {
+			sendToDLQ(ctx, dlqManager, taskInstance, dagModel, result.ErrorMessage)
+		}
+
+		return nil
 	}
+}
 
-	// Update task state to running
-	if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateQueued, models.StateRunning); err != nil {
-		return fmt.Errorf("failed to update task state to running: %w", err)
+// waitForRetry waits for the retry delay of a task instance. It returns the cause of the cancellation
+// if ctx or the task's DAG run is cancelled first.
+func (e *SequentialExecutor) waitForRetry(ctx context.Context, taskInstance *models.TaskInstance, delay time.Duration) error {
+	waitCtx, release := e.active.taskContext(ctx, taskInstance.DAGRunID)
+	defer release()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-waitCtx.Done():
+		return context.Cause(waitCtx)
+	case <-timer.C:
+		return nil
 	}
+}
 
+// runAttempt runs a single attempt of a task with its timeout
+func (e *SequentialExecutor) runAttempt(ctx context.Context, executor TaskExecutor, task *models.Task, taskInstance *models.TaskInstance, taskLogRepo storage.TaskLogRepository, resultManager *results.Manager, params map[string]interface{}) *TaskResult {
 	e.mu.Lock()
 	e.status.ActiveTasks++
 	e.mu.Unlock()
 
-	// Execute with timeout
-	taskCtx := ctx
+	defer func() {
+		e.mu.Lock()
+		e.status.ActiveTasks--
+		e.mu.Unlock()
+	}()
+
+	// Execute with timeout, interrupting the task if its DAG run is cancelled
+	taskCtx, releaseTask := e.active.taskContext(ctx, taskInstance.DAGRunID)
+	defer releaseTask()
 	if task.Timeout > 0 {
 		var cancel context.CancelFunc
-		taskCtx, cancel = context.WithTimeout(ctx, task.Timeout)
+		taskCtx, cancel = context.WithTimeout(taskCtx, task.Timeout)
 		defer cancel()
 	}
 
-	// Execute the task
-	result := executor.Execute(taskCtx, task, taskInstance)
+	// Renew the task's lease while it runs so that it is not reaped as a zombie
+	stopHeartbeat := startHeartbeat(ctx, defaultHeartbeatInterval, "task instance "+taskInstance.ID, func(ctx context.Context) error {
+		return e.taskRepo.Heartbeat(ctx, taskInstance.ID)
+	})
+	defer stopHeartbeat()
 
-	e.mu.Lock()
-	e.status.ActiveTasks--
-	if result.State == models.StateSuccess {
-		e.status.CompletedTasks++
-	} else {
-		e.status.FailedTasks++
-	}
-	e.mu.Unlock()
-
-	// Update task instance with result
-	taskInstance.State = result.State
-	taskInstance.StartDate = &result.StartTime
-	taskInstance.EndDate = &result.EndTime
-	taskInstance.Duration = result.EndTime.Sub(result.StartTime)
-	taskInstance.Hostname = result.Hostname
-	taskInstance.ErrorMessage = result.ErrorMessage
-
-	// Update state in database
-	if err := e.taskRepo.UpdateState(ctx, taskInstance.ID, models.StateRunning, result.State); err != nil {
-		return fmt.Errorf("failed to update task state: %w", err)
-	}
+	// Execute the task, streaming its output to the task logs
+	taskCtx, closeLogs := attachLogSink(taskCtx, taskLogRepo, taskInstance.ID, nil)
+	defer closeLogs()
+	taskCtx, taskResults := attachResults(taskCtx, resultManager, taskInstance)
+	taskCtx = WithParams(taskCtx, params)
 
-	return nil
+	result := executor.Execute(taskCtx, task, taskInstance)
+	markCancelled(taskCtx, result)
+	taskResults.collect(result)
+	return result
 }
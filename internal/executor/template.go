@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// templateContext returns the context a task attempt's command is rendered in. Results are pulled from
+// the result manager, which may be nil.
+func templateContext(ctx context.Context, manager *results.Manager, taskInstance *models.TaskInstance, dagRun *models.DAGRun, dagModel *models.DAG) *render.Context {
+	c := &render.Context{
+		DAGRunID:  taskInstance.DAGRunID,
+		TaskID:    taskInstance.TaskID,
+		TryNumber: taskInstance.TryNumber,
+		Params:    runParams(dagRun, dagModel),
+	}
+	if dagRun != nil {
+		c.DAGID = dagRun.DAGID
+		c.ExecutionDate = dagRun.ExecutionDate
+	}
+	if manager != nil {
+		dagRunID := taskInstance.DAGRunID
+		c.Result = func(taskID, key string) (json.RawMessage, error) {
+			return manager.Pull(ctx, dagRunID, taskID, key)
+		}
+	}
+	return c
+}
+
+// renderCommand renders the command template of a task attempt and records the rendered command on its
+// task instance. It returns the task to execute, a copy of task when the command is a template.
+// Go tasks name a registered function rather than running a command, so they are never rendered.
+func renderCommand(ctx context.Context, renderer *render.Renderer, taskRepo storage.TaskInstanceRepository, manager *results.Manager, task *models.Task, taskInstance *models.TaskInstance, dagRun *models.DAGRun, dagModel *models.DAG) (*models.Task, error) {
+	if task.Type == models.TaskTypeGo || !render.IsTemplate(task.Command) {
+		return task, nil
+	}
+
+	command, err := renderer.Render(task.Command, templateContext(ctx, manager, taskInstance, dagRun, dagModel))
+	if err != nil {
+		return nil, err
+	}
+
+	taskInstance.RenderedCommand = command
+	if err := taskRepo.Update(ctx, taskInstance); err != nil {
+		log.Printf("Failed to record rendered command of task %s: %v", task.ID, err)
+	}
+
+	rendered := *task
+	rendered.Command = command
+	return &rendered, nil
+}
+
+// templateFailed returns the result of a task attempt whose command template could not be rendered,
+// which fails without running
+func templateFailed(err error) *TaskResult {
+	now := time.Now()
+	hostname, _ := os.Hostname()
+	return &TaskResult{
+		State:        models.StateFailed,
+		ErrorCode:    ErrorCodeInvalidTemplate,
+		ErrorMessage: err.Error(),
+		StartTime:    now,
+		EndTime:      now,
+		Hostname:     hostname,
+	}
+}
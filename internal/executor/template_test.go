@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestSequentialExecutor_RendersCommandTemplates(t *testing.T) {
+	goExecutor := NewGoFuncTaskExecutor()
+	goExecutor.RegisterFunction("extract", func(ctx context.Context) error {
+		return PushResult(ctx, "rows", 42)
+	})
+
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(goExecutor)
+	exec.RegisterTaskExecutor(NewBashTaskExecutor())
+	exec.SetResults(results.NewManager(results.NewMemoryStore(), nil))
+
+	dagModel := &models.DAG{
+		ID:     "dag1",
+		Params: []models.Param{{Name: "table", Default: "events"}},
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeGo},
+			{
+				ID:           "load",
+				Type:         models.TaskTypeBash,
+				Command:      `echo {{ .params.table }} {{ .ds }} {{ result "extract" "rows" }} {{ .try_number }}`,
+				Dependencies: []string{"extract"},
+			},
+			{ID: "report", Type: models.TaskTypeBash, Command: "echo done", Dependencies: []string{"load"}},
+		},
+	}
+	dagRun := &models.DAGRun{
+		ID:            "run1",
+		DAGID:         "dag1",
+		State:         models.StateQueued,
+		ExecutionDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+	}
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if dagRun.State != models.StateSuccess {
+		t.Fatalf("DAG run state = %s, want %s", dagRun.State, models.StateSuccess)
+	}
+
+	instances, _ := taskRepo.ListByDAGRun(context.Background(), "run1")
+	rendered := make(map[string]string)
+	for _, instance := range instances {
+		rendered[instance.TaskID] = instance.RenderedCommand
+	}
+	if want := "echo events 2024-03-05 42 1"; rendered["load"] != want {
+		t.Errorf("Rendered command of load = %q, want %q", rendered["load"], want)
+	}
+	// Commands that are not templates are not recorded
+	if rendered["report"] != "" {
+		t.Errorf("Rendered command of report = %q, want none", rendered["report"])
+	}
+}
+
+func TestSequentialExecutor_InvalidTemplateFailsTask(t *testing.T) {
+	taskRepo := newMemTaskInstanceRepo()
+	exec := NewSequentialExecutor(taskRepo, &memDAGRunRepo{}, nil)
+	exec.RegisterTaskExecutor(NewBashTaskExecutor())
+
+	dagModel := &models.DAG{
+		ID:    "dag1",
+		Tasks: []models.Task{{ID: "load", Type: models.TaskTypeBash, Command: "echo {{ .params.missing }}"}},
+	}
+	dagRun := &models.DAGRun{ID: "run1", DAGID: "dag1", State: models.StateQueued}
+	if err := exec.Execute(context.Background(), dagRun, dagModel); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	instances, _ := taskRepo.ListByDAGRun(context.Background(), "run1")
+	if len(instances) != 1 || instances[0].State != models.StateFailed || instances[0].RenderedCommand != "" {
+		t.Fatalf("Task instances = %+v, want a failed load task without a rendered command", instances)
+	}
+}
@@ -3,14 +3,17 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
@@ -23,13 +26,34 @@ type Worker struct {
 	taskExecutors map[models.TaskType]TaskExecutor
 	config        *ExecutorConfig
 
-	taskSub      *nats.Subscription
-	activeTasks  int
-	mu           sync.RWMutex
-	running      bool
-	wg           sync.WaitGroup
+	taskSub     *nats.Subscription
+	cancelSub   *nats.Subscription
+	activeTasks map[string]*activeTask // Task instance ID -> task being run
+	cancelled   map[string]time.Time   // Task instance ID -> when its cancellation arrived before the task
+	mu          sync.RWMutex
+	running     bool
+	wg          sync.WaitGroup
 }
 
+// activeTask is a task a worker is running
+type activeTask struct {
+	msg    *nats.Msg
+	cancel context.CancelCauseFunc
+}
+
+// cancelledTaskRetention is how long a worker remembers the cancellation of a task it has not received,
+// in case the task is delivered to it later
+const cancelledTaskRetention = time.Hour
+
+// resultPullTimeout bounds how long a task waits for the control plane to serve a value it pulls
+const resultPullTimeout = 10 * time.Second
+
+// taskFetchWait is how long a worker waits for a pending task before fetching again
+const taskFetchWait = 5 * time.Second
+
+// redeliveryCheckTimeout bounds how long a worker waits for the control plane to decide on a redelivered task
+const redeliveryCheckTimeout = 10 * time.Second
+
 // NewWorker creates a new distributed worker
 func NewWorker(natsURL string, config *ExecutorConfig) (*Worker, error) {
 	if config == nil {
@@ -59,7 +83,8 @@ func NewWorker(natsURL string, config *ExecutorConfig) (*Worker, error) {
 		js:            js,
 		taskExecutors: make(map[models.TaskType]TaskExecutor),
 		config:        config,
-		activeTasks:   0,
+		activeTasks:   make(map[string]*activeTask),
+		cancelled:     make(map[string]time.Time),
 		running:       false,
 	}, nil
 }
@@ -82,20 +107,32 @@ func (w *Worker) Start(ctx context.Context) error {
 
 	w.running = true
 
-	// Subscribe to pending tasks with multiple workers
+	// Pull pending tasks from the consumer shared by all workers.
+	// Messages of running tasks are kept in progress by heartbeats, so a message is only
+	// redelivered once the worker holding it stopped for a whole lease. Tasks run one at a time and
+	// a worker only fetches a message when it is idle, so messages never wait behind a long task
+	// until their ack deadline passes.
 	var err error
-	w.taskSub, err = w.js.QueueSubscribe(
+	w.taskSub, err = w.js.PullSubscribe(
 		TasksPendingSubject,
 		"workers",
-		w.handleTask,
-		nats.Durable("workers"),
 		nats.ManualAck(),
-		nats.AckWait(5*time.Minute),
+		nats.AckWait(w.config.leaseTimeout()),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to tasks: %w", err)
 	}
 
+	// Every worker hears every cancellation, since any of them may be running or about to receive the task
+	w.cancelSub, err = w.nc.Subscribe(TaskCancelSubjectPrefix+"*", w.handleCancel)
+	if err != nil {
+		w.taskSub.Unsubscribe()
+		return fmt.Errorf("failed to subscribe to task cancellations: %w", err)
+	}
+
+	w.wg.Add(1)
+	go w.fetchTasks(ctx, w.taskSub)
+
 	// Start heartbeat goroutine
 	w.wg.Add(1)
 	go w.sendHeartbeats(ctx)
@@ -120,6 +157,9 @@ func (w *Worker) Stop(ctx context.Context) error {
 	if w.taskSub != nil {
 		w.taskSub.Unsubscribe()
 	}
+	if w.cancelSub != nil {
+		w.cancelSub.Unsubscribe()
+	}
 
 	// Wait for active tasks to complete
 	done := make(chan struct{})
@@ -142,6 +182,36 @@ func (w *Worker) Stop(ctx context.Context) error {
 	return nil
 }
 
+// fetchTasks runs the pending tasks it fetches one at a time until ctx is done or the worker stops
+func (w *Worker) fetchTasks(ctx context.Context, sub *nats.Subscription) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		msgs, err := sub.Fetch(1, nats.MaxWait(taskFetchWait))
+		if err != nil {
+			if errors.Is(err, nats.ErrTimeout) {
+				continue
+			}
+			if !sub.IsValid() {
+				return
+			}
+			log.Printf("Failed to fetch tasks: %v", err)
+			time.Sleep(taskFetchWait)
+			continue
+		}
+
+		for _, msg := range msgs {
+			w.handleTask(msg)
+		}
+	}
+}
+
 // handleTask processes a single task from the queue
 func (w *Worker) handleTask(msg *nats.Msg) {
 	var taskMsg TaskMessage
@@ -153,6 +223,29 @@ func (w *Worker) handleTask(msg *nats.Msg) {
 
 	log.Printf("Worker %s received task %s (type: %s)", w.id, taskMsg.TaskID, taskMsg.TaskType)
 
+	// A redelivered task may have been handed to a worker that died or could not report its result,
+	// or may only have waited too long in a worker's queue. The control plane tells which: running
+	// a task that was started could duplicate its side effects, so such attempts are reported as
+	// lost and the control plane decides whether to retry them.
+	if meta, err := msg.Metadata(); err == nil && meta.NumDelivered > 1 {
+		action, err := w.checkRedelivery(&taskMsg)
+		if err != nil {
+			log.Printf("Failed to check redelivery of task %s: %v", taskMsg.TaskID, err)
+			msg.NakWithDelay(w.config.heartbeatInterval())
+			return
+		}
+		switch action {
+		case RedeliveryDrop:
+			log.Printf("Task %s was redelivered (delivery %d) but its attempt is concluded or still running, dropping it", taskMsg.TaskID, meta.NumDelivered)
+			msg.Ack()
+			return
+		case RedeliveryLost:
+			w.reportLost(msg, &taskMsg, meta.NumDelivered)
+			return
+		}
+		log.Printf("Task %s was redelivered (delivery %d) before any worker started it, running it", taskMsg.TaskID, meta.NumDelivered)
+	}
+
 	// Get task executor
 	w.mu.RLock()
 	executor, ok := w.taskExecutors[models.TaskType(taskMsg.TaskType)]
@@ -165,6 +258,7 @@ func (w *Worker) handleTask(msg *nats.Msg) {
 			WorkerID:       w.id,
 			State:          string(models.StateFailed),
 			ErrorMessage:   fmt.Sprintf("No executor for task type: %s", taskMsg.TaskType),
+			TryNumber:      taskMsg.TryNumber,
 			StartTime:      time.Now(),
 			EndTime:        time.Now(),
 			Hostname:       w.hostname,
@@ -174,13 +268,45 @@ func (w *Worker) handleTask(msg *nats.Msg) {
 		return
 	}
 
-	// Increment active tasks
+	// Track the task so heartbeats report it and cancellations reach it, unless it was cancelled
+	// before it arrived, and tell the control plane right away
+	ctx, cancelTask := context.WithCancelCause(context.Background())
+	defer cancelTask(nil)
+
 	w.mu.Lock()
-	w.activeTasks++
+	_, cancelled := w.cancelled[taskMsg.TaskInstanceID]
+	if cancelled {
+		delete(w.cancelled, taskMsg.TaskInstanceID)
+	} else {
+		w.activeTasks[taskMsg.TaskInstanceID] = &activeTask{msg: msg, cancel: cancelTask}
+	}
 	w.mu.Unlock()
 
+	if cancelled {
+		log.Printf("Task %s was cancelled before it started", taskMsg.TaskID)
+		now := time.Now()
+		result := &TaskResultMessage{
+			TaskInstanceID: taskMsg.TaskInstanceID,
+			WorkerID:       w.id,
+			State:          string(models.StateCancelled),
+			ErrorMessage:   "task was cancelled before it started",
+			ErrorCode:      ErrorCodeCancelled,
+			TryNumber:      taskMsg.TryNumber,
+			StartTime:      now,
+			EndTime:        now,
+			Hostname:       w.hostname,
+		}
+		if err := w.publishResult(result); err != nil {
+			log.Printf("Failed to publish result: %v", err)
+			msg.Nak()
+			return
+		}
+		msg.Ack()
+		return
+	}
+	w.publishHeartbeat()
+
 	// Execute task
-	ctx := context.Background()
 	if taskMsg.Timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, taskMsg.Timeout)
@@ -188,24 +314,35 @@ func (w *Worker) handleTask(msg *nats.Msg) {
 	}
 
 	task := &models.Task{
-		ID:      taskMsg.TaskID,
-		Type:    models.TaskType(taskMsg.TaskType),
-		Command: taskMsg.Command,
-		Timeout: taskMsg.Timeout,
-		Retries: taskMsg.Retries,
+		ID:             taskMsg.TaskID,
+		Type:           models.TaskType(taskMsg.TaskType),
+		Command:        taskMsg.Command,
+		Timeout:        taskMsg.Timeout,
+		Retries:        taskMsg.Retries,
+		CircuitBreaker: taskMsg.CircuitBreaker,
+		Branch:         taskMsg.Branch,
+		ResultPaths:    taskMsg.ResultPaths,
 	}
 
 	taskInstance := &models.TaskInstance{
-		ID:       taskMsg.TaskInstanceID,
-		TaskID:   taskMsg.TaskID,
-		DAGRunID: taskMsg.DAGRunID,
+		ID:        taskMsg.TaskInstanceID,
+		TaskID:    taskMsg.TaskID,
+		DAGRunID:  taskMsg.DAGRunID,
+		TryNumber: taskMsg.TryNumber,
+		WorkerID:  w.id,
 	}
 
-	result := executor.Execute(ctx, task, taskInstance)
+	// Stream output back to the control plane while the task runs, pulling results through it
+	logSink := w.newLogSink(taskMsg.TaskInstanceID)
+	taskResults := NewTaskResults(w.pullResult(taskMsg.DAGRunID))
+	taskCtx := WithParams(WithTaskResults(WithLogSink(ctx, logSink), taskResults), taskMsg.Params)
+	result := executor.Execute(taskCtx, task, taskInstance)
+	logSink.Close()
+	markCancelled(ctx, result)
+	taskResults.collect(result)
 
-	// Decrement active tasks
 	w.mu.Lock()
-	w.activeTasks--
+	delete(w.activeTasks, taskMsg.TaskInstanceID)
 	w.mu.Unlock()
 
 	// Publish result
@@ -215,6 +352,12 @@ func (w *Worker) handleTask(msg *nats.Msg) {
 		State:          string(result.State),
 		Output:         result.Output,
 		ErrorMessage:   result.ErrorMessage,
+		ExitCode:       result.ExitCode,
+		ErrorCode:      result.ErrorCode,
+		RetryAfter:     result.RetryAfter,
+		TryNumber:      taskMsg.TryNumber,
+		Branches:       result.Branches,
+		Results:        result.Results,
 		StartTime:      result.StartTime,
 		EndTime:        result.EndTime,
 		Hostname:       result.Hostname,
@@ -230,6 +373,82 @@ func (w *Worker) handleTask(msg *nats.Msg) {
 	log.Printf("Worker %s completed task %s with state %s", w.id, taskMsg.TaskID, result.State)
 }
 
+// reportLost reports the attempt of a redelivered task whose worker died as lost instead of running it again
+func (w *Worker) reportLost(msg *nats.Msg, taskMsg *TaskMessage, numDelivered uint64) {
+	log.Printf("Task %s was redelivered (delivery %d) after its worker stopped, reporting the attempt as lost", taskMsg.TaskID, numDelivered)
+	now := time.Now()
+	result := &TaskResultMessage{
+		TaskInstanceID: taskMsg.TaskInstanceID,
+		WorkerID:       w.id,
+		State:          string(models.StateFailed),
+		ErrorMessage:   "task was redelivered after its worker stopped",
+		ErrorCode:      ErrorCodeWorkerLost,
+		TryNumber:      taskMsg.TryNumber,
+		StartTime:      now,
+		EndTime:        now,
+		Hostname:       w.hostname,
+	}
+	if err := w.publishResult(result); err != nil {
+		log.Printf("Failed to publish result: %v", err)
+		msg.Nak()
+		return
+	}
+	msg.Ack()
+}
+
+// checkRedelivery asks the control plane what to do with on a redelivered task message
+func (w *Worker) checkRedelivery(taskMsg *TaskMessage) (string, error) {
+	data, err := json.Marshal(&RedeliveryCheck{
+		TaskInstanceID: taskMsg.TaskInstanceID,
+		TryNumber:      taskMsg.TryNumber,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal redelivery check: %w", err)
+	}
+
+	msg, err := w.nc.Request(TaskRedeliveredSubject, data, redeliveryCheckTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to request redelivery check: %w", err)
+	}
+
+	var reply RedeliveryReply
+	if err := json.Unmarshal(msg.Data, &reply); err != nil {
+		return "", fmt.Errorf("failed to unmarshal redelivery reply: %w", err)
+	}
+	if reply.Error != "" {
+		return "", errors.New(reply.Error)
+	}
+	return reply.Action, nil
+}
+
+// handleCancel interrupts a task cancelled by the control plane if this worker runs it,
+// and otherwise remembers the cancellation in case the task is delivered here later
+func (w *Worker) handleCancel(msg *nats.Msg) {
+	taskInstanceID := strings.TrimPrefix(msg.Subject, TaskCancelSubjectPrefix)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if task, ok := w.activeTasks[taskInstanceID]; ok {
+		log.Printf("Worker %s cancelling task instance %s", w.id, taskInstanceID)
+		task.cancel(ErrDAGRunCancelled)
+		return
+	}
+	w.cancelled[taskInstanceID] = time.Now()
+}
+
+// forgetCancellations drops cancellations of tasks that were not delivered within the retention period
+func (w *Worker) forgetCancellations() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for taskInstanceID, cancelledAt := range w.cancelled {
+		if time.Since(cancelledAt) > cancelledTaskRetention {
+			delete(w.cancelled, taskInstanceID)
+		}
+	}
+}
+
 // publishResult publishes a task result to NATS
 func (w *Worker) publishResult(result *TaskResultMessage) error {
 	data, err := json.Marshal(result)
@@ -237,6 +456,19 @@ func (w *Worker) publishResult(result *TaskResultMessage) error {
 		return fmt.Errorf("failed to marshal result: %w", err)
 	}
 
+	// Values too large for a NATS message cannot reach the result store, which fails the attempt
+	if int64(len(data)) > w.nc.MaxPayload() && len(result.Results) > 0 {
+		failed := *result
+		failed.Results = nil
+		failed.Output = ""
+		if failed.State == string(models.StateSuccess) {
+			failed.State = string(models.StateFailed)
+			failed.ErrorCode = ErrorCodeInvalidResult
+			failed.ErrorMessage = fmt.Sprintf("Task results take %d bytes, more than the largest NATS message", len(data))
+		}
+		return w.publishResult(&failed)
+	}
+
 	_, err = w.js.Publish(TasksResultsSubject, data)
 	if err != nil {
 		return fmt.Errorf("failed to publish result: %w", err)
@@ -245,11 +477,78 @@ func (w *Worker) publishResult(result *TaskResultMessage) error {
 	return nil
 }
 
+// pullResult returns a puller requesting values pushed by tasks of a DAG run from the control plane
+func (w *Worker) pullResult(dagRunID string) ResultPuller {
+	return func(ctx context.Context, taskID, key string) (json.RawMessage, error) {
+		data, err := json.Marshal(&ResultPullRequest{
+			DAGRunID: dagRunID,
+			TaskID:   taskID,
+			Key:      key,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal result pull: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, resultPullTimeout)
+		defer cancel()
+
+		msg, err := w.nc.RequestWithContext(ctx, ResultsPullSubject, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to request result: %w", err)
+		}
+
+		var reply ResultPullReply
+		if err := json.Unmarshal(msg.Data, &reply); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal result pull reply: %w", err)
+		}
+		switch {
+		case reply.NotFound:
+			return nil, results.ErrNotFound
+		case reply.Error != "":
+			return nil, errors.New(reply.Error)
+		}
+		return reply.Value, nil
+	}
+}
+
+// newLogSink creates a log sink that publishes task output to NATS in batches
+func (w *Worker) newLogSink(taskInstanceID string) *BatchLogSink {
+	return NewBatchLogSink(func(lines []LogLine) error {
+		return w.publishLogs(taskInstanceID, lines)
+	}, w.config.LogSink)
+}
+
+// publishLogs publishes a batch of log lines, splitting it if it exceeds the NATS payload limit
+func (w *Worker) publishLogs(taskInstanceID string, lines []LogLine) error {
+	data, err := json.Marshal(&TaskLogMessage{
+		TaskInstanceID: taskInstanceID,
+		WorkerID:       w.id,
+		Lines:          lines,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal task logs: %w", err)
+	}
+
+	if int64(len(data)) > w.nc.MaxPayload() && len(lines) > 1 {
+		half := len(lines) / 2
+		if err := w.publishLogs(taskInstanceID, lines[:half]); err != nil {
+			return err
+		}
+		return w.publishLogs(taskInstanceID, lines[half:])
+	}
+
+	if err := w.nc.Publish(TaskLogsSubjectPrefix+taskInstanceID, data); err != nil {
+		return fmt.Errorf("failed to publish task logs: %w", err)
+	}
+
+	return nil
+}
+
 // sendHeartbeats sends periodic heartbeats to the executor
 func (w *Worker) sendHeartbeats(ctx context.Context) {
 	defer w.wg.Done()
 
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(w.config.heartbeatInterval())
 	defer ticker.Stop()
 
 	for {
@@ -258,31 +557,53 @@ func (w *Worker) sendHeartbeats(ctx context.Context) {
 			return
 		case <-ticker.C:
 			w.mu.RLock()
-			if !w.running {
-				w.mu.RUnlock()
+			running := w.running
+			w.mu.RUnlock()
+			if !running {
 				return
 			}
-			activeTasks := w.activeTasks
-			w.mu.RUnlock()
 
-			heartbeat := &WorkerHeartbeat{
-				WorkerID:    w.id,
-				Hostname:    w.hostname,
-				ActiveTasks: activeTasks,
-				Timestamp:   time.Now(),
-			}
+			w.publishHeartbeat()
+			w.forgetCancellations()
+		}
+	}
+}
 
-			data, err := json.Marshal(heartbeat)
-			if err != nil {
-				log.Printf("Failed to marshal heartbeat: %v", err)
-				continue
-			}
+// publishHeartbeat reports the worker and the task instances it runs to the executor,
+// and keeps the JetStream messages of those tasks from being redelivered
+func (w *Worker) publishHeartbeat() {
+	w.mu.RLock()
+	taskInstanceIDs := make([]string, 0, len(w.activeTasks))
+	msgs := make([]*nats.Msg, 0, len(w.activeTasks))
+	for id, task := range w.activeTasks {
+		taskInstanceIDs = append(taskInstanceIDs, id)
+		msgs = append(msgs, task.msg)
+	}
+	w.mu.RUnlock()
 
-			if err := w.nc.Publish(WorkerHeartbeatSubject, data); err != nil {
-				log.Printf("Failed to publish heartbeat: %v", err)
-			}
+	for _, msg := range msgs {
+		if err := msg.InProgress(); err != nil {
+			log.Printf("Failed to extend ack deadline of task message: %v", err)
 		}
 	}
+
+	heartbeat := &WorkerHeartbeat{
+		WorkerID:        w.id,
+		Hostname:        w.hostname,
+		ActiveTasks:     len(taskInstanceIDs),
+		TaskInstanceIDs: taskInstanceIDs,
+		Timestamp:       time.Now(),
+	}
+
+	data, err := json.Marshal(heartbeat)
+	if err != nil {
+		log.Printf("Failed to marshal heartbeat: %v", err)
+		return
+	}
+
+	if err := w.nc.Publish(WorkerHeartbeatSubject, data); err != nil {
+		log.Printf("Failed to publish heartbeat: %v", err)
+	}
 }
 
 // GetID returns the worker ID
@@ -294,5 +615,5 @@ func (w *Worker) GetID() string {
 func (w *Worker) GetActiveTasks() int {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
-	return w.activeTasks
+	return len(w.activeTasks)
 }
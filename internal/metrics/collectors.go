@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"gorm.io/gorm"
+)
+
+// collectTimeout bounds the queries made while a scrape is served
+const collectTimeout = 5 * time.Second
+
+// RegisterGauge registers a gauge whose value is read from value on every scrape
+func RegisterGauge(name, help string, value func() float64) {
+	Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      name,
+		Help:      help,
+	}, value))
+}
+
+// RegisterDLQ exports the number of entries in a dead letter queue
+func RegisterDLQ(queue dlq.Queue) {
+	RegisterGauge("dlq_entries", "Number of task instances in the dead letter queue.", func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+		defer cancel()
+
+		count, err := queue.Count(ctx)
+		if err != nil {
+			log.Printf("Failed to count dead letter queue entries: %v", err)
+			return 0
+		}
+		return float64(count)
+	})
+}
+
+// breakers exports the state of every registered circuit breaker
+var breakers = &breakerCollector{
+	breakers: make(map[string]*circuitbreaker.CircuitBreaker),
+	state: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "circuit_breaker", "state"),
+		"Current state of a circuit breaker; 1 for the state it is in, 0 for the others.",
+		[]string{"name", "state"}, nil),
+	failures: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "circuit_breaker", "consecutive_failures"),
+		"Consecutive failures seen by a circuit breaker.",
+		[]string{"name"}, nil),
+}
+
+// RegisterCircuitBreaker exports the state of a circuit breaker under the given name
+func RegisterCircuitBreaker(name string, cb *circuitbreaker.CircuitBreaker) {
+	breakers.mu.Lock()
+	breakers.breakers[name] = cb
+	breakers.mu.Unlock()
+
+	Register(breakers)
+}
+
+type breakerCollector struct {
+	mu       sync.RWMutex
+	breakers map[string]*circuitbreaker.CircuitBreaker
+	state    *prometheus.Desc
+	failures *prometheus.Desc
+}
+
+func (c *breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+	ch <- c.failures
+}
+
+func (c *breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	states := []circuitbreaker.State{circuitbreaker.StateClosed, circuitbreaker.StateOpen, circuitbreaker.StateHalfOpen}
+	for name, cb := range c.breakers {
+		stats := cb.GetStats()
+		for _, state := range states {
+			value := 0.0
+			if stats.State == state {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, name, state.String())
+		}
+		ch <- prometheus.MustNewConstMetric(c.failures, prometheus.GaugeValue, float64(stats.ConsecutiveFailures), name)
+	}
+}
+
+// RegisterStateCounts exports the number of DAG runs and task instances in each state, by DAG, read from the database
+func RegisterStateCounts(db *gorm.DB) {
+	Register(&stateCountCollector{
+		db: db,
+		dagRuns: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "dag_runs"),
+			"Number of DAG runs, by DAG and state.", []string{"dag_id", "state"}, nil),
+		taskInstances: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "task_instances"),
+			"Number of task instances, by DAG and state.", []string{"dag_id", "state"}, nil),
+	})
+}
+
+type stateCountCollector struct {
+	db            *gorm.DB
+	dagRuns       *prometheus.Desc
+	taskInstances *prometheus.Desc
+}
+
+// stateCount is a row of a count of DAG runs or task instances grouped by DAG and state
+type stateCount struct {
+	DAGID string
+	State string
+	Count int64
+}
+
+func (c *stateCountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.dagRuns
+	ch <- c.taskInstances
+}
+
+func (c *stateCountCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	queries := []struct {
+		desc  *prometheus.Desc
+		query string
+	}{
+		{c.dagRuns, "SELECT dag_id, state, COUNT(*) AS count FROM dag_runs GROUP BY dag_id, state"},
+		{c.taskInstances, "SELECT dag_runs.dag_id, task_instances.state, COUNT(*) AS count FROM task_instances " +
+			"JOIN dag_runs ON dag_runs.id = task_instances.dag_run_id GROUP BY dag_runs.dag_id, task_instances.state"},
+	}
+
+	for _, q := range queries {
+		var counts []stateCount
+		if err := c.db.WithContext(ctx).Raw(q.query).Scan(&counts).Error; err != nil {
+			log.Printf("Failed to count states for metrics: %v", err)
+			continue
+		}
+		for _, count := range counts {
+			ch <- prometheus.MustNewConstMetric(q.desc, prometheus.GaugeValue, float64(count.Count), count.DAGID, count.State)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package metrics exports Prometheus metrics of the server, scheduler and worker
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// Namespace prefixes every metric name
+const Namespace = "workflow"
+
+// durationBuckets cover tasks and DAG runs from a second to a few hours
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200, 14400}
+
+var (
+	dagRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "dag_run_duration_seconds",
+		Help:      "Duration of finished DAG runs, by DAG and final state.",
+		Buckets:   durationBuckets,
+	}, []string{"dag_id", "state"})
+
+	taskInstanceDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "task_instance_duration_seconds",
+		Help:      "Duration of finished task attempts, by DAG and state.",
+		Buckets:   durationBuckets,
+	}, []string{"dag_id", "state"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of API requests, by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// ObserveDAGRun records a DAG run that reached its final state after running for duration
+func ObserveDAGRun(dagID string, finalState models.State, duration time.Duration) {
+	dagRunDuration.WithLabelValues(dagID, string(finalState)).Observe(duration.Seconds())
+}
+
+// ObserveTaskInstance records a task attempt that ended in the given state after running for duration
+func ObserveTaskInstance(dagID string, state models.State, duration time.Duration) {
+	taskInstanceDuration.WithLabelValues(dagID, string(state)).Observe(duration.Seconds())
+}
+
+// ObserveHTTPRequest records the latency of an API request
+func ObserveHTTPRequest(method, route, status string, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, status).Observe(duration.Seconds())
+}
+
+// Handler returns the HTTP handler serving every registered metric
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve serves /metrics on addr until ctx is done, for binaries without an HTTP server
+func Serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving metrics on %s/metrics", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Failed to serve metrics: %v", err)
+	}
+}
+
+// Register registers a collector, ignoring collectors that are already registered
+func Register(collector prometheus.Collector) {
+	if err := prometheus.Register(collector); err != nil {
+		var alreadyRegistered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &alreadyRegistered) {
+			log.Printf("Failed to register metrics collector: %v", err)
+		}
+	}
+}
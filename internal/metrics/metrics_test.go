@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// scrape returns the text served by the metrics handler
+func scrape(t *testing.T) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	return recorder.Body.String()
+}
+
+func TestHandler_ExportsRegisteredMetrics(t *testing.T) {
+	ObserveDAGRun("metrics-test-dag", models.StateSuccess, 3*time.Second)
+	ObserveTaskInstance("metrics-test-dag", models.StateFailed, time.Second)
+	ObserveHTTPRequest("GET", "/api/v1/dags/:id", "200", 10*time.Millisecond)
+
+	queue := dlq.NewMemoryQueue()
+	if err := queue.Add(context.Background(), &dlq.Entry{ID: "entry-1", TaskInstanceID: "ti-1"}); err != nil {
+		t.Fatalf("Failed to add DLQ entry: %v", err)
+	}
+	RegisterDLQ(queue)
+
+	cb := circuitbreaker.New(&circuitbreaker.Config{MaxFailures: 1, Timeout: time.Minute})
+	cb.Execute(context.Background(), func() error { return errors.New("boom") })
+	RegisterCircuitBreaker("metrics-test", cb)
+
+	body := scrape(t)
+
+	expected := []string{
+		`workflow_dag_run_duration_seconds_count{dag_id="metrics-test-dag",state="success"} 1`,
+		`workflow_task_instance_duration_seconds_count{dag_id="metrics-test-dag",state="failed"} 1`,
+		`workflow_http_request_duration_seconds_count{method="GET",route="/api/v1/dags/:id",status="200"} 1`,
+		`workflow_dlq_entries 1`,
+		`workflow_circuit_breaker_state{name="metrics-test",state="open"} 1`,
+		`workflow_circuit_breaker_state{name="metrics-test",state="closed"} 0`,
+		`workflow_circuit_breaker_consecutive_failures{name="metrics-test"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestRegister_IgnoresDuplicates(t *testing.T) {
+	RegisterGauge("metrics_test_gauge", "Test gauge.", func() float64 { return 1 })
+	RegisterGauge("metrics_test_gauge", "Test gauge.", func() float64 { return 2 })
+
+	if !strings.Contains(scrape(t), "workflow_metrics_test_gauge 1") {
+		t.Error("Expected the first registration of a gauge to be kept")
+	}
+}
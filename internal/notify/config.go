@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Config declares the notifiers of a process by name, the notifiers told about events not tied
+// to a DAG, and templates overriding the default messages. DAGs refer to notifiers by name
+// in their notification rules.
+type Config struct {
+	Notifiers map[string]NotifierConfig `yaml:"notifiers"`
+	Default   []string                  `yaml:"default"` // Notified about dead letter queue and circuit breaker events
+	Templates map[EventType]Template    `yaml:"templates"`
+}
+
+// NotifierConfig configures a notifier of type webhook, slack or email
+type NotifierConfig struct {
+	Type string `yaml:"type"`
+
+	// webhook and slack
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"` // Signs webhook requests when set
+
+	// email
+	SMTPAddr string   `yaml:"smtp_addr"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// LoadConfig reads a YAML notification config. References to environment variables
+// such as ${SLACK_WEBHOOK_URL} are expanded, so that secrets can stay out of the file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification config: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification config: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Build creates the configured notifiers and templates
+func (c *Config) Build() (map[string]Notifier, *Templates, error) {
+	notifiers := make(map[string]Notifier, len(c.Notifiers))
+	for name, nc := range c.Notifiers {
+		notifier, err := nc.build()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid notifier %s: %w", name, err)
+		}
+		notifiers[name] = notifier
+	}
+
+	for _, name := range c.Default {
+		if _, ok := notifiers[name]; !ok {
+			return nil, nil, fmt.Errorf("default notifier %s is not configured", name)
+		}
+	}
+
+	templates, err := NewTemplates(c.Templates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return notifiers, templates, nil
+}
+
+// build creates the notifier of a config
+func (nc *NotifierConfig) build() (Notifier, error) {
+	switch nc.Type {
+	case "webhook":
+		if nc.URL == "" {
+			return nil, fmt.Errorf("webhook notifier requires a url")
+		}
+		return NewWebhookNotifier(nc.URL, nc.Secret), nil
+	case "slack":
+		if nc.URL == "" {
+			return nil, fmt.Errorf("slack notifier requires a url")
+		}
+		return NewSlackNotifier(nc.URL), nil
+	case "email":
+		if nc.SMTPAddr == "" || nc.From == "" || len(nc.To) == 0 {
+			return nil, fmt.Errorf("email notifier requires smtp_addr, from and to")
+		}
+		return NewEmailNotifier(EmailConfig{
+			Addr:     nc.SMTPAddr,
+			Username: nc.Username,
+			Password: nc.Password,
+			From:     nc.From,
+			To:       nc.To,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q, expected webhook, slack or email", nc.Type)
+	}
+}
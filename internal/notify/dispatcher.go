@@ -0,0 +1,345 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+const (
+	// dispatchTimeout bounds the delivery of an event to all of its notifiers
+	dispatchTimeout = 30 * time.Second
+
+	// rememberedEvents is how many delivered outbox events are remembered to drop redeliveries
+	rememberedEvents = 1024
+)
+
+// Dispatcher routes events to notifiers. Events of DAG runs and tasks go to the notifiers named
+// by the notification rules of their DAG, other events to the default notifiers.
+//
+// A Dispatcher is fed from several places: as a state.EventPublisher of an outbox relay it is told about
+// failed, retried and succeeded tasks and DAG runs; as an sla.Notifier about SLA misses; and it provides
+// the callbacks of the dead letter queue, circuit breakers and error propagation.
+type Dispatcher struct {
+	notifiers  map[string]Notifier
+	defaults   []string
+	templates  *Templates
+	dagRepo    storage.DAGRepository
+	dagRunRepo storage.DAGRunRepository
+	taskRepo   storage.TaskInstanceRepository
+
+	// Outbox IDs of recent events; the relay delivers an event again when another publisher failed
+	mu       sync.Mutex
+	seen     map[int64]bool
+	seenRing []int64
+	seenNext int
+}
+
+// NewDispatcher creates a dispatcher sending events to notifiers by name
+func NewDispatcher(
+	notifiers map[string]Notifier,
+	templates *Templates,
+	dagRepo storage.DAGRepository,
+	dagRunRepo storage.DAGRunRepository,
+	taskRepo storage.TaskInstanceRepository,
+) *Dispatcher {
+	return &Dispatcher{
+		notifiers:  notifiers,
+		templates:  templates,
+		dagRepo:    dagRepo,
+		dagRunRepo: dagRunRepo,
+		taskRepo:   taskRepo,
+		seen:       make(map[int64]bool),
+		seenRing:   make([]int64, rememberedEvents),
+	}
+}
+
+// NewDispatcherFromConfig creates a dispatcher with the notifiers, defaults and templates of config
+func NewDispatcherFromConfig(
+	config *Config,
+	dagRepo storage.DAGRepository,
+	dagRunRepo storage.DAGRunRepository,
+	taskRepo storage.TaskInstanceRepository,
+) (*Dispatcher, error) {
+	notifiers, templates, err := config.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	d := NewDispatcher(notifiers, templates, dagRepo, dagRunRepo, taskRepo)
+	d.SetDefaults(config.Default...)
+	return d, nil
+}
+
+// SetDefaults sets the notifiers told about events not tied to a DAG
+func (d *Dispatcher) SetDefaults(names ...string) {
+	d.defaults = names
+}
+
+// Notify sends an event to the notifiers its DAG's rules name for it, or to the default notifiers
+// when it is not tied to a DAG. Events of DAGs without a rule for them are dropped.
+func (d *Dispatcher) Notify(ctx context.Context, event *Event) error {
+	names := d.defaults
+	if event.DAGID != "" {
+		dag, err := d.dagRepo.Get(ctx, event.DAGID)
+		if err != nil {
+			return fmt.Errorf("failed to get DAG %s: %w", event.DAGID, err)
+		}
+		if event.DAGName == "" {
+			event.DAGName = dag.Name
+		}
+		names = rulesFor(dag.Notifications, event.Type)
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	return d.Dispatch(ctx, names, event)
+}
+
+// Dispatch renders an event and sends it to the named notifiers, returning the failures of all of them
+func (d *Dispatcher) Dispatch(ctx context.Context, names []string, event *Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	msg, err := d.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		notifier, ok := d.notifiers[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("notifier %s is not configured", name))
+			continue
+		}
+		if err := notifier.Notify(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// rulesFor returns the notifiers named by rules for an event type
+func rulesFor(rules *models.NotificationRules, eventType EventType) []string {
+	if rules == nil {
+		return nil
+	}
+
+	switch eventType {
+	case EventTaskFailed, EventDAGRunFailed:
+		return rules.OnFailure
+	case EventDAGRunSucceeded:
+		return rules.OnSuccess
+	case EventTaskRetry:
+		return rules.OnRetry
+	case EventSLAMiss:
+		return rules.OnSLAMiss
+	default:
+		return nil
+	}
+}
+
+// notifyAsync notifies about an event in the background, logging failures
+func (d *Dispatcher) notifyAsync(event *Event) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dispatchTimeout)
+		defer cancel()
+
+		if err := d.Notify(ctx, event); err != nil {
+			log.Printf("Failed to notify %s: %v", event.Type, err)
+		}
+	}()
+}
+
+// Publish implements state.EventPublisher. Failed, retried and succeeded tasks and DAG runs are
+// notified about in the background, so that slow notifiers do not hold up the outbox relay;
+// notification failures are logged rather than retried, and redelivered events are dropped.
+func (d *Dispatcher) Publish(event state.TransitionEvent) error {
+	if transitionEventType(&event) == "" || !d.firstDelivery(event.OutboxID) {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dispatchTimeout)
+		defer cancel()
+
+		if err := d.handleTransition(ctx, &event); err != nil {
+			log.Printf("Failed to notify state change of %s %s: %v", event.EntityType, event.EntityID, err)
+		}
+	}()
+
+	return nil
+}
+
+// firstDelivery records an outbox ID and reports whether it was not seen recently
+func (d *Dispatcher) firstDelivery(outboxID int64) bool {
+	if outboxID == 0 {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.seen[outboxID] {
+		return false
+	}
+	delete(d.seen, d.seenRing[d.seenNext])
+	d.seenRing[d.seenNext] = outboxID
+	d.seenNext = (d.seenNext + 1) % len(d.seenRing)
+	d.seen[outboxID] = true
+	return true
+}
+
+// transitionEventType returns the event type of a state change, or "" if nobody is notified about it
+func transitionEventType(event *state.TransitionEvent) EventType {
+	switch event.EntityType {
+	case "dag_run":
+		switch event.NewState {
+		case models.StateSuccess:
+			return EventDAGRunSucceeded
+		case models.StateFailed:
+			return EventDAGRunFailed
+		}
+	case "task_instance":
+		switch event.NewState {
+		case models.StateFailed:
+			return EventTaskFailed
+		case models.StateRetrying:
+			return EventTaskRetry
+		}
+	}
+	return ""
+}
+
+// handleTransition notifies about a state change
+func (d *Dispatcher) handleTransition(ctx context.Context, transition *state.TransitionEvent) error {
+	eventType := transitionEventType(transition)
+	if eventType == "" {
+		return nil
+	}
+
+	event := &Event{
+		Type:  eventType,
+		State: transition.NewState,
+	}
+	event.DAGID, _ = transition.Metadata[state.MetadataDAGID].(string)
+	event.DAGRunID, _ = transition.Metadata[state.MetadataDAGRunID].(string)
+
+	switch transition.EntityType {
+	case "dag_run":
+		event.DAGRunID = transition.EntityID
+	case "task_instance":
+		taskInstance, err := d.taskRepo.Get(ctx, transition.EntityID)
+		if err != nil {
+			return fmt.Errorf("failed to get task instance: %w", err)
+		}
+		event.TaskID = taskInstance.TaskID
+		event.TaskInstanceID = taskInstance.ID
+		event.TryNumber = taskInstance.TryNumber
+		event.MaxTries = taskInstance.MaxTries
+		event.Error = taskInstance.ErrorMessage
+	}
+
+	if event.DAGID == "" {
+		return fmt.Errorf("state change event has no DAG ID")
+	}
+
+	return d.Notify(ctx, event)
+}
+
+// NotifySLAMiss implements sla.Notifier
+func (d *Dispatcher) NotifySLAMiss(ctx context.Context, miss *models.SLAMiss) error {
+	deadline := miss.Deadline
+	return d.Notify(ctx, &Event{
+		Type:           EventSLAMiss,
+		DAGID:          miss.DAGID,
+		DAGRunID:       miss.DAGRunID,
+		TaskID:         miss.TaskID,
+		TaskInstanceID: miss.TaskInstanceID,
+		State:          miss.State,
+		Deadline:       &deadline,
+		Time:           miss.DetectedAt,
+	})
+}
+
+// DLQThresholdReached is the dlq.Manager threshold callback; the default notifiers are told
+// how many entries the dead letter queue holds
+func (d *Dispatcher) DLQThresholdReached(count int) {
+	d.notifyAsync(&Event{
+		Type:    EventDLQThreshold,
+		Details: map[string]string{"count": strconv.Itoa(count)},
+	})
+}
+
+// CircuitBreakerStateChanged returns a circuitbreaker.Config OnStateChange callback telling
+// the default notifiers about state changes of the named circuit breaker
+func (d *Dispatcher) CircuitBreakerStateChanged(name string) func(from, to circuitbreaker.State) {
+	return func(from, to circuitbreaker.State) {
+		d.notifyAsync(&Event{
+			Type:    EventCircuitBreaker,
+			Details: map[string]string{"name": name, "from": from.String(), "to": to.String()},
+		})
+	}
+}
+
+// OnTaskFailure is an errorhandling.ErrorCallback for processes that handle task failures through
+// a PropagationHandler rather than relaying the outbox to the dispatcher. Notification failures are
+// logged so that they do not change how the task failure is handled.
+func (d *Dispatcher) OnTaskFailure(ctx context.Context, task *models.Task, taskInstance *models.TaskInstance, err error) error {
+	event := &Event{
+		Type:           EventTaskFailed,
+		DAGRunID:       taskInstance.DAGRunID,
+		TaskID:         task.ID,
+		TaskInstanceID: taskInstance.ID,
+		State:          models.StateFailed,
+		TryNumber:      taskInstance.TryNumber,
+		MaxTries:       taskInstance.MaxTries,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	dagRun, dagRunErr := d.dagRunRepo.Get(ctx, taskInstance.DAGRunID)
+	if dagRunErr != nil {
+		log.Printf("Failed to notify failure of task %s: failed to get DAG run %s: %v", task.ID, taskInstance.DAGRunID, dagRunErr)
+		return nil
+	}
+	event.DAGID = dagRun.DAGID
+
+	if notifyErr := d.Notify(ctx, event); notifyErr != nil {
+		log.Printf("Failed to notify failure of task %s: %v", task.ID, notifyErr)
+	}
+	return nil
+}
+
+// OnDAGFailure is the PropagationConfig OnDAGFailure callback; it notifies and returns err unchanged
+func (d *Dispatcher) OnDAGFailure(ctx context.Context, dagRun *models.DAGRun, err error) error {
+	event := &Event{
+		Type:     EventDAGRunFailed,
+		DAGID:    dagRun.DAGID,
+		DAGRunID: dagRun.ID,
+		State:    models.StateFailed,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	if notifyErr := d.Notify(ctx, event); notifyErr != nil {
+		log.Printf("Failed to notify failure of DAG run %s: %v", dagRun.ID, notifyErr)
+	}
+	return err
+}
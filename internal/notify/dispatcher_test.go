@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+type fakeDAGRepository struct {
+	storage.DAGRepository
+	dags map[string]*models.DAG
+}
+
+func (r *fakeDAGRepository) Get(ctx context.Context, id string) (*models.DAG, error) {
+	dag, ok := r.dags[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return dag, nil
+}
+
+type fakeTaskInstanceRepository struct {
+	storage.TaskInstanceRepository
+	instances map[string]*models.TaskInstance
+}
+
+func (r *fakeTaskInstanceRepository) Get(ctx context.Context, id string) (*models.TaskInstance, error) {
+	ti, ok := r.instances[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return ti, nil
+}
+
+// recorder is a notifier remembering the messages it was given
+type recorder struct {
+	messages []*Message
+}
+
+func (r *recorder) Notify(ctx context.Context, msg *Message) error {
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func newTestDispatcher(t *testing.T) (*Dispatcher, map[string]*recorder) {
+	t.Helper()
+	templates, err := NewTemplates(nil)
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	recorders := map[string]*recorder{"slack": {}, "email": {}, "audit": {}}
+	notifiers := make(map[string]Notifier)
+	for name, r := range recorders {
+		notifiers[name] = r
+	}
+
+	dagRepo := &fakeDAGRepository{dags: map[string]*models.DAG{
+		"dag1": {
+			ID:   "dag1",
+			Name: "etl",
+			Notifications: &models.NotificationRules{
+				OnFailure: []string{"slack", "email"},
+				OnSLAMiss: []string{"slack"},
+			},
+		},
+		"dag2": {ID: "dag2", Name: "quiet"},
+	}}
+	taskRepo := &fakeTaskInstanceRepository{instances: map[string]*models.TaskInstance{
+		"ti1": {ID: "ti1", TaskID: "extract", DAGRunID: "run1", State: models.StateFailed, TryNumber: 2, ErrorMessage: "boom"},
+	}}
+
+	d := NewDispatcher(notifiers, templates, dagRepo, nil, taskRepo)
+	d.SetDefaults("audit")
+	return d, recorders
+}
+
+func TestDispatcher_RoutesTransitionsByDAGRules(t *testing.T) {
+	d, recorders := newTestDispatcher(t)
+
+	err := d.handleTransition(context.Background(), &state.TransitionEvent{
+		EntityType: "task_instance",
+		EntityID:   "ti1",
+		OldState:   models.StateRunning,
+		NewState:   models.StateFailed,
+		Metadata:   map[string]interface{}{state.MetadataDAGID: "dag1", state.MetadataDAGRunID: "run1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to handle transition: %v", err)
+	}
+
+	for _, name := range []string{"slack", "email"} {
+		if len(recorders[name].messages) != 1 {
+			t.Fatalf("Expected 1 message for %s, got %d", name, len(recorders[name].messages))
+		}
+	}
+	msg := recorders["slack"].messages[0]
+	if msg.Type != EventTaskFailed || msg.TaskID != "extract" || msg.DAGName != "etl" || msg.Error != "boom" || msg.TryNumber != 2 {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if len(recorders["audit"].messages) != 0 {
+		t.Error("Expected DAG events not to reach the default notifiers")
+	}
+
+	// No rule for successes, and no rules at all for dag2
+	for _, transition := range []state.TransitionEvent{
+		{EntityType: "dag_run", EntityID: "run1", NewState: models.StateSuccess, Metadata: map[string]interface{}{state.MetadataDAGID: "dag1"}},
+		{EntityType: "dag_run", EntityID: "run2", NewState: models.StateFailed, Metadata: map[string]interface{}{state.MetadataDAGID: "dag2"}},
+		{EntityType: "dag_run", EntityID: "run1", NewState: models.StateRunning, Metadata: map[string]interface{}{state.MetadataDAGID: "dag1"}},
+	} {
+		if err := d.handleTransition(context.Background(), &transition); err != nil {
+			t.Fatalf("Failed to handle transition: %v", err)
+		}
+	}
+	if len(recorders["slack"].messages) != 1 {
+		t.Errorf("Expected no further messages, got %d", len(recorders["slack"].messages))
+	}
+}
+
+func TestDispatcher_NotifySLAMiss(t *testing.T) {
+	d, recorders := newTestDispatcher(t)
+
+	deadline := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	err := d.NotifySLAMiss(context.Background(), &models.SLAMiss{
+		DAGID:    "dag1",
+		DAGRunID: "run1",
+		State:    models.StateRunning,
+		Deadline: deadline,
+	})
+	if err != nil {
+		t.Fatalf("Failed to notify SLA miss: %v", err)
+	}
+
+	if len(recorders["slack"].messages) != 1 || len(recorders["email"].messages) != 0 {
+		t.Fatalf("Expected the SLA miss to reach slack only")
+	}
+	msg := recorders["slack"].messages[0]
+	if msg.Subject != "[etl] DAG run missed its SLA" {
+		t.Errorf("Unexpected subject %q", msg.Subject)
+	}
+}
+
+func TestDispatcher_DefaultNotifiers(t *testing.T) {
+	d, recorders := newTestDispatcher(t)
+
+	err := d.Notify(context.Background(), &Event{
+		Type:    EventCircuitBreaker,
+		Details: map[string]string{"name": "http", "from": "closed", "to": "open"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	if len(recorders["audit"].messages) != 1 {
+		t.Fatalf("Expected 1 message for the default notifier, got %d", len(recorders["audit"].messages))
+	}
+	if subject := recorders["audit"].messages[0].Subject; subject != "Circuit breaker http is open" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+}
+
+func TestDispatcher_UnknownNotifier(t *testing.T) {
+	d, recorders := newTestDispatcher(t)
+
+	if err := d.Dispatch(context.Background(), []string{"missing", "audit"}, taskFailedEvent()); err == nil {
+		t.Error("Expected error for unconfigured notifier")
+	}
+	if len(recorders["audit"].messages) != 1 {
+		t.Error("Expected the configured notifier to be notified anyway")
+	}
+}
+
+func TestDispatcher_DropsRedeliveries(t *testing.T) {
+	d, _ := newTestDispatcher(t)
+
+	if !d.firstDelivery(1) || d.firstDelivery(1) {
+		t.Error("Expected a redelivered event to be dropped")
+	}
+	for id := int64(2); id <= rememberedEvents+1; id++ {
+		d.firstDelivery(id)
+	}
+	if !d.firstDelivery(1) {
+		t.Error("Expected the oldest remembered event to be forgotten")
+	}
+	if !d.firstDelivery(0) || !d.firstDelivery(0) {
+		t.Error("Expected events outside the outbox to always be delivered")
+	}
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// defaultSMTPTimeout bounds the delivery of an email
+const defaultSMTPTimeout = 10 * time.Second
+
+// EmailConfig configures an EmailNotifier
+type EmailConfig struct {
+	Addr     string // SMTP server as host:port
+	Username string // Authenticates with PLAIN auth when set
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailNotifier sends messages as plain text email through an SMTP server.
+// STARTTLS is used when the server offers it.
+type EmailNotifier struct {
+	config  EmailConfig
+	timeout time.Duration
+}
+
+// NewEmailNotifier creates a notifier sending email through the configured SMTP server
+func NewEmailNotifier(config EmailConfig) *EmailNotifier {
+	return &EmailNotifier{
+		config:  config,
+		timeout: defaultSMTPTimeout,
+	}
+}
+
+// Notify sends the message to every recipient
+func (n *EmailNotifier) Notify(ctx context.Context, msg *Message) error {
+	if len(n.config.To) == 0 {
+		return fmt.Errorf("email notifier has no recipients")
+	}
+
+	deadline := time.Now().Add(n.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", n.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", n.config.Addr, err)
+	}
+	conn.SetDeadline(deadline)
+
+	host, _, err := net.SplitHostPort(n.config.Addr)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("invalid SMTP address %s: %w", n.config.Addr, err)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, host)); err != nil {
+			return fmt.Errorf("failed to authenticate to SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.config.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range n.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(n.buildMessage(msg)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage returns the headers and body of the email of a message
+func (n *EmailNotifier) buildMessage(msg *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(n.config.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
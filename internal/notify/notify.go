@@ -0,0 +1,73 @@
+// Package notify tells people and systems about failures, successes, retries and SLA misses
+// through webhooks, Slack-compatible incoming webhooks and email
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// EventType identifies what happened
+type EventType string
+
+const (
+	EventTaskFailed      EventType = "task_failed"       // A task instance failed for good
+	EventTaskRetry       EventType = "task_retry"        // A failed task instance is retried
+	EventDAGRunFailed    EventType = "dag_run_failed"    // A DAG run failed
+	EventDAGRunSucceeded EventType = "dag_run_succeeded" // A DAG run succeeded
+	EventSLAMiss         EventType = "sla_miss"          // A task instance or DAG run missed its SLA
+	EventDLQThreshold    EventType = "dlq_threshold"     // The dead letter queue holds too many entries
+	EventCircuitBreaker  EventType = "circuit_breaker"   // A circuit breaker changed state
+)
+
+// Event describes something worth notifying about. Events of DAG runs and tasks are routed
+// by the notification rules of their DAG; other events go to the default notifiers.
+type Event struct {
+	Type           EventType         `json:"event"`
+	DAGID          string            `json:"dag_id,omitempty"`
+	DAGName        string            `json:"dag_name,omitempty"`
+	DAGRunID       string            `json:"dag_run_id,omitempty"`
+	TaskID         string            `json:"task_id,omitempty"`
+	TaskInstanceID string            `json:"task_instance_id,omitempty"`
+	State          models.State      `json:"state,omitempty"`
+	TryNumber      int               `json:"try_number,omitempty"`
+	MaxTries       int               `json:"max_tries,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Deadline       *time.Time        `json:"deadline,omitempty"` // SLA deadline of an SLA miss
+	Details        map[string]string `json:"details,omitempty"`  // Event specific values such as the name of a circuit breaker
+	Time           time.Time         `json:"time"`
+}
+
+// Message is an event rendered for people to read
+type Message struct {
+	Event
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers messages to a channel
+type Notifier interface {
+	Notify(ctx context.Context, msg *Message) error
+}
+
+// NotifierFunc adapts a function to a Notifier
+type NotifierFunc func(ctx context.Context, msg *Message) error
+
+// Notify calls f
+func (f NotifierFunc) Notify(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// isFailure reports whether an event signals something that went wrong
+func (e *Event) isFailure() bool {
+	switch e.Type {
+	case EventDAGRunSucceeded:
+		return false
+	case EventCircuitBreaker:
+		return e.Details["to"] != "closed"
+	default:
+		return true
+	}
+}
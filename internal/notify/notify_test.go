@@ -0,0 +1,261 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func testMessage(t *testing.T, event *Event) *Message {
+	t.Helper()
+	templates, err := NewTemplates(nil)
+	if err != nil {
+		t.Fatalf("Failed to parse default templates: %v", err)
+	}
+	msg, err := templates.Render(event)
+	if err != nil {
+		t.Fatalf("Failed to render message: %v", err)
+	}
+	return msg
+}
+
+func taskFailedEvent() *Event {
+	return &Event{
+		Type:      EventTaskFailed,
+		DAGID:     "dag1",
+		DAGName:   "etl",
+		DAGRunID:  "run1",
+		TaskID:    "extract",
+		State:     models.StateFailed,
+		TryNumber: 3,
+		Error:     "exit status 1",
+		Time:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestTemplates_Render(t *testing.T) {
+	msg := testMessage(t, taskFailedEvent())
+
+	if msg.Subject != "[etl] Task extract failed" {
+		t.Errorf("Unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "after 3 attempt(s) in run run1") || !strings.Contains(msg.Body, "Error: exit status 1") {
+		t.Errorf("Unexpected body %q", msg.Body)
+	}
+}
+
+func TestTemplates_Overrides(t *testing.T) {
+	templates, err := NewTemplates(map[EventType]Template{
+		EventTaskFailed: {Subject: "{{.TaskID}} is down"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	msg, err := templates.Render(taskFailedEvent())
+	if err != nil {
+		t.Fatalf("Failed to render message: %v", err)
+	}
+	if msg.Subject != "extract is down" {
+		t.Errorf("Expected overridden subject, got %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "Error: exit status 1") {
+		t.Errorf("Expected default body, got %q", msg.Body)
+	}
+
+	if _, err := NewTemplates(map[EventType]Template{"unknown": {Subject: "x"}}); err == nil {
+		t.Error("Expected error for template of unknown event type")
+	}
+	if _, err := NewTemplates(map[EventType]Template{EventTaskFailed: {Subject: "{{.TaskID"}}); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var received Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("s3cret", r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	msg := testMessage(t, taskFailedEvent())
+	if err := NewWebhookNotifier(server.URL, "s3cret").Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+	if received.Type != EventTaskFailed || received.TaskID != "extract" || received.Subject != msg.Subject {
+		t.Errorf("Unexpected payload %+v", received)
+	}
+
+	// A receiver with another secret rejects the request
+	if err := NewWebhookNotifier(server.URL, "other").Notify(context.Background(), msg); err == nil {
+		t.Error("Expected error for request with wrong signature")
+	}
+}
+
+func TestSlackNotifier_Notify(t *testing.T) {
+	var payload slackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	msg := testMessage(t, taskFailedEvent())
+	if err := NewSlackNotifier(server.URL).Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	if payload.Text != msg.Subject {
+		t.Errorf("Expected text %q, got %q", msg.Subject, payload.Text)
+	}
+	if len(payload.Attachments) != 1 || payload.Attachments[0].Color != slackColorFailure || payload.Attachments[0].Text != msg.Body {
+		t.Errorf("Unexpected attachments %+v", payload.Attachments)
+	}
+}
+
+// smtpStub accepts one email per connection and sends what it received on messages
+func smtpStub(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 stub ESMTP")
+
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					messages <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+
+			switch strings.ToUpper(strings.Fields(line)[0]) {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				reply("250 OK")
+			case "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestEmailNotifier_Notify(t *testing.T) {
+	addr, messages := smtpStub(t)
+
+	notifier := NewEmailNotifier(EmailConfig{
+		Addr: addr,
+		From: "workflow@example.com",
+		To:   []string{"oncall@example.com", "data@example.com"},
+	})
+	msg := testMessage(t, taskFailedEvent())
+	if err := notifier.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	select {
+	case email := <-messages:
+		for _, want := range []string{
+			"From: workflow@example.com",
+			"To: oncall@example.com, data@example.com",
+			"Subject: [etl] Task extract failed",
+			"Error: exit status 1",
+		} {
+			if !strings.Contains(email, want) {
+				t.Errorf("Expected email to contain %q, got:\n%s", want, email)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for email")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_SLACK_URL", "https://hooks.example.com/T000")
+
+	path := filepath.Join(t.TempDir(), "notifications.yaml")
+	err := os.WriteFile(path, []byte(`
+notifiers:
+  ops-slack:
+    type: slack
+    url: ${TEST_SLACK_URL}
+  audit:
+    type: webhook
+    url: https://audit.example.com/events
+    secret: s3cret
+default:
+  - audit
+templates:
+  task_failed:
+    subject: "{{.TaskID}} failed"
+`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Notifiers["ops-slack"].URL != "https://hooks.example.com/T000" {
+		t.Errorf("Expected expanded URL, got %q", config.Notifiers["ops-slack"].URL)
+	}
+
+	notifiers, _, err := config.Build()
+	if err != nil {
+		t.Fatalf("Failed to build notifiers: %v", err)
+	}
+	if _, ok := notifiers["audit"].(*WebhookNotifier); !ok {
+		t.Errorf("Expected audit to be a webhook notifier, got %T", notifiers["audit"])
+	}
+
+	config.Default = []string{"missing"}
+	if _, _, err := config.Build(); err == nil {
+		t.Error("Expected error for unconfigured default notifier")
+	}
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Attachment colors of Slack messages
+const (
+	slackColorFailure = "danger"
+	slackColorSuccess = "good"
+)
+
+// SlackNotifier posts messages to a Slack-compatible incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a notifier posting to a Slack incoming webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: defaultHTTPTimeout},
+	}
+}
+
+// slackPayload is the body of an incoming webhook request
+type slackPayload struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Text   string       `json:"text"`
+	Fields []slackField `json:"fields,omitempty"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Notify posts the message with its subject as text and its body and identifiers as an attachment
+func (n *SlackNotifier) Notify(ctx context.Context, msg *Message) error {
+	color := slackColorSuccess
+	if msg.isFailure() {
+		color = slackColorFailure
+	}
+
+	var fields []slackField
+	for _, field := range []struct{ title, value string }{
+		{"DAG", msg.DAGName},
+		{"DAG run", msg.DAGRunID},
+		{"Task", msg.TaskID},
+		{"State", string(msg.State)},
+	} {
+		if field.value != "" {
+			fields = append(fields, slackField{Title: field.title, Value: field.value, Short: true})
+		}
+	}
+
+	body, err := json.Marshal(slackPayload{
+		Text: msg.Subject,
+		Attachments: []slackAttachment{{
+			Color:  color,
+			Text:   msg.Body,
+			Fields: fields,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack payload: %w", err)
+	}
+
+	return postJSON(ctx, n.client, n.webhookURL, body, nil)
+}
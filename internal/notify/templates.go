@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Template is the text/template source of the subject and body of the messages of an event type.
+// Templates are executed with the Event.
+type Template struct {
+	Subject string `json:"subject" yaml:"subject"`
+	Body    string `json:"body" yaml:"body"`
+}
+
+// defaultTemplates are used for the event types without a configured template
+var defaultTemplates = map[EventType]Template{
+	EventTaskFailed: {
+		Subject: `[{{.DAGName}}] Task {{.TaskID}} failed`,
+		Body: `Task {{.TaskID}} of DAG {{.DAGName}} failed after {{.TryNumber}} attempt(s) in run {{.DAGRunID}}.` +
+			`{{if .Error}}` + "\n\n" + `Error: {{.Error}}{{end}}`,
+	},
+	EventTaskRetry: {
+		Subject: `[{{.DAGName}}] Task {{.TaskID}} is retried`,
+		Body: `Attempt {{.TryNumber}}{{if .MaxTries}} of {{.MaxTries}}{{end}} of task {{.TaskID}} of DAG {{.DAGName}} failed ` +
+			`in run {{.DAGRunID}} and will be retried.{{if .Error}}` + "\n\n" + `Error: {{.Error}}{{end}}`,
+	},
+	EventDAGRunFailed: {
+		Subject: `[{{.DAGName}}] DAG run failed`,
+		Body:    `Run {{.DAGRunID}} of DAG {{.DAGName}} failed.`,
+	},
+	EventDAGRunSucceeded: {
+		Subject: `[{{.DAGName}}] DAG run succeeded`,
+		Body:    `Run {{.DAGRunID}} of DAG {{.DAGName}} succeeded.`,
+	},
+	EventSLAMiss: {
+		Subject: `[{{.DAGName}}] {{if .TaskID}}Task {{.TaskID}}{{else}}DAG run{{end}} missed its SLA`,
+		Body: `{{if .TaskID}}Task {{.TaskID}} of run{{else}}Run{{end}} {{.DAGRunID}} of DAG {{.DAGName}} was {{.State}}` +
+			`{{if .Deadline}} at its SLA deadline {{.Deadline.Format "2006-01-02T15:04:05Z07:00"}}{{else}} past its SLA deadline{{end}}.`,
+	},
+	EventDLQThreshold: {
+		Subject: `Dead letter queue holds {{index .Details "count"}} entries`,
+		Body:    `The dead letter queue holds {{index .Details "count"}} failed task instances, at or above its alert threshold.`,
+	},
+	EventCircuitBreaker: {
+		Subject: `Circuit breaker {{index .Details "name"}} is {{index .Details "to"}}`,
+		Body:    `Circuit breaker {{index .Details "name"}} changed from {{index .Details "from"}} to {{index .Details "to"}}.`,
+	},
+}
+
+// Templates renders events into messages
+type Templates struct {
+	subjects map[EventType]*template.Template
+	bodies   map[EventType]*template.Template
+}
+
+// NewTemplates parses the default templates with overrides replacing those of their event types
+func NewTemplates(overrides map[EventType]Template) (*Templates, error) {
+	t := &Templates{
+		subjects: make(map[EventType]*template.Template),
+		bodies:   make(map[EventType]*template.Template),
+	}
+
+	sources := make(map[EventType]Template, len(defaultTemplates))
+	for eventType, source := range defaultTemplates {
+		sources[eventType] = source
+	}
+	for eventType, source := range overrides {
+		if _, ok := defaultTemplates[eventType]; !ok {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		defaults := sources[eventType]
+		if source.Subject == "" {
+			source.Subject = defaults.Subject
+		}
+		if source.Body == "" {
+			source.Body = defaults.Body
+		}
+		sources[eventType] = source
+	}
+
+	for eventType, source := range sources {
+		subject, err := template.New(string(eventType) + "_subject").Parse(source.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s subject template: %w", eventType, err)
+		}
+		body, err := template.New(string(eventType) + "_body").Parse(source.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s body template: %w", eventType, err)
+		}
+		t.subjects[eventType] = subject
+		t.bodies[eventType] = body
+	}
+
+	return t, nil
+}
+
+// Render renders the message of an event
+func (t *Templates) Render(event *Event) (*Message, error) {
+	subject, ok := t.subjects[event.Type]
+	if !ok {
+		return nil, fmt.Errorf("no template for event type %q", event.Type)
+	}
+
+	var buf bytes.Buffer
+	if err := subject.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", event.Type, err)
+	}
+	// Subjects end up in email headers
+	subjectText := strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := t.bodies[event.Type].Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("failed to render %s body: %w", event.Type, err)
+	}
+
+	return &Message{
+		Event:   *event,
+		Subject: subjectText,
+		Body:    buf.String(),
+	}, nil
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// PreviewRunID stands in for the ID of a DAG run that does not exist yet
+const PreviewRunID = "<run_id>"
+
+// ErrTaskNotFound is returned when previewing a task the DAG does not have
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskPreview is the command a task of a DAG would run
+type TaskPreview struct {
+	TaskID          string
+	Command         string
+	RenderedCommand string // Empty when rendering failed
+	Err             error
+}
+
+// Preview renders the commands of a DAG's tasks for its first attempt at an execution date, without
+// running them. Only the task with the given ID is rendered unless taskID is empty. Results of upstream
+// tasks do not exist before the run, so templates pulling them show placeholders such as <result extract.rows>.
+func (r *Renderer) Preview(dagModel *models.DAG, executionDate time.Time, taskID string) ([]TaskPreview, error) {
+	var previews []TaskPreview
+	for _, task := range dagModel.Tasks {
+		if taskID != "" && task.ID != taskID {
+			continue
+		}
+
+		preview := TaskPreview{TaskID: task.ID, Command: task.Command, RenderedCommand: task.Command}
+		if task.Type != models.TaskTypeGo {
+			preview.RenderedCommand, preview.Err = r.Render(task.Command, &Context{
+				DAGID:         dagModel.ID,
+				DAGRunID:      PreviewRunID,
+				TaskID:        task.ID,
+				TryNumber:     1,
+				ExecutionDate: executionDate,
+				Params:        dagModel.ParamValues(nil),
+				Result:        placeholderResult,
+			})
+		}
+		previews = append(previews, preview)
+	}
+
+	if taskID != "" && len(previews) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	return previews, nil
+}
+
+// placeholderResult stands in for the value an upstream task will push
+func placeholderResult(taskID, key string) (json.RawMessage, error) {
+	return json.Marshal(fmt.Sprintf("<result %s.%s>", taskID, key))
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrNoResults is returned when a template pulls a result while no result store is available
+var ErrNoResults = errors.New("results of upstream tasks are not available")
+
+// ResultFunc returns the JSON value a task of the DAG run pushed under a key
+type ResultFunc func(taskID, key string) (json.RawMessage, error)
+
+// Context holds the run a task command is rendered for
+type Context struct {
+	DAGID         string
+	DAGRunID      string
+	TaskID        string
+	TryNumber     int
+	ExecutionDate time.Time
+	Params        map[string]interface{} // Parameters of the DAG run, by name
+	Result        ResultFunc             // Pulls the values of upstream tasks; nil when there are none
+}
+
+// Renderer renders task commands as Go text/template templates. Templates refer to the run through
+// .dag_id, .run_id, .task_id, .try_number, .execution_date (and its .ds, .ds_nodash, .ts, .ts_nodash
+// and .unix formats), .params and .env, and pull the values of upstream tasks with {{ result "task" "key" }}.
+type Renderer struct {
+	env []string // Environment variables templates may read through .env
+}
+
+// NewRenderer creates a renderer exposing the given environment variables to templates
+func NewRenderer(env []string) *Renderer {
+	return &Renderer{env: env}
+}
+
+// ParseEnvNames parses a comma-separated list of environment variable names, as given in configuration
+func ParseEnvNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParseExecutionDate parses an execution date to render for, given as RFC3339 or YYYY-MM-DD
+func ParseExecutionDate(value string) (time.Time, error) {
+	if executionDate, err := time.Parse(time.RFC3339, value); err == nil {
+		return executionDate, nil
+	}
+	executionDate, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid execution date %q, expected RFC3339 or YYYY-MM-DD", value)
+	}
+	return executionDate, nil
+}
+
+// IsTemplate reports whether a command contains template actions
+func IsTemplate(command string) bool {
+	return strings.Contains(command, "{{")
+}
+
+// Parse checks the syntax of a command template
+func Parse(command string) error {
+	_, err := parse(command, nil)
+	return err
+}
+
+// parse parses a command template, pulling results through result
+func parse(command string, result ResultFunc) (*template.Template, error) {
+	tmpl, err := template.New("command").
+		Option("missingkey=error").
+		Funcs(funcs(result)).
+		Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render renders a command for a task attempt. Commands without template actions are returned unchanged.
+func (r *Renderer) Render(command string, c *Context) (string, error) {
+	if !IsTemplate(command) {
+		return command, nil
+	}
+
+	tmpl, err := parse(command, c.Result)
+	if err != nil {
+		return "", err
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, r.data(c)); err != nil {
+		return "", fmt.Errorf("failed to render command: %w", err)
+	}
+	return rendered.String(), nil
+}
+
+// data returns the variables of a template
+func (r *Renderer) data(c *Context) map[string]interface{} {
+	executionDate := c.ExecutionDate.UTC()
+
+	params := c.Params
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	// Only variables that are set are visible, so that templates referring to others fail
+	env := make(map[string]string, len(r.env))
+	for _, name := range r.env {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+
+	return map[string]interface{}{
+		"dag_id":         c.DAGID,
+		"run_id":         c.DAGRunID,
+		"task_id":        c.TaskID,
+		"try_number":     c.TryNumber,
+		"execution_date": executionDate,
+		"ds":             executionDate.Format("2006-01-02"),
+		"ds_nodash":      executionDate.Format("20060102"),
+		"ts":             executionDate.Format(time.RFC3339),
+		"ts_nodash":      executionDate.Format("20060102T150405"),
+		"unix":           executionDate.Unix(),
+		"params":         params,
+		"env":            env,
+	}
+}
+
+// funcs returns the functions available to templates
+func funcs(result ResultFunc) template.FuncMap {
+	return template.FuncMap{
+		// result pulls the value an upstream task pushed under a key
+		"result": func(taskID, key string) (interface{}, error) {
+			if result == nil {
+				return nil, ErrNoResults
+			}
+			data, err := result(taskID, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to pull result %s of task %s: %w", key, taskID, err)
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, fmt.Errorf("failed to decode result %s of task %s: %w", key, taskID, err)
+			}
+			return value, nil
+		},
+		// json encodes a value as JSON, such as an object pulled from an upstream task
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		},
+		// shquote quotes a value as a single shell word
+		"shquote": func(value interface{}) string {
+			return "'" + strings.ReplaceAll(fmt.Sprint(value), "'", `'\''`) + "'"
+		},
+	}
+}
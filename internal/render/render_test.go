@@ -0,0 +1,186 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func testContext() *Context {
+	return &Context{
+		DAGID:         "etl",
+		DAGRunID:      "run1",
+		TaskID:        "load",
+		TryNumber:     2,
+		ExecutionDate: time.Date(2024, 3, 5, 6, 7, 8, 0, time.UTC),
+		Params:        map[string]interface{}{"table": "events", "limit": 10},
+	}
+}
+
+func TestRenderer_Render(t *testing.T) {
+	renderer := NewRenderer(nil)
+
+	tests := map[string]string{
+		"echo plain": "echo plain",
+		"{{ .dag_id }}/{{ .run_id }}/{{ .task_id }}":           "etl/run1/load",
+		"try {{ .try_number }}":                                "try 2",
+		"{{ .ds }} {{ .ds_nodash }}":                           "2024-03-05 20240305",
+		"{{ .ts }} {{ .ts_nodash }} {{ .unix }}":               "2024-03-05T06:07:08Z 20240305T060708 1709618828",
+		`{{ .execution_date.Format "2006/01" }}`:               "2024/03",
+		"load {{ .params.table }} --limit {{ .params.limit }}": "load events --limit 10",
+		"echo {{ shquote \"it's\" }}":                          `echo 'it'\''s'`,
+	}
+	for command, want := range tests {
+		got, err := renderer.Render(command, testContext())
+		if err != nil {
+			t.Errorf("Render(%q) failed: %v", command, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Render(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestRenderer_RenderErrors(t *testing.T) {
+	renderer := NewRenderer(nil)
+
+	for _, command := range []string{
+		"{{ .unknown }}",
+		"{{ .params.missing }}",
+		"{{ .env.HOME }}",
+		"{{ if }}",
+		`{{ result "extract" "rows" }}`,
+	} {
+		if _, err := renderer.Render(command, testContext()); err == nil {
+			t.Errorf("Render(%q) succeeded, want an error", command)
+		}
+	}
+}
+
+func TestRenderer_Env(t *testing.T) {
+	t.Setenv("RENDER_TEST_BUCKET", "s3://bucket")
+	t.Setenv("RENDER_TEST_SECRET", "secret")
+
+	renderer := NewRenderer([]string{"RENDER_TEST_BUCKET"})
+	got, err := renderer.Render("aws s3 ls {{ .env.RENDER_TEST_BUCKET }}", testContext())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if got != "aws s3 ls s3://bucket" {
+		t.Errorf("Render = %q, want %q", got, "aws s3 ls s3://bucket")
+	}
+
+	// Variables that are not whitelisted are not visible
+	if _, err := renderer.Render("{{ .env.RENDER_TEST_SECRET }}", testContext()); err == nil {
+		t.Error("Expected a variable that is not whitelisted to fail rendering")
+	}
+}
+
+func TestRenderer_Result(t *testing.T) {
+	errMissing := errors.New("not found")
+	c := testContext()
+	c.Result = func(taskID, key string) (json.RawMessage, error) {
+		switch taskID + "/" + key {
+		case "extract/rows":
+			return json.RawMessage(`12345678901234567890`), nil
+		case "extract/tables":
+			return json.RawMessage(`["a","b"]`), nil
+		}
+		return nil, errMissing
+	}
+
+	renderer := NewRenderer(nil)
+	got, err := renderer.Render(`load --rows {{ result "extract" "rows" }} --tables {{ result "extract" "tables" | json | shquote }}`, c)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := `load --rows 12345678901234567890 --tables '["a","b"]'`; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+
+	_, err = renderer.Render(`{{ result "extract" "missing" }}`, c)
+	if !errors.Is(err, errMissing) {
+		t.Errorf("Render error = %v, want %v", err, errMissing)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if err := Parse(`echo {{ .ds }} {{ result "extract" "rows" | json }}`); err != nil {
+		t.Errorf("Parse failed: %v", err)
+	}
+	for _, command := range []string{"{{ .ds", "{{ unknown }}", "{{ end }}"} {
+		if err := Parse(command); err == nil || !strings.Contains(err.Error(), "invalid command template") {
+			t.Errorf("Parse(%q) error = %v, want an invalid template error", command, err)
+		}
+	}
+}
+
+func TestRenderer_Preview(t *testing.T) {
+	dagModel := &models.DAG{
+		ID:     "etl",
+		Params: []models.Param{{Name: "table", Default: "events"}},
+		Tasks: []models.Task{
+			{ID: "extract", Type: models.TaskTypeGo, Command: "extract"},
+			{ID: "load", Type: models.TaskTypeBash, Command: `load {{ .params.table }} {{ .ds }} {{ result "extract" "rows" }} {{ .run_id }}`},
+			{ID: "broken", Type: models.TaskTypeBash, Command: "echo {{ .params.missing }}"},
+		},
+	}
+	renderer := NewRenderer(nil)
+	executionDate := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	previews, err := renderer.Preview(dagModel, executionDate, "")
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if len(previews) != 3 {
+		t.Fatalf("Expected 3 previews, got %d", len(previews))
+	}
+	if previews[0].RenderedCommand != "extract" || previews[0].Err != nil {
+		t.Errorf("Go task preview = %+v, want its command unchanged", previews[0])
+	}
+	if want := "load events 2024-03-05 <result extract.rows> <run_id>"; previews[1].RenderedCommand != want {
+		t.Errorf("Rendered command = %q, want %q", previews[1].RenderedCommand, want)
+	}
+	if previews[2].Err == nil {
+		t.Error("Expected the preview of a broken template to report an error")
+	}
+
+	previews, err = renderer.Preview(dagModel, executionDate, "load")
+	if err != nil || len(previews) != 1 || previews[0].TaskID != "load" {
+		t.Errorf("Preview of load = %+v, %v; want only load", previews, err)
+	}
+	if _, err := renderer.Preview(dagModel, executionDate, "missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Preview error = %v, want %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestParseExecutionDate(t *testing.T) {
+	for value, want := range map[string]time.Time{
+		"2024-03-05":                time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		"2024-03-05T06:07:08Z":      time.Date(2024, 3, 5, 6, 7, 8, 0, time.UTC),
+		"2024-03-05T08:07:08+02:00": time.Date(2024, 3, 5, 6, 7, 8, 0, time.UTC),
+	} {
+		got, err := ParseExecutionDate(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseExecutionDate(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseExecutionDate("yesterday"); err == nil {
+		t.Error("Expected an invalid execution date to be rejected")
+	}
+}
+
+func TestParseEnvNames(t *testing.T) {
+	names := ParseEnvNames(" BUCKET, REGION ,,")
+	if len(names) != 2 || names[0] != "BUCKET" || names[1] != "REGION" {
+		t.Errorf("ParseEnvNames = %v, want [BUCKET REGION]", names)
+	}
+	if names := ParseEnvNames(""); names != nil {
+		t.Errorf("ParseEnvNames of an empty list = %v, want none", names)
+	}
+}
@@ -0,0 +1,92 @@
+package results
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore holds result values too large to be stored inline
+type BlobStore interface {
+	// Put stores a blob, replacing any blob with the same key
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get retrieves a blob; it returns ErrNotFound if there is none with the key
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// blobKey returns the key of the blob holding a result value
+func blobKey(dagRunID, taskID, key string) string {
+	return url.PathEscape(dagRunID) + "/" + url.PathEscape(taskID) + "/" + url.PathEscape(key) + ".json"
+}
+
+// FileBlobStore stores blobs as files under a directory, such as a volume shared by every process
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore creates a blob store keeping its files under dir, creating the directory if needed
+func NewFileBlobStore(dir string) (*FileBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &FileBlobStore{dir: dir}, nil
+}
+
+// path returns the file of a blob, refusing keys that would leave the directory
+func (s *FileBlobStore) path(key string) (string, error) {
+	if !filepath.IsLocal(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put stores a blob, replacing any blob with the same key. The file is renamed into place,
+// so that readers never see a partial blob.
+func (s *FileBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a blob
+func (s *FileBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
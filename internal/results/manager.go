@@ -0,0 +1,161 @@
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+)
+
+const (
+	// DefaultMaxValueSize is the largest value stored inline by default, in bytes
+	DefaultMaxValueSize = 64 << 10
+	// DefaultMaxBlobSize is the largest value spilled to the blob store by default, in bytes
+	DefaultMaxBlobSize = 16 << 20
+)
+
+// Config holds the size limits of task results
+type Config struct {
+	// MaxValueSize is the largest value stored inline in the result store
+	MaxValueSize int
+	// MaxBlobSize is the largest value spilled to the blob store
+	MaxBlobSize int
+	// BlobStore receives values over MaxValueSize; without it such values are rejected
+	BlobStore BlobStore
+}
+
+// DefaultConfig returns the default result size limits, without a blob store
+func DefaultConfig() *Config {
+	return &Config{
+		MaxValueSize: DefaultMaxValueSize,
+		MaxBlobSize:  DefaultMaxBlobSize,
+	}
+}
+
+// Manager pushes and pulls task results, enforcing the size limits and spilling
+// large values to the blob store
+type Manager struct {
+	store  Store
+	config *Config
+}
+
+// NewManager creates a new result manager
+func NewManager(store Store, config *Config) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Manager{
+		store:  store,
+		config: config,
+	}
+}
+
+// GetStore returns the underlying result store
+func (m *Manager) GetStore() Store {
+	return m.store
+}
+
+// Push stores a JSON value a task of a DAG run pushed under a key, replacing its previous value
+func (m *Manager) Push(ctx context.Context, dagRunID, taskID, key string, value json.RawMessage) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if !json.Valid(value) {
+		return fmt.Errorf("%w: key %s", ErrInvalidValue, key)
+	}
+
+	result := &Result{
+		DAGRunID: dagRunID,
+		TaskID:   taskID,
+		Key:      key,
+		Value:    value,
+		Size:     len(value),
+	}
+
+	if result.Size > m.config.MaxValueSize {
+		if m.config.BlobStore == nil || result.Size > m.config.MaxBlobSize {
+			return fmt.Errorf("%w: key %s has %d bytes", ErrValueTooLarge, key, result.Size)
+		}
+
+		result.BlobKey = blobKey(dagRunID, taskID, key)
+		if err := m.config.BlobStore.Put(ctx, result.BlobKey, value); err != nil {
+			return fmt.Errorf("failed to spill result %s to the blob store: %w", key, err)
+		}
+		result.Value = nil
+	}
+
+	if err := m.store.Put(ctx, result); err != nil {
+		return fmt.Errorf("failed to store result %s: %w", key, err)
+	}
+	return nil
+}
+
+// PushAll stores the values a task of a DAG run pushed, by key. It stops at the first value that cannot be stored.
+func (m *Manager) PushAll(ctx context.Context, dagRunID, taskID string, values map[string]json.RawMessage) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := m.Push(ctx, dagRunID, taskID, key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pull returns the JSON value a task of a DAG run pushed under a key
+func (m *Manager) Pull(ctx context.Context, dagRunID, taskID, key string) (json.RawMessage, error) {
+	result, err := m.Get(ctx, dagRunID, taskID, key)
+	if err != nil {
+		return nil, err
+	}
+	return result.Value, nil
+}
+
+// Get returns the result a task of a DAG run pushed under a key, with a spilled value loaded from the blob store
+func (m *Manager) Get(ctx context.Context, dagRunID, taskID, key string) (*Result, error) {
+	result, err := m.store.Get(ctx, dagRunID, taskID, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.load(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// List lists the results a task of a DAG run pushed, by key, with spilled values loaded from the blob store
+func (m *Manager) List(ctx context.Context, dagRunID, taskID string) ([]*Result, error) {
+	results, err := m.store.List(ctx, dagRunID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if err := m.load(ctx, result); err != nil {
+			// The other results are still worth showing
+			log.Printf("Failed to load result %s of task %s: %v", result.Key, taskID, err)
+		}
+	}
+	return results, nil
+}
+
+// load reads a value spilled to the blob store into the result
+func (m *Manager) load(ctx context.Context, result *Result) error {
+	if result.BlobKey == "" {
+		return nil
+	}
+	if m.config.BlobStore == nil {
+		return fmt.Errorf("result %s is spilled to a blob, but no blob store is configured", result.Key)
+	}
+
+	value, err := m.config.BlobStore.Get(ctx, result.BlobKey)
+	if err != nil {
+		return fmt.Errorf("failed to load result %s from the blob store: %w", result.Key, err)
+	}
+	result.Value = value
+	return nil
+}
@@ -0,0 +1,139 @@
+package results
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// resultModel is the database model of a task result
+type resultModel struct {
+	DAGRunID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TaskID    string    `gorm:"type:varchar(255);primaryKey"`
+	Key       string    `gorm:"type:varchar(255);primaryKey"`
+	Value     []byte    `gorm:"type:jsonb"`         // Null when spilled to a blob
+	BlobKey   string    `gorm:"type:text;not null"` // Empty when stored inline
+	Size      int       `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for resultModel
+func (resultModel) TableName() string {
+	return "task_results"
+}
+
+// result converts a model to a Result
+func (m *resultModel) result() *Result {
+	return &Result{
+		DAGRunID:  m.DAGRunID.String(),
+		TaskID:    m.TaskID,
+		Key:       m.Key,
+		Value:     m.Value,
+		BlobKey:   m.BlobKey,
+		Size:      m.Size,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+// PostgresStore is a result store kept in the task_results table, so that results are shared by
+// every process using the database. Results are deleted with their DAG run.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore creates a result store stored in Postgres
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// parseDAGRunID parses the DAG run ID of a result; a malformed ID cannot name a DAG run
+func parseDAGRunID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid DAG run ID %q", ErrNotFound, id)
+	}
+	return parsed, nil
+}
+
+// Put stores a result, replacing the value a task pushed earlier under the same key
+func (s *PostgresStore) Put(ctx context.Context, result *Result) error {
+	dagRunID, err := uuid.Parse(result.DAGRunID)
+	if err != nil {
+		return fmt.Errorf("invalid DAG run ID %q: %w", result.DAGRunID, err)
+	}
+
+	now := time.Now()
+	model := &resultModel{
+		DAGRunID:  dagRunID,
+		TaskID:    result.TaskID,
+		Key:       result.Key,
+		BlobKey:   result.BlobKey,
+		Size:      result.Size,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if len(result.Value) > 0 {
+		model.Value = result.Value
+	}
+
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dag_run_id"}, {Name: "task_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "blob_key", "size", "updated_at"}),
+	}).Create(model).Error
+	if err != nil {
+		return fmt.Errorf("failed to store task result: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves the result a task of a DAG run pushed under a key
+func (s *PostgresStore) Get(ctx context.Context, dagRunID, taskID, key string) (*Result, error) {
+	runID, err := parseDAGRunID(dagRunID)
+	if err != nil {
+		return nil, err
+	}
+
+	var model resultModel
+	err = s.db.WithContext(ctx).
+		Where("dag_run_id = ? AND task_id = ? AND key = ?", runID, taskID, key).
+		First(&model).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get task result: %w", err)
+	}
+
+	return model.result(), nil
+}
+
+// List lists the results a task of a DAG run pushed, by key
+func (s *PostgresStore) List(ctx context.Context, dagRunID, taskID string) ([]*Result, error) {
+	runID, err := parseDAGRunID(dagRunID)
+	if err != nil {
+		return nil, err
+	}
+
+	var models []resultModel
+	err = s.db.WithContext(ctx).
+		Where("dag_run_id = ? AND task_id = ?", runID, taskID).
+		Order("key").
+		Find(&models).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task results: %w", err)
+	}
+
+	results := make([]*Result, len(models))
+	for i := range models {
+		results[i] = models[i].result()
+	}
+
+	return results, nil
+}
@@ -0,0 +1,144 @@
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no task pushed a result under a key
+	ErrNotFound = errors.New("task result not found")
+
+	// ErrInvalidKey is returned when a result key is empty or contains unsupported characters
+	ErrInvalidKey = errors.New("invalid task result key")
+
+	// ErrInvalidValue is returned when a result value is not JSON
+	ErrInvalidValue = errors.New("task result value is not JSON")
+
+	// ErrValueTooLarge is returned when a result value exceeds the size limit
+	ErrValueTooLarge = errors.New("task result value too large")
+)
+
+// keyPattern matches valid result keys
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,255}$`)
+
+// ValidateKey checks that a result key is non-empty and only contains letters, digits, '_', '.' and '-'
+func ValidateKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// Result is a value a task of a DAG run pushed for downstream tasks, keyed by (run, task, key)
+type Result struct {
+	DAGRunID  string          `json:"dag_run_id"`
+	TaskID    string          `json:"task_id"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value,omitempty"`    // JSON value; empty in the store when spilled to a blob
+	BlobKey   string          `json:"blob_key,omitempty"` // Blob holding the value when it exceeded the inline size limit
+	Size      int             `json:"size"`               // Size of the JSON value in bytes
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Type returns the JSON type of the value: string, number, boolean, object, array or null.
+// It is empty while the value is spilled to a blob and not loaded.
+func (r *Result) Type() string {
+	for _, c := range r.Value {
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '"':
+			return "string"
+		case '{':
+			return "object"
+		case '[':
+			return "array"
+		case 't', 'f':
+			return "boolean"
+		case 'n':
+			return "null"
+		default:
+			return "number"
+		}
+	}
+	return ""
+}
+
+// Store persists task results
+type Store interface {
+	// Put stores a result, replacing the value a task pushed earlier under the same key
+	Put(ctx context.Context, result *Result) error
+
+	// Get retrieves the result a task of a DAG run pushed under a key
+	Get(ctx context.Context, dagRunID, taskID, key string) (*Result, error)
+
+	// List lists the results a task of a DAG run pushed, by key
+	List(ctx context.Context, dagRunID, taskID string) ([]*Result, error)
+}
+
+// MemoryStore is an in-memory implementation of the result store (for testing/development)
+type MemoryStore struct {
+	mu      sync.RWMutex
+	results map[string]*Result
+}
+
+// NewMemoryStore creates a new in-memory result store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		results: make(map[string]*Result),
+	}
+}
+
+// memoryKey returns the key of a result in the memory store
+func memoryKey(dagRunID, taskID, key string) string {
+	return dagRunID + "\x00" + taskID + "\x00" + key
+}
+
+// Put stores a result, replacing the value a task pushed earlier under the same key
+func (s *MemoryStore) Put(ctx context.Context, result *Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *result
+	stored.UpdatedAt = time.Now()
+	s.results[memoryKey(result.DAGRunID, result.TaskID, result.Key)] = &stored
+	return nil
+}
+
+// Get retrieves the result a task of a DAG run pushed under a key
+func (s *MemoryStore) Get(ctx context.Context, dagRunID, taskID, key string) (*Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, ok := s.results[memoryKey(dagRunID, taskID, key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *result
+	return &copied, nil
+}
+
+// List lists the results a task of a DAG run pushed, by key
+func (s *MemoryStore) List(ctx context.Context, dagRunID, taskID string) ([]*Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []*Result
+	for _, result := range s.results {
+		if result.DAGRunID == dagRunID && result.TaskID == taskID {
+			copied := *result
+			results = append(results, &copied)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Key < results[j].Key
+	})
+	return results, nil
+}
@@ -0,0 +1,178 @@
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStore_PutGetList(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	for _, result := range []*Result{
+		{DAGRunID: "run1", TaskID: "extract", Key: "tables", Value: json.RawMessage(`["a","b"]`), Size: 9},
+		{DAGRunID: "run1", TaskID: "extract", Key: "rows", Value: json.RawMessage(`42`), Size: 2},
+		{DAGRunID: "run1", TaskID: "load", Key: "rows", Value: json.RawMessage(`40`), Size: 2},
+		{DAGRunID: "run2", TaskID: "extract", Key: "rows", Value: json.RawMessage(`7`), Size: 1},
+	} {
+		if err := store.Put(ctx, result); err != nil {
+			t.Fatalf("Failed to put result: %v", err)
+		}
+	}
+
+	// Pushing again replaces the value
+	if err := store.Put(ctx, &Result{DAGRunID: "run1", TaskID: "extract", Key: "rows", Value: json.RawMessage(`43`), Size: 2}); err != nil {
+		t.Fatalf("Failed to put result: %v", err)
+	}
+
+	result, err := store.Get(ctx, "run1", "extract", "rows")
+	if err != nil {
+		t.Fatalf("Failed to get result: %v", err)
+	}
+	if string(result.Value) != "43" {
+		t.Errorf("Expected value 43, got %s", result.Value)
+	}
+	if result.UpdatedAt.IsZero() {
+		t.Error("Expected the update time to be set")
+	}
+
+	if _, err := store.Get(ctx, "run1", "extract", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	listed, err := store.List(ctx, "run1", "extract")
+	if err != nil {
+		t.Fatalf("Failed to list results: %v", err)
+	}
+	if len(listed) != 2 || listed[0].Key != "rows" || listed[1].Key != "tables" {
+		t.Errorf("Expected results rows and tables of run1/extract, got %+v", listed)
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	for _, key := range []string{"rows", "row_count", "stats.v2", "job-id"} {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("Expected key %q to be valid, got %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "row count", "a/b", strings.Repeat("k", 256)} {
+		if err := ValidateKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected key %q to be invalid, got %v", key, err)
+		}
+	}
+}
+
+func TestResult_Type(t *testing.T) {
+	tests := map[string]string{
+		`"text"`:   "string",
+		` 42`:      "number",
+		`-1.5`:     "number",
+		`true`:     "boolean",
+		`false`:    "boolean",
+		`null`:     "null",
+		`{"a": 1}`: "object",
+		`[1, 2]`:   "array",
+		``:         "",
+	}
+	for value, want := range tests {
+		result := &Result{Value: json.RawMessage(value)}
+		if got := result.Type(); got != want {
+			t.Errorf("Type of %q = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestManager_Push(t *testing.T) {
+	manager := NewManager(NewMemoryStore(), &Config{MaxValueSize: 16, MaxBlobSize: 64})
+	ctx := context.Background()
+
+	if err := manager.Push(ctx, "run1", "extract", "rows", json.RawMessage(`42`)); err != nil {
+		t.Fatalf("Failed to push result: %v", err)
+	}
+	value, err := manager.Pull(ctx, "run1", "extract", "rows")
+	if err != nil {
+		t.Fatalf("Failed to pull result: %v", err)
+	}
+	if string(value) != "42" {
+		t.Errorf("Expected value 42, got %s", value)
+	}
+
+	if err := manager.Push(ctx, "run1", "extract", "bad key", json.RawMessage(`1`)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+	if err := manager.Push(ctx, "run1", "extract", "rows", json.RawMessage(`{not json`)); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue, got %v", err)
+	}
+
+	// Without a blob store, values over the inline limit are rejected
+	large := json.RawMessage(`"` + strings.Repeat("x", 32) + `"`)
+	if err := manager.Push(ctx, "run1", "extract", "large", large); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+}
+
+func TestManager_SpillsToBlobStore(t *testing.T) {
+	blobs, err := NewFileBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create blob store: %v", err)
+	}
+	store := NewMemoryStore()
+	manager := NewManager(store, &Config{MaxValueSize: 16, MaxBlobSize: 64, BlobStore: blobs})
+	ctx := context.Background()
+
+	large := json.RawMessage(`"` + strings.Repeat("x", 32) + `"`)
+	if err := manager.Push(ctx, "run1", "extract", "large", large); err != nil {
+		t.Fatalf("Failed to push result: %v", err)
+	}
+
+	// The store only keeps a reference to the blob
+	stored, err := store.Get(ctx, "run1", "extract", "large")
+	if err != nil {
+		t.Fatalf("Failed to get result: %v", err)
+	}
+	if stored.BlobKey == "" || len(stored.Value) != 0 {
+		t.Errorf("Expected the value to be spilled to a blob, got %+v", stored)
+	}
+	if stored.Size != len(large) {
+		t.Errorf("Expected size %d, got %d", len(large), stored.Size)
+	}
+
+	value, err := manager.Pull(ctx, "run1", "extract", "large")
+	if err != nil {
+		t.Fatalf("Failed to pull result: %v", err)
+	}
+	if string(value) != string(large) {
+		t.Errorf("Expected value %s, got %s", large, value)
+	}
+
+	listed, err := manager.List(ctx, "run1", "extract")
+	if err != nil {
+		t.Fatalf("Failed to list results: %v", err)
+	}
+	if len(listed) != 1 || string(listed[0].Value) != string(large) || listed[0].Type() != "string" {
+		t.Errorf("Expected the spilled value to be loaded, got %+v", listed)
+	}
+
+	// Values over the blob limit are rejected
+	huge := json.RawMessage(`"` + strings.Repeat("x", 128) + `"`)
+	if err := manager.Push(ctx, "run1", "extract", "huge", huge); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+}
+
+func TestFileBlobStore_RejectsKeysOutsideDirectory(t *testing.T) {
+	blobs, err := NewFileBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create blob store: %v", err)
+	}
+
+	if err := blobs.Put(context.Background(), "../escape.json", []byte(`1`)); err == nil {
+		t.Error("Expected a key leaving the directory to be rejected")
+	}
+	if _, err := blobs.Get(context.Background(), "run1/missing.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
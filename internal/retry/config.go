@@ -1,7 +1,10 @@
 package retry
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
 // Config holds retry configuration for a task
@@ -41,6 +44,53 @@ func NewConfig(maxAttempts int, strategy Strategy) *Config {
 	}
 }
 
+// NewConfigFromPolicy creates a retry config from a task's retry policy
+func NewConfigFromPolicy(policy *models.RetryPolicy, maxAttempts int) (*Config, error) {
+	strategy, err := NewStrategyFromPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	config := NewConfig(maxAttempts, strategy)
+	if len(policy.RetryOn) > 0 {
+		config.WithRetryOnErrorCodes(policy.RetryOn...)
+	}
+	return config, nil
+}
+
+// NewStrategyFromPolicy creates the retry strategy named by a retry policy.
+// Unset delays default to those of DefaultExponentialBackoff.
+func NewStrategyFromPolicy(policy *models.RetryPolicy) (Strategy, error) {
+	if policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+		return nil, fmt.Errorf("retry delays cannot be negative")
+	}
+
+	baseDelay := policy.BaseDelay
+	if baseDelay == 0 {
+		baseDelay = 1 * time.Second
+	}
+	maxDelay := policy.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = 5 * time.Minute
+	}
+	if maxDelay < baseDelay {
+		return nil, fmt.Errorf("max_delay %s is shorter than base_delay %s", maxDelay, baseDelay)
+	}
+
+	switch policy.Strategy {
+	case models.RetryStrategyExponential, "":
+		return NewExponentialBackoff(baseDelay, maxDelay, policy.Jitter), nil
+	case models.RetryStrategyLinear:
+		return NewLinearBackoff(baseDelay, maxDelay, baseDelay, policy.Jitter), nil
+	case models.RetryStrategyFixed:
+		return NewFixedDelay(baseDelay, policy.Jitter), nil
+	case models.RetryStrategyNone:
+		return NewNoRetry(), nil
+	default:
+		return nil, fmt.Errorf("invalid retry strategy: %s", policy.Strategy)
+	}
+}
+
 // WithRetryOnErrorCodes sets the error codes to retry on
 func (c *Config) WithRetryOnErrorCodes(codes ...string) *Config {
 	c.RetryOnErrorCodes = codes
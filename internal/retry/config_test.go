@@ -0,0 +1,96 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestNewConfigFromPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *models.RetryPolicy
+		attempt   int
+		wantDelay time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "exponential",
+			policy:    &models.RetryPolicy{Strategy: models.RetryStrategyExponential, BaseDelay: 2 * time.Second, MaxDelay: time.Minute},
+			attempt:   3,
+			wantDelay: 8 * time.Second,
+		},
+		{
+			name:      "linear",
+			policy:    &models.RetryPolicy{Strategy: models.RetryStrategyLinear, BaseDelay: 2 * time.Second, MaxDelay: time.Minute},
+			attempt:   3,
+			wantDelay: 6 * time.Second,
+		},
+		{
+			name:      "fixed",
+			policy:    &models.RetryPolicy{Strategy: models.RetryStrategyFixed, BaseDelay: 5 * time.Second},
+			attempt:   3,
+			wantDelay: 5 * time.Second,
+		},
+		{
+			name:      "default strategy and delays",
+			policy:    &models.RetryPolicy{},
+			attempt:   1,
+			wantDelay: 1 * time.Second,
+		},
+		{
+			name:    "unknown strategy",
+			policy:  &models.RetryPolicy{Strategy: "random"},
+			wantErr: true,
+		},
+		{
+			name:    "max delay shorter than base delay",
+			policy:  &models.RetryPolicy{BaseDelay: time.Minute, MaxDelay: time.Second},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfigFromPolicy(tt.policy, 3)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("NewConfigFromPolicy() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfigFromPolicy() error = %v", err)
+			}
+
+			if delay := config.CalculateNextDelay(tt.attempt); delay != tt.wantDelay {
+				t.Errorf("CalculateNextDelay(%d) = %v, want %v", tt.attempt, delay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromPolicy_RetryOn(t *testing.T) {
+	config, err := NewConfigFromPolicy(&models.RetryPolicy{Strategy: models.RetryStrategyNone}, 3)
+	if err != nil {
+		t.Fatalf("NewConfigFromPolicy() error = %v", err)
+	}
+	if config.ShouldRetry(1) {
+		t.Error("Expected strategy none never to retry")
+	}
+	if !config.ShouldRetryError("anything") {
+		t.Error("Expected an empty retry_on to retry every error")
+	}
+
+	config, err = NewConfigFromPolicy(&models.RetryPolicy{RetryOn: []string{"timeout", "137"}}, 3)
+	if err != nil {
+		t.Fatalf("NewConfigFromPolicy() error = %v", err)
+	}
+	if !config.ShouldRetryError("137") {
+		t.Error("Expected exit code 137 to be retried")
+	}
+	if config.ShouldRetryError("1") {
+		t.Error("Expected exit code 1 not to be retried")
+	}
+}
@@ -217,6 +217,7 @@ func (be *BackfillEngine) createBackfillRun(dag *models.DAG, execDate time.Time)
 		ExecutionDate:   execDate,
 		State:           models.StateQueued,
 		ExternalTrigger: false, // Backfill runs are not external triggers
+		DAGVersion:      dag.Version,
 	}
 
 	if err := be.dagRunRepo.Create(be.ctx, dagRun); err != nil {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
@@ -28,6 +29,10 @@ type Config struct {
 
 	// MaxCatchupRuns is the maximum number of catchup runs to create
 	MaxCatchupRuns int
+
+	// LeaseTimeout is how long a running DAG run may go without a heartbeat
+	// before the scheduler takes it over and resumes it
+	LeaseTimeout time.Duration
 }
 
 // DefaultConfig returns the default scheduler configuration
@@ -38,6 +43,7 @@ func DefaultConfig() *Config {
 		DefaultTimezone:      "UTC",
 		EnableCatchup:        true,
 		MaxCatchupRuns:       50,
+		LeaseTimeout:         time.Minute,
 	}
 }
 
@@ -49,7 +55,11 @@ type Scheduler struct {
 	taskInstanceRepo  storage.TaskInstanceRepository
 	cronScheduler     *CronScheduler
 	concurrencyMgr    *ConcurrencyManager
+	executor          executor.Executor
+	recovery          *executor.Recovery
 	priorityQueue     *PriorityQueue
+	activeRuns        map[string]string // DAG run ID -> DAG ID for runs holding concurrency slots
+	activeRunsMu      sync.Mutex
 	mu                sync.RWMutex
 	running           bool
 	ctx               context.Context
@@ -58,12 +68,15 @@ type Scheduler struct {
 }
 
 // New creates a new Scheduler instance
+// DAG runs are handed to exec for execution, and their concurrency slots are
+// released when exec reports that the run reached a terminal state
 func New(
 	config *Config,
 	dagRepo storage.DAGRepository,
 	dagRunRepo storage.DAGRunRepository,
 	taskInstanceRepo storage.TaskInstanceRepository,
 	concurrencyMgr *ConcurrencyManager,
+	exec executor.Executor,
 ) *Scheduler {
 	if config == nil {
 		config = DefaultConfig()
@@ -71,16 +84,23 @@ func New(
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Scheduler{
+	s := &Scheduler{
 		config:           config,
 		dagRepo:          dagRepo,
 		dagRunRepo:       dagRunRepo,
 		taskInstanceRepo: taskInstanceRepo,
 		concurrencyMgr:   concurrencyMgr,
+		executor:         exec,
+		recovery:         executor.NewRecovery(dagRepo, dagRunRepo, taskInstanceRepo, config.LeaseTimeout),
 		priorityQueue:    NewPriorityQueue(),
+		activeRuns:       make(map[string]string),
 		ctx:              ctx,
 		cancel:           cancel,
 	}
+
+	exec.OnDAGRunComplete(s.handleDAGRunComplete)
+
+	return s
 }
 
 // Start begins the scheduler's operation
@@ -102,6 +122,11 @@ func (s *Scheduler) Start() error {
 
 	s.cronScheduler = NewCronScheduler(location, s.createDAGRun)
 
+	// Queue runs that were waiting when the scheduler last stopped, before catchup creates new ones
+	if err := s.requeuePendingRuns(); err != nil {
+		return fmt.Errorf("failed to requeue pending DAG runs: %w", err)
+	}
+
 	// Load all active DAGs and register them with cron scheduler
 	if err := s.loadAndRegisterDAGs(); err != nil {
 		return fmt.Errorf("failed to load DAGs: %w", err)
@@ -113,6 +138,13 @@ func (s *Scheduler) Start() error {
 	s.wg.Add(1)
 	go s.schedulingLoop()
 
+	// Resume runs left running by a scheduler that crashed or restarted
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.recovery.Run(s.ctx, s.resumeDAGRun)
+	}()
+
 	log.Println("Scheduler started successfully")
 	return nil
 }
@@ -151,6 +183,11 @@ func (s *Scheduler) IsRunning() bool {
 	return s.running
 }
 
+// QueueDepth returns the number of DAG runs waiting in the priority queue
+func (s *Scheduler) QueueDepth() int {
+	return s.priorityQueue.Len()
+}
+
 // TriggerDAG manually triggers a DAG run
 func (s *Scheduler) TriggerDAG(dagID string, executionDate time.Time) (*models.DAGRun, error) {
 	// Get DAG from repository
@@ -170,6 +207,7 @@ func (s *Scheduler) TriggerDAG(dagID string, executionDate time.Time) (*models.D
 		ExecutionDate:   executionDate,
 		State:           models.StateQueued,
 		ExternalTrigger: true,
+		DAGVersion:      dag.Version,
 	}
 
 	// Save to database
@@ -209,14 +247,14 @@ func (s *Scheduler) schedulingLoop() {
 
 // processScheduledRuns processes pending DAG runs from the priority queue
 func (s *Scheduler) processScheduledRuns() {
-	// Check global concurrency limit
-	if !s.concurrencyMgr.CanScheduleGlobal() {
-		log.Println("Global concurrency limit reached, skipping scheduling")
-		return
-	}
-
 	// Process items from priority queue
 	for {
+		// Check global concurrency limit
+		if !s.concurrencyMgr.CanScheduleGlobal() {
+			log.Println("Global concurrency limit reached, skipping scheduling")
+			return
+		}
+
 		item := s.priorityQueue.Pop()
 		if item == nil {
 			break // Queue is empty
@@ -229,19 +267,20 @@ func (s *Scheduler) processScheduledRuns() {
 			break
 		}
 
+		// Acquire concurrency slots before submitting, since a run may complete
+		// before submission returns
+		s.acquireSlots(item.DAGRunID, item.DAGID)
+
 		// Submit DAG run for execution
 		if err := s.submitDAGRun(item); err != nil {
 			log.Printf("Failed to submit DAG run %s: %v", item.DAGRunID, err)
+			s.releaseSlots(item.DAGRunID)
 			continue
 		}
-
-		// Increment concurrency counters
-		s.concurrencyMgr.IncrementGlobal()
-		s.concurrencyMgr.IncrementDAG(item.DAGID)
 	}
 }
 
-// submitDAGRun submits a DAG run for execution
+// submitDAGRun loads a DAG run and its DAG and hands them to the executor
 func (s *Scheduler) submitDAGRun(item *PriorityQueueItem) error {
 	// Get the DAG run from database
 	dagRun, err := s.dagRunRepo.GetByID(s.ctx, item.DAGRunID)
@@ -249,19 +288,131 @@ func (s *Scheduler) submitDAGRun(item *PriorityQueueItem) error {
 		return fmt.Errorf("failed to get DAG run: %w", err)
 	}
 
-	// Update state to running
-	now := time.Now()
-	dagRun.State = models.StateRunning
-	dagRun.StartDate = &now
+	// The run may have been started elsewhere since it was queued
+	if dagRun.State != models.StateQueued {
+		return fmt.Errorf("DAG run is %s, not queued", dagRun.State)
+	}
 
-	if err := s.dagRunRepo.Update(s.ctx, dagRun); err != nil {
-		return fmt.Errorf("failed to update DAG run state: %w", err)
+	// Load the DAG definition version the run was created with
+	dagVersion, err := s.dagRepo.GetVersion(s.ctx, dagRun.DAGID, dagRun.DAGVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get DAG version %d: %w", dagRun.DAGVersion, err)
 	}
+	dagModel := dagVersion.DAG
+
+	// Execute asynchronously so that blocking executors (e.g. sequential) do not
+	// stall the scheduling loop
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		if err := s.executor.Execute(s.ctx, dagRun, dagModel); err != nil {
+			log.Printf("Failed to execute DAG run %s: %v", dagRun.ID, err)
+			s.failDAGRun(dagRun.ID)
+			s.releaseSlots(dagRun.ID)
+		}
+	}()
 
 	log.Printf("Submitted DAG run %s for execution", item.DAGRunID)
 	return nil
 }
 
+// resumeDAGRun hands an orphaned DAG run back to the executor
+func (s *Scheduler) resumeDAGRun(run *executor.OrphanedRun) {
+	dagRun := run.DAGRun
+
+	// The run was already admitted, so it takes its slots regardless of the limits
+	s.acquireSlots(dagRun.ID, dagRun.DAGID)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		if err := s.executor.Resume(s.ctx, dagRun, run.DAG, run.TaskInstances); err != nil {
+			log.Printf("Failed to resume DAG run %s: %v", dagRun.ID, err)
+			s.failDAGRun(dagRun.ID)
+			s.releaseSlots(dagRun.ID)
+		}
+	}()
+
+	log.Printf("Resumed orphaned DAG run %s", dagRun.ID)
+}
+
+// requeuePendingRuns adds the queued DAG runs in the database to the priority queue
+func (s *Scheduler) requeuePendingRuns() error {
+	queued := models.StateQueued
+	dagRuns, err := s.dagRunRepo.List(s.ctx, storage.DAGRunFilters{State: &queued})
+	if err != nil {
+		return err
+	}
+
+	for _, dagRun := range dagRuns {
+		priority := PriorityMedium
+		if dagRun.ExternalTrigger {
+			priority = PriorityHigh
+		}
+
+		s.priorityQueue.Push(&PriorityQueueItem{
+			DAGRunID:      dagRun.ID,
+			DAGID:         dagRun.DAGID,
+			ExecutionDate: dagRun.ExecutionDate,
+			Priority:      priority,
+			EnqueuedAt:    time.Now(),
+		})
+	}
+
+	if len(dagRuns) > 0 {
+		log.Printf("Requeued %d pending DAG runs", len(dagRuns))
+	}
+	return nil
+}
+
+// handleDAGRunComplete releases the concurrency slots held by a finished DAG run
+func (s *Scheduler) handleDAGRunComplete(dagRun *models.DAGRun, finalState models.State) {
+	if s.releaseSlots(dagRun.ID) {
+		log.Printf("DAG run %s finished with state %s, released concurrency slots", dagRun.ID, finalState)
+	}
+}
+
+// acquireSlots increments the global and per-DAG concurrency counters for a DAG run
+func (s *Scheduler) acquireSlots(dagRunID, dagID string) {
+	s.activeRunsMu.Lock()
+	defer s.activeRunsMu.Unlock()
+
+	s.activeRuns[dagRunID] = dagID
+	s.concurrencyMgr.IncrementGlobal()
+	s.concurrencyMgr.IncrementDAG(dagID)
+}
+
+// releaseSlots decrements the concurrency counters held by a DAG run
+// It returns false if the run holds no slots, so that each run is released only once
+func (s *Scheduler) releaseSlots(dagRunID string) bool {
+	s.activeRunsMu.Lock()
+	defer s.activeRunsMu.Unlock()
+
+	dagID, ok := s.activeRuns[dagRunID]
+	if !ok {
+		return false
+	}
+
+	delete(s.activeRuns, dagRunID)
+	s.concurrencyMgr.DecrementGlobal()
+	s.concurrencyMgr.DecrementDAG(dagID)
+	return true
+}
+
+// failDAGRun marks a DAG run that could not be executed as failed if it is still queued.
+// A run that left queued was claimed by an executor, possibly in another process, so it is left
+// to its owner; runs whose owner stopped are picked up by recovery once their lease expires.
+func (s *Scheduler) failDAGRun(dagRunID string) {
+	// Use a fresh context so the run is marked even during shutdown
+	ctx := context.Background()
+
+	if err := s.dagRunRepo.UpdateState(ctx, dagRunID, models.StateQueued, models.StateFailed); err != nil {
+		log.Printf("Did not mark DAG run %s as failed, it is no longer queued: %v", dagRunID, err)
+	}
+}
+
 // loadAndRegisterDAGs loads all active DAGs from the database and registers them with the cron scheduler
 func (s *Scheduler) loadAndRegisterDAGs() error {
 	// Get all DAGs
@@ -346,6 +497,12 @@ func (s *Scheduler) createDAGRun(dagID string, executionDate time.Time) error {
 		return nil
 	}
 
+	// Pin the run to the current DAG definition version
+	dag, err := s.dagRepo.Get(s.ctx, dagID)
+	if err != nil {
+		return fmt.Errorf("failed to get DAG: %w", err)
+	}
+
 	// Create new DAG run
 	dagRun := &models.DAGRun{
 		ID:              uuid.New().String(),
@@ -353,6 +510,7 @@ func (s *Scheduler) createDAGRun(dagID string, executionDate time.Time) error {
 		ExecutionDate:   executionDate,
 		State:           models.StateQueued,
 		ExternalTrigger: false,
+		DAGVersion:      dag.Version,
 	}
 
 	if err := s.dagRunRepo.Create(s.ctx, dagRun); err != nil {
@@ -0,0 +1,284 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// fakeDAGRepo serves DAGs from memory; unused methods panic via the embedded nil interface
+type fakeDAGRepo struct {
+	storage.DAGRepository
+	dags map[string]*models.DAG
+}
+
+func (r *fakeDAGRepo) Get(ctx context.Context, id string) (*models.DAG, error) {
+	dag, ok := r.dags[id]
+	if !ok {
+		return nil, fmt.Errorf("DAG not found: %s", id)
+	}
+	return dag, nil
+}
+
+func (r *fakeDAGRepo) GetVersion(ctx context.Context, id string, version int) (*models.DAGVersion, error) {
+	dag, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if dag.Version != version {
+		return nil, storage.ErrNotFound
+	}
+	return &models.DAGVersion{DAGID: id, Version: version, DAG: dag}, nil
+}
+
+// fakeDAGRunRepo stores DAG runs in memory
+type fakeDAGRunRepo struct {
+	storage.DAGRunRepository
+	mu   sync.Mutex
+	runs map[string]*models.DAGRun
+}
+
+func (r *fakeDAGRunRepo) GetByID(ctx context.Context, id string) (*models.DAGRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, ok := r.runs[id]
+	if !ok {
+		return nil, fmt.Errorf("DAG run not found: %s", id)
+	}
+	copied := *run
+	return &copied, nil
+}
+
+func (r *fakeDAGRunRepo) UpdateState(ctx context.Context, id string, oldState, newState models.State) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, ok := r.runs[id]
+	if !ok || run.State != oldState {
+		return fmt.Errorf("unexpected state for DAG run %s", id)
+	}
+	run.State = newState
+	return nil
+}
+
+func (r *fakeDAGRunRepo) state(id string) models.State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs[id].State
+}
+
+// fakeExecutor records submitted runs and lets the test complete them
+type fakeExecutor struct {
+	mu         sync.Mutex
+	executed   chan *models.DAG
+	resumed    chan *models.DAGRun
+	err        error
+	onComplete executor.DAGRunCompleteFunc
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.executed <- dag
+	return nil
+}
+
+func (e *fakeExecutor) Resume(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG, taskInstances []*models.TaskInstance) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.resumed <- dagRun
+	return nil
+}
+
+func (e *fakeExecutor) Cancel(ctx context.Context, dagRunID string) error {
+	return executor.ErrDAGRunNotActive
+}
+
+func (e *fakeExecutor) Start(ctx context.Context) error { return nil }
+func (e *fakeExecutor) Stop(ctx context.Context) error  { return nil }
+
+func (e *fakeExecutor) GetStatus() executor.ExecutorStatus {
+	return executor.ExecutorStatus{Running: true}
+}
+
+func (e *fakeExecutor) OnDAGRunComplete(callback executor.DAGRunCompleteFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onComplete = callback
+}
+
+func (e *fakeExecutor) complete(dagRun *models.DAGRun, finalState models.State) {
+	e.mu.Lock()
+	onComplete := e.onComplete
+	e.mu.Unlock()
+	onComplete(dagRun, finalState)
+}
+
+func newTestScheduler(exec *fakeExecutor) (*Scheduler, *fakeDAGRunRepo, *ConcurrencyManager) {
+	dagRepo := &fakeDAGRepo{dags: map[string]*models.DAG{
+		"dag1": {
+			ID:      "dag1",
+			Name:    "dag1",
+			Version: 2,
+			Tasks:   []models.Task{{ID: "task1", Type: models.TaskTypeBash, Command: "echo hi"}},
+		},
+	}}
+	dagRunRepo := &fakeDAGRunRepo{runs: map[string]*models.DAGRun{
+		"run1": {ID: "run1", DAGID: "dag1", State: models.StateQueued, DAGVersion: 2},
+	}}
+	cm := NewConcurrencyManager(context.Background(), &ConcurrencyConfig{MaxGlobalConcurrency: 10, DefaultDAGConcurrency: 2})
+
+	s := New(DefaultConfig(), dagRepo, dagRunRepo, nil, cm, exec)
+	s.priorityQueue.Push(&PriorityQueueItem{
+		DAGRunID:   "run1",
+		DAGID:      "dag1",
+		Priority:   PriorityMedium,
+		EnqueuedAt: time.Now(),
+	})
+
+	return s, dagRunRepo, cm
+}
+
+func TestScheduler_SubmitsRunsToExecutor(t *testing.T) {
+	exec := &fakeExecutor{executed: make(chan *models.DAG, 1)}
+	s, _, cm := newTestScheduler(exec)
+	defer s.cancel()
+
+	s.processScheduledRuns()
+
+	select {
+	case dag := <-exec.executed:
+		if len(dag.Tasks) != 1 {
+			t.Errorf("executor received %d tasks, want 1", len(dag.Tasks))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("DAG run was not handed to the executor")
+	}
+
+	if cm.GetGlobalCount() != 1 || cm.GetDAGCount("dag1") != 1 {
+		t.Errorf("expected slots to be held, got global=%d dag=%d", cm.GetGlobalCount(), cm.GetDAGCount("dag1"))
+	}
+
+	exec.complete(&models.DAGRun{ID: "run1", DAGID: "dag1"}, models.StateSuccess)
+
+	if cm.GetGlobalCount() != 0 || cm.GetDAGCount("dag1") != 0 {
+		t.Errorf("expected slots to be released, got global=%d dag=%d", cm.GetGlobalCount(), cm.GetDAGCount("dag1"))
+	}
+
+	// A second completion for the same run must not release slots again
+	exec.complete(&models.DAGRun{ID: "run1", DAGID: "dag1"}, models.StateSuccess)
+	if cm.GetGlobalCount() != 0 {
+		t.Errorf("expected global count to stay at 0, got %d", cm.GetGlobalCount())
+	}
+}
+
+func TestScheduler_ReleasesSlotsWhenExecuteFails(t *testing.T) {
+	exec := &fakeExecutor{err: fmt.Errorf("executor is not running")}
+	s, dagRunRepo, cm := newTestScheduler(exec)
+	defer s.cancel()
+
+	s.processScheduledRuns()
+	s.wg.Wait()
+
+	if cm.GetGlobalCount() != 0 || cm.GetDAGCount("dag1") != 0 {
+		t.Errorf("expected slots to be released, got global=%d dag=%d", cm.GetGlobalCount(), cm.GetDAGCount("dag1"))
+	}
+
+	if got := dagRunRepo.state("run1"); got != models.StateFailed {
+		t.Errorf("DAG run state = %s, want %s", got, models.StateFailed)
+	}
+}
+
+// claimingExecutor fails like an executor that lost the race for a DAG run to another process
+type claimingExecutor struct {
+	fakeExecutor
+	dagRunRepo *fakeDAGRunRepo
+}
+
+func (e *claimingExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG) error {
+	if err := e.dagRunRepo.UpdateState(ctx, dagRun.ID, models.StateQueued, models.StateRunning); err != nil {
+		return err
+	}
+	return fmt.Errorf("failed to update DAG run state: DAG run %s was claimed", dagRun.ID)
+}
+
+func TestScheduler_LeavesClaimedRunsAloneWhenExecuteFails(t *testing.T) {
+	exec := &claimingExecutor{}
+	s, dagRunRepo, cm := newTestScheduler(&exec.fakeExecutor)
+	defer s.cancel()
+	exec.dagRunRepo = dagRunRepo
+	s.executor = exec
+
+	s.processScheduledRuns()
+	s.wg.Wait()
+
+	if cm.GetGlobalCount() != 0 || cm.GetDAGCount("dag1") != 0 {
+		t.Errorf("expected slots to be released, got global=%d dag=%d", cm.GetGlobalCount(), cm.GetDAGCount("dag1"))
+	}
+	if got := dagRunRepo.state("run1"); got != models.StateRunning {
+		t.Errorf("DAG run state = %s, want %s", got, models.StateRunning)
+	}
+}
+
+func TestScheduler_ResumesOrphanedRuns(t *testing.T) {
+	exec := &fakeExecutor{resumed: make(chan *models.DAGRun, 1)}
+	s, _, cm := newTestScheduler(exec)
+	defer s.cancel()
+
+	s.resumeDAGRun(&executor.OrphanedRun{
+		DAGRun: &models.DAGRun{ID: "run2", DAGID: "dag1", State: models.StateRunning, DAGVersion: 2},
+		DAG:    &models.DAG{ID: "dag1"},
+	})
+
+	select {
+	case dagRun := <-exec.resumed:
+		if dagRun.ID != "run2" {
+			t.Errorf("executor resumed %s, want run2", dagRun.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("DAG run was not handed back to the executor")
+	}
+
+	if cm.GetGlobalCount() != 1 || cm.GetDAGCount("dag1") != 1 {
+		t.Errorf("expected resumed run to hold slots, got global=%d dag=%d", cm.GetGlobalCount(), cm.GetDAGCount("dag1"))
+	}
+
+	exec.complete(&models.DAGRun{ID: "run2", DAGID: "dag1"}, models.StateSuccess)
+	if cm.GetGlobalCount() != 0 {
+		t.Errorf("expected slots to be released, got global=%d", cm.GetGlobalCount())
+	}
+}
+
+func TestScheduler_SkipsRunsNoLongerQueued(t *testing.T) {
+	exec := &fakeExecutor{executed: make(chan *models.DAG, 1)}
+	s, dagRunRepo, cm := newTestScheduler(exec)
+	defer s.cancel()
+
+	// Another process started the run after it was queued
+	dagRunRepo.runs["run1"].State = models.StateRunning
+
+	s.processScheduledRuns()
+	s.wg.Wait()
+
+	select {
+	case <-exec.executed:
+		t.Fatal("DAG run that is already running was executed again")
+	default:
+	}
+
+	if cm.GetGlobalCount() != 0 {
+		t.Errorf("expected slots to be released, got global=%d", cm.GetGlobalCount())
+	}
+	if got := dagRunRepo.state("run1"); got != models.StateRunning {
+		t.Errorf("DAG run state = %s, want %s", got, models.StateRunning)
+	}
+}
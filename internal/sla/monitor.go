@@ -0,0 +1,152 @@
+// Package sla detects task instances and DAG runs that miss their SLA
+package sla
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// Notifier is told about every SLA miss once it has been recorded
+type Notifier interface {
+	NotifySLAMiss(ctx context.Context, miss *models.SLAMiss) error
+}
+
+// NotifierFunc adapts a function to a Notifier
+type NotifierFunc func(ctx context.Context, miss *models.SLAMiss) error
+
+// NotifySLAMiss calls f
+func (f NotifierFunc) NotifySLAMiss(ctx context.Context, miss *models.SLAMiss) error {
+	return f(ctx, miss)
+}
+
+// MonitorConfig configures a Monitor
+type MonitorConfig struct {
+	CheckInterval time.Duration        // How often SLAs are checked
+	Reference     storage.SLAReference // What SLAs are measured from
+	Lookback      time.Duration        // Only DAG runs created within the lookback are checked
+}
+
+// DefaultMonitorConfig returns the default SLA monitor configuration
+func DefaultMonitorConfig() MonitorConfig {
+	return MonitorConfig{
+		CheckInterval: 30 * time.Second,
+		Reference:     storage.SLAFromExecutionDate,
+		Lookback:      24 * time.Hour,
+	}
+}
+
+// Monitor periodically compares task instances and DAG runs against the SLA of their definition.
+// Each miss is recorded once, which publishes a state change event through the outbox,
+// and then handed to the notifiers of the process that recorded it.
+type Monitor struct {
+	repo   storage.SLAMissRepository
+	config MonitorConfig
+
+	mu        sync.RWMutex
+	notifiers []Notifier
+}
+
+// NewMonitor creates an SLA monitor calling notifiers for every new miss
+func NewMonitor(repo storage.SLAMissRepository, config MonitorConfig, notifiers ...Notifier) *Monitor {
+	defaults := DefaultMonitorConfig()
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaults.CheckInterval
+	}
+	if config.Reference == "" {
+		config.Reference = defaults.Reference
+	}
+	if config.Lookback <= 0 {
+		config.Lookback = defaults.Lookback
+	}
+
+	return &Monitor{
+		repo:      repo,
+		config:    config,
+		notifiers: notifiers,
+	}
+}
+
+// AddNotifier adds a notifier called for every new miss
+func (m *Monitor) AddNotifier(notifier Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notifiers = append(m.notifiers, notifier)
+}
+
+// Check records the misses found at now and notifies about them. It returns the newly recorded misses;
+// misses recorded by another process in the meantime are skipped.
+func (m *Monitor) Check(ctx context.Context, now time.Time) ([]*models.SLAMiss, error) {
+	misses, err := m.repo.FindMisses(ctx, storage.SLACheckOptions{
+		Now:       now,
+		Since:     now.Add(-m.config.Lookback),
+		Reference: m.config.Reference,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find SLA misses: %w", err)
+	}
+
+	var recorded []*models.SLAMiss
+	for _, miss := range misses {
+		miss.DetectedAt = now
+		ok, err := m.repo.Record(ctx, miss)
+		if err != nil {
+			log.Printf("Failed to record SLA miss of DAG run %s: %v", miss.DAGRunID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		log.Printf("SLA miss: %s (DAG: %s, DAG run: %s) was %s at its deadline %s",
+			describe(miss), miss.DAGID, miss.DAGRunID, miss.State, miss.Deadline.Format(time.RFC3339))
+		m.notify(ctx, miss)
+		recorded = append(recorded, miss)
+	}
+
+	return recorded, nil
+}
+
+// notify hands a miss to every notifier; failures are logged, the miss stays recorded
+func (m *Monitor) notify(ctx context.Context, miss *models.SLAMiss) {
+	m.mu.RLock()
+	notifiers := m.notifiers
+	m.mu.RUnlock()
+
+	for _, notifier := range notifiers {
+		if err := notifier.NotifySLAMiss(ctx, miss); err != nil {
+			log.Printf("Failed to notify SLA miss %s: %v", miss.ID, err)
+		}
+	}
+}
+
+// Run checks SLAs right away and then every check interval until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Check(ctx, time.Now().UTC()); err != nil {
+			log.Printf("Failed to check SLAs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// describe names the task or DAG run that missed its SLA
+func describe(miss *models.SLAMiss) string {
+	if miss.TaskID == "" {
+		return "DAG run"
+	}
+	return "task " + miss.TaskID
+}
@@ -0,0 +1,93 @@
+package sla
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// fakeRepository finds the same misses on every check and records each of them once
+type fakeRepository struct {
+	storage.SLAMissRepository
+	misses   []models.SLAMiss
+	recorded map[string]bool
+	opts     storage.SLACheckOptions
+}
+
+func (r *fakeRepository) FindMisses(ctx context.Context, opts storage.SLACheckOptions) ([]*models.SLAMiss, error) {
+	r.opts = opts
+	misses := make([]*models.SLAMiss, len(r.misses))
+	for i := range r.misses {
+		miss := r.misses[i]
+		misses[i] = &miss
+	}
+	return misses, nil
+}
+
+func (r *fakeRepository) Record(ctx context.Context, miss *models.SLAMiss) (bool, error) {
+	key := miss.DAGRunID + "/" + miss.TaskID
+	if r.recorded[key] {
+		return false, nil
+	}
+	r.recorded[key] = true
+	miss.ID = key
+	return true, nil
+}
+
+func TestMonitor_Check(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeRepository{
+		misses: []models.SLAMiss{
+			{DAGID: "dag1", DAGRunID: "run1", TaskID: "extract", State: models.StateRunning},
+			{DAGID: "dag1", DAGRunID: "run1", State: models.StateRunning},
+		},
+		recorded: map[string]bool{},
+	}
+
+	var notified []string
+	monitor := NewMonitor(repo, MonitorConfig{Lookback: time.Hour, Reference: storage.SLAFromStartDate},
+		NotifierFunc(func(ctx context.Context, miss *models.SLAMiss) error {
+			notified = append(notified, miss.ID)
+			return nil
+		}))
+	monitor.AddNotifier(NotifierFunc(func(ctx context.Context, miss *models.SLAMiss) error {
+		return errors.New("unreachable")
+	}))
+
+	recorded, err := monitor.Check(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(recorded) != 2 {
+		t.Fatalf("Expected 2 recorded misses, got %d", len(recorded))
+	}
+	if recorded[0].DetectedAt != now {
+		t.Errorf("Expected misses to be detected at %v, got %v", now, recorded[0].DetectedAt)
+	}
+	if len(notified) != 2 || notified[0] != "run1/extract" || notified[1] != "run1/" {
+		t.Errorf("Expected both misses to be notified despite a failing notifier, got %v", notified)
+	}
+	if repo.opts.Since != now.Add(-time.Hour) || repo.opts.Reference != storage.SLAFromStartDate {
+		t.Errorf("Unexpected check options: %+v", repo.opts)
+	}
+
+	// Misses are only notified by the check that recorded them
+	recorded, err = monitor.Check(context.Background(), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(recorded) != 0 || len(notified) != 2 {
+		t.Errorf("Expected recorded misses not to be notified again, got %d recorded and %d notified", len(recorded), len(notified))
+	}
+}
+
+func TestNewMonitor_Defaults(t *testing.T) {
+	monitor := NewMonitor(&fakeRepository{}, MonitorConfig{})
+	if monitor.config != DefaultMonitorConfig() {
+		t.Errorf("Expected default configuration, got %+v", monitor.config)
+	}
+}
@@ -19,7 +19,7 @@ type HistoryEntry struct {
 	OldState   *string                `gorm:"type:varchar(50)" json:"old_state"`
 	NewState   string                 `gorm:"type:varchar(50);not null" json:"new_state"`
 	ChangedAt  time.Time              `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_state_history_changed_at" json:"changed_at"`
-	Metadata   map[string]interface{} `gorm:"type:jsonb;default:'{}'" json:"metadata"`
+	Metadata   map[string]interface{} `gorm:"type:jsonb;serializer:json;default:'{}'" json:"metadata"`
 }
 
 // TableName specifies the table name for HistoryEntry
@@ -27,18 +27,36 @@ func NewStateMachine() *StateMachine {
 			models.StateQueued: {
 				models.StateRunning,
 				models.StateSkipped,
-				models.StateFailed, // Can fail during queue (e.g., invalid config)
+				models.StateFailed,         // Can fail during queue (e.g., invalid config)
+				models.StateUpstreamFailed, // A dependency failed before the task started
+				models.StateCancelled,      // The DAG run was cancelled before the task started
 			},
 			models.StateRunning: {
 				models.StateSuccess,
 				models.StateFailed,
 				models.StateRetrying,
 				models.StateUpstreamFailed,
+				models.StateCancelled,
+				models.StateUpForReschedule, // A sensor released its slot until its next poke
 			},
 			models.StateRetrying: {
 				models.StateRunning,
 				models.StateFailed,
 				models.StateSuccess,
+				models.StateCancelled,
+				models.StateScheduled, // The next attempt waits for a point in time
+			},
+			models.StateScheduled: {
+				models.StateQueued,
+				models.StateRunning,
+				models.StateFailed,
+				models.StateCancelled,
+			},
+			models.StateUpForReschedule: {
+				models.StateQueued,
+				models.StateRunning,
+				models.StateFailed,
+				models.StateCancelled,
 			},
 			models.StateFailed: {
 				models.StateRetrying, // Manual retry
@@ -48,8 +66,9 @@ func NewStateMachine() *StateMachine {
 				models.StateQueued, // Retry entire DAG run
 			},
 			// Terminal states generally don't transition
-			models.StateSuccess: {},
-			models.StateSkipped: {},
+			models.StateSuccess:   {},
+			models.StateSkipped:   {},
+			models.StateCancelled: {},
 		},
 	}
 }
@@ -97,6 +116,12 @@ func (sm *StateMachine) IsTerminalState(state models.State) bool {
 	return state.IsTerminal()
 }
 
+// Metadata keys set on the events of state changes written by the repositories
+const (
+	MetadataDAGID    = "dag_id"     // ID of the DAG of the DAG run or task instance
+	MetadataDAGRunID = "dag_run_id" // ID of the DAG run, or of the DAG run of the task instance
+)
+
 // TransitionEvent represents a state transition event
 type TransitionEvent struct {
 	EntityType string        // "dag_run" or "task_instance"
@@ -104,6 +129,7 @@ type TransitionEvent struct {
 	OldState   models.State
 	NewState   models.State
 	Metadata   map[string]interface{}
+	OutboxID   int64 // Set on events delivered by an OutboxRelay, so that subscribers can drop redeliveries
 }
 
 // EventPublisher is an interface for publishing state change events
@@ -139,23 +165,29 @@ func NewManager(publisher EventPublisher) *Manager {
 // Transition performs a state transition and publishes an event
 func (m *Manager) Transition(entityType, entityID string, from, to models.State, metadata map[string]interface{}) error {
 	// Validate transition
-	if err := m.machine.ValidateTransition(from, to); err != nil {
+	if err := m.Validate(from, to); err != nil {
 		return err
 	}
 
-	// Publish event
-	event := TransitionEvent{
+	return m.Publish(TransitionEvent{
 		EntityType: entityType,
 		EntityID:   entityID,
 		OldState:   from,
 		NewState:   to,
 		Metadata:   metadata,
-	}
+	})
+}
 
+// Validate checks a state transition without publishing an event
+func (m *Manager) Validate(from, to models.State) error {
+	return m.machine.ValidateTransition(from, to)
+}
+
+// Publish publishes the event of a state transition that has already been persisted
+func (m *Manager) Publish(event TransitionEvent) error {
 	if err := m.publisher.Publish(event); err != nil {
 		return fmt.Errorf("failed to publish state transition event: %w", err)
 	}
-
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
@@ -19,17 +20,34 @@ func TestStateMachine_CanTransition(t *testing.T) {
 		{"Queued to Running", models.StateQueued, models.StateRunning, true},
 		{"Queued to Skipped", models.StateQueued, models.StateSkipped, true},
 		{"Queued to Failed", models.StateQueued, models.StateFailed, true},
+		{"Queued to UpstreamFailed", models.StateQueued, models.StateUpstreamFailed, true},
+		{"Queued to Cancelled", models.StateQueued, models.StateCancelled, true},
 
 		// Valid transitions from Running
 		{"Running to Success", models.StateRunning, models.StateSuccess, true},
 		{"Running to Failed", models.StateRunning, models.StateFailed, true},
 		{"Running to Retrying", models.StateRunning, models.StateRetrying, true},
 		{"Running to UpstreamFailed", models.StateRunning, models.StateUpstreamFailed, true},
+		{"Running to Cancelled", models.StateRunning, models.StateCancelled, true},
+		{"Running to UpForReschedule", models.StateRunning, models.StateUpForReschedule, true},
 
 		// Valid transitions from Retrying
 		{"Retrying to Running", models.StateRetrying, models.StateRunning, true},
 		{"Retrying to Failed", models.StateRetrying, models.StateFailed, true},
 		{"Retrying to Success", models.StateRetrying, models.StateSuccess, true},
+		{"Retrying to Cancelled", models.StateRetrying, models.StateCancelled, true},
+		{"Retrying to Scheduled", models.StateRetrying, models.StateScheduled, true},
+
+		// Valid transitions from Scheduled
+		{"Scheduled to Queued", models.StateScheduled, models.StateQueued, true},
+		{"Scheduled to Running", models.StateScheduled, models.StateRunning, true},
+		{"Scheduled to Cancelled", models.StateScheduled, models.StateCancelled, true},
+
+		// Valid transitions from UpForReschedule
+		{"UpForReschedule to Queued", models.StateUpForReschedule, models.StateQueued, true},
+		{"UpForReschedule to Running", models.StateUpForReschedule, models.StateRunning, true},
+		{"UpForReschedule to Failed", models.StateUpForReschedule, models.StateFailed, true},
+		{"UpForReschedule to Cancelled", models.StateUpForReschedule, models.StateCancelled, true},
 
 		// Valid transitions from Failed
 		{"Failed to Retrying", models.StateFailed, models.StateRetrying, true},
@@ -46,6 +64,11 @@ func TestStateMachine_CanTransition(t *testing.T) {
 		{"Success to Running", models.StateSuccess, models.StateRunning, false},
 		{"Success to Failed", models.StateSuccess, models.StateFailed, false},
 		{"Skipped to Running", models.StateSkipped, models.StateRunning, false},
+		{"Cancelled to Running", models.StateCancelled, models.StateRunning, false},
+		{"Cancelled to Queued", models.StateCancelled, models.StateQueued, false},
+		{"Queued to Scheduled", models.StateQueued, models.StateScheduled, false},
+		{"Scheduled to Success", models.StateScheduled, models.StateSuccess, false},
+		{"UpForReschedule to Success", models.StateUpForReschedule, models.StateSuccess, false},
 		{"Queued to Success", models.StateQueued, models.StateSuccess, false},
 		{"Running to Queued", models.StateRunning, models.StateQueued, false},
 	}
@@ -96,12 +119,15 @@ func TestStateMachine_GetNextStates(t *testing.T) {
 		current  models.State
 		expected int // number of valid next states
 	}{
-		{"Queued has 3 next states", models.StateQueued, 3},
-		{"Running has 4 next states", models.StateRunning, 4},
-		{"Retrying has 3 next states", models.StateRetrying, 3},
+		{"Queued has 5 next states", models.StateQueued, 5},
+		{"Running has 6 next states", models.StateRunning, 6},
+		{"Retrying has 5 next states", models.StateRetrying, 5},
+		{"Scheduled has 4 next states", models.StateScheduled, 4},
+		{"UpForReschedule has 4 next states", models.StateUpForReschedule, 4},
 		{"Failed has 2 next states", models.StateFailed, 2},
 		{"Success has 0 next states", models.StateSuccess, 0},
 		{"Skipped has 0 next states", models.StateSkipped, 0},
+		{"Cancelled has 0 next states", models.StateCancelled, 0},
 	}
 
 	for _, tt := range tests {
@@ -125,10 +151,13 @@ func TestStateMachine_IsTerminalState(t *testing.T) {
 		{"Success is terminal", models.StateSuccess, true},
 		{"Failed is terminal", models.StateFailed, true},
 		{"Skipped is terminal", models.StateSkipped, true},
+		{"Cancelled is terminal", models.StateCancelled, true},
 		{"Queued is not terminal", models.StateQueued, false},
 		{"Running is not terminal", models.StateRunning, false},
 		{"Retrying is not terminal", models.StateRetrying, false},
 		{"UpstreamFailed is not terminal", models.StateUpstreamFailed, false},
+		{"Scheduled is not terminal", models.StateScheduled, false},
+		{"UpForReschedule is not terminal", models.StateUpForReschedule, false},
 	}
 
 	for _, tt := range tests {
@@ -212,6 +241,29 @@ func TestManager_Transition(t *testing.T) {
 	}
 }
 
+func TestManager_ValidateDoesNotPublish(t *testing.T) {
+	var publishedEvents []TransitionEvent
+	manager := NewManager(&mockPublisher{events: &publishedEvents})
+
+	if err := manager.Validate(models.StateQueued, models.StateRunning); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	if err := manager.Validate(models.StateSuccess, models.StateRunning); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrInvalidTransition)
+	}
+	if len(publishedEvents) != 0 {
+		t.Errorf("Validate() published %d events, want 0", len(publishedEvents))
+	}
+
+	event := TransitionEvent{EntityType: "task_instance", EntityID: "123", OldState: models.StateQueued, NewState: models.StateRunning}
+	if err := manager.Publish(event); err != nil {
+		t.Errorf("Publish() error = %v, want nil", err)
+	}
+	if len(publishedEvents) != 1 || publishedEvents[0].EntityID != "123" {
+		t.Errorf("Publish() published %v, want the event", publishedEvents)
+	}
+}
+
 func TestNoOpPublisher(t *testing.T) {
 	publisher := &NoOpPublisher{}
 	event := TransitionEvent{
@@ -0,0 +1,269 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+	"gorm.io/gorm"
+)
+
+// outboxLockKey is the Postgres advisory lock held by the relay delivering the outbox,
+// so that relays of several processes do not deliver events of the same entity out of order
+const outboxLockKey = 7236001
+
+// OutboxEntry is a state change waiting to be delivered by an OutboxRelay
+type OutboxEntry struct {
+	ID            int64                  `gorm:"primaryKey;autoIncrement"`
+	EntityType    string                 `gorm:"type:varchar(50);not null"`
+	EntityID      uuid.UUID              `gorm:"type:uuid;not null"`
+	OldState      *string                `gorm:"type:varchar(50)"`
+	NewState      string                 `gorm:"type:varchar(50);not null"`
+	Metadata      map[string]interface{} `gorm:"type:jsonb;serializer:json;default:'{}'"`
+	CreatedAt     time.Time              `gorm:"not null"`
+	Attempts      int                    `gorm:"not null;default:0"`
+	LastError     string                 `gorm:"type:text;not null;default:''"`
+	NextAttemptAt time.Time              `gorm:"not null"`
+	DeliveredAt   *time.Time
+}
+
+// TableName specifies the table name for OutboxEntry
+func (OutboxEntry) TableName() string {
+	return "state_outbox"
+}
+
+// event returns the transition event of an outbox entry
+func (e *OutboxEntry) event() TransitionEvent {
+	var oldState models.State
+	if e.OldState != nil {
+		oldState = models.State(*e.OldState)
+	}
+
+	return TransitionEvent{
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID.String(),
+		OldState:   oldState,
+		NewState:   models.State(e.NewState),
+		Metadata:   e.Metadata,
+		OutboxID:   e.ID,
+	}
+}
+
+// Outbox writes state change events to the state_outbox table
+type Outbox struct {
+	db *gorm.DB
+}
+
+// NewOutbox creates an outbox writing through db, usually the transaction of the state change
+func NewOutbox(db *gorm.DB) *Outbox {
+	return &Outbox{db: db}
+}
+
+// Enqueue stores an event for delivery once the surrounding transaction commits
+func (o *Outbox) Enqueue(ctx context.Context, event TransitionEvent) error {
+	entityUUID, err := uuid.Parse(event.EntityID)
+	if err != nil {
+		return fmt.Errorf("invalid entity ID: %w", err)
+	}
+
+	var oldState *string
+	if event.OldState != "" {
+		str := string(event.OldState)
+		oldState = &str
+	}
+
+	now := time.Now().UTC()
+	entry := OutboxEntry{
+		EntityType:    event.EntityType,
+		EntityID:      entityUUID,
+		OldState:      oldState,
+		NewState:      string(event.NewState),
+		Metadata:      event.Metadata,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+
+	if err := o.db.WithContext(ctx).Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to enqueue state change event: %w", err)
+	}
+
+	return nil
+}
+
+// ListAfter returns the events of the entries written after the given outbox ID in order,
+// whether they have been delivered yet or not
+func (o *Outbox) ListAfter(ctx context.Context, afterID int64, limit int) ([]TransitionEvent, error) {
+	query := o.db.WithContext(ctx).Where("id > ?", afterID).Order("id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var entries []OutboxEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list outbox entries: %w", err)
+	}
+
+	events := make([]TransitionEvent, len(entries))
+	for i := range entries {
+		events[i] = entries[i].event()
+	}
+
+	return events, nil
+}
+
+// OutboxRelayConfig configures an OutboxRelay
+type OutboxRelayConfig struct {
+	PollInterval time.Duration // How often pending entries are looked for
+	BatchSize    int           // Maximum entries delivered per poll
+	MaxBackoff   time.Duration // Upper bound of the delay before a failed entry is retried
+	Retention    time.Duration // How long delivered entries are kept
+}
+
+// DefaultOutboxRelayConfig returns the default outbox relay configuration
+func DefaultOutboxRelayConfig() OutboxRelayConfig {
+	return OutboxRelayConfig{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		MaxBackoff:   time.Minute,
+		Retention:    24 * time.Hour,
+	}
+}
+
+// OutboxRelay delivers outbox entries to a publisher at least once.
+// Entries of an entity are delivered in the order they were written: when one fails,
+// the later entries of its entity wait until it is retried successfully.
+type OutboxRelay struct {
+	db        *gorm.DB
+	publisher EventPublisher
+	config    OutboxRelayConfig
+}
+
+// NewOutboxRelay creates a relay delivering the outbox of db to publisher
+func NewOutboxRelay(db *gorm.DB, publisher EventPublisher, config OutboxRelayConfig) *OutboxRelay {
+	defaults := DefaultOutboxRelayConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+
+	return &OutboxRelay{
+		db:        db,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+// Run delivers pending entries every poll interval until ctx is done
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches are delivered
+		for {
+			delivered, err := r.RelayOnce(ctx)
+			if err != nil {
+				log.Printf("Failed to relay state change events: %v", err)
+			}
+			if err != nil || delivered < r.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce delivers a batch of pending entries and purges expired delivered ones.
+// It returns the number of delivered entries; nothing is delivered while another relay holds the outbox.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	delivered := 0
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockKey).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to lock outbox: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		var entries []OutboxEntry
+		if err := tx.Where("delivered_at IS NULL").Order("id").Limit(r.config.BatchSize).Find(&entries).Error; err != nil {
+			return fmt.Errorf("failed to list outbox entries: %w", err)
+		}
+
+		now := time.Now().UTC()
+		blocked := make(map[string]bool) // Entities with an earlier entry still pending
+		for i := range entries {
+			entry := &entries[i]
+			entity := entry.EntityType + "/" + entry.EntityID.String()
+			if blocked[entity] || entry.NextAttemptAt.After(now) {
+				blocked[entity] = true
+				continue
+			}
+
+			if err := r.publisher.Publish(entry.event()); err != nil {
+				blocked[entity] = true
+				if err := r.deferEntry(tx, entry, now, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := tx.Model(entry).Update("delivered_at", now).Error; err != nil {
+				return fmt.Errorf("failed to mark outbox entry %d delivered: %w", entry.ID, err)
+			}
+			delivered++
+		}
+
+		if err := tx.Where("delivered_at < ?", now.Add(-r.config.Retention)).Delete(&OutboxEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to purge delivered outbox entries: %w", err)
+		}
+
+		return nil
+	})
+
+	return delivered, err
+}
+
+// deferEntry records a failed delivery and schedules the next attempt with exponential backoff
+func (r *OutboxRelay) deferEntry(tx *gorm.DB, entry *OutboxEntry, now time.Time, cause error) error {
+	attempts := entry.Attempts + 1
+
+	backoff := r.config.PollInterval
+	for i := 1; i < attempts && backoff < r.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.config.MaxBackoff {
+		backoff = r.config.MaxBackoff
+	}
+
+	log.Printf("Failed to deliver state change event %d (attempt %d), retrying in %s: %v", entry.ID, attempts, backoff, cause)
+
+	err := tx.Model(entry).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      cause.Error(),
+		"next_attempt_at": now.Add(backoff),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to defer outbox entry %d: %w", entry.ID, err)
+	}
+
+	return nil
+}
@@ -3,15 +3,20 @@ package state
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 )
 
 const (
 	// StateChangeChannel is the Redis pub/sub channel for state changes
 	StateChangeChannel = "workflow:state_changes"
+
+	// StateChangeSubjectPrefix prefixes the NATS subjects of state changes
+	StateChangeSubjectPrefix = "workflow.state_changes."
 )
 
 // RedisPublisher publishes state change events to Redis pub/sub
@@ -77,6 +82,47 @@ func (p *RedisPublisher) Subscribe(ctx context.Context, handler func(TransitionE
 	}
 }
 
+// Events subscribes to state change events and returns once the subscription is active.
+// Events are delivered on the returned channel, which is closed once ctx is done.
+func (p *RedisPublisher) Events(ctx context.Context) (<-chan TransitionEvent, error) {
+	pubsub := p.client.Subscribe(ctx, StateChangeChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	events := make(chan TransitionEvent, 64)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				var event TransitionEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
 // MultiPublisher publishes to multiple publishers
 type MultiPublisher struct {
 	publishers []EventPublisher
@@ -89,14 +135,44 @@ func NewMultiPublisher(publishers ...EventPublisher) *MultiPublisher {
 	}
 }
 
-// Publish publishes to all publishers
+// Publish publishes to all publishers, even if some of them fail, and returns their errors
 func (p *MultiPublisher) Publish(event TransitionEvent) error {
+	var errs []error
 	for _, publisher := range p.publishers {
 		if err := publisher.Publish(event); err != nil {
-			// Continue publishing to other publishers even if one fails
-			// In production, you might want to log this error
-			continue
+			errs = append(errs, err)
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// NATSPublisher publishes state change events to NATS on StateChangeSubjectPrefix.<entity type>.<entity ID>
+type NATSPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher creates a new NATS event publisher
+func NewNATSPublisher(conn *nats.Conn) *NATSPublisher {
+	return &NATSPublisher{
+		conn: conn,
+	}
+}
+
+// Publish publishes a state transition event to NATS and waits until the server received it
+func (p *NATSPublisher) Publish(event TransitionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	subject := StateChangeSubjectPrefix + event.EntityType + "." + event.EntityID
+	if err := p.conn.Publish(subject, data); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+
+	if err := p.conn.FlushTimeout(5 * time.Second); err != nil {
+		return fmt.Errorf("failed to flush NATS connection: %w", err)
+	}
+
 	return nil
 }
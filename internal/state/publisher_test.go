@@ -0,0 +1,38 @@
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+func TestMultiPublisher_ReturnsErrors(t *testing.T) {
+	var publishedEvents []TransitionEvent
+	errRedisDown := errors.New("redis down")
+	publisher := NewMultiPublisher(&failingPublisher{err: errRedisDown}, &mockPublisher{events: &publishedEvents})
+
+	event := TransitionEvent{
+		EntityType: "task_instance",
+		EntityID:   "123",
+		OldState:   models.StateQueued,
+		NewState:   models.StateRunning,
+	}
+
+	err := publisher.Publish(event)
+	if !errors.Is(err, errRedisDown) {
+		t.Errorf("Publish() error = %v, want %v", err, errRedisDown)
+	}
+	if len(publishedEvents) != 1 {
+		t.Errorf("Publish() reached %d of the remaining publishers, want 1", len(publishedEvents))
+	}
+}
+
+// failingPublisher fails every publish
+type failingPublisher struct {
+	err error
+}
+
+func (p *failingPublisher) Publish(event TransitionEvent) error {
+	return p.err
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type dagRepository struct {
@@ -18,17 +20,31 @@ func NewDAGRepository(db *gorm.DB) DAGRepository {
 	return &dagRepository{db: db}
 }
 
+// Create stores a new DAG together with its tasks as version 1 of its definition
 func (r *dagRepository) Create(ctx context.Context, dag *models.DAG) error {
 	model, err := FromDAG(dag)
 	if err != nil {
 		return fmt.Errorf("failed to convert DAG to model: %w", err)
 	}
+	model.Version = 1
 
-	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
-		return fmt.Errorf("failed to create DAG: %w", err)
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(model).Error; err != nil {
+			return fmt.Errorf("failed to create DAG: %w", err)
+		}
+
+		if err := r.replaceTasks(tx, model.ID, model.Tasks); err != nil {
+			return err
+		}
+
+		return r.createVersion(tx, model.ID, 1, dag)
+	})
+	if err != nil {
+		return err
 	}
 
 	dag.ID = model.ID.String()
+	dag.Version = model.Version
 	dag.CreatedAt = model.CreatedAt
 	dag.UpdatedAt = model.UpdatedAt
 
@@ -42,7 +58,7 @@ func (r *dagRepository) Get(ctx context.Context, id string) (*models.DAG, error)
 	}
 
 	var model DAGModel
-	if err := r.db.WithContext(ctx).Where("id = ?", dagID).First(&model).Error; err != nil {
+	if err := r.withTasks(r.db.WithContext(ctx)).Where("id = ?", dagID).First(&model).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("DAG not found: %s", id)
 		}
@@ -59,7 +75,7 @@ func (r *dagRepository) GetByID(ctx context.Context, id string) (*models.DAG, er
 
 func (r *dagRepository) GetByName(ctx context.Context, name string) (*models.DAG, error) {
 	var model DAGModel
-	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&model).Error; err != nil {
+	if err := r.withTasks(r.db.WithContext(ctx)).Where("name = ?", name).First(&model).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("DAG not found: %s", name)
 		}
@@ -70,7 +86,7 @@ func (r *dagRepository) GetByName(ctx context.Context, name string) (*models.DAG
 }
 
 func (r *dagRepository) List(ctx context.Context, filters ...DAGFilters) ([]*models.DAG, error) {
-	query := r.db.WithContext(ctx).Model(&DAGModel{})
+	query := r.withTasks(r.db.WithContext(ctx)).Model(&DAGModel{})
 
 	// Apply filters if provided
 	if len(filters) > 0 {
@@ -109,6 +125,8 @@ func (r *dagRepository) List(ctx context.Context, filters ...DAGFilters) ([]*mod
 	return dags, nil
 }
 
+// Update stores changes to a DAG. If its definition changed, a new immutable
+// version is recorded and dag.Version is set to it.
 func (r *dagRepository) Update(ctx context.Context, dag *models.DAG) error {
 	dagID, err := uuid.Parse(dag.ID)
 	if err != nil {
@@ -122,11 +140,44 @@ func (r *dagRepository) Update(ctx context.Context, dag *models.DAG) error {
 
 	model.ID = dagID
 
-	if err := r.db.WithContext(ctx).Model(&DAGModel{}).Where("id = ?", dagID).Updates(model).Error; err != nil {
-		return fmt.Errorf("failed to update DAG: %w", err)
-	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the DAG row so concurrent updates cannot allocate the same version
+		var current DAGModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", dagID).First(&current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("DAG not found: %s", dag.ID)
+			}
+			return fmt.Errorf("failed to get DAG: %w", err)
+		}
 
-	return nil
+		changed, err := r.definitionChanged(tx, dagID, current.Version, dag)
+		if err != nil {
+			return err
+		}
+
+		model.Version = current.Version
+		if changed {
+			model.Version = current.Version + 1
+			if err := r.createVersion(tx, dagID, model.Version, dag); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&DAGModel{}).Omit(clause.Associations).Where("id = ?", dagID).Updates(model).Error; err != nil {
+			return fmt.Errorf("failed to update DAG: %w", err)
+		}
+		// Updates skips zero values, and an SLA, the notification rules, the circuit breaker settings or the params may be removed
+		if err := tx.Model(&DAGModel{}).Where("id = ?", dagID).Select("sla", "notifications", "circuit_breaker", "params").Updates(model).Error; err != nil {
+			return fmt.Errorf("failed to update DAG SLA, notifications, circuit breaker settings and params: %w", err)
+		}
+
+		if err := r.replaceTasks(tx, dagID, model.Tasks); err != nil {
+			return err
+		}
+
+		dag.Version = model.Version
+		return nil
+	})
 }
 
 func (r *dagRepository) Delete(ctx context.Context, id string) error {
@@ -167,3 +218,120 @@ func (r *dagRepository) Unpause(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (r *dagRepository) ListVersions(ctx context.Context, dagID string) ([]*models.DAGVersion, error) {
+	id, err := uuid.Parse(dagID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DAG ID: %w", err)
+	}
+
+	var versionModels []DAGVersionModel
+	if err := r.db.WithContext(ctx).
+		Where("dag_id = ?", id).
+		Order("version DESC").
+		Find(&versionModels).Error; err != nil {
+		return nil, fmt.Errorf("failed to list DAG versions: %w", err)
+	}
+
+	versions := make([]*models.DAGVersion, len(versionModels))
+	for i := range versionModels {
+		version, err := versionModels[i].ToDAGVersion()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode DAG version %d: %w", versionModels[i].Version, err)
+		}
+		versions[i] = version
+	}
+
+	return versions, nil
+}
+
+func (r *dagRepository) GetVersion(ctx context.Context, dagID string, version int) (*models.DAGVersion, error) {
+	id, err := uuid.Parse(dagID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DAG ID: %w", err)
+	}
+
+	var model DAGVersionModel
+	if err := r.db.WithContext(ctx).Where("dag_id = ? AND version = ?", id, version).First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get DAG version: %w", err)
+	}
+
+	result, err := model.ToDAGVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode DAG version %d: %w", version, err)
+	}
+
+	return result, nil
+}
+
+// createVersion records an immutable snapshot of a DAG definition within the given transaction
+func (r *dagRepository) createVersion(tx *gorm.DB, dagID uuid.UUID, version int, definition *models.DAG) error {
+	hash, err := dag.ContentHash(definition)
+	if err != nil {
+		return fmt.Errorf("failed to hash DAG definition: %w", err)
+	}
+
+	snapshot := *definition
+	snapshot.ID = dagID.String()
+	snapshot.Version = version
+
+	model, err := FromDAGVersion(dagID, version, hash, &snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to convert DAG version to model: %w", err)
+	}
+
+	if err := tx.Create(model).Error; err != nil {
+		return fmt.Errorf("failed to create DAG version: %w", err)
+	}
+
+	return nil
+}
+
+// definitionChanged reports whether a DAG definition differs from the stored version
+func (r *dagRepository) definitionChanged(tx *gorm.DB, dagID uuid.UUID, version int, definition *models.DAG) (bool, error) {
+	hash, err := dag.ContentHash(definition)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash DAG definition: %w", err)
+	}
+
+	var current DAGVersionModel
+	if err := tx.Where("dag_id = ? AND version = ?", dagID, version).First(&current).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get DAG version: %w", err)
+	}
+
+	return current.ContentHash != hash, nil
+}
+
+// withTasks preloads task definitions in their declared order
+func (r *dagRepository) withTasks(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	})
+}
+
+// replaceTasks replaces the stored task definitions of a DAG within the given transaction
+func (r *dagRepository) replaceTasks(tx *gorm.DB, dagID uuid.UUID, tasks []DAGTaskModel) error {
+	if err := tx.Where("dag_id = ?", dagID).Delete(&DAGTaskModel{}).Error; err != nil {
+		return fmt.Errorf("failed to delete DAG tasks: %w", err)
+	}
+
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	for i := range tasks {
+		tasks[i].DAGID = dagID
+	}
+
+	if err := tx.Create(&tasks).Error; err != nil {
+		return fmt.Errorf("failed to create DAG tasks: %w", err)
+	}
+
+	return nil
+}
@@ -12,15 +12,21 @@ import (
 )
 
 type dagRunRepository struct {
-	db            *gorm.DB
-	stateManager  *state.Manager
+	db     *gorm.DB
+	states *stateWriter
 }
 
 // NewDAGRunRepository creates a new DAG run repository
 func NewDAGRunRepository(db *gorm.DB, stateManager *state.Manager) DAGRunRepository {
 	return &dagRunRepository{
-		db:           db,
-		stateManager: stateManager,
+		db: db,
+		states: &stateWriter{
+			db:           db,
+			stateManager: stateManager,
+			entityType:   "dag_run",
+			newModel:     func() interface{} { return &DAGRunModel{} },
+			scopeQuery:   "SELECT dag_id, id AS dag_run_id FROM dag_runs WHERE id = ?",
+		},
 	}
 }
 
@@ -30,7 +36,7 @@ func (r *dagRunRepository) Create(ctx context.Context, run *models.DAGRun) error
 		return fmt.Errorf("failed to convert DAG run to model: %w", err)
 	}
 
-	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+	if err := r.states.create(ctx, model, model.ID, models.State(model.State)); err != nil {
 		return fmt.Errorf("failed to create DAG run: %w", err)
 	}
 
@@ -140,7 +146,11 @@ func (r *dagRunRepository) Update(ctx context.Context, run *models.DAGRun) error
 
 	model.ID = runID
 
-	if err := r.db.WithContext(ctx).Model(&DAGRunModel{}).Where("id = ?", runID).Updates(model).Error; err != nil {
+	err = r.states.update(ctx, runID, models.State(model.State), func(version int) interface{} {
+		model.Version = version
+		return model
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update DAG run: %w", err)
 	}
 
@@ -153,31 +163,67 @@ func (r *dagRunRepository) UpdateState(ctx context.Context, id string, oldState,
 		return fmt.Errorf("invalid DAG run ID: %w", err)
 	}
 
-	// Validate state transition
-	if err := r.stateManager.Transition("dag_run", id, oldState, newState, nil); err != nil {
-		return fmt.Errorf("invalid state transition: %w", err)
+	updates := map[string]interface{}{}
+	if newState == models.StateRunning {
+		// Entering running starts the lease that heartbeats renew
+		updates["last_heartbeat_at"] = time.Now()
+	}
+
+	if err := r.states.transition(ctx, runID, oldState, newState, updates); err != nil {
+		return fmt.Errorf("failed to update DAG run state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *dagRunRepository) Heartbeat(ctx context.Context, id string) error {
+	runID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid DAG run ID: %w", err)
 	}
 
-	// Use optimistic locking to prevent concurrent updates
 	result := r.db.WithContext(ctx).
 		Model(&DAGRunModel{}).
-		Where("id = ? AND state = ?", runID, string(oldState)).
-		Updates(map[string]interface{}{
-			"state":   string(newState),
-			"version": gorm.Expr("version + 1"),
-		})
+		Where("id = ? AND state = ?", runID, string(models.StateRunning)).
+		Update("last_heartbeat_at", time.Now())
 
 	if result.Error != nil {
-		return fmt.Errorf("failed to update DAG run state: %w", result.Error)
+		return fmt.Errorf("failed to record DAG run heartbeat: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return state.ErrOptimisticLock
+		return ErrNotFound
 	}
 
 	return nil
 }
 
+func (r *dagRunRepository) ClaimOrphaned(ctx context.Context, id string, leaseTimeout time.Duration) (bool, error) {
+	runID, err := uuid.Parse(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid DAG run ID: %w", err)
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-leaseTimeout)
+
+	// Renewing the lease in the same statement that checks it lets only one process claim the run
+	result := r.db.WithContext(ctx).
+		Model(&DAGRunModel{}).
+		Where("id = ? AND state = ?", runID, string(models.StateRunning)).
+		Where("last_heartbeat_at IS NULL OR last_heartbeat_at < ?", cutoff).
+		Updates(map[string]interface{}{
+			"last_heartbeat_at": now,
+			"version":           gorm.Expr("version + 1"),
+		})
+
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim orphaned DAG run: %w", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
 func (r *dagRunRepository) Delete(ctx context.Context, id string) error {
 	runID, err := uuid.Parse(id)
 	if err != nil {
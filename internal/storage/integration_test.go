@@ -4,10 +4,15 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
@@ -193,6 +198,158 @@ func TestDAGRepository_Integration(t *testing.T) {
 		}
 	})
 
+	t.Run("Persist DAG tasks", func(t *testing.T) {
+		dag := &models.DAG{
+			Name:      "tasks-dag-" + uuid.New().String(),
+			Schedule:  "0 0 * * *",
+			StartDate: time.Now().UTC(),
+			Tasks: []models.Task{
+				{ID: "extract", Name: "Extract", Type: models.TaskTypeBash, Command: "echo extract", Timeout: time.Minute},
+				{ID: "transform", Name: "Transform", Type: models.TaskTypeBash, Command: "echo transform", Dependencies: []string{"extract"}, Retries: 2,
+					RetryPolicy: &models.RetryPolicy{Strategy: models.RetryStrategyFixed, BaseDelay: 10 * time.Second, RetryOn: []string{"75"}}},
+				{ID: "load", Name: "Load", Type: models.TaskTypeHTTP, Command: "POST http://example.com", Dependencies: []string{"transform"}, SLA: time.Hour},
+			},
+		}
+
+		if err := dagRepo.Create(ctx, dag); err != nil {
+			t.Fatalf("Failed to create DAG: %v", err)
+		}
+
+		retrieved, err := dagRepo.Get(ctx, dag.ID)
+		if err != nil {
+			t.Fatalf("Failed to get DAG: %v", err)
+		}
+
+		if len(retrieved.Tasks) != 3 {
+			t.Fatalf("Retrieved DAG has %d tasks, want 3", len(retrieved.Tasks))
+		}
+		for i, task := range dag.Tasks {
+			got := retrieved.Tasks[i]
+			if got.ID != task.ID || got.Command != task.Command || got.Type != task.Type {
+				t.Errorf("Task %d = %+v, want %+v", i, got, task)
+			}
+			if len(got.Dependencies) != len(task.Dependencies) {
+				t.Errorf("Task %s dependencies = %v, want %v", task.ID, got.Dependencies, task.Dependencies)
+			}
+			if got.Retries != task.Retries || got.Timeout != task.Timeout || got.SLA != task.SLA {
+				t.Errorf("Task %s settings = %+v, want %+v", task.ID, got, task)
+			}
+			if (got.RetryPolicy == nil) != (task.RetryPolicy == nil) {
+				t.Errorf("Task %s retry policy = %+v, want %+v", task.ID, got.RetryPolicy, task.RetryPolicy)
+			}
+		}
+		if policy := retrieved.Tasks[1].RetryPolicy; policy == nil || policy.BaseDelay != 10*time.Second || len(policy.RetryOn) != 1 {
+			t.Errorf("Task transform retry policy = %+v, want fixed 10s on exit code 75", policy)
+		}
+
+		// Replace the task list on update
+		dag.Tasks = dag.Tasks[:1]
+		if err := dagRepo.Update(ctx, dag); err != nil {
+			t.Fatalf("Failed to update DAG: %v", err)
+		}
+
+		updated, err := dagRepo.Get(ctx, dag.ID)
+		if err != nil {
+			t.Fatalf("Failed to get updated DAG: %v", err)
+		}
+		if len(updated.Tasks) != 1 || updated.Tasks[0].ID != "extract" {
+			t.Errorf("Updated DAG tasks = %+v, want only 'extract'", updated.Tasks)
+		}
+	})
+
+	t.Run("Persist DAG notification rules", func(t *testing.T) {
+		dag := &models.DAG{
+			Name:      "notified-dag-" + uuid.New().String(),
+			StartDate: time.Now().UTC(),
+			Tasks: []models.Task{
+				{ID: "extract", Type: models.TaskTypeBash, Command: "echo extract"},
+			},
+			Notifications: &models.NotificationRules{OnFailure: []string{"ops-slack", "oncall-email"}, OnRetry: []string{"ops-slack"}},
+		}
+
+		if err := dagRepo.Create(ctx, dag); err != nil {
+			t.Fatalf("Failed to create DAG: %v", err)
+		}
+
+		retrieved, err := dagRepo.Get(ctx, dag.ID)
+		if err != nil {
+			t.Fatalf("Failed to get DAG: %v", err)
+		}
+		if rules := retrieved.Notifications; rules == nil || len(rules.OnFailure) != 2 || len(rules.OnRetry) != 1 {
+			t.Errorf("Notification rules = %+v, want on_failure and on_retry", rules)
+		}
+
+		// Removing the rules is persisted too
+		dag.Notifications = nil
+		if err := dagRepo.Update(ctx, dag); err != nil {
+			t.Fatalf("Failed to update DAG: %v", err)
+		}
+		updated, err := dagRepo.Get(ctx, dag.ID)
+		if err != nil {
+			t.Fatalf("Failed to get updated DAG: %v", err)
+		}
+		if updated.Notifications != nil {
+			t.Errorf("Notification rules after removal = %+v, want none", updated.Notifications)
+		}
+	})
+
+	t.Run("Version DAG definitions", func(t *testing.T) {
+		dag := &models.DAG{
+			Name:      "versioned-dag-" + uuid.New().String(),
+			Schedule:  "0 0 * * *",
+			StartDate: time.Now().UTC(),
+			Tasks: []models.Task{
+				{ID: "extract", Type: models.TaskTypeBash, Command: "echo extract"},
+			},
+		}
+
+		if err := dagRepo.Create(ctx, dag); err != nil {
+			t.Fatalf("Failed to create DAG: %v", err)
+		}
+		if dag.Version != 1 {
+			t.Errorf("New DAG version = %d, want 1", dag.Version)
+		}
+
+		// Saving an unchanged definition must not create a version
+		if err := dagRepo.Update(ctx, dag); err != nil {
+			t.Fatalf("Failed to update DAG: %v", err)
+		}
+		if dag.Version != 1 {
+			t.Errorf("DAG version after no-op update = %d, want 1", dag.Version)
+		}
+
+		dag.Tasks[0].Command = "echo extract --full"
+		if err := dagRepo.Update(ctx, dag); err != nil {
+			t.Fatalf("Failed to update DAG: %v", err)
+		}
+		if dag.Version != 2 {
+			t.Errorf("DAG version after definition change = %d, want 2", dag.Version)
+		}
+
+		versions, err := dagRepo.ListVersions(ctx, dag.ID)
+		if err != nil {
+			t.Fatalf("Failed to list versions: %v", err)
+		}
+		if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+			t.Fatalf("Unexpected versions: %+v", versions)
+		}
+		if versions[0].ContentHash == versions[1].ContentHash {
+			t.Error("Expected versions to have different content hashes")
+		}
+
+		v1, err := dagRepo.GetVersion(ctx, dag.ID, 1)
+		if err != nil {
+			t.Fatalf("Failed to get version 1: %v", err)
+		}
+		if len(v1.DAG.Tasks) != 1 || v1.DAG.Tasks[0].Command != "echo extract" {
+			t.Errorf("Version 1 tasks = %+v, want the original command", v1.DAG.Tasks)
+		}
+
+		if _, err := dagRepo.GetVersion(ctx, dag.ID, 3); err != ErrNotFound {
+			t.Errorf("GetVersion for a missing version returned %v, want ErrNotFound", err)
+		}
+	})
+
 	t.Run("Delete DAG", func(t *testing.T) {
 		dag := &models.DAG{
 			Name:      "delete-dag-" + uuid.New().String(),
@@ -334,13 +491,73 @@ func TestDAGRunRepository_Integration(t *testing.T) {
 			t.Errorf("Latest run DAGID = %s, want %s", latest.DAGID, dag.ID)
 		}
 	})
+
+	t.Run("Heartbeat and Claim Orphaned DAG Run", func(t *testing.T) {
+		run := &models.DAGRun{
+			DAGID:         dag.ID,
+			ExecutionDate: time.Now().UTC().Add(-time.Hour),
+			State:         models.StateQueued,
+		}
+
+		if err := dagRunRepo.Create(ctx, run); err != nil {
+			t.Fatalf("Failed to create DAG run: %v", err)
+		}
+
+		// Queued runs have no lease to renew
+		if err := dagRunRepo.Heartbeat(ctx, run.ID); err != ErrNotFound {
+			t.Errorf("Heartbeat of queued run error = %v, want %v", err, ErrNotFound)
+		}
+
+		if err := dagRunRepo.UpdateState(ctx, run.ID, models.StateQueued, models.StateRunning); err != nil {
+			t.Fatalf("Failed to update DAG run state: %v", err)
+		}
+
+		running, err := dagRunRepo.Get(ctx, run.ID)
+		if err != nil {
+			t.Fatalf("Failed to get DAG run: %v", err)
+		}
+		if running.LastHeartbeatAt == nil {
+			t.Fatal("Starting a DAG run should record a heartbeat")
+		}
+
+		if err := dagRunRepo.Heartbeat(ctx, run.ID); err != nil {
+			t.Fatalf("Failed to record heartbeat: %v", err)
+		}
+
+		// The lease is fresh, so the run is not orphaned
+		claimed, err := dagRunRepo.ClaimOrphaned(ctx, run.ID, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to claim DAG run: %v", err)
+		}
+		if claimed {
+			t.Error("DAG run with a fresh heartbeat should not be claimed")
+		}
+
+		// With a zero lease the run counts as orphaned
+		time.Sleep(10 * time.Millisecond)
+		claimed, err = dagRunRepo.ClaimOrphaned(ctx, run.ID, 0)
+		if err != nil {
+			t.Fatalf("Failed to claim DAG run: %v", err)
+		}
+		if !claimed {
+			t.Error("Expected orphaned DAG run to be claimed")
+		}
+
+		claimed, err = dagRunRepo.ClaimOrphaned(ctx, run.ID, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to claim DAG run: %v", err)
+		}
+		if claimed {
+			t.Error("Claiming should renew the lease")
+		}
+	})
 }
 
 func TestTaskInstanceRepository_Integration(t *testing.T) {
 	db, cleanup := SetupTestDB(t)
 	defer cleanup()
 
-	dagRepo, dagRunRepo, taskInstanceRepo, _ := CreateTestRepositories(db.DB)
+	dagRepo, dagRunRepo, taskInstanceRepo, taskLogRepo := CreateTestRepositories(db.DB)
 	ctx := context.Background()
 
 	// Create test DAG and DAG run
@@ -420,6 +637,192 @@ func TestTaskInstanceRepository_Integration(t *testing.T) {
 		}
 	})
 
+	t.Run("Record State History and Reject Stale Writes", func(t *testing.T) {
+		task := &models.TaskInstance{
+			TaskID:   "history-task",
+			DAGRunID: dagRun.ID,
+			State:    models.StateQueued,
+			MaxTries: 1,
+		}
+		if err := taskInstanceRepo.Create(ctx, task); err != nil {
+			t.Fatalf("Failed to create task instance: %v", err)
+		}
+
+		if err := taskInstanceRepo.UpdateState(ctx, task.ID, models.StateQueued, models.StateRunning); err != nil {
+			t.Fatalf("Failed to update task instance state: %v", err)
+		}
+
+		// A writer that still believes the task is queued loses the compare-and-swap
+		err := taskInstanceRepo.UpdateState(ctx, task.ID, models.StateQueued, models.StateSkipped)
+		if !errors.Is(err, state.ErrOptimisticLock) {
+			t.Errorf("Stale UpdateState error = %v, want %v", err, state.ErrOptimisticLock)
+		}
+
+		// Updates go through the state machine too
+		stale, err := taskInstanceRepo.Get(ctx, task.ID)
+		if err != nil {
+			t.Fatalf("Failed to get task instance: %v", err)
+		}
+		stale.State = models.StateQueued
+		if err := taskInstanceRepo.Update(ctx, stale); !errors.Is(err, state.ErrInvalidTransition) {
+			t.Errorf("Update error = %v, want %v", err, state.ErrInvalidTransition)
+		}
+
+		history, err := state.NewHistoryTracker(db.DB).GetHistory(ctx, "task_instance", task.ID, 0)
+		if err != nil {
+			t.Fatalf("Failed to get state history: %v", err)
+		}
+		if len(history) != 2 {
+			t.Fatalf("State history has %d entries, want 2", len(history))
+		}
+		if history[0].OldState == nil || *history[0].OldState != string(models.StateQueued) || history[0].NewState != string(models.StateRunning) {
+			t.Errorf("Latest state history entry = %v -> %s, want queued -> running", history[0].OldState, history[0].NewState)
+		}
+		if history[1].OldState != nil || history[1].NewState != string(models.StateQueued) {
+			t.Errorf("First state history entry = %v -> %s, want creation as queued", history[1].OldState, history[1].NewState)
+		}
+	})
+
+	t.Run("Relay State Changes Through the Outbox", func(t *testing.T) {
+		task := &models.TaskInstance{
+			TaskID:   "outbox-task",
+			DAGRunID: dagRun.ID,
+			State:    models.StateQueued,
+			MaxTries: 1,
+		}
+		if err := taskInstanceRepo.Create(ctx, task); err != nil {
+			t.Fatalf("Failed to create task instance: %v", err)
+		}
+		if err := taskInstanceRepo.UpdateState(ctx, task.ID, models.StateQueued, models.StateRunning); err != nil {
+			t.Fatalf("Failed to update task instance state: %v", err)
+		}
+		if err := taskInstanceRepo.UpdateState(ctx, task.ID, models.StateRunning, models.StateSuccess); err != nil {
+			t.Fatalf("Failed to update task instance state: %v", err)
+		}
+
+		publisher := &outboxRecorder{entityID: task.ID, failures: 1}
+		relay := state.NewOutboxRelay(db.DB, publisher, state.OutboxRelayConfig{PollInterval: time.Millisecond, MaxBackoff: time.Millisecond})
+
+		// The first delivery fails, so the later changes of the task instance wait for it
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("Failed to relay outbox: %v", err)
+		}
+		if len(publisher.delivered) != 0 {
+			t.Fatalf("Delivered %v after a failed delivery, want nothing", publisher.delivered)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("Failed to relay outbox: %v", err)
+		}
+
+		want := []models.State{models.StateQueued, models.StateRunning, models.StateSuccess}
+		if len(publisher.delivered) != len(want) {
+			t.Fatalf("Delivered %v, want %v", publisher.delivered, want)
+		}
+		for i, event := range publisher.delivered {
+			if event.NewState != want[i] || event.OutboxID == 0 {
+				t.Errorf("Event %d = %s (outbox ID %d), want %s with an outbox ID", i, event.NewState, event.OutboxID, want[i])
+			}
+		}
+
+		// Delivered entries are not published again
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("Failed to relay outbox: %v", err)
+		}
+		if len(publisher.delivered) != len(want) {
+			t.Errorf("Delivered %d events after redelivery, want %d", len(publisher.delivered), len(want))
+		}
+	})
+
+	t.Run("Store Reschedule and Cancel States", func(t *testing.T) {
+		task := &models.TaskInstance{
+			TaskID:   "sensor-task",
+			DAGRunID: dagRun.ID,
+			State:    models.StateQueued,
+			MaxTries: 1,
+		}
+		if err := taskInstanceRepo.Create(ctx, task); err != nil {
+			t.Fatalf("Failed to create task instance: %v", err)
+		}
+
+		transitions := [][2]models.State{
+			{models.StateQueued, models.StateRunning},
+			{models.StateRunning, models.StateUpForReschedule},
+			{models.StateUpForReschedule, models.StateRunning},
+			{models.StateRunning, models.StateRetrying},
+			{models.StateRetrying, models.StateScheduled},
+			{models.StateScheduled, models.StateCancelled},
+		}
+		for _, transition := range transitions {
+			if err := taskInstanceRepo.UpdateState(ctx, task.ID, transition[0], transition[1]); err != nil {
+				t.Fatalf("Failed to update task instance state from %s to %s: %v", transition[0], transition[1], err)
+			}
+		}
+
+		updated, err := taskInstanceRepo.Get(ctx, task.ID)
+		if err != nil {
+			t.Fatalf("Failed to get updated task instance: %v", err)
+		}
+		if updated.State != models.StateCancelled {
+			t.Errorf("Task instance state = %s, want %s", updated.State, models.StateCancelled)
+		}
+
+		// Unknown states are never stored
+		updated.State = models.State("up_for_retry")
+		if err := taskInstanceRepo.Update(ctx, updated); err == nil {
+			t.Error("Expected storing an unknown state to fail")
+		}
+	})
+
+	t.Run("Heartbeat Worker Tasks", func(t *testing.T) {
+		task := &models.TaskInstance{
+			TaskID:   "worker-task",
+			DAGRunID: dagRun.ID,
+			State:    models.StateQueued,
+			MaxTries: 1,
+		}
+
+		if err := taskInstanceRepo.Create(ctx, task); err != nil {
+			t.Fatalf("Failed to create task instance: %v", err)
+		}
+		if err := taskInstanceRepo.UpdateState(ctx, task.ID, models.StateQueued, models.StateRunning); err != nil {
+			t.Fatalf("Failed to update task instance state: %v", err)
+		}
+
+		if err := taskInstanceRepo.HeartbeatWorkerTasks(ctx, "worker-1", []string{task.ID}); err != nil {
+			t.Fatalf("Failed to record worker heartbeat: %v", err)
+		}
+
+		running := models.StateRunning
+		owned, err := taskInstanceRepo.List(ctx, TaskInstanceFilters{WorkerID: "worker-1", State: &running})
+		if err != nil {
+			t.Fatalf("Failed to list task instances by worker: %v", err)
+		}
+		if len(owned) != 1 || owned[0].ID != task.ID {
+			t.Fatalf("Expected worker-1 to own task instance %s, got %d instances", task.ID, len(owned))
+		}
+		if owned[0].LastHeartbeatAt == nil {
+			t.Error("Worker heartbeat should renew the task lease")
+		}
+
+		// A new attempt starts without a worker
+		if err := taskInstanceRepo.UpdateState(ctx, task.ID, models.StateRunning, models.StateRetrying); err != nil {
+			t.Fatalf("Failed to update task instance state: %v", err)
+		}
+		if err := taskInstanceRepo.UpdateState(ctx, task.ID, models.StateRetrying, models.StateRunning); err != nil {
+			t.Fatalf("Failed to update task instance state: %v", err)
+		}
+
+		retried, err := taskInstanceRepo.Get(ctx, task.ID)
+		if err != nil {
+			t.Fatalf("Failed to get task instance: %v", err)
+		}
+		if retried.WorkerID != "" {
+			t.Errorf("Retried task instance WorkerID = %q, want empty", retried.WorkerID)
+		}
+	})
+
 	t.Run("List Task Instances by DAG Run", func(t *testing.T) {
 		// Create multiple task instances
 		for i := 0; i < 3; i++ {
@@ -444,4 +847,422 @@ func TestTaskInstanceRepository_Integration(t *testing.T) {
 			t.Errorf("Expected at least 3 task instances, got %d", len(tasks))
 		}
 	})
+	t.Run("Create and List Task Log Batches", func(t *testing.T) {
+		task := &models.TaskInstance{
+			TaskID:   "logging-task",
+			DAGRunID: dagRun.ID,
+			State:    models.StateQueued,
+			MaxTries: 1,
+		}
+		if err := taskInstanceRepo.Create(ctx, task); err != nil {
+			t.Fatalf("Failed to create task instance: %v", err)
+		}
+
+		now := time.Now().UTC()
+		logs := []TaskLogModel{
+			{Stream: "stdout", LineNumber: 1, LogData: "starting", Timestamp: now},
+			{Stream: "stderr", LineNumber: 2, LogData: "warning", Timestamp: now},
+			{Stream: "stdout", LineNumber: 3, LogData: "done", Timestamp: now},
+		}
+		if err := taskLogRepo.CreateBatch(ctx, task.ID, logs); err != nil {
+			t.Fatalf("Failed to create task logs: %v", err)
+		}
+
+		retrieved, err := taskLogRepo.List(ctx, task.ID, 0)
+		if err != nil {
+			t.Fatalf("Failed to list task logs: %v", err)
+		}
+		if len(retrieved) != 3 {
+			t.Fatalf("Expected 3 log lines, got %d", len(retrieved))
+		}
+		if retrieved[1].Stream != "stderr" || retrieved[1].LogData != "warning" || retrieved[1].LineNumber != 2 {
+			t.Errorf("Unexpected second log line: %+v", retrieved[1])
+		}
+
+		tail, err := taskLogRepo.ListAfter(ctx, task.ID, retrieved[0].Seq, 10)
+		if err != nil {
+			t.Fatalf("Failed to list task logs after offset: %v", err)
+		}
+		if len(tail) != 2 || tail[0].LogData != "warning" || tail[1].LogData != "done" {
+			t.Errorf("Unexpected log lines after offset: %+v", tail)
+		}
+	})
+}
+
+func TestSLAMissRepository_Integration(t *testing.T) {
+	db, cleanup := SetupTestDB(t)
+	defer cleanup()
+
+	dagRepo, dagRunRepo, taskInstanceRepo, _ := CreateTestRepositories(db.DB)
+	slaMissRepo := NewSLAMissRepository(db.DB)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// The DAG run should take at most an hour and its slow task half an hour
+	dag := &models.DAG{
+		Name:      "test-sla-" + uuid.New().String(),
+		StartDate: now,
+		SLA:       time.Hour,
+		Tasks: []models.Task{
+			{ID: "slow", Name: "Slow", Type: models.TaskTypeBash, Command: "sleep 3600", SLA: 30 * time.Minute},
+			{ID: "unbounded", Name: "Unbounded", Type: models.TaskTypeBash, Command: "sleep 3600"},
+		},
+	}
+	if err := dagRepo.Create(ctx, dag); err != nil {
+		t.Fatalf("Failed to create test DAG: %v", err)
+	}
+
+	dagRun := &models.DAGRun{
+		DAGID:         dag.ID,
+		ExecutionDate: now.Add(-2 * time.Hour),
+		State:         models.StateRunning,
+		DAGVersion:    dag.Version,
+	}
+	if err := dagRunRepo.Create(ctx, dagRun); err != nil {
+		t.Fatalf("Failed to create DAG run: %v", err)
+	}
+
+	for _, taskID := range []string{"slow", "unbounded"} {
+		err := taskInstanceRepo.Create(ctx, &models.TaskInstance{
+			TaskID:    taskID,
+			DAGRunID:  dagRun.ID,
+			State:     models.StateRunning,
+			TryNumber: 1,
+			MaxTries:  1,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create task instance: %v", err)
+		}
+	}
+
+	opts := SLACheckOptions{Now: now, Since: now.Add(-24 * time.Hour)}
+
+	t.Run("Find and Record SLA Misses", func(t *testing.T) {
+		misses, err := slaMissRepo.FindMisses(ctx, opts)
+		if err != nil {
+			t.Fatalf("Failed to find SLA misses: %v", err)
+		}
+		if len(misses) != 2 {
+			t.Fatalf("Expected misses of the slow task and the DAG run, got %+v", misses)
+		}
+
+		for _, miss := range misses {
+			expectedSLA := time.Hour
+			if miss.TaskID == "slow" {
+				expectedSLA = 30 * time.Minute
+				if miss.TaskInstanceID == "" {
+					t.Error("Expected the task miss to reference its task instance")
+				}
+			} else if miss.TaskID != "" {
+				t.Errorf("Unexpected miss of task %s", miss.TaskID)
+			}
+			if miss.SLA != expectedSLA || !miss.Deadline.Equal(dagRun.ExecutionDate.Add(expectedSLA).Truncate(time.Microsecond)) {
+				t.Errorf("Unexpected SLA %v or deadline %v of %q", miss.SLA, miss.Deadline, miss.TaskID)
+			}
+
+			recorded, err := slaMissRepo.Record(ctx, miss)
+			if err != nil || !recorded {
+				t.Fatalf("Expected the miss to be recorded, got %v, %v", recorded, err)
+			}
+			recorded, err = slaMissRepo.Record(ctx, miss)
+			if err != nil || recorded {
+				t.Errorf("Expected a miss to be recorded once, got %v, %v", recorded, err)
+			}
+		}
+
+		misses, err = slaMissRepo.FindMisses(ctx, opts)
+		if err != nil {
+			t.Fatalf("Failed to find SLA misses: %v", err)
+		}
+		if len(misses) != 0 {
+			t.Errorf("Expected recorded misses not to be found again, got %+v", misses)
+		}
+
+		var events int64
+		db.DB.Model(&state.OutboxEntry{}).Where("entity_type = ?", "sla_miss").Count(&events)
+		if events != 2 {
+			t.Errorf("Expected 2 SLA miss events in the outbox, got %d", events)
+		}
+	})
+
+	t.Run("Measure SLAs From the Start Date", func(t *testing.T) {
+		startOpts := opts
+		startOpts.Reference = SLAFromStartDate
+		if _, err := slaMissRepo.FindMisses(ctx, startOpts); err != nil {
+			t.Fatalf("Failed to find SLA misses from start dates: %v", err)
+		}
+
+		startOpts.Reference = "created_at"
+		if _, err := slaMissRepo.FindMisses(ctx, startOpts); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for an unknown reference, got %v", err)
+		}
+	})
+
+	t.Run("List SLA Misses", func(t *testing.T) {
+		misses, err := slaMissRepo.List(ctx, SLAMissFilters{DAGID: dag.ID, TaskID: "slow"})
+		if err != nil {
+			t.Fatalf("Failed to list SLA misses: %v", err)
+		}
+		if len(misses) != 1 || misses[0].DAGRunID != dagRun.ID || misses[0].State != models.StateRunning {
+			t.Errorf("Unexpected SLA misses: %+v", misses)
+		}
+
+		after := now.Add(time.Hour)
+		misses, err = slaMissRepo.List(ctx, SLAMissFilters{DAGRunID: dagRun.ID, After: &after})
+		if err != nil {
+			t.Fatalf("Failed to list SLA misses: %v", err)
+		}
+		if len(misses) != 0 {
+			t.Errorf("Expected no misses detected in the future, got %+v", misses)
+		}
+	})
+}
+
+func TestDeadLetterQueue_Integration(t *testing.T) {
+	db, cleanup := SetupTestDB(t)
+	defer cleanup()
+
+	dagRepo, dagRunRepo, taskInstanceRepo, _ := CreateTestRepositories(db.DB)
+	queue := dlq.NewPostgresQueue(db.DB)
+	manager := dlq.NewManager(queue, 0)
+	ctx := context.Background()
+
+	dag := &models.DAG{
+		Name:      "test-dlq-" + uuid.New().String(),
+		StartDate: time.Now().UTC(),
+	}
+	if err := dagRepo.Create(ctx, dag); err != nil {
+		t.Fatalf("Failed to create test DAG: %v", err)
+	}
+
+	dagRun := &models.DAGRun{
+		DAGID:         dag.ID,
+		ExecutionDate: time.Now().UTC(),
+		State:         models.StateFailed,
+	}
+	if err := dagRunRepo.Create(ctx, dagRun); err != nil {
+		t.Fatalf("Failed to create DAG run: %v", err)
+	}
+
+	instances := make(map[string]*models.TaskInstance)
+	for _, taskID := range []string{"extract", "load"} {
+		instance := &models.TaskInstance{
+			TaskID:    taskID,
+			DAGRunID:  dagRun.ID,
+			State:     models.StateFailed,
+			TryNumber: 2,
+			MaxTries:  2,
+		}
+		if err := taskInstanceRepo.Create(ctx, instance); err != nil {
+			t.Fatalf("Failed to create task instance: %v", err)
+		}
+		instances[taskID] = instance
+	}
+
+	t.Run("Add and Get Entries", func(t *testing.T) {
+		for _, taskID := range []string{"extract", "load"} {
+			if err := manager.AddFailedTask(ctx, instances[taskID], dag, errors.New(taskID+" failed")); err != nil {
+				t.Fatalf("Failed to add DLQ entry: %v", err)
+			}
+		}
+
+		entry, err := queue.Get(ctx, instances["extract"].ID)
+		if err != nil {
+			t.Fatalf("Failed to get DLQ entry: %v", err)
+		}
+		if entry.TaskID != "extract" || entry.DAGID != dag.ID || entry.Attempts != 2 || entry.ErrorMessage != "extract failed" {
+			t.Errorf("Unexpected DLQ entry: %+v", entry)
+		}
+
+		if err := manager.AddFailedTask(ctx, instances["extract"], dag, nil); !errors.Is(err, dlq.ErrAlreadyExists) {
+			t.Errorf("Expected ErrAlreadyExists for a pending entry, got %v", err)
+		}
+		if _, err := queue.Get(ctx, uuid.New().String()); !errors.Is(err, dlq.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if _, err := queue.Get(ctx, "not-a-uuid"); !errors.Is(err, dlq.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a malformed ID, got %v", err)
+		}
+
+		count, err := queue.Count(ctx)
+		if err != nil || count != 2 {
+			t.Errorf("Count = %d (%v), want 2", count, err)
+		}
+	})
+
+	t.Run("List Entries with Filters", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		future := time.Now().Add(time.Hour)
+		pending := false
+
+		for _, tc := range []struct {
+			name    string
+			filters *dlq.Filters
+			want    int
+		}{
+			{"all", nil, 2},
+			{"by DAG", &dlq.Filters{DAGID: dag.ID}, 2},
+			{"by task", &dlq.Filters{TaskID: "load"}, 1},
+			{"pending", &dlq.Filters{Replayed: &pending}, 2},
+			{"failed after", &dlq.Filters{After: &future}, 0},
+			{"failed between", &dlq.Filters{After: &past, Before: &future}, 2},
+			{"paginated", &dlq.Filters{Limit: 1, Offset: 1}, 1},
+		} {
+			entries, err := queue.List(ctx, tc.filters)
+			if err != nil {
+				t.Fatalf("Failed to list DLQ entries %s: %v", tc.name, err)
+			}
+			if len(entries) != tc.want {
+				t.Errorf("Listed %d entries %s, want %d", len(entries), tc.name, tc.want)
+			}
+		}
+	})
+
+	t.Run("Replay and Fail Again", func(t *testing.T) {
+		id := instances["extract"].ID
+		if err := queue.Replay(ctx, id); err != nil {
+			t.Fatalf("Failed to replay DLQ entry: %v", err)
+		}
+
+		entry, _ := queue.Get(ctx, id)
+		if !entry.Replayed || entry.ReplayedAt == nil {
+			t.Errorf("Expected the entry to be replayed, got %+v", entry)
+		}
+
+		// The new try failed as well
+		instances["extract"].TryNumber = 3
+		if err := manager.AddFailedTask(ctx, instances["extract"], dag, errors.New("failed again")); err != nil {
+			t.Fatalf("Failed to add DLQ entry of replayed task: %v", err)
+		}
+		entry, _ = queue.Get(ctx, id)
+		if entry.Replayed || entry.ReplayedAt != nil || entry.Attempts != 3 || entry.ErrorMessage != "failed again" {
+			t.Errorf("Expected the replayed entry to be replaced, got %+v", entry)
+		}
+	})
+
+	t.Run("Delete and Purge Entries", func(t *testing.T) {
+		if err := queue.Delete(ctx, instances["load"].ID); err != nil {
+			t.Fatalf("Failed to delete DLQ entry: %v", err)
+		}
+		if err := queue.Delete(ctx, instances["load"].ID); !errors.Is(err, dlq.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a deleted entry, got %v", err)
+		}
+
+		if err := queue.Purge(ctx); err != nil {
+			t.Fatalf("Failed to purge DLQ: %v", err)
+		}
+		if count, _ := queue.Count(ctx); count != 0 {
+			t.Errorf("Expected an empty DLQ after purging, got %d entries", count)
+		}
+	})
+}
+
+// outboxRecorder records the state change events of one entity and fails the first ones
+type outboxRecorder struct {
+	entityID  string
+	failures  int
+	delivered []state.TransitionEvent
+}
+
+func (p *outboxRecorder) Publish(event state.TransitionEvent) error {
+	if event.EntityID != p.entityID {
+		return nil
+	}
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("publisher unavailable")
+	}
+	p.delivered = append(p.delivered, event)
+	return nil
+}
+
+func TestTaskResults_Integration(t *testing.T) {
+	db, cleanup := SetupTestDB(t)
+	defer cleanup()
+
+	dagRepo, dagRunRepo, _, _ := CreateTestRepositories(db.DB)
+	store := results.NewPostgresStore(db.DB)
+	ctx := context.Background()
+
+	dag := &models.DAG{
+		Name:      "test-results-" + uuid.New().String(),
+		StartDate: time.Now().UTC(),
+	}
+	if err := dagRepo.Create(ctx, dag); err != nil {
+		t.Fatalf("Failed to create test DAG: %v", err)
+	}
+
+	dagRun := &models.DAGRun{
+		DAGID:         dag.ID,
+		ExecutionDate: time.Now().UTC(),
+		State:         models.StateRunning,
+	}
+	if err := dagRunRepo.Create(ctx, dagRun); err != nil {
+		t.Fatalf("Failed to create DAG run: %v", err)
+	}
+
+	t.Run("Push, Replace and Pull", func(t *testing.T) {
+		manager := results.NewManager(store, nil)
+		if err := manager.Push(ctx, dagRun.ID, "extract", "rows", json.RawMessage(`41`)); err != nil {
+			t.Fatalf("Failed to push result: %v", err)
+		}
+		if err := manager.Push(ctx, dagRun.ID, "extract", "rows", json.RawMessage(`42`)); err != nil {
+			t.Fatalf("Failed to replace result: %v", err)
+		}
+		if err := manager.Push(ctx, dagRun.ID, "extract", "stats", json.RawMessage(`{"tables": 3}`)); err != nil {
+			t.Fatalf("Failed to push result: %v", err)
+		}
+
+		value, err := manager.Pull(ctx, dagRun.ID, "extract", "rows")
+		if err != nil {
+			t.Fatalf("Failed to pull result: %v", err)
+		}
+		if string(value) != "42" {
+			t.Errorf("Expected value 42, got %s", value)
+		}
+
+		listed, err := manager.List(ctx, dagRun.ID, "extract")
+		if err != nil {
+			t.Fatalf("Failed to list results: %v", err)
+		}
+		if len(listed) != 2 || listed[0].Key != "rows" || listed[1].Key != "stats" || listed[1].Type() != "object" {
+			t.Errorf("Unexpected results: %+v", listed)
+		}
+
+		if _, err := manager.Pull(ctx, dagRun.ID, "extract", "missing"); !errors.Is(err, results.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if _, err := manager.Pull(ctx, "not-a-uuid", "extract", "rows"); !errors.Is(err, results.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a malformed DAG run ID, got %v", err)
+		}
+	})
+
+	t.Run("Spill to Blob Store", func(t *testing.T) {
+		blobs, err := results.NewFileBlobStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create blob store: %v", err)
+		}
+		manager := results.NewManager(store, &results.Config{MaxValueSize: 8, MaxBlobSize: 1024, BlobStore: blobs})
+
+		large := json.RawMessage(`"a value over the inline limit"`)
+		if err := manager.Push(ctx, dagRun.ID, "load", "report", large); err != nil {
+			t.Fatalf("Failed to push result: %v", err)
+		}
+
+		stored, err := store.Get(ctx, dagRun.ID, "load", "report")
+		if err != nil {
+			t.Fatalf("Failed to get result: %v", err)
+		}
+		if stored.BlobKey == "" || len(stored.Value) != 0 || stored.Size != len(large) {
+			t.Errorf("Expected the value to be spilled to a blob, got %+v", stored)
+		}
+
+		value, err := manager.Pull(ctx, dagRun.ID, "load", "report")
+		if err != nil {
+			t.Fatalf("Failed to pull result: %v", err)
+		}
+		if string(value) != string(large) {
+			t.Errorf("Expected value %s, got %s", large, value)
+		}
+	})
 }
@@ -64,16 +64,24 @@ func (s *StringArray) Scan(value interface{}) error {
 
 // DAGModel represents the database model for a DAG
 type DAGModel struct {
-	ID          uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Name        string      `gorm:"type:varchar(255);unique;not null;index:idx_dags_name"`
-	Description string      `gorm:"type:text"`
-	Schedule    string      `gorm:"type:varchar(100)"`
-	IsPaused    bool        `gorm:"default:false;index:idx_dags_is_paused"`
-	Tags        StringArray `gorm:"type:jsonb;default:'[]'"`
-	StartDate   time.Time   `gorm:"not null"`
-	EndDate     *time.Time
-	CreatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	ID             uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Name           string      `gorm:"type:varchar(255);unique;not null;index:idx_dags_name"`
+	Description    string      `gorm:"type:text"`
+	Schedule       string      `gorm:"type:varchar(100)"`
+	IsPaused       bool        `gorm:"default:false;index:idx_dags_is_paused"`
+	Tags           StringArray `gorm:"type:jsonb;default:'[]'"`
+	StartDate      time.Time   `gorm:"not null"`
+	EndDate        *time.Time
+	Version        int                          `gorm:"not null;default:1"`                        // Current definition version
+	SLA            int64                        `gorm:"column:sla;type:bigint;not null;default:0"` // SLA of a DAG run in nanoseconds
+	Notifications  *models.NotificationRules    `gorm:"type:jsonb;serializer:json"`                // Null when nobody is notified
+	CircuitBreaker *models.CircuitBreakerConfig `gorm:"type:jsonb;serializer:json"`                // Null when the executor's defaults apply
+	Params         []models.Param               `gorm:"type:jsonb;serializer:json"`                // Null when the DAG has no parameters
+	CreatedAt      time.Time                    `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time                    `gorm:"not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	Tasks []DAGTaskModel `gorm:"foreignKey:DAGID"`
 }
 
 // TableName specifies the table name for DAGModel
@@ -81,21 +89,66 @@ func (DAGModel) TableName() string {
 	return "dags"
 }
 
+// DAGTaskModel represents the database model for a task definition within a DAG
+type DAGTaskModel struct {
+	ID             uuid.UUID                    `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	DAGID          uuid.UUID                    `gorm:"type:uuid;not null;index:idx_dag_tasks_dag_id"`
+	TaskID         string                       `gorm:"type:varchar(255);not null"`
+	Name           string                       `gorm:"type:varchar(255)"`
+	Type           string                       `gorm:"type:varchar(50);not null"`
+	Command        string                       `gorm:"type:text;not null"`
+	Dependencies   StringArray                  `gorm:"type:jsonb;default:'[]'"`
+	Retries        int                          `gorm:"not null;default:0"`
+	Timeout        int64                        `gorm:"type:bigint;not null;default:0"`            // Timeout in nanoseconds
+	SLA            int64                        `gorm:"column:sla;type:bigint;not null;default:0"` // SLA in nanoseconds
+	Position       int                          `gorm:"not null;default:0"`                        // Order within the DAG definition
+	RetryPolicy    *models.RetryPolicy          `gorm:"type:jsonb;serializer:json"`                // Null when the executor's retry strategy applies
+	CircuitBreaker *models.CircuitBreakerConfig `gorm:"type:jsonb;serializer:json"`                // Null when the DAG's circuit breaker settings apply
+	TriggerRule    string                       `gorm:"type:varchar(20);not null;default:''"`      // Empty when the task runs once all dependencies succeeded
+	Branch         *models.BranchConfig         `gorm:"type:jsonb;serializer:json"`                // Null unless the task is a branch task
+	ResultPaths    map[string]string            `gorm:"type:jsonb;serializer:json"`                // Null unless an HTTP task pushes response body fields as results
+	CreatedAt      time.Time                    `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time                    `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the table name for DAGTaskModel
+func (DAGTaskModel) TableName() string {
+	return "dag_tasks"
+}
+
+// DAGVersionModel represents the database model for an immutable DAG definition snapshot
+type DAGVersionModel struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	DAGID       uuid.UUID `gorm:"type:uuid;not null;index:idx_dag_versions_dag_id"`
+	Version     int       `gorm:"not null"`
+	ContentHash string    `gorm:"type:varchar(64);not null"`
+	Definition  JSONB     `gorm:"type:jsonb;not null"` // Full models.DAG snapshot including tasks
+	CreatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the table name for DAGVersionModel
+func (DAGVersionModel) TableName() string {
+	return "dag_versions"
+}
+
 // DAGRunModel represents the database model for a DAG run
 type DAGRunModel struct {
-	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	DAGID           uuid.UUID  `gorm:"type:uuid;not null;index:idx_dag_runs_dag_id"`
-	ExecutionDate   time.Time  `gorm:"not null;index:idx_dag_runs_execution_date"`
-	State           string     `gorm:"type:varchar(50);not null;default:'queued';index:idx_dag_runs_state"`
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	DAGID           uuid.UUID `gorm:"type:uuid;not null;index:idx_dag_runs_dag_id"`
+	ExecutionDate   time.Time `gorm:"not null;index:idx_dag_runs_execution_date"`
+	State           string    `gorm:"type:varchar(50);not null;default:'queued';index:idx_dag_runs_state"`
 	StartDate       *time.Time
 	EndDate         *time.Time
-	ExternalTrigger bool      `gorm:"default:false"`
-	CreatedAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_dag_runs_created_at"`
-	UpdatedAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
-	Version         int       `gorm:"not null;default:1"` // For optimistic locking
+	ExternalTrigger bool `gorm:"default:false"`
+	DAGVersion      int  `gorm:"not null;default:1"` // DAG definition version the run executes
+	LastHeartbeatAt *time.Time
+	Conf            map[string]interface{} `gorm:"type:jsonb;serializer:json"` // Null when the run was not triggered with parameters
+	CreatedAt       time.Time              `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_dag_runs_created_at"`
+	UpdatedAt       time.Time              `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	Version         int                    `gorm:"not null;default:1"` // For optimistic locking
 
 	// Relationships
-	DAG           DAGModel        `gorm:"foreignKey:DAGID"`
+	DAG           DAGModel            `gorm:"foreignKey:DAGID"`
 	TaskInstances []TaskInstanceModel `gorm:"foreignKey:DAGRunID"`
 }
 
@@ -106,23 +159,27 @@ func (DAGRunModel) TableName() string {
 
 // TaskInstanceModel represents the database model for a task instance
 type TaskInstanceModel struct {
-	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	TaskID       string     `gorm:"type:varchar(255);not null;index:idx_task_instances_task_id"`
-	DAGRunID     uuid.UUID  `gorm:"type:uuid;not null;index:idx_task_instances_dag_run_id"`
-	State        string     `gorm:"type:varchar(50);not null;default:'queued';index:idx_task_instances_state"`
-	TryNumber    int        `gorm:"not null;default:1"`
-	MaxTries     int        `gorm:"not null;default:1"`
-	StartDate    *time.Time
-	EndDate      *time.Time
-	Duration     *int64     `gorm:"type:bigint"` // Duration in nanoseconds
-	Hostname     string     `gorm:"type:varchar(255)"`
-	ErrorMessage string     `gorm:"type:text"`
-	CreatedAt    time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_task_instances_created_at"`
-	UpdatedAt    time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP"`
-	Version      int        `gorm:"not null;default:1"` // For optimistic locking
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TaskID          string    `gorm:"type:varchar(255);not null;index:idx_task_instances_task_id"`
+	DAGRunID        uuid.UUID `gorm:"type:uuid;not null;index:idx_task_instances_dag_run_id"`
+	State           string    `gorm:"type:varchar(50);not null;default:'queued';index:idx_task_instances_state"`
+	TryNumber       int       `gorm:"not null;default:1"`
+	MaxTries        int       `gorm:"not null;default:1"`
+	StartDate       *time.Time
+	EndDate         *time.Time
+	Duration        *int64 `gorm:"type:bigint"` // Duration in nanoseconds
+	Hostname        string `gorm:"type:varchar(255)"`
+	ErrorMessage    string `gorm:"type:text"`
+	LastHeartbeatAt *time.Time
+	WorkerID        string    `gorm:"type:varchar(255);not null;default:'';index:idx_task_instances_worker_id"`
+	Branches        []string  `gorm:"type:jsonb;serializer:json"`    // Null unless a branch task succeeded; empty when it followed no task
+	RenderedCommand string    `gorm:"type:text;not null;default:''"` // Command of the current attempt after rendering its template
+	CreatedAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_task_instances_created_at"`
+	UpdatedAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	Version         int       `gorm:"not null;default:1"` // For optimistic locking
 
 	// Relationships
-	DAGRun DAGRunModel   `gorm:"foreignKey:DAGRunID"`
+	DAGRun DAGRunModel    `gorm:"foreignKey:DAGRunID"`
 	Logs   []TaskLogModel `gorm:"foreignKey:TaskInstanceID"`
 }
 
@@ -135,6 +192,9 @@ func (TaskInstanceModel) TableName() string {
 type TaskLogModel struct {
 	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	TaskInstanceID uuid.UUID `gorm:"type:uuid;not null;index:idx_task_logs_task_instance_id"`
+	Seq            int64     `gorm:"type:bigserial;->"`
+	Stream         string    `gorm:"type:varchar(10);not null;default:'stdout'"`
+	LineNumber     int       `gorm:"not null;default:0"`
 	LogData        string    `gorm:"type:text;not null"`
 	Timestamp      time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_task_logs_timestamp"`
 
@@ -147,20 +207,50 @@ func (TaskLogModel) TableName() string {
 	return "task_logs"
 }
 
+// SLAMissModel represents the database model for an SLA miss
+type SLAMissModel struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	DAGID          uuid.UUID  `gorm:"type:uuid;not null;index:idx_sla_misses_dag_id"`
+	DAGRunID       uuid.UUID  `gorm:"type:uuid;not null"`
+	TaskID         string     `gorm:"type:varchar(255);not null;default:''"` // Empty for a miss of the whole DAG run
+	TaskInstanceID *uuid.UUID `gorm:"type:uuid"`
+	ExecutionDate  time.Time  `gorm:"not null"`
+	SLA            int64      `gorm:"column:sla;type:bigint;not null"` // SLA in nanoseconds
+	Deadline       time.Time  `gorm:"not null"`
+	State          string     `gorm:"type:varchar(50);not null"`
+	DetectedAt     time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_sla_misses_detected_at"`
+}
+
+// TableName specifies the table name for SLAMissModel
+func (SLAMissModel) TableName() string {
+	return "sla_misses"
+}
+
 // ToDAG converts a DAGModel to a models.DAG
+// Tasks are taken from the Tasks relationship, which must be preloaded ordered by position
 func (d *DAGModel) ToDAG() *models.DAG {
+	tasks := make([]models.Task, len(d.Tasks))
+	for i := range d.Tasks {
+		tasks[i] = d.Tasks[i].ToTask()
+	}
+
 	return &models.DAG{
-		ID:          d.ID.String(),
-		Name:        d.Name,
-		Description: d.Description,
-		Schedule:    d.Schedule,
-		Tasks:       []models.Task{}, // Tasks need to be loaded separately
-		StartDate:   d.StartDate,
-		EndDate:     d.EndDate,
-		Tags:        []string(d.Tags),
-		IsPaused:    d.IsPaused,
-		CreatedAt:   d.CreatedAt,
-		UpdatedAt:   d.UpdatedAt,
+		ID:             d.ID.String(),
+		Name:           d.Name,
+		Description:    d.Description,
+		Schedule:       d.Schedule,
+		Tasks:          tasks,
+		StartDate:      d.StartDate,
+		EndDate:        d.EndDate,
+		Tags:           []string(d.Tags),
+		IsPaused:       d.IsPaused,
+		Version:        d.Version,
+		SLA:            time.Duration(d.SLA),
+		Notifications:  d.Notifications,
+		CircuitBreaker: d.CircuitBreaker,
+		Params:         d.Params,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
 	}
 }
 
@@ -171,17 +261,121 @@ func FromDAG(d *models.DAG) (*DAGModel, error) {
 		id = uuid.New()
 	}
 
+	tasks := make([]DAGTaskModel, len(d.Tasks))
+	for i := range d.Tasks {
+		tasks[i] = FromTask(id, i, &d.Tasks[i])
+	}
+
 	return &DAGModel{
-		ID:          id,
-		Name:        d.Name,
-		Description: d.Description,
-		Schedule:    d.Schedule,
-		IsPaused:    d.IsPaused,
-		Tags:        StringArray(d.Tags),
-		StartDate:   d.StartDate,
-		EndDate:     d.EndDate,
-		CreatedAt:   d.CreatedAt,
-		UpdatedAt:   d.UpdatedAt,
+		ID:             id,
+		Name:           d.Name,
+		Description:    d.Description,
+		Schedule:       d.Schedule,
+		IsPaused:       d.IsPaused,
+		Tags:           StringArray(d.Tags),
+		StartDate:      d.StartDate,
+		EndDate:        d.EndDate,
+		Version:        d.Version,
+		SLA:            int64(d.SLA),
+		Notifications:  d.Notifications,
+		CircuitBreaker: d.CircuitBreaker,
+		Params:         d.Params,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		Tasks:          tasks,
+	}, nil
+}
+
+// ToTask converts a DAGTaskModel to a models.Task
+func (t *DAGTaskModel) ToTask() models.Task {
+	dependencies := []string(t.Dependencies)
+	if dependencies == nil {
+		dependencies = []string{}
+	}
+
+	return models.Task{
+		ID:             t.TaskID,
+		Name:           t.Name,
+		Type:           models.TaskType(t.Type),
+		Command:        t.Command,
+		Dependencies:   dependencies,
+		Retries:        t.Retries,
+		Timeout:        time.Duration(t.Timeout),
+		SLA:            time.Duration(t.SLA),
+		RetryPolicy:    t.RetryPolicy,
+		CircuitBreaker: t.CircuitBreaker,
+		TriggerRule:    models.TriggerRule(t.TriggerRule),
+		Branch:         t.Branch,
+		ResultPaths:    t.ResultPaths,
+	}
+}
+
+// FromTask converts a models.Task to a DAGTaskModel belonging to the given DAG
+func FromTask(dagID uuid.UUID, position int, task *models.Task) DAGTaskModel {
+	dependencies := task.Dependencies
+	if dependencies == nil {
+		dependencies = []string{}
+	}
+
+	return DAGTaskModel{
+		DAGID:          dagID,
+		TaskID:         task.ID,
+		Name:           task.Name,
+		Type:           string(task.Type),
+		Command:        task.Command,
+		Dependencies:   StringArray(dependencies),
+		Retries:        task.Retries,
+		Timeout:        int64(task.Timeout),
+		SLA:            int64(task.SLA),
+		Position:       position,
+		RetryPolicy:    task.RetryPolicy,
+		CircuitBreaker: task.CircuitBreaker,
+		TriggerRule:    string(task.TriggerRule),
+		Branch:         task.Branch,
+		ResultPaths:    task.ResultPaths,
+	}
+}
+
+// ToDAGVersion converts a DAGVersionModel to a models.DAGVersion
+func (v *DAGVersionModel) ToDAGVersion() (*models.DAGVersion, error) {
+	data, err := json.Marshal(v.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	var dag models.DAG
+	if err := json.Unmarshal(data, &dag); err != nil {
+		return nil, err
+	}
+	dag.ID = v.DAGID.String()
+	dag.Version = v.Version
+
+	return &models.DAGVersion{
+		DAGID:       v.DAGID.String(),
+		Version:     v.Version,
+		ContentHash: v.ContentHash,
+		DAG:         &dag,
+		CreatedAt:   v.CreatedAt,
+	}, nil
+}
+
+// FromDAGVersion creates a DAGVersionModel snapshotting the given DAG definition
+func FromDAGVersion(dagID uuid.UUID, version int, contentHash string, d *models.DAG) (*DAGVersionModel, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var definition JSONB
+	if err := json.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+
+	return &DAGVersionModel{
+		DAGID:       dagID,
+		Version:     version,
+		ContentHash: contentHash,
+		Definition:  definition,
 	}, nil
 }
 
@@ -195,6 +389,9 @@ func (dr *DAGRunModel) ToDAGRun() *models.DAGRun {
 		StartDate:       dr.StartDate,
 		EndDate:         dr.EndDate,
 		ExternalTrigger: dr.ExternalTrigger,
+		DAGVersion:      dr.DAGVersion,
+		LastHeartbeatAt: dr.LastHeartbeatAt,
+		Conf:            dr.Conf,
 	}
 }
 
@@ -218,6 +415,9 @@ func FromDAGRun(dr *models.DAGRun) (*DAGRunModel, error) {
 		StartDate:       dr.StartDate,
 		EndDate:         dr.EndDate,
 		ExternalTrigger: dr.ExternalTrigger,
+		DAGVersion:      dr.DAGVersion,
+		LastHeartbeatAt: dr.LastHeartbeatAt,
+		Conf:            dr.Conf,
 		Version:         1,
 	}, nil
 }
@@ -230,17 +430,21 @@ func (ti *TaskInstanceModel) ToTaskInstance() *models.TaskInstance {
 	}
 
 	return &models.TaskInstance{
-		ID:           ti.ID.String(),
-		TaskID:       ti.TaskID,
-		DAGRunID:     ti.DAGRunID.String(),
-		State:        models.State(ti.State),
-		TryNumber:    ti.TryNumber,
-		MaxTries:     ti.MaxTries,
-		StartDate:    ti.StartDate,
-		EndDate:      ti.EndDate,
-		Duration:     duration,
-		Hostname:     ti.Hostname,
-		ErrorMessage: ti.ErrorMessage,
+		ID:              ti.ID.String(),
+		TaskID:          ti.TaskID,
+		DAGRunID:        ti.DAGRunID.String(),
+		State:           models.State(ti.State),
+		TryNumber:       ti.TryNumber,
+		MaxTries:        ti.MaxTries,
+		StartDate:       ti.StartDate,
+		EndDate:         ti.EndDate,
+		Duration:        duration,
+		Hostname:        ti.Hostname,
+		ErrorMessage:    ti.ErrorMessage,
+		LastHeartbeatAt: ti.LastHeartbeatAt,
+		WorkerID:        ti.WorkerID,
+		Branches:        ti.Branches,
+		RenderedCommand: ti.RenderedCommand,
 	}
 }
 
@@ -263,17 +467,85 @@ func FromTaskInstance(ti *models.TaskInstance) (*TaskInstanceModel, error) {
 	}
 
 	return &TaskInstanceModel{
-		ID:           id,
-		TaskID:       ti.TaskID,
-		DAGRunID:     dagRunID,
-		State:        string(ti.State),
-		TryNumber:    ti.TryNumber,
-		MaxTries:     ti.MaxTries,
-		StartDate:    ti.StartDate,
-		EndDate:      ti.EndDate,
-		Duration:     duration,
-		Hostname:     ti.Hostname,
-		ErrorMessage: ti.ErrorMessage,
-		Version:      1,
+		ID:              id,
+		TaskID:          ti.TaskID,
+		DAGRunID:        dagRunID,
+		State:           string(ti.State),
+		TryNumber:       ti.TryNumber,
+		MaxTries:        ti.MaxTries,
+		StartDate:       ti.StartDate,
+		EndDate:         ti.EndDate,
+		Duration:        duration,
+		Hostname:        ti.Hostname,
+		ErrorMessage:    ti.ErrorMessage,
+		LastHeartbeatAt: ti.LastHeartbeatAt,
+		WorkerID:        ti.WorkerID,
+		Branches:        ti.Branches,
+		RenderedCommand: ti.RenderedCommand,
+		Version:         1,
+	}, nil
+}
+
+// ToSLAMiss converts an SLAMissModel to a models.SLAMiss
+func (m *SLAMissModel) ToSLAMiss() *models.SLAMiss {
+	miss := &models.SLAMiss{
+		ID:            m.ID.String(),
+		DAGID:         m.DAGID.String(),
+		DAGRunID:      m.DAGRunID.String(),
+		TaskID:        m.TaskID,
+		ExecutionDate: m.ExecutionDate,
+		SLA:           time.Duration(m.SLA),
+		Deadline:      m.Deadline,
+		State:         models.State(m.State),
+		DetectedAt:    m.DetectedAt,
+	}
+	if m.TaskInstanceID != nil {
+		miss.TaskInstanceID = m.TaskInstanceID.String()
+	}
+	return miss
+}
+
+// FromSLAMiss converts a models.SLAMiss to an SLAMissModel
+func FromSLAMiss(miss *models.SLAMiss) (*SLAMissModel, error) {
+	id, err := uuid.Parse(miss.ID)
+	if err != nil {
+		id = uuid.New()
+	}
+
+	dagID, err := uuid.Parse(miss.DAGID)
+	if err != nil {
+		return nil, err
+	}
+
+	dagRunID, err := uuid.Parse(miss.DAGRunID)
+	if err != nil {
+		return nil, err
+	}
+
+	var taskInstanceID *uuid.UUID
+	if miss.TaskInstanceID != "" {
+		parsed, err := uuid.Parse(miss.TaskInstanceID)
+		if err != nil {
+			return nil, err
+		}
+		taskInstanceID = &parsed
+	}
+
+	detectedAt := miss.DetectedAt
+	if detectedAt.IsZero() {
+		detectedAt = time.Now().UTC()
+	}
+
+	return &SLAMissModel{
+		ID:             id,
+		DAGID:          dagID,
+		DAGRunID:       dagRunID,
+		TaskID:         miss.TaskID,
+		TaskInstanceID: taskInstanceID,
+		ExecutionDate:  miss.ExecutionDate,
+		SLA:            int64(miss.SLA),
+		Deadline:       miss.Deadline,
+		State:          string(miss.State),
+		DetectedAt:     detectedAt,
 	}, nil
 }
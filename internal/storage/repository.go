@@ -18,6 +18,8 @@ type DAGRepository interface {
 	Delete(ctx context.Context, id string) error
 	Pause(ctx context.Context, id string) error
 	Unpause(ctx context.Context, id string) error
+	ListVersions(ctx context.Context, dagID string) ([]*models.DAGVersion, error)
+	GetVersion(ctx context.Context, dagID string, version int) (*models.DAGVersion, error)
 }
 
 // DAGFilters defines filters for listing DAGs
@@ -39,6 +41,10 @@ type DAGRunRepository interface {
 	UpdateState(ctx context.Context, id string, oldState, newState models.State) error
 	Delete(ctx context.Context, id string) error
 	GetLatestRun(ctx context.Context, dagID string) (*models.DAGRun, error)
+	Heartbeat(ctx context.Context, id string) error
+	// ClaimOrphaned takes over a running DAG run whose heartbeat is older than leaseTimeout.
+	// It reports false if the run is not orphaned or another process claimed it first.
+	ClaimOrphaned(ctx context.Context, id string, leaseTimeout time.Duration) (bool, error)
 }
 
 // DAGRunFilters defines filters for listing DAG runs
@@ -61,12 +67,17 @@ type TaskInstanceRepository interface {
 	UpdateState(ctx context.Context, id string, oldState, newState models.State) error
 	Delete(ctx context.Context, id string) error
 	ListByDAGRun(ctx context.Context, dagRunID string) ([]*models.TaskInstance, error)
+	Heartbeat(ctx context.Context, id string) error
+	// HeartbeatWorkerTasks renews the leases of the running task instances a distributed worker reports
+	// and records the worker on them
+	HeartbeatWorkerTasks(ctx context.Context, workerID string, ids []string) error
 }
 
 // TaskInstanceFilters defines filters for listing task instances
 type TaskInstanceFilters struct {
 	DAGRunID string
 	TaskID   string
+	WorkerID string
 	State    *models.State
 	Limit    int
 	Offset   int
@@ -75,6 +86,45 @@ type TaskInstanceFilters struct {
 // TaskLogRepository defines the interface for task log persistence
 type TaskLogRepository interface {
 	Create(ctx context.Context, taskInstanceID, logData string) error
+	CreateBatch(ctx context.Context, taskInstanceID string, logs []TaskLogModel) error
 	List(ctx context.Context, taskInstanceID string, limit int) ([]TaskLogModel, error)
+	ListAfter(ctx context.Context, taskInstanceID string, afterSeq int64, limit int) ([]TaskLogModel, error)
 	Delete(ctx context.Context, taskInstanceID string) error
 }
+
+// SLAMissRepository defines the interface for SLA miss persistence
+type SLAMissRepository interface {
+	// FindMisses returns the task instances and DAG runs that missed their SLA and have no recorded miss yet
+	FindMisses(ctx context.Context, opts SLACheckOptions) ([]*models.SLAMiss, error)
+	// Record stores a miss and its state change event. It reports false if the miss was already recorded.
+	Record(ctx context.Context, miss *models.SLAMiss) (bool, error)
+	List(ctx context.Context, filters SLAMissFilters) ([]*models.SLAMiss, error)
+}
+
+// SLAReference is the point in time an SLA is measured from
+type SLAReference string
+
+const (
+	// SLAFromExecutionDate measures SLAs from the execution date of the DAG run
+	SLAFromExecutionDate SLAReference = "execution_date"
+	// SLAFromStartDate measures SLAs from the start of the task instance or DAG run
+	SLAFromStartDate SLAReference = "start_date"
+)
+
+// SLACheckOptions defines how SLA misses are looked for
+type SLACheckOptions struct {
+	Now       time.Time    // Deadlines before now are missed by runs and tasks that have not finished
+	Since     time.Time    // Only DAG runs created at or after since are checked
+	Reference SLAReference // Defaults to SLAFromExecutionDate
+}
+
+// SLAMissFilters defines filters for listing SLA misses
+type SLAMissFilters struct {
+	DAGID    string
+	DAGRunID string
+	TaskID   string
+	After    *time.Time // Detected after
+	Before   *time.Time // Detected before
+	Limit    int
+	Offset   int
+}
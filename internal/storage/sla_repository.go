@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SLA misses are looked for in the definition version each DAG run executes.
+// %[1]s is the column the SLA is measured from; the deadline is that column plus the SLA.
+const (
+	taskSLAMissQuery = `
+SELECT dr.dag_id, dr.id AS dag_run_id, ti.task_id, ti.id AS task_instance_id, dr.execution_date,
+	t.sla, %[1]s + t.sla / 1000 * INTERVAL '1 microsecond' AS deadline, ti.state
+FROM task_instances ti
+JOIN dag_runs dr ON dr.id = ti.dag_run_id
+JOIN dag_versions dv ON dv.dag_id = dr.dag_id AND dv.version = dr.dag_version
+CROSS JOIN LATERAL (
+	SELECT (task->>'sla')::bigint AS sla FROM jsonb_array_elements(dv.definition->'tasks') task
+	WHERE task->>'id' = ti.task_id
+) t
+WHERE t.sla > 0
+	AND dr.created_at >= @since
+	AND ti.state NOT IN ('skipped', 'upstream_failed', 'cancelled')
+	AND (CASE WHEN ti.state IN ('success', 'failed') THEN COALESCE(ti.end_date, @now) ELSE @now END)
+		> %[1]s + t.sla / 1000 * INTERVAL '1 microsecond'
+	AND NOT EXISTS (SELECT 1 FROM sla_misses m WHERE m.dag_run_id = dr.id AND m.task_id = ti.task_id)`
+
+	dagRunSLAMissQuery = `
+SELECT dr.dag_id, dr.id AS dag_run_id, dr.execution_date,
+	t.sla, %[1]s + t.sla / 1000 * INTERVAL '1 microsecond' AS deadline, dr.state
+FROM dag_runs dr
+JOIN dag_versions dv ON dv.dag_id = dr.dag_id AND dv.version = dr.dag_version
+CROSS JOIN LATERAL (SELECT COALESCE((dv.definition->>'sla')::bigint, 0) AS sla) t
+WHERE t.sla > 0
+	AND dr.created_at >= @since
+	AND dr.state <> 'cancelled'
+	AND (CASE WHEN dr.state IN ('success', 'failed') THEN COALESCE(dr.end_date, @now) ELSE @now END)
+		> %[1]s + t.sla / 1000 * INTERVAL '1 microsecond'
+	AND NOT EXISTS (SELECT 1 FROM sla_misses m WHERE m.dag_run_id = dr.id AND m.task_id = '')`
+)
+
+type slaMissRepository struct {
+	db *gorm.DB
+}
+
+// NewSLAMissRepository creates a new SLA miss repository
+func NewSLAMissRepository(db *gorm.DB) SLAMissRepository {
+	return &slaMissRepository{db: db}
+}
+
+func (r *slaMissRepository) FindMisses(ctx context.Context, opts SLACheckOptions) ([]*models.SLAMiss, error) {
+	taskReference, dagRunReference := "dr.execution_date", "dr.execution_date"
+	switch opts.Reference {
+	case SLAFromExecutionDate, "":
+	case SLAFromStartDate:
+		taskReference, dagRunReference = "ti.start_date", "dr.start_date"
+	default:
+		return nil, fmt.Errorf("%w: unknown SLA reference %q", ErrInvalidInput, opts.Reference)
+	}
+
+	params := map[string]interface{}{"now": opts.Now, "since": opts.Since}
+
+	var taskMisses []SLAMissModel
+	if err := r.db.WithContext(ctx).Raw(fmt.Sprintf(taskSLAMissQuery, taskReference), params).Scan(&taskMisses).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task SLA misses: %w", err)
+	}
+
+	var dagRunMisses []SLAMissModel
+	if err := r.db.WithContext(ctx).Raw(fmt.Sprintf(dagRunSLAMissQuery, dagRunReference), params).Scan(&dagRunMisses).Error; err != nil {
+		return nil, fmt.Errorf("failed to find DAG run SLA misses: %w", err)
+	}
+
+	misses := make([]*models.SLAMiss, 0, len(taskMisses)+len(dagRunMisses))
+	for _, model := range append(taskMisses, dagRunMisses...) {
+		miss := model.ToSLAMiss()
+		miss.ID = ""
+		misses = append(misses, miss)
+	}
+
+	return misses, nil
+}
+
+func (r *slaMissRepository) Record(ctx context.Context, miss *models.SLAMiss) (bool, error) {
+	model, err := FromSLAMiss(miss)
+	if err != nil {
+		return false, fmt.Errorf("failed to convert SLA miss to model: %w", err)
+	}
+
+	recorded := false
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A miss is recorded once per task and DAG run, by whichever process detects it first
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(model)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		recorded = true
+
+		return state.NewOutbox(tx).Enqueue(ctx, state.TransitionEvent{
+			EntityType: "sla_miss",
+			EntityID:   model.ID.String(),
+			NewState:   models.State(model.State),
+			Metadata: map[string]interface{}{
+				state.MetadataDAGID:    model.DAGID.String(),
+				state.MetadataDAGRunID: model.DAGRunID.String(),
+				"task_id":              model.TaskID,
+				"deadline":             model.Deadline.UTC().Format(time.RFC3339),
+			},
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record SLA miss: %w", err)
+	}
+
+	if recorded {
+		miss.ID = model.ID.String()
+		miss.DetectedAt = model.DetectedAt
+	}
+
+	return recorded, nil
+}
+
+func (r *slaMissRepository) List(ctx context.Context, filters SLAMissFilters) ([]*models.SLAMiss, error) {
+	query := r.db.WithContext(ctx).Model(&SLAMissModel{})
+
+	if filters.DAGID != "" {
+		dagID, err := uuid.Parse(filters.DAGID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DAG ID: %w", err)
+		}
+		query = query.Where("dag_id = ?", dagID)
+	}
+
+	if filters.DAGRunID != "" {
+		dagRunID, err := uuid.Parse(filters.DAGRunID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DAG run ID: %w", err)
+		}
+		query = query.Where("dag_run_id = ?", dagRunID)
+	}
+
+	if filters.TaskID != "" {
+		query = query.Where("task_id = ?", filters.TaskID)
+	}
+
+	if filters.After != nil {
+		query = query.Where("detected_at > ?", *filters.After)
+	}
+
+	if filters.Before != nil {
+		query = query.Where("detected_at < ?", *filters.Before)
+	}
+
+	query = query.Order("detected_at DESC")
+
+	if filters.Limit > 0 {
+		query = query.Limit(filters.Limit)
+	}
+
+	if filters.Offset > 0 {
+		query = query.Offset(filters.Offset)
+	}
+
+	var missModels []SLAMissModel
+	if err := query.Find(&missModels).Error; err != nil {
+		return nil, fmt.Errorf("failed to list SLA misses: %w", err)
+	}
+
+	misses := make([]*models.SLAMiss, len(missModels))
+	for i := range missModels {
+		misses[i] = missModels[i].ToSLAMiss()
+	}
+
+	return misses, nil
+}
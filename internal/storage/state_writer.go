@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+	"gorm.io/gorm"
+)
+
+// stateWriter writes rows of a table whose state is governed by the state machine.
+// Every write is a compare-and-swap on the row's version. State changes are validated and
+// recorded in state_history and the state_outbox in the same transaction; an OutboxRelay publishes them.
+type stateWriter struct {
+	db           *gorm.DB
+	stateManager *state.Manager
+	entityType   string             // "dag_run" or "task_instance"
+	newModel     func() interface{} // Returns an empty model of the table
+	scopeQuery   string             // Selects the dag_id and dag_run_id of a row by ID
+}
+
+// rowScope identifies the DAG and DAG run a row belongs to
+type rowScope struct {
+	DAGID    uuid.UUID
+	DAGRunID uuid.UUID
+}
+
+// versionedRow is the part of a row read before a compare-and-swap
+type versionedRow struct {
+	State   string
+	Version int
+}
+
+// create inserts a new row and records its initial state
+func (w *stateWriter) create(ctx context.Context, model interface{}, id uuid.UUID, initial models.State) error {
+	if initial == "" {
+		initial = models.StateQueued // Column default
+	}
+	if !initial.IsValid() {
+		return fmt.Errorf("%w: unknown initial state %q", state.ErrInvalidTransition, initial)
+	}
+
+	return w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		return w.record(ctx, tx, id, "", initial)
+	})
+}
+
+// transition moves a row from one state to another, writing updates in the same statement.
+// It returns state.ErrOptimisticLock if the row is no longer in the from state.
+func (w *stateWriter) transition(ctx context.Context, id uuid.UUID, from, to models.State, updates map[string]interface{}) error {
+	if err := w.stateManager.Validate(from, to); err != nil {
+		return fmt.Errorf("invalid state transition: %w", err)
+	}
+
+	return w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := w.current(tx, id)
+		if err != nil {
+			return err
+		}
+		if current.State != string(from) {
+			return state.ErrOptimisticLock
+		}
+
+		updates["state"] = string(to)
+		updates["version"] = current.Version + 1
+		if err := w.swap(tx, id, current.Version, updates); err != nil {
+			return err
+		}
+
+		return w.record(ctx, tx, id, from, to)
+	})
+}
+
+// update writes the values returned by values, which receives the version the row moves to.
+// If to differs from the stored state, the change is validated and recorded like a transition;
+// an empty to leaves the state untouched.
+func (w *stateWriter) update(ctx context.Context, id uuid.UUID, to models.State, values func(version int) interface{}) error {
+	return w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := w.current(tx, id)
+		if err != nil {
+			return err
+		}
+
+		from := models.State(current.State)
+		changed := to != "" && to != from
+		if changed {
+			if err := w.stateManager.Validate(from, to); err != nil {
+				return fmt.Errorf("invalid state transition: %w", err)
+			}
+		}
+
+		if err := w.swap(tx, id, current.Version, values(current.Version+1)); err != nil {
+			return err
+		}
+
+		if !changed {
+			return nil
+		}
+		return w.record(ctx, tx, id, from, to)
+	})
+}
+
+// current reads the state and version of a row
+func (w *stateWriter) current(tx *gorm.DB, id uuid.UUID) (*versionedRow, error) {
+	var row versionedRow
+	if err := tx.Model(w.newModel()).Select("state", "version").Where("id = ?", id).Take(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &row, nil
+}
+
+// swap writes values to a row if it still has the given version
+func (w *stateWriter) swap(tx *gorm.DB, id uuid.UUID, version int, values interface{}) error {
+	result := tx.Model(w.newModel()).Where("id = ? AND version = ?", id, version).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return state.ErrOptimisticLock
+	}
+	return nil
+}
+
+// record writes a state change to state_history and the state_outbox.
+// The metadata of the change names the DAG and DAG run of the row so that subscribers can filter on them.
+func (w *stateWriter) record(ctx context.Context, tx *gorm.DB, id uuid.UUID, from, to models.State) error {
+	var scope rowScope
+	if err := tx.Raw(w.scopeQuery, id).Scan(&scope).Error; err != nil {
+		return fmt.Errorf("failed to read DAG of %s: %w", w.entityType, err)
+	}
+	metadata := map[string]interface{}{
+		state.MetadataDAGID:    scope.DAGID.String(),
+		state.MetadataDAGRunID: scope.DAGRunID.String(),
+	}
+
+	if err := state.NewHistoryTracker(tx).Record(ctx, w.entityType, id.String(), from, to, metadata); err != nil {
+		return err
+	}
+
+	return state.NewOutbox(tx).Enqueue(ctx, state.TransitionEvent{
+		EntityType: w.entityType,
+		EntityID:   id.String(),
+		OldState:   from,
+		NewState:   to,
+		Metadata:   metadata,
+	})
+}
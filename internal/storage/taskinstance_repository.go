@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/therealutkarshpriyadarshi/dag/internal/state"
@@ -11,15 +12,22 @@ import (
 )
 
 type taskInstanceRepository struct {
-	db           *gorm.DB
-	stateManager *state.Manager
+	db     *gorm.DB
+	states *stateWriter
 }
 
 // NewTaskInstanceRepository creates a new task instance repository
 func NewTaskInstanceRepository(db *gorm.DB, stateManager *state.Manager) TaskInstanceRepository {
 	return &taskInstanceRepository{
-		db:           db,
-		stateManager: stateManager,
+		db: db,
+		states: &stateWriter{
+			db:           db,
+			stateManager: stateManager,
+			entityType:   "task_instance",
+			newModel:     func() interface{} { return &TaskInstanceModel{} },
+			scopeQuery: "SELECT dag_runs.dag_id, task_instances.dag_run_id FROM task_instances " +
+				"JOIN dag_runs ON dag_runs.id = task_instances.dag_run_id WHERE task_instances.id = ?",
+		},
 	}
 }
 
@@ -29,7 +37,7 @@ func (r *taskInstanceRepository) Create(ctx context.Context, instance *models.Ta
 		return fmt.Errorf("failed to convert task instance to model: %w", err)
 	}
 
-	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+	if err := r.states.create(ctx, model, model.ID, models.State(model.State)); err != nil {
 		return fmt.Errorf("failed to create task instance: %w", err)
 	}
 
@@ -90,6 +98,10 @@ func (r *taskInstanceRepository) List(ctx context.Context, filters TaskInstanceF
 		query = query.Where("task_id = ?", filters.TaskID)
 	}
 
+	if filters.WorkerID != "" {
+		query = query.Where("worker_id = ?", filters.WorkerID)
+	}
+
 	if filters.State != nil {
 		query = query.Where("state = ?", string(*filters.State))
 	}
@@ -130,7 +142,11 @@ func (r *taskInstanceRepository) Update(ctx context.Context, instance *models.Ta
 
 	model.ID = instanceID
 
-	if err := r.db.WithContext(ctx).Model(&TaskInstanceModel{}).Where("id = ?", instanceID).Updates(model).Error; err != nil {
+	err = r.states.update(ctx, instanceID, models.State(model.State), func(version int) interface{} {
+		model.Version = version
+		return model
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update task instance: %w", err)
 	}
 
@@ -143,26 +159,65 @@ func (r *taskInstanceRepository) UpdateState(ctx context.Context, id string, old
 		return fmt.Errorf("invalid task instance ID: %w", err)
 	}
 
-	// Validate state transition
-	if err := r.stateManager.Transition("task_instance", id, oldState, newState, nil); err != nil {
-		return fmt.Errorf("invalid state transition: %w", err)
+	updates := map[string]interface{}{}
+	if newState == models.StateRunning {
+		// Entering running starts the lease that heartbeats renew; the attempt has no worker yet
+		updates["last_heartbeat_at"] = time.Now()
+		updates["worker_id"] = ""
+	}
+
+	if err := r.states.transition(ctx, instanceID, oldState, newState, updates); err != nil {
+		return fmt.Errorf("failed to update task instance state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *taskInstanceRepository) Heartbeat(ctx context.Context, id string) error {
+	instanceID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid task instance ID: %w", err)
 	}
 
-	// Use optimistic locking to prevent concurrent updates
 	result := r.db.WithContext(ctx).
 		Model(&TaskInstanceModel{}).
-		Where("id = ? AND state = ?", instanceID, string(oldState)).
-		Updates(map[string]interface{}{
-			"state":   string(newState),
-			"version": gorm.Expr("version + 1"),
-		})
+		Where("id = ? AND state = ?", instanceID, string(models.StateRunning)).
+		Update("last_heartbeat_at", time.Now())
 
 	if result.Error != nil {
-		return fmt.Errorf("failed to update task instance state: %w", result.Error)
+		return fmt.Errorf("failed to record task instance heartbeat: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return state.ErrOptimisticLock
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *taskInstanceRepository) HeartbeatWorkerTasks(ctx context.Context, workerID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	instanceIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		instanceID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid task instance ID: %w", err)
+		}
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&TaskInstanceModel{}).
+		Where("id IN ? AND state = ?", instanceIDs, string(models.StateRunning)).
+		Updates(map[string]interface{}{
+			"last_heartbeat_at": time.Now(),
+			"worker_id":         workerID,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record worker heartbeat: %w", err)
 	}
 
 	return nil
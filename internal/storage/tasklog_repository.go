@@ -35,6 +35,28 @@ func (r *taskLogRepository) Create(ctx context.Context, taskInstanceID, logData
 	return nil
 }
 
+// CreateBatch inserts several log lines for a task instance in a single statement
+func (r *taskLogRepository) CreateBatch(ctx context.Context, taskInstanceID string, logs []TaskLogModel) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	instanceID, err := uuid.Parse(taskInstanceID)
+	if err != nil {
+		return fmt.Errorf("invalid task instance ID: %w", err)
+	}
+
+	for i := range logs {
+		logs[i].TaskInstanceID = instanceID
+	}
+
+	if err := r.db.WithContext(ctx).Create(&logs).Error; err != nil {
+		return fmt.Errorf("failed to create task logs: %w", err)
+	}
+
+	return nil
+}
+
 func (r *taskLogRepository) List(ctx context.Context, taskInstanceID string, limit int) ([]TaskLogModel, error) {
 	instanceID, err := uuid.Parse(taskInstanceID)
 	if err != nil {
@@ -43,7 +65,30 @@ func (r *taskLogRepository) List(ctx context.Context, taskInstanceID string, lim
 
 	query := r.db.WithContext(ctx).
 		Where("task_instance_id = ?", instanceID).
-		Order("timestamp ASC")
+		Order("seq ASC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var logs []TaskLogModel
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list task logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+// ListAfter returns log lines of a task instance written after the given sequence number, in write order
+func (r *taskLogRepository) ListAfter(ctx context.Context, taskInstanceID string, afterSeq int64, limit int) ([]TaskLogModel, error) {
+	instanceID, err := uuid.Parse(taskInstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task instance ID: %w", err)
+	}
+
+	query := r.db.WithContext(ctx).
+		Where("task_instance_id = ? AND seq > ?", instanceID, afterSeq).
+		Order("seq ASC")
 
 	if limit > 0 {
 		query = query.Limit(limit)
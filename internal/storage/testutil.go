@@ -76,8 +76,13 @@ func SetupTestDB(t *testing.T) (*DB, func()) {
 		// Clean up test data
 		db.Exec("TRUNCATE TABLE task_logs CASCADE")
 		db.Exec("TRUNCATE TABLE state_history CASCADE")
+		db.Exec("TRUNCATE TABLE state_outbox CASCADE")
+		db.Exec("TRUNCATE TABLE sla_misses CASCADE")
+		db.Exec("TRUNCATE TABLE dead_letter_queue CASCADE")
+		db.Exec("TRUNCATE TABLE task_results CASCADE")
 		db.Exec("TRUNCATE TABLE task_instances CASCADE")
 		db.Exec("TRUNCATE TABLE dag_runs CASCADE")
+		db.Exec("TRUNCATE TABLE dag_tasks CASCADE")
 		db.Exec("TRUNCATE TABLE dags CASCADE")
 		db.Close()
 	}
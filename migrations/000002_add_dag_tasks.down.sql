@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS update_dag_tasks_updated_at ON dag_tasks;
+
+DROP TABLE IF EXISTS dag_tasks;
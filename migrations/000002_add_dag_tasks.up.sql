@@ -0,0 +1,23 @@
+-- DAG tasks table: task definitions belonging to a DAG
+CREATE TABLE dag_tasks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    dag_id UUID NOT NULL REFERENCES dags(id) ON DELETE CASCADE,
+    task_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255),
+    type VARCHAR(50) NOT NULL,
+    command TEXT NOT NULL DEFAULT '',
+    dependencies JSONB DEFAULT '[]'::jsonb,
+    retries INTEGER NOT NULL DEFAULT 0,
+    timeout BIGINT NOT NULL DEFAULT 0, -- Nanoseconds
+    sla BIGINT NOT NULL DEFAULT 0, -- Nanoseconds
+    position INTEGER NOT NULL DEFAULT 0, -- Order of the task within the DAG definition
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_task_per_dag UNIQUE (dag_id, task_id)
+);
+
+-- Create indexes for dag_tasks
+CREATE INDEX idx_dag_tasks_dag_id ON dag_tasks(dag_id);
+
+CREATE TRIGGER update_dag_tasks_updated_at BEFORE UPDATE ON dag_tasks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
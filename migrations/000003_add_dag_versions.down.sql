@@ -0,0 +1,3 @@
+ALTER TABLE dag_runs DROP COLUMN IF EXISTS dag_version;
+ALTER TABLE dags DROP COLUMN IF EXISTS version;
+DROP TABLE IF EXISTS dag_versions;
@@ -0,0 +1,53 @@
+-- DAG versions table: immutable snapshots of each DAG definition
+CREATE TABLE dag_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    dag_id UUID NOT NULL REFERENCES dags(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content_hash VARCHAR(64) NOT NULL,
+    definition JSONB NOT NULL, -- Full models.DAG snapshot including tasks
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_version_per_dag UNIQUE (dag_id, version)
+);
+
+-- Create indexes for dag_versions
+CREATE INDEX idx_dag_versions_dag_id ON dag_versions(dag_id);
+
+-- Current definition version of each DAG
+ALTER TABLE dags ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- DAG definition version each run executes
+ALTER TABLE dag_runs ADD COLUMN dag_version INTEGER NOT NULL DEFAULT 1;
+
+-- Snapshot existing DAGs as version 1. The content hash is left empty, so the
+-- first update after this migration always records a new version.
+INSERT INTO dag_versions (dag_id, version, content_hash, definition)
+SELECT
+    d.id,
+    1,
+    '',
+    jsonb_build_object(
+        'id', d.id,
+        'name', d.name,
+        'description', COALESCE(d.description, ''),
+        'schedule', COALESCE(d.schedule, ''),
+        'start_date', to_char(d.start_date, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'end_date', to_char(d.end_date, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'tags', COALESCE(d.tags, '[]'::jsonb),
+        'is_paused', d.is_paused,
+        'version', 1,
+        'tasks', COALESCE((
+            SELECT jsonb_agg(jsonb_build_object(
+                'id', t.task_id,
+                'name', COALESCE(t.name, ''),
+                'type', t.type,
+                'command', t.command,
+                'dependencies', COALESCE(t.dependencies, '[]'::jsonb),
+                'retries', t.retries,
+                'timeout', t.timeout,
+                'sla', t.sla
+            ) ORDER BY t.position)
+            FROM dag_tasks t
+            WHERE t.dag_id = d.id
+        ), '[]'::jsonb)
+    )
+FROM dags d;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_task_logs_instance_line;
+ALTER TABLE task_logs DROP COLUMN IF EXISTS line_number;
+ALTER TABLE task_logs DROP COLUMN IF EXISTS stream;
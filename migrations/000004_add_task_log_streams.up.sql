@@ -0,0 +1,5 @@
+-- Task logs are written line by line while a task runs
+ALTER TABLE task_logs ADD COLUMN stream VARCHAR(10) NOT NULL DEFAULT 'stdout';
+ALTER TABLE task_logs ADD COLUMN line_number INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_task_logs_instance_line ON task_logs(task_instance_id, line_number);
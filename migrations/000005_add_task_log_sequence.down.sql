@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_task_logs_instance_seq;
+ALTER TABLE task_logs DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,4 @@
+-- Monotonic sequence used as a cursor when tailing task logs
+ALTER TABLE task_logs ADD COLUMN seq BIGSERIAL;
+
+CREATE INDEX idx_task_logs_instance_seq ON task_logs(task_instance_id, seq);
@@ -0,0 +1 @@
+ALTER TABLE dag_tasks DROP COLUMN IF EXISTS retry_policy;
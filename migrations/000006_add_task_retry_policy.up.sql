@@ -0,0 +1,2 @@
+-- Tasks may override the executor retry strategy with their own policy
+ALTER TABLE dag_tasks ADD COLUMN retry_policy JSONB;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_dag_runs_state_heartbeat;
+ALTER TABLE task_instances DROP COLUMN IF EXISTS last_heartbeat_at;
+ALTER TABLE dag_runs DROP COLUMN IF EXISTS last_heartbeat_at;
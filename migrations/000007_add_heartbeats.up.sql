@@ -0,0 +1,5 @@
+-- Processes scheduling DAG runs and executors running tasks renew these leases while they are alive
+ALTER TABLE dag_runs ADD COLUMN last_heartbeat_at TIMESTAMP;
+ALTER TABLE task_instances ADD COLUMN last_heartbeat_at TIMESTAMP;
+
+CREATE INDEX idx_dag_runs_state_heartbeat ON dag_runs(state, last_heartbeat_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_task_instances_worker_id;
+ALTER TABLE task_instances DROP COLUMN IF EXISTS worker_id;
@@ -0,0 +1,4 @@
+-- Distributed workers report the task instances they run; the worker ID lets the control plane find them when a worker dies
+ALTER TABLE task_instances ADD COLUMN worker_id VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_task_instances_worker_id ON task_instances(worker_id);
@@ -0,0 +1,2 @@
+ALTER TABLE task_instances DROP CONSTRAINT IF EXISTS chk_task_instances_state;
+ALTER TABLE dag_runs DROP CONSTRAINT IF EXISTS chk_dag_runs_state;
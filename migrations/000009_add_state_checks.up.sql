@@ -0,0 +1,10 @@
+-- Only the states known to the state machine can be stored, including cancelled, scheduled and up_for_reschedule
+ALTER TABLE dag_runs ADD CONSTRAINT chk_dag_runs_state CHECK (state IN (
+    'queued', 'scheduled', 'running', 'success', 'failed', 'retrying',
+    'up_for_reschedule', 'skipped', 'upstream_failed', 'cancelled'
+));
+
+ALTER TABLE task_instances ADD CONSTRAINT chk_task_instances_state CHECK (state IN (
+    'queued', 'scheduled', 'running', 'success', 'failed', 'retrying',
+    'up_for_reschedule', 'skipped', 'upstream_failed', 'cancelled'
+));
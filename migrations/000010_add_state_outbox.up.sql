@@ -0,0 +1,18 @@
+-- State changes are written to the outbox in the transaction of the change and delivered by a relay afterwards
+CREATE TABLE state_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    old_state VARCHAR(50),
+    new_state VARCHAR(50) NOT NULL,
+    metadata JSONB DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP
+);
+
+-- The relay scans pending entries in order; delivered entries are purged by age
+CREATE INDEX idx_state_outbox_pending ON state_outbox(id) WHERE delivered_at IS NULL;
+CREATE INDEX idx_state_outbox_delivered_at ON state_outbox(delivered_at) WHERE delivered_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sla_misses;
+
+ALTER TABLE dags DROP COLUMN IF EXISTS sla;
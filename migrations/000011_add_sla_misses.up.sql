@@ -0,0 +1,21 @@
+-- Time a DAG run may take before it misses its SLA
+ALTER TABLE dags ADD COLUMN sla BIGINT NOT NULL DEFAULT 0; -- Nanoseconds, 0 disables the check
+
+-- SLA misses table: task instances and DAG runs that did not finish before their SLA deadline
+CREATE TABLE sla_misses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    dag_id UUID NOT NULL REFERENCES dags(id) ON DELETE CASCADE,
+    dag_run_id UUID NOT NULL REFERENCES dag_runs(id) ON DELETE CASCADE,
+    task_id VARCHAR(255) NOT NULL DEFAULT '', -- Empty for a miss of the whole DAG run
+    task_instance_id UUID REFERENCES task_instances(id) ON DELETE CASCADE,
+    execution_date TIMESTAMP NOT NULL,
+    sla BIGINT NOT NULL, -- Nanoseconds
+    deadline TIMESTAMP NOT NULL,
+    state VARCHAR(50) NOT NULL, -- State of the task instance or DAG run when the miss was detected
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_sla_miss_per_task UNIQUE (dag_run_id, task_id)
+);
+
+-- Create indexes for sla_misses
+CREATE INDEX idx_sla_misses_dag_id ON sla_misses(dag_id);
+CREATE INDEX idx_sla_misses_detected_at ON sla_misses(detected_at);
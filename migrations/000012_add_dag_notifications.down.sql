@@ -0,0 +1 @@
+ALTER TABLE dags DROP COLUMN IF EXISTS notifications;
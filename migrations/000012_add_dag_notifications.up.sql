@@ -0,0 +1,2 @@
+-- Notifiers told about runs and tasks of a DAG, by kind of event
+ALTER TABLE dags ADD COLUMN notifications JSONB; -- Null when nobody is notified
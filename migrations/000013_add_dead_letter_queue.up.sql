@@ -0,0 +1,21 @@
+-- Dead letter queue table: task instances whose attempts are exhausted, kept until an operator replays or deletes them
+CREATE TABLE dead_letter_queue (
+    id UUID PRIMARY KEY, -- ID of the task instance
+    task_instance_id UUID NOT NULL REFERENCES task_instances(id) ON DELETE CASCADE,
+    task_id VARCHAR(255) NOT NULL,
+    dag_run_id UUID NOT NULL REFERENCES dag_runs(id) ON DELETE CASCADE,
+    dag_id UUID NOT NULL REFERENCES dags(id) ON DELETE CASCADE,
+    failure_reason VARCHAR(255) NOT NULL DEFAULT '',
+    failure_time TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_attempt_time TIMESTAMP NOT NULL,
+    error_message TEXT NOT NULL DEFAULT '',
+    metadata JSONB DEFAULT '{}'::jsonb,
+    replayed BOOLEAN NOT NULL DEFAULT FALSE,
+    replayed_at TIMESTAMP
+);
+
+-- Create indexes for dead_letter_queue
+CREATE INDEX idx_dead_letter_queue_dag_id ON dead_letter_queue(dag_id);
+CREATE INDEX idx_dead_letter_queue_failure_time ON dead_letter_queue(failure_time);
+CREATE INDEX idx_dead_letter_queue_pending ON dead_letter_queue(failure_time) WHERE NOT replayed;
@@ -0,0 +1,2 @@
+ALTER TABLE dag_tasks DROP COLUMN IF EXISTS circuit_breaker;
+ALTER TABLE dags DROP COLUMN IF EXISTS circuit_breaker;
@@ -0,0 +1,3 @@
+-- Circuit breaker settings of the external dependencies of a DAG's tasks, and of single tasks
+ALTER TABLE dags ADD COLUMN circuit_breaker JSONB; -- Null when the executor's defaults apply
+ALTER TABLE dag_tasks ADD COLUMN circuit_breaker JSONB; -- Null when the DAG's settings apply
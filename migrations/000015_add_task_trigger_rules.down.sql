@@ -0,0 +1,2 @@
+ALTER TABLE dag_tasks DROP CONSTRAINT IF EXISTS chk_dag_tasks_trigger_rule;
+ALTER TABLE dag_tasks DROP COLUMN IF EXISTS trigger_rule;
@@ -0,0 +1,6 @@
+-- Tasks may run on other outcomes of their dependencies than all of them succeeding
+ALTER TABLE dag_tasks ADD COLUMN trigger_rule VARCHAR(20) NOT NULL DEFAULT ''; -- Empty means all_success
+
+ALTER TABLE dag_tasks ADD CONSTRAINT chk_dag_tasks_trigger_rule CHECK (trigger_rule IN (
+    '', 'all_success', 'all_done', 'one_failed', 'none_failed', 'one_success'
+));
@@ -0,0 +1,2 @@
+ALTER TABLE task_instances DROP COLUMN IF EXISTS branches;
+ALTER TABLE dag_tasks DROP COLUMN IF EXISTS branch;
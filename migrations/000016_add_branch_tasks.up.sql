@@ -0,0 +1,3 @@
+-- Branch tasks choose which of their immediate downstream tasks to follow
+ALTER TABLE dag_tasks ADD COLUMN branch JSONB; -- Null unless the task is a branch task
+ALTER TABLE task_instances ADD COLUMN branches JSONB; -- Downstream tasks a succeeded branch task follows
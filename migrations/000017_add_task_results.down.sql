@@ -0,0 +1,2 @@
+ALTER TABLE dag_tasks DROP COLUMN IF EXISTS result_paths;
+DROP TABLE IF EXISTS task_results;
@@ -0,0 +1,15 @@
+-- Task results table: values tasks push for downstream tasks of the same DAG run, keyed by (run, task, key)
+CREATE TABLE task_results (
+    dag_run_id UUID NOT NULL REFERENCES dag_runs(id) ON DELETE CASCADE,
+    task_id VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value JSONB, -- Null when the value was spilled to the blob store
+    blob_key TEXT NOT NULL DEFAULT '', -- Blob holding values over the inline size limit
+    size INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (dag_run_id, task_id, key)
+);
+
+-- HTTP tasks push fields of their JSON response body as results
+ALTER TABLE dag_tasks ADD COLUMN result_paths JSONB; -- Dot-separated paths by result key; null when the task pushes none
@@ -0,0 +1,2 @@
+ALTER TABLE task_instances DROP COLUMN IF EXISTS rendered_command;
+ALTER TABLE dags DROP COLUMN IF EXISTS params;
@@ -0,0 +1,3 @@
+-- Task commands are rendered as templates, which read the parameters of their DAG
+ALTER TABLE dags ADD COLUMN params JSONB; -- Null when the DAG has no parameters
+ALTER TABLE task_instances ADD COLUMN rendered_command TEXT NOT NULL DEFAULT ''; -- Command of the current attempt after rendering
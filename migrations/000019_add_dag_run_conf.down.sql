@@ -0,0 +1 @@
+ALTER TABLE dag_runs DROP COLUMN IF EXISTS conf;
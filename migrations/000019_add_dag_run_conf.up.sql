@@ -0,0 +1,2 @@
+-- Triggered runs set the parameters of their DAG through a conf
+ALTER TABLE dag_runs ADD COLUMN conf JSONB; -- Null when the run was not triggered with parameters
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+)
+
+// CircuitBreakerResponse represents the state of a circuit breaker guarding an external dependency of tasks
+type CircuitBreakerResponse struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastFailureTime     *time.Time `json:"last_failure_time,omitempty"`
+	LastStateChange     time.Time  `json:"last_state_change"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"` // When an open breaker lets a trial request through
+}
+
+// CircuitBreakerListResponse represents the circuit breakers of the server's task executors, by name
+type CircuitBreakerListResponse struct {
+	CircuitBreakers []CircuitBreakerResponse `json:"circuit_breakers"`
+}
+
+// ToCircuitBreakerResponse converts a named circuit breaker to a CircuitBreakerResponse
+func ToCircuitBreakerResponse(name string, cb *circuitbreaker.CircuitBreaker) CircuitBreakerResponse {
+	stats := cb.GetStats()
+	response := CircuitBreakerResponse{
+		Name:                name,
+		State:               stats.State.String(),
+		ConsecutiveFailures: stats.ConsecutiveFailures,
+		LastStateChange:     stats.LastStateChange,
+	}
+
+	if !stats.LastFailureTime.IsZero() {
+		response.LastFailureTime = &stats.LastFailureTime
+	}
+	if retryAfter := cb.RetryAfter(); retryAfter > 0 {
+		openUntil := time.Now().Add(retryAfter)
+		response.OpenUntil = &openUntil
+	}
+
+	return response
+}
@@ -1,8 +1,11 @@
 package dto
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
@@ -16,6 +19,10 @@ type CreateDAGRequest struct {
 	EndDate     *time.Time `json:"end_date,omitempty"`
 	Tags        []string   `json:"tags"`
 	IsPaused    bool       `json:"is_paused"`
+	SLA         time.Duration `json:"sla,omitempty" validate:"min=0"`
+	Notifications *NotificationRulesDTO `json:"notifications,omitempty"`
+	CircuitBreaker *CircuitBreakerDTO `json:"circuit_breaker,omitempty"`
+	Params      []ParamDTO `json:"params,omitempty" validate:"omitempty,dive"`
 }
 
 // UpdateDAGRequest represents the request to update an existing DAG
@@ -28,18 +35,98 @@ type UpdateDAGRequest struct {
 	EndDate     *time.Time `json:"end_date,omitempty"`
 	Tags        []string   `json:"tags,omitempty"`
 	IsPaused    *bool      `json:"is_paused,omitempty"`
+	SLA         *time.Duration `json:"sla,omitempty" validate:"omitempty,min=0"`
+	Notifications *NotificationRulesDTO `json:"notifications,omitempty"` // An empty object removes every rule
+	CircuitBreaker *CircuitBreakerDTO `json:"circuit_breaker,omitempty"` // An empty object restores the executor's defaults
+	Params      []ParamDTO `json:"params,omitempty" validate:"omitempty,dive"` // An empty list removes every parameter
 }
 
 // TaskDTO represents a task in a DAG
 type TaskDTO struct {
-	ID           string        `json:"id" validate:"required"`
-	Name         string        `json:"name" validate:"required"`
-	Type         string        `json:"type" validate:"required,oneof=bash http python go docker"`
-	Command      string        `json:"command" validate:"required"`
-	Dependencies []string      `json:"dependencies"`
-	Retries      int           `json:"retries" validate:"min=0,max=10"`
-	Timeout      time.Duration `json:"timeout" validate:"min=0"`
-	SLA          time.Duration `json:"sla" validate:"min=0"`
+	ID           string          `json:"id" validate:"required"`
+	Name         string          `json:"name" validate:"required"`
+	Type         string          `json:"type" validate:"required,oneof=bash http python go docker"`
+	Command      string          `json:"command" validate:"required"`
+	Dependencies []string        `json:"dependencies"`
+	Retries      int             `json:"retries" validate:"min=0,max=10"`
+	Timeout      time.Duration   `json:"timeout" validate:"min=0"`
+	SLA          time.Duration   `json:"sla" validate:"min=0"`
+	Retry        *RetryPolicyDTO `json:"retry,omitempty"`
+	CircuitBreaker *CircuitBreakerDTO `json:"circuit_breaker,omitempty"`
+	TriggerRule  string          `json:"trigger_rule,omitempty" validate:"omitempty,oneof=all_success all_done one_failed none_failed one_success"`
+	Branch       *BranchDTO      `json:"branch,omitempty"`
+	ResultPaths  map[string]string `json:"result_paths,omitempty" validate:"omitempty,dive,keys,required,endkeys,required"`
+}
+
+// ParamDTO represents a parameter of a DAG
+type ParamDTO struct {
+	Name        string        `json:"name" validate:"required"`
+	Type        string        `json:"type,omitempty" validate:"omitempty,oneof=string integer number boolean array object"`
+	Default     interface{}   `json:"default,omitempty"`
+	Required    bool          `json:"required,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+// BranchDTO represents the branch settings of a branch task
+type BranchDTO struct {
+	Targets []string `json:"targets" validate:"required,min=1,dive,required"`
+	Field   string   `json:"field,omitempty"`
+}
+
+// RetryPolicyDTO represents the retry policy of a task
+type RetryPolicyDTO struct {
+	Strategy  string   `json:"strategy" validate:"omitempty,oneof=exponential linear fixed none"`
+	BaseDelay Duration `json:"base_delay,omitempty" validate:"min=0"`
+	MaxDelay  Duration `json:"max_delay,omitempty" validate:"min=0"`
+	Jitter    bool     `json:"jitter,omitempty"`
+	RetryOn   []string `json:"retry_on,omitempty"`
+}
+
+// CircuitBreakerDTO represents the circuit breaker settings of a DAG or task
+type CircuitBreakerDTO struct {
+	MaxFailures int      `json:"max_failures,omitempty" validate:"min=0"`
+	Timeout     Duration `json:"timeout,omitempty" validate:"min=0"`
+	Disabled    bool     `json:"disabled,omitempty"`
+}
+
+// NotificationRulesDTO names the notifiers told about each kind of event of a DAG
+type NotificationRulesDTO struct {
+	OnFailure []string `json:"on_failure,omitempty" validate:"omitempty,dive,required"`
+	OnSuccess []string `json:"on_success,omitempty" validate:"omitempty,dive,required"`
+	OnSLAMiss []string `json:"on_sla_miss,omitempty" validate:"omitempty,dive,required"`
+	OnRetry   []string `json:"on_retry,omitempty" validate:"omitempty,dive,required"`
+}
+
+// Duration is a time.Duration encoded as a duration string such as "30s".
+// Numbers are also accepted and read as nanoseconds.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", v, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", data)
+	}
+	return nil
 }
 
 // DAGResponse represents the response for a DAG
@@ -53,6 +140,11 @@ type DAGResponse struct {
 	EndDate     *time.Time    `json:"end_date,omitempty"`
 	Tags        []string      `json:"tags"`
 	IsPaused    bool          `json:"is_paused"`
+	Version     int           `json:"version"`
+	SLA         time.Duration `json:"sla,omitempty"`
+	Notifications *NotificationRulesDTO `json:"notifications,omitempty"`
+	CircuitBreaker *CircuitBreakerDTO `json:"circuit_breaker,omitempty"`
+	Params      []ParamDTO    `json:"params,omitempty"`
 	CreatedAt   time.Time     `json:"created_at"`
 	UpdatedAt   time.Time     `json:"updated_at"`
 }
@@ -63,6 +155,51 @@ type DAGListResponse struct {
 	Pagination PaginationMeta `json:"pagination"`
 }
 
+// DAGVersionResponse represents an immutable version of a DAG definition
+type DAGVersionResponse struct {
+	DAGID       string       `json:"dag_id"`
+	Version     int          `json:"version"`
+	ContentHash string       `json:"content_hash"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Definition  *DAGResponse `json:"definition,omitempty"`
+}
+
+// DAGVersionListResponse represents the versions of a DAG, newest first
+type DAGVersionListResponse struct {
+	Versions []DAGVersionResponse `json:"versions"`
+}
+
+// TaskChangeDTO lists the changed fields of a task
+type TaskChangeDTO struct {
+	TaskID string   `json:"task_id"`
+	Fields []string `json:"fields"`
+}
+
+// DAGDiffResponse represents the differences between two DAG versions
+type DAGDiffResponse struct {
+	FromVersion   int             `json:"from_version"`
+	ToVersion     int             `json:"to_version"`
+	ChangedFields []string        `json:"changed_fields"`
+	AddedTasks    []string        `json:"added_tasks"`
+	RemovedTasks  []string        `json:"removed_tasks"`
+	ModifiedTasks []TaskChangeDTO `json:"modified_tasks"`
+}
+
+// RenderedTaskDTO represents the command a task would run
+type RenderedTaskDTO struct {
+	TaskID          string `json:"task_id"`
+	Command         string `json:"command"`
+	RenderedCommand string `json:"rendered_command,omitempty"`
+	Error           string `json:"error,omitempty"` // Why the command template could not be rendered
+}
+
+// RenderDAGResponse represents the commands the tasks of a DAG would run at an execution date
+type RenderDAGResponse struct {
+	DAGID         string            `json:"dag_id"`
+	ExecutionDate time.Time         `json:"execution_date"`
+	Tasks         []RenderedTaskDTO `json:"tasks"`
+}
+
 // ToTaskDTO converts a models.Task to a TaskDTO
 func ToTaskDTO(task models.Task) TaskDTO {
 	return TaskDTO{
@@ -74,6 +211,159 @@ func ToTaskDTO(task models.Task) TaskDTO {
 		Retries:      task.Retries,
 		Timeout:      task.Timeout,
 		SLA:          task.SLA,
+		Retry:        toRetryPolicyDTO(task.RetryPolicy),
+		CircuitBreaker: toCircuitBreakerDTO(task.CircuitBreaker),
+		TriggerRule:  string(task.TriggerRule),
+		Branch:       toBranchDTO(task.Branch),
+		ResultPaths:  task.ResultPaths,
+	}
+}
+
+// toRetryPolicyDTO converts a models.RetryPolicy to a RetryPolicyDTO
+func toRetryPolicyDTO(policy *models.RetryPolicy) *RetryPolicyDTO {
+	if policy == nil {
+		return nil
+	}
+
+	return &RetryPolicyDTO{
+		Strategy:  string(policy.Strategy),
+		BaseDelay: Duration(policy.BaseDelay),
+		MaxDelay:  Duration(policy.MaxDelay),
+		Jitter:    policy.Jitter,
+		RetryOn:   policy.RetryOn,
+	}
+}
+
+// ToRetryPolicy converts a RetryPolicyDTO to a models.RetryPolicy
+func (r *RetryPolicyDTO) ToRetryPolicy() *models.RetryPolicy {
+	if r == nil {
+		return nil
+	}
+
+	return &models.RetryPolicy{
+		Strategy:  models.RetryStrategy(r.Strategy),
+		BaseDelay: time.Duration(r.BaseDelay),
+		MaxDelay:  time.Duration(r.MaxDelay),
+		Jitter:    r.Jitter,
+		RetryOn:   r.RetryOn,
+	}
+}
+
+// toNotificationRulesDTO converts models.NotificationRules to a NotificationRulesDTO
+func toNotificationRulesDTO(rules *models.NotificationRules) *NotificationRulesDTO {
+	if rules == nil {
+		return nil
+	}
+
+	return &NotificationRulesDTO{
+		OnFailure: rules.OnFailure,
+		OnSuccess: rules.OnSuccess,
+		OnSLAMiss: rules.OnSLAMiss,
+		OnRetry:   rules.OnRetry,
+	}
+}
+
+// ToNotificationRules converts a NotificationRulesDTO to models.NotificationRules,
+// returning nil when no notifier is named
+func (r *NotificationRulesDTO) ToNotificationRules() *models.NotificationRules {
+	if r == nil || len(r.OnFailure)+len(r.OnSuccess)+len(r.OnSLAMiss)+len(r.OnRetry) == 0 {
+		return nil
+	}
+
+	return &models.NotificationRules{
+		OnFailure: r.OnFailure,
+		OnSuccess: r.OnSuccess,
+		OnSLAMiss: r.OnSLAMiss,
+		OnRetry:   r.OnRetry,
+	}
+}
+
+// toCircuitBreakerDTO converts a models.CircuitBreakerConfig to a CircuitBreakerDTO
+func toCircuitBreakerDTO(config *models.CircuitBreakerConfig) *CircuitBreakerDTO {
+	if config == nil {
+		return nil
+	}
+
+	return &CircuitBreakerDTO{
+		MaxFailures: config.MaxFailures,
+		Timeout:     Duration(config.Timeout),
+		Disabled:    config.Disabled,
+	}
+}
+
+// ToCircuitBreakerConfig converts a CircuitBreakerDTO to a models.CircuitBreakerConfig,
+// returning nil when it changes no default
+func (c *CircuitBreakerDTO) ToCircuitBreakerConfig() *models.CircuitBreakerConfig {
+	if c == nil || *c == (CircuitBreakerDTO{}) {
+		return nil
+	}
+
+	return &models.CircuitBreakerConfig{
+		MaxFailures: c.MaxFailures,
+		Timeout:     time.Duration(c.Timeout),
+		Disabled:    c.Disabled,
+	}
+}
+
+// toParamDTOs converts the parameters of a DAG to ParamDTOs
+func toParamDTOs(params []models.Param) []ParamDTO {
+	if len(params) == 0 {
+		return nil
+	}
+	dtos := make([]ParamDTO, len(params))
+	for i, param := range params {
+		dtos[i] = ParamDTO{
+			Name:        param.Name,
+			Type:        string(param.Type),
+			Default:     param.Default,
+			Required:    param.Required,
+			Enum:        param.Enum,
+			Description: param.Description,
+		}
+	}
+	return dtos
+}
+
+// ToParams converts ParamDTOs to the parameters of a DAG
+func ToParams(dtos []ParamDTO) []models.Param {
+	if len(dtos) == 0 {
+		return nil
+	}
+	params := make([]models.Param, len(dtos))
+	for i, param := range dtos {
+		params[i] = models.Param{
+			Name:        param.Name,
+			Type:        models.ParamType(param.Type),
+			Default:     param.Default,
+			Required:    param.Required,
+			Enum:        param.Enum,
+			Description: param.Description,
+		}
+	}
+	return params
+}
+
+// toBranchDTO converts a models.BranchConfig to a BranchDTO
+func toBranchDTO(config *models.BranchConfig) *BranchDTO {
+	if config == nil {
+		return nil
+	}
+
+	return &BranchDTO{
+		Targets: config.Targets,
+		Field:   config.Field,
+	}
+}
+
+// ToBranchConfig converts a BranchDTO to a models.BranchConfig
+func (b *BranchDTO) ToBranchConfig() *models.BranchConfig {
+	if b == nil {
+		return nil
+	}
+
+	return &models.BranchConfig{
+		Targets: b.Targets,
+		Field:   b.Field,
 	}
 }
 
@@ -88,6 +378,11 @@ func (t TaskDTO) ToTask() models.Task {
 		Retries:      t.Retries,
 		Timeout:      t.Timeout,
 		SLA:          t.SLA,
+		RetryPolicy:  t.Retry.ToRetryPolicy(),
+		CircuitBreaker: t.CircuitBreaker.ToCircuitBreakerConfig(),
+		TriggerRule:  models.TriggerRule(t.TriggerRule),
+		Branch:       t.Branch.ToBranchConfig(),
+		ResultPaths:  t.ResultPaths,
 	}
 }
 
@@ -108,11 +403,51 @@ func ToDAGResponse(dag *models.DAG) DAGResponse {
 		EndDate:     dag.EndDate,
 		Tags:        dag.Tags,
 		IsPaused:    dag.IsPaused,
+		Version:     dag.Version,
+		SLA:         dag.SLA,
+		Notifications: toNotificationRulesDTO(dag.Notifications),
+		CircuitBreaker: toCircuitBreakerDTO(dag.CircuitBreaker),
+		Params:      toParamDTOs(dag.Params),
 		CreatedAt:   dag.CreatedAt,
 		UpdatedAt:   dag.UpdatedAt,
 	}
 }
 
+// ToDAGVersionResponse converts a models.DAGVersion to a DAGVersionResponse
+// The full definition is only included when withDefinition is true
+func ToDAGVersionResponse(version *models.DAGVersion, withDefinition bool) DAGVersionResponse {
+	response := DAGVersionResponse{
+		DAGID:       version.DAGID,
+		Version:     version.Version,
+		ContentHash: version.ContentHash,
+		CreatedAt:   version.CreatedAt,
+	}
+
+	if withDefinition && version.DAG != nil {
+		definition := ToDAGResponse(version.DAG)
+		response.Definition = &definition
+	}
+
+	return response
+}
+
+// ToDAGDiffResponse converts a dag.DAGDiff to a DAGDiffResponse
+func ToDAGDiffResponse(diff *dag.DAGDiff) DAGDiffResponse {
+	modified := make([]TaskChangeDTO, len(diff.ModifiedTasks))
+	for i, change := range diff.ModifiedTasks {
+		modified[i] = TaskChangeDTO{TaskID: change.TaskID, Fields: change.Fields}
+	}
+
+	return DAGDiffResponse{
+		FromVersion:   diff.FromVersion,
+		ToVersion:     diff.ToVersion,
+		ChangedFields: diff.ChangedFields,
+		AddedTasks:    diff.AddedTasks,
+		RemovedTasks:  diff.RemovedTasks,
+		ModifiedTasks: modified,
+	}
+}
+
 // ToDAG converts a CreateDAGRequest to a models.DAG
 func (r CreateDAGRequest) ToDAG() *models.DAG {
 	tasks := make([]models.Task, len(r.Tasks))
@@ -129,6 +464,10 @@ func (r CreateDAGRequest) ToDAG() *models.DAG {
 		EndDate:     r.EndDate,
 		Tags:        r.Tags,
 		IsPaused:    r.IsPaused,
+		SLA:         r.SLA,
+		Notifications: r.Notifications.ToNotificationRules(),
+		CircuitBreaker: r.CircuitBreaker.ToCircuitBreakerConfig(),
+		Params:      ToParams(r.Params),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
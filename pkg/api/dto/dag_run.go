@@ -9,18 +9,20 @@ import (
 // TriggerDAGRequest represents the request to manually trigger a DAG
 type TriggerDAGRequest struct {
 	ExecutionDate *time.Time             `json:"execution_date,omitempty"`
-	Config        map[string]interface{} `json:"config,omitempty"`
+	Conf          map[string]interface{} `json:"conf,omitempty"` // Parameter values of the run, checked against the DAG's params
 }
 
 // DAGRunResponse represents the response for a DAG run
 type DAGRunResponse struct {
-	ID              string     `json:"id"`
-	DAGID           string     `json:"dag_id"`
-	ExecutionDate   time.Time  `json:"execution_date"`
-	State           string     `json:"state"`
-	StartDate       *time.Time `json:"start_date,omitempty"`
-	EndDate         *time.Time `json:"end_date,omitempty"`
-	ExternalTrigger bool       `json:"external_trigger"`
+	ID              string                 `json:"id"`
+	DAGID           string                 `json:"dag_id"`
+	ExecutionDate   time.Time              `json:"execution_date"`
+	State           string                 `json:"state"`
+	StartDate       *time.Time             `json:"start_date,omitempty"`
+	EndDate         *time.Time             `json:"end_date,omitempty"`
+	ExternalTrigger bool                   `json:"external_trigger"`
+	DAGVersion      int                    `json:"dag_version"`
+	Conf            map[string]interface{} `json:"conf,omitempty"`
 }
 
 // DAGRunListResponse represents a paginated list of DAG runs
@@ -45,5 +47,7 @@ func ToDAGRunResponse(run *models.DAGRun) DAGRunResponse {
 		StartDate:       run.StartDate,
 		EndDate:         run.EndDate,
 		ExternalTrigger: run.ExternalTrigger,
+		DAGVersion:      run.DAGVersion,
+		Conf:            run.Conf,
 	}
 }
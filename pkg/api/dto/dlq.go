@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+)
+
+// DLQEntryResponse represents a task instance in the dead letter queue
+type DLQEntryResponse struct {
+	ID              string                 `json:"id"`
+	TaskInstanceID  string                 `json:"task_instance_id"`
+	TaskID          string                 `json:"task_id"`
+	DAGRunID        string                 `json:"dag_run_id"`
+	DAGID           string                 `json:"dag_id"`
+	FailureReason   string                 `json:"failure_reason"`
+	FailureTime     time.Time              `json:"failure_time"`
+	Attempts        int                    `json:"attempts"`
+	LastAttemptTime time.Time              `json:"last_attempt_time"`
+	ErrorMessage    string                 `json:"error_message,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Replayed        bool                   `json:"replayed"`
+	ReplayedAt      *time.Time             `json:"replayed_at,omitempty"`
+}
+
+// DLQEntryListResponse represents a paginated list of dead letter queue entries
+type DLQEntryListResponse struct {
+	Entries    []DLQEntryResponse `json:"entries"`
+	Pagination PaginationMeta     `json:"pagination"`
+}
+
+// BulkReplayRequest selects the dead letter queue entries to replay, either by ID
+// or by the DAG and task of the entries that were not replayed yet
+type BulkReplayRequest struct {
+	IDs    []string `json:"ids" validate:"required_without_all=DAGID TaskID,max=100,dive,required"`
+	DAGID  string   `json:"dag_id"`
+	TaskID string   `json:"task_id"`
+}
+
+// ReplayResponse represents the new try of a replayed task instance
+type ReplayResponse struct {
+	EntryID        string `json:"entry_id"`
+	TaskInstanceID string `json:"task_instance_id"`
+	TryNumber      int    `json:"try_number"`
+}
+
+// ReplayFailure represents a dead letter queue entry that could not be replayed
+type ReplayFailure struct {
+	EntryID string `json:"entry_id"`
+	Error   string `json:"error"`
+}
+
+// BulkReplayResponse represents the outcome of a bulk replay
+type BulkReplayResponse struct {
+	Replayed []ReplayResponse `json:"replayed"`
+	Failed   []ReplayFailure  `json:"failed"`
+}
+
+// ToDLQEntryResponse converts a dlq.Entry to a DLQEntryResponse
+func ToDLQEntryResponse(entry *dlq.Entry) DLQEntryResponse {
+	return DLQEntryResponse{
+		ID:              entry.ID,
+		TaskInstanceID:  entry.TaskInstanceID,
+		TaskID:          entry.TaskID,
+		DAGRunID:        entry.DAGRunID,
+		DAGID:           entry.DAGID,
+		FailureReason:   entry.FailureReason,
+		FailureTime:     entry.FailureTime,
+		Attempts:        entry.Attempts,
+		LastAttemptTime: entry.LastAttemptTime,
+		ErrorMessage:    entry.ErrorMessage,
+		Metadata:        entry.Metadata,
+		Replayed:        entry.Replayed,
+		ReplayedAt:      entry.ReplayedAt,
+	}
+}
@@ -0,0 +1,12 @@
+package dto
+
+// StateChangeEvent is a state change of a DAG run or task instance streamed by GET /api/v1/events
+type StateChangeEvent struct {
+	ID         int64  `json:"id,omitempty"` // Event ID to resume from; 0 for events that cannot be replayed
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	DAGID      string `json:"dag_id,omitempty"`
+	DAGRunID   string `json:"dag_run_id,omitempty"`
+	OldState   string `json:"old_state,omitempty"`
+	NewState   string `json:"new_state"`
+}
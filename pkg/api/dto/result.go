@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+)
+
+// TaskResultResponse represents a value a task instance pushed for downstream tasks
+type TaskResultResponse struct {
+	Key       string          `json:"key"`
+	Type      string          `json:"type,omitempty"` // JSON type of the value; empty if a spilled value could not be loaded
+	Value     json.RawMessage `json:"value,omitempty"`
+	Size      int             `json:"size"`
+	Spilled   bool            `json:"spilled"` // Whether the value exceeded the inline size limit and is kept in the blob store
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// TaskResultsResponse represents the values a task instance pushed
+type TaskResultsResponse struct {
+	Results []TaskResultResponse `json:"results"`
+}
+
+// ToTaskResultResponse converts a results.Result to a TaskResultResponse
+func ToTaskResultResponse(result *results.Result) TaskResultResponse {
+	return TaskResultResponse{
+		Key:       result.Key,
+		Type:      result.Type(),
+		Value:     result.Value,
+		Size:      result.Size,
+		Spilled:   result.BlobKey != "",
+		UpdatedAt: result.UpdatedAt,
+	}
+}
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// SLAMissResponse represents a task instance or DAG run that missed its SLA
+type SLAMissResponse struct {
+	ID             string    `json:"id"`
+	DAGID          string    `json:"dag_id"`
+	DAGRunID       string    `json:"dag_run_id"`
+	TaskID         string    `json:"task_id,omitempty"`
+	TaskInstanceID string    `json:"task_instance_id,omitempty"`
+	ExecutionDate  time.Time `json:"execution_date"`
+	SLA            string    `json:"sla"`
+	Deadline       time.Time `json:"deadline"`
+	State          string    `json:"state"`
+	DetectedAt     time.Time `json:"detected_at"`
+}
+
+// SLAMissListResponse represents a paginated list of SLA misses
+type SLAMissListResponse struct {
+	SLAMisses  []SLAMissResponse `json:"sla_misses"`
+	Pagination PaginationMeta    `json:"pagination"`
+}
+
+// ToSLAMissResponse converts a models.SLAMiss to an SLAMissResponse
+func ToSLAMissResponse(miss *models.SLAMiss) SLAMissResponse {
+	return SLAMissResponse{
+		ID:             miss.ID,
+		DAGID:          miss.DAGID,
+		DAGRunID:       miss.DAGRunID,
+		TaskID:         miss.TaskID,
+		TaskInstanceID: miss.TaskInstanceID,
+		ExecutionDate:  miss.ExecutionDate,
+		SLA:            miss.SLA.String(),
+		Deadline:       miss.Deadline,
+		State:          string(miss.State),
+		DetectedAt:     miss.DetectedAt,
+	}
+}
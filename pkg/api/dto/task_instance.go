@@ -8,17 +8,19 @@ import (
 
 // TaskInstanceResponse represents the response for a task instance
 type TaskInstanceResponse struct {
-	ID           string     `json:"id"`
-	TaskID       string     `json:"task_id"`
-	DAGRunID     string     `json:"dag_run_id"`
-	State        string     `json:"state"`
-	TryNumber    int        `json:"try_number"`
-	MaxTries     int        `json:"max_tries"`
-	StartDate    *time.Time `json:"start_date,omitempty"`
-	EndDate      *time.Time `json:"end_date,omitempty"`
-	Duration     string     `json:"duration,omitempty"`
-	Hostname     string     `json:"hostname,omitempty"`
-	ErrorMessage string     `json:"error_message,omitempty"`
+	ID              string     `json:"id"`
+	TaskID          string     `json:"task_id"`
+	DAGRunID        string     `json:"dag_run_id"`
+	State           string     `json:"state"`
+	TryNumber       int        `json:"try_number"`
+	MaxTries        int        `json:"max_tries"`
+	StartDate       *time.Time `json:"start_date,omitempty"`
+	EndDate         *time.Time `json:"end_date,omitempty"`
+	Duration        string     `json:"duration,omitempty"`
+	Hostname        string     `json:"hostname,omitempty"`
+	ErrorMessage    string     `json:"error_message,omitempty"`
+	Branches        []string   `json:"branches,omitempty"`         // Downstream tasks a succeeded branch task follows
+	RenderedCommand string     `json:"rendered_command,omitempty"` // Command of the current attempt after rendering its template
 }
 
 // TaskInstanceListResponse represents a paginated list of task instances
@@ -29,9 +31,18 @@ type TaskInstanceListResponse struct {
 
 // TaskLogResponse represents a task log entry
 type TaskLogResponse struct {
-	ID        string    `json:"id"`
-	LogData   string    `json:"log_data"`
-	Timestamp time.Time `json:"timestamp"`
+	ID         string    `json:"id"`
+	Seq        int64     `json:"seq"`
+	Stream     string    `json:"stream"`
+	LineNumber int       `json:"line_number"`
+	LogData    string    `json:"log_data"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// TaskLogStreamEndEvent is sent when a streamed task instance reaches a terminal state
+type TaskLogStreamEndEvent struct {
+	TaskInstanceID string `json:"task_instance_id"`
+	State          string `json:"state"`
 }
 
 // TaskLogsResponse represents a list of task logs
@@ -48,16 +59,18 @@ func ToTaskInstanceResponse(ti *models.TaskInstance) TaskInstanceResponse {
 	}
 
 	return TaskInstanceResponse{
-		ID:           ti.ID,
-		TaskID:       ti.TaskID,
-		DAGRunID:     ti.DAGRunID,
-		State:        string(ti.State),
-		TryNumber:    ti.TryNumber,
-		MaxTries:     ti.MaxTries,
-		StartDate:    ti.StartDate,
-		EndDate:      ti.EndDate,
-		Duration:     duration,
-		Hostname:     ti.Hostname,
-		ErrorMessage: ti.ErrorMessage,
+		ID:              ti.ID,
+		TaskID:          ti.TaskID,
+		DAGRunID:        ti.DAGRunID,
+		State:           string(ti.State),
+		TryNumber:       ti.TryNumber,
+		MaxTries:        ti.MaxTries,
+		StartDate:       ti.StartDate,
+		EndDate:         ti.EndDate,
+		Duration:        duration,
+		Hostname:        ti.Hostname,
+		ErrorMessage:    ti.ErrorMessage,
+		Branches:        ti.Branches,
+		RenderedCommand: ti.RenderedCommand,
 	}
 }
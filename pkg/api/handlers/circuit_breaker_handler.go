@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/middleware"
+)
+
+// CircuitBreakerHandler handles HTTP requests about the circuit breakers of the task executors
+type CircuitBreakerHandler struct {
+	breakers *circuitbreaker.Registry
+}
+
+// NewCircuitBreakerHandler creates a new circuit breaker handler
+func NewCircuitBreakerHandler(breakers *circuitbreaker.Registry) *CircuitBreakerHandler {
+	return &CircuitBreakerHandler{
+		breakers: breakers,
+	}
+}
+
+// ListCircuitBreakers handles GET /api/v1/admin/circuit-breakers
+// @Summary List circuit breakers
+// @Description Get the state of the circuit breakers guarding the external dependencies of tasks, such as HTTP hosts and the Docker daemon
+// @Tags admin
+// @Produce json
+// @Success 200 {object} dto.CircuitBreakerListResponse
+// @Router /api/v1/admin/circuit-breakers [get]
+func (h *CircuitBreakerHandler) ListCircuitBreakers(c *gin.Context) {
+	names := h.breakers.Names()
+
+	response := dto.CircuitBreakerListResponse{
+		CircuitBreakers: make([]dto.CircuitBreakerResponse, 0, len(names)),
+	}
+	for _, name := range names {
+		if cb, ok := h.breakers.Lookup(name); ok {
+			response.CircuitBreakers = append(response.CircuitBreakers, dto.ToCircuitBreakerResponse(name, cb))
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCircuitBreaker handles GET /api/v1/admin/circuit-breakers/:name
+// @Summary Get circuit breaker
+// @Description Get the state of a circuit breaker by name
+// @Tags admin
+// @Produce json
+// @Param name path string true "Circuit breaker name"
+// @Success 200 {object} dto.CircuitBreakerResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /api/v1/admin/circuit-breakers/{name} [get]
+func (h *CircuitBreakerHandler) GetCircuitBreaker(c *gin.Context) {
+	name := c.Param("name")
+
+	cb, ok := h.breakers.Lookup(name)
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, "CIRCUIT_BREAKER_NOT_FOUND", "Circuit breaker not found: "+name)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToCircuitBreakerResponse(name, cb))
+}
+
+// ResetCircuitBreaker handles POST /api/v1/admin/circuit-breakers/:name/reset
+// @Summary Reset circuit breaker
+// @Description Close a circuit breaker, so that tasks call its dependency again right away
+// @Tags admin
+// @Produce json
+// @Param name path string true "Circuit breaker name"
+// @Success 200 {object} dto.CircuitBreakerResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /api/v1/admin/circuit-breakers/{name}/reset [post]
+func (h *CircuitBreakerHandler) ResetCircuitBreaker(c *gin.Context) {
+	name := c.Param("name")
+
+	cb, ok := h.breakers.Lookup(name)
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, "CIRCUIT_BREAKER_NOT_FOUND", "Circuit breaker not found: "+name)
+		return
+	}
+
+	cb.Reset()
+
+	c.JSON(http.StatusOK, dto.ToCircuitBreakerResponse(name, cb))
+}
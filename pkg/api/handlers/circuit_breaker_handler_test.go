@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therealutkarshpriyadarshi/dag/internal/circuitbreaker"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
+)
+
+func newCircuitBreakerTestRouter(t *testing.T) (*gin.Engine, *circuitbreaker.Registry) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	registry := circuitbreaker.NewRegistry()
+	open := registry.Get("http:api.example.com", &circuitbreaker.Config{MaxFailures: 1, Timeout: time.Minute, HalfOpenMaxRequests: 1})
+	open.Execute(context.Background(), func() error { return errors.New("connection refused") })
+	registry.Get("docker", nil)
+
+	handler := handlers.NewCircuitBreakerHandler(registry)
+	router := gin.New()
+	router.GET("/api/v1/admin/circuit-breakers", handler.ListCircuitBreakers)
+	router.GET("/api/v1/admin/circuit-breakers/:name", handler.GetCircuitBreaker)
+	router.POST("/api/v1/admin/circuit-breakers/:name/reset", handler.ResetCircuitBreaker)
+	return router, registry
+}
+
+func TestListCircuitBreakers(t *testing.T) {
+	router, _ := newCircuitBreakerTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/circuit-breakers", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.CircuitBreakerListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.CircuitBreakers, 2)
+
+	assert.Equal(t, "docker", response.CircuitBreakers[0].Name)
+	assert.Equal(t, "closed", response.CircuitBreakers[0].State)
+	assert.Nil(t, response.CircuitBreakers[0].OpenUntil)
+
+	assert.Equal(t, "http:api.example.com", response.CircuitBreakers[1].Name)
+	assert.Equal(t, "open", response.CircuitBreakers[1].State)
+	assert.Equal(t, 1, response.CircuitBreakers[1].ConsecutiveFailures)
+	require.NotNil(t, response.CircuitBreakers[1].OpenUntil)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *response.CircuitBreakers[1].OpenUntil, 5*time.Second)
+}
+
+func TestResetCircuitBreaker(t *testing.T) {
+	router, registry := newCircuitBreakerTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/circuit-breakers/http:api.example.com/reset", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.CircuitBreakerResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "closed", response.State)
+
+	cb, _ := registry.Lookup("http:api.example.com")
+	assert.Equal(t, circuitbreaker.StateClosed, cb.GetState())
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/admin/circuit-breakers/http:unknown.example.com", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/admin/circuit-breakers/http:unknown.example.com/reset", nil),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "CIRCUIT_BREAKER_NOT_FOUND")
+	}
+}
@@ -3,9 +3,11 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/render"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/middleware"
@@ -14,18 +16,26 @@ import (
 
 // DAGHandler handles DAG-related HTTP requests
 type DAGHandler struct {
-	dagRepo storage.DAGRepository
-	engine  *dag.Engine
+	dagRepo   storage.DAGRepository
+	validator *dag.Validator
+	renderer  *render.Renderer
 }
 
 // NewDAGHandler creates a new DAG handler
-func NewDAGHandler(dagRepo storage.DAGRepository, engine *dag.Engine) *DAGHandler {
+func NewDAGHandler(dagRepo storage.DAGRepository, validator *dag.Validator) *DAGHandler {
 	return &DAGHandler{
-		dagRepo: dagRepo,
-		engine:  engine,
+		dagRepo:   dagRepo,
+		validator: validator,
+		renderer:  render.NewRenderer(nil),
 	}
 }
 
+// SetRenderer sets the renderer previewing task commands, which should expose the same
+// environment variables as the executors
+func (h *DAGHandler) SetRenderer(renderer *render.Renderer) {
+	h.renderer = renderer
+}
+
 // CreateDAG handles POST /api/v1/dags
 // @Summary Create a new DAG
 // @Description Create a new DAG workflow
@@ -46,29 +56,13 @@ func (h *DAGHandler) CreateDAG(c *gin.Context) {
 	// Convert DTO to model
 	dagModel := req.ToDAG()
 
-	// Validate DAG structure using the DAG engine
-	builder := dag.NewDAGBuilder(dagModel.Name).
-		WithDescription(dagModel.Description).
-		WithSchedule(dagModel.Schedule)
-
-	for _, task := range dagModel.Tasks {
-		builder.AddTask(task.ID, task.Name, task.Type, task.Command, task.Dependencies...)
-	}
-
-	dagInstance, err := builder.Build()
-	if err != nil {
-		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_DAG", err.Error())
-		return
-	}
-
 	// Validate the DAG (check for cycles, etc.)
-	if err := dagInstance.Validate(); err != nil {
+	if err := h.validator.Validate(dagModel); err != nil {
 		middleware.AbortWithError(c, http.StatusBadRequest, "DAG_VALIDATION_FAILED", err.Error())
 		return
 	}
 
-	// Save to database
-	dagModel.Tasks = dagInstance.GetTasks()
+	// Save to database, including task definitions
 	if err := h.dagRepo.Create(c.Request.Context(), dagModel); err != nil {
 		middleware.AbortWithError(c, http.StatusInternalServerError, "CREATE_FAILED", err.Error())
 		return
@@ -155,13 +149,13 @@ func (h *DAGHandler) ListDAGs(c *gin.Context) {
 func (h *DAGHandler) GetDAG(c *gin.Context) {
 	id := c.Param("id")
 
-	dag, err := h.dagRepo.Get(c.Request.Context(), id)
+	dagModel, err := h.dagRepo.Get(c.Request.Context(), id)
 	if err != nil {
 		middleware.AbortWithError(c, http.StatusNotFound, "DAG_NOT_FOUND", "DAG not found")
 		return
 	}
 
-	response := dto.ToDAGResponse(dag)
+	response := dto.ToDAGResponse(dagModel)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -187,7 +181,7 @@ func (h *DAGHandler) UpdateDAG(c *gin.Context) {
 	}
 
 	// Get existing DAG
-	dag, err := h.dagRepo.Get(c.Request.Context(), id)
+	dagModel, err := h.dagRepo.Get(c.Request.Context(), id)
 	if err != nil {
 		middleware.AbortWithError(c, http.StatusNotFound, "DAG_NOT_FOUND", "DAG not found")
 		return
@@ -195,59 +189,120 @@ func (h *DAGHandler) UpdateDAG(c *gin.Context) {
 
 	// Update fields
 	if req.Name != nil {
-		dag.Name = *req.Name
+		dagModel.Name = *req.Name
 	}
 	if req.Description != nil {
-		dag.Description = *req.Description
+		dagModel.Description = *req.Description
 	}
 	if req.Schedule != nil {
-		dag.Schedule = *req.Schedule
+		dagModel.Schedule = *req.Schedule
 	}
 	if req.Tasks != nil {
 		tasks := make([]models.Task, len(req.Tasks))
 		for i, taskDTO := range req.Tasks {
 			tasks[i] = taskDTO.ToTask()
 		}
-		dag.Tasks = tasks
-
+		dagModel.Tasks = tasks
+	}
+	if req.Params != nil {
+		dagModel.Params = dto.ToParams(req.Params)
+	}
+	if req.Tasks != nil || req.Params != nil {
 		// Validate the updated DAG
-		builder := dag.NewDAGBuilder(dag.Name)
-		for _, task := range dag.Tasks {
-			builder.AddTask(task.ID, task.Name, task.Type, task.Command, task.Dependencies...)
-		}
-		dagInstance, err := builder.Build()
-		if err != nil {
-			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_DAG", err.Error())
-			return
-		}
-		if err := dagInstance.Validate(); err != nil {
+		if err := h.validator.Validate(dagModel); err != nil {
 			middleware.AbortWithError(c, http.StatusBadRequest, "DAG_VALIDATION_FAILED", err.Error())
 			return
 		}
 	}
 	if req.StartDate != nil {
-		dag.StartDate = *req.StartDate
+		dagModel.StartDate = *req.StartDate
 	}
 	if req.EndDate != nil {
-		dag.EndDate = req.EndDate
+		dagModel.EndDate = req.EndDate
 	}
 	if req.Tags != nil {
-		dag.Tags = req.Tags
+		dagModel.Tags = req.Tags
 	}
 	if req.IsPaused != nil {
-		dag.IsPaused = *req.IsPaused
+		dagModel.IsPaused = *req.IsPaused
+	}
+	if req.SLA != nil {
+		dagModel.SLA = *req.SLA
+	}
+	if req.Notifications != nil {
+		dagModel.Notifications = req.Notifications.ToNotificationRules()
+	}
+	if req.CircuitBreaker != nil {
+		dagModel.CircuitBreaker = req.CircuitBreaker.ToCircuitBreakerConfig()
 	}
 
 	// Save to database
-	if err := h.dagRepo.Update(c.Request.Context(), dag); err != nil {
+	if err := h.dagRepo.Update(c.Request.Context(), dagModel); err != nil {
 		middleware.AbortWithError(c, http.StatusInternalServerError, "UPDATE_FAILED", err.Error())
 		return
 	}
 
-	response := dto.ToDAGResponse(dag)
+	response := dto.ToDAGResponse(dagModel)
 	c.JSON(http.StatusOK, response)
 }
 
+// RenderDAG handles GET /api/v1/dags/:id/render
+// @Summary Preview task commands
+// @Description Render the command templates of a DAG's tasks for an execution date without running them
+// @Tags dags
+// @Produce json
+// @Param id path string true "DAG ID"
+// @Param execution_date query string false "Execution date (RFC3339 or YYYY-MM-DD), defaults to now"
+// @Param task_id query string false "Only render this task"
+// @Success 200 {object} dto.RenderDAGResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /api/v1/dags/{id}/render [get]
+func (h *DAGHandler) RenderDAG(c *gin.Context) {
+	id := c.Param("id")
+
+	executionDate := time.Now().UTC()
+	if value := c.Query("execution_date"); value != "" {
+		var err error
+		if executionDate, err = render.ParseExecutionDate(value); err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_TIME",
+				"Invalid execution_date, expected RFC3339 or YYYY-MM-DD: "+value)
+			return
+		}
+	}
+
+	dagModel, err := h.dagRepo.Get(c.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "DAG_NOT_FOUND", "DAG not found")
+		return
+	}
+
+	previews, err := h.renderer.Preview(dagModel, executionDate, c.Query("task_id"))
+	if err != nil {
+		// The only task that can be missing is the one asked for
+		middleware.AbortWithError(c, http.StatusNotFound, "TASK_NOT_FOUND", err.Error())
+		return
+	}
+
+	tasks := make([]dto.RenderedTaskDTO, len(previews))
+	for i, preview := range previews {
+		tasks[i] = dto.RenderedTaskDTO{
+			TaskID:          preview.TaskID,
+			Command:         preview.Command,
+			RenderedCommand: preview.RenderedCommand,
+		}
+		if preview.Err != nil {
+			tasks[i].Error = preview.Err.Error()
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.RenderDAGResponse{
+		DAGID:         dagModel.ID,
+		ExecutionDate: executionDate,
+		Tasks:         tasks,
+	})
+}
+
 // DeleteDAG handles DELETE /api/v1/dags/:id
 // @Summary Delete DAG
 // @Description Delete a DAG
@@ -313,3 +368,175 @@ func (h *DAGHandler) UnpauseDAG(c *gin.Context) {
 		Message: "DAG unpaused successfully",
 	})
 }
+
+// ListDAGVersions handles GET /api/v1/dags/:id/versions
+// @Summary List DAG versions
+// @Description List the immutable versions of a DAG definition, newest first
+// @Tags dags
+// @Produce json
+// @Param id path string true "DAG ID"
+// @Success 200 {object} dto.DAGVersionListResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dags/{id}/versions [get]
+func (h *DAGHandler) ListDAGVersions(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.dagRepo.Get(c.Request.Context(), id); err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "DAG_NOT_FOUND", "DAG not found")
+		return
+	}
+
+	versions, err := h.dagRepo.ListVersions(c.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "LIST_FAILED", err.Error())
+		return
+	}
+
+	versionResponses := make([]dto.DAGVersionResponse, len(versions))
+	for i, version := range versions {
+		versionResponses[i] = dto.ToDAGVersionResponse(version, false)
+	}
+
+	c.JSON(http.StatusOK, dto.DAGVersionListResponse{Versions: versionResponses})
+}
+
+// GetDAGVersion handles GET /api/v1/dags/:id/versions/:version
+// @Summary Get DAG version
+// @Description Get the full definition of a specific DAG version
+// @Tags dags
+// @Produce json
+// @Param id path string true "DAG ID"
+// @Param version path int true "Version number"
+// @Success 200 {object} dto.DAGVersionResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /api/v1/dags/{id}/versions/{version} [get]
+func (h *DAGHandler) GetDAGVersion(c *gin.Context) {
+	id := c.Param("id")
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil || versionNumber < 1 {
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_VERSION", "Version must be a positive integer")
+		return
+	}
+
+	version, err := h.dagRepo.GetVersion(c.Request.Context(), id, versionNumber)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "VERSION_NOT_FOUND", "DAG version not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToDAGVersionResponse(version, true))
+}
+
+// DiffDAGVersions handles GET /api/v1/dags/:id/diff
+// @Summary Diff DAG versions
+// @Description Compare two versions of a DAG definition
+// @Tags dags
+// @Produce json
+// @Param id path string true "DAG ID"
+// @Param from query int true "Base version"
+// @Param to query int false "Target version (defaults to the current version)"
+// @Success 200 {object} dto.DAGDiffResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /api/v1/dags/{id}/diff [get]
+func (h *DAGHandler) DiffDAGVersions(c *gin.Context) {
+	id := c.Param("id")
+
+	fromVersion, err := strconv.Atoi(c.Query("from"))
+	if err != nil || fromVersion < 1 {
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_VERSION", "Query parameter 'from' must be a positive integer")
+		return
+	}
+
+	var toVersion int
+	if toStr := c.Query("to"); toStr != "" {
+		toVersion, err = strconv.Atoi(toStr)
+		if err != nil || toVersion < 1 {
+			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_VERSION", "Query parameter 'to' must be a positive integer")
+			return
+		}
+	} else {
+		current, err := h.dagRepo.Get(c.Request.Context(), id)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusNotFound, "DAG_NOT_FOUND", "DAG not found")
+			return
+		}
+		toVersion = current.Version
+	}
+
+	from, err := h.dagRepo.GetVersion(c.Request.Context(), id, fromVersion)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "VERSION_NOT_FOUND", "DAG version not found: "+strconv.Itoa(fromVersion))
+		return
+	}
+
+	to, err := h.dagRepo.GetVersion(c.Request.Context(), id, toVersion)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "VERSION_NOT_FOUND", "DAG version not found: "+strconv.Itoa(toVersion))
+		return
+	}
+
+	diff, err := dag.Diff(from.DAG, to.DAG)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "DIFF_FAILED", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToDAGDiffResponse(diff))
+}
+
+// RollbackDAG handles POST /api/v1/dags/:id/versions/:version/rollback
+// @Summary Roll back DAG
+// @Description Restore the definition of an earlier version. This records a new version; history is never rewritten.
+// @Tags dags
+// @Produce json
+// @Param id path string true "DAG ID"
+// @Param version path int true "Version to restore"
+// @Success 200 {object} dto.DAGResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dags/{id}/versions/{version}/rollback [post]
+func (h *DAGHandler) RollbackDAG(c *gin.Context) {
+	id := c.Param("id")
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil || versionNumber < 1 {
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_VERSION", "Version must be a positive integer")
+		return
+	}
+
+	current, err := h.dagRepo.Get(c.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "DAG_NOT_FOUND", "DAG not found")
+		return
+	}
+
+	target, err := h.dagRepo.GetVersion(c.Request.Context(), id, versionNumber)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "VERSION_NOT_FOUND", "DAG version not found")
+		return
+	}
+
+	// Restore the definition but keep the DAG's identity and operational state
+	restored := *target.DAG
+	restored.ID = current.ID
+	restored.IsPaused = current.IsPaused
+	restored.Version = current.Version
+	restored.CreatedAt = current.CreatedAt
+
+	if err := h.validator.Validate(&restored); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "DAG_VALIDATION_FAILED", err.Error())
+		return
+	}
+
+	if err := h.dagRepo.Update(c.Request.Context(), &restored); err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "ROLLBACK_FAILED", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToDAGResponse(&restored))
+}
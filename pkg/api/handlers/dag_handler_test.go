@@ -2,6 +2,7 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
@@ -22,12 +24,12 @@ type MockDAGRepository struct {
 	mock.Mock
 }
 
-func (m *MockDAGRepository) Create(ctx interface{}, dag *models.DAG) error {
+func (m *MockDAGRepository) Create(ctx context.Context, dag *models.DAG) error {
 	args := m.Called(ctx, dag)
 	return args.Error(0)
 }
 
-func (m *MockDAGRepository) Get(ctx interface{}, id string) (*models.DAG, error) {
+func (m *MockDAGRepository) Get(ctx context.Context, id string) (*models.DAG, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -35,7 +37,7 @@ func (m *MockDAGRepository) Get(ctx interface{}, id string) (*models.DAG, error)
 	return args.Get(0).(*models.DAG), args.Error(1)
 }
 
-func (m *MockDAGRepository) GetByID(ctx interface{}, id string) (*models.DAG, error) {
+func (m *MockDAGRepository) GetByID(ctx context.Context, id string) (*models.DAG, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -43,7 +45,7 @@ func (m *MockDAGRepository) GetByID(ctx interface{}, id string) (*models.DAG, er
 	return args.Get(0).(*models.DAG), args.Error(1)
 }
 
-func (m *MockDAGRepository) GetByName(ctx interface{}, name string) (*models.DAG, error) {
+func (m *MockDAGRepository) GetByName(ctx context.Context, name string) (*models.DAG, error) {
 	args := m.Called(ctx, name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -51,7 +53,7 @@ func (m *MockDAGRepository) GetByName(ctx interface{}, name string) (*models.DAG
 	return args.Get(0).(*models.DAG), args.Error(1)
 }
 
-func (m *MockDAGRepository) List(ctx interface{}, filters ...interface{}) ([]*models.DAG, error) {
+func (m *MockDAGRepository) List(ctx context.Context, filters ...storage.DAGFilters) ([]*models.DAG, error) {
 	args := m.Called(ctx, filters)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -59,35 +61,54 @@ func (m *MockDAGRepository) List(ctx interface{}, filters ...interface{}) ([]*mo
 	return args.Get(0).([]*models.DAG), args.Error(1)
 }
 
-func (m *MockDAGRepository) Update(ctx interface{}, dag *models.DAG) error {
+func (m *MockDAGRepository) Update(ctx context.Context, dag *models.DAG) error {
 	args := m.Called(ctx, dag)
 	return args.Error(0)
 }
 
-func (m *MockDAGRepository) Delete(ctx interface{}, id string) error {
+func (m *MockDAGRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockDAGRepository) Pause(ctx interface{}, id string) error {
+func (m *MockDAGRepository) Pause(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockDAGRepository) Unpause(ctx interface{}, id string) error {
+func (m *MockDAGRepository) Unpause(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockDAGRepository) ListVersions(ctx context.Context, dagID string) ([]*models.DAGVersion, error) {
+	args := m.Called(ctx, dagID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.DAGVersion), args.Error(1)
+}
+
+func (m *MockDAGRepository) GetVersion(ctx context.Context, dagID string, version int) (*models.DAGVersion, error) {
+	args := m.Called(ctx, dagID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DAGVersion), args.Error(1)
+}
+
 func TestCreateDAG(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	t.Run("successful creation", func(t *testing.T) {
 		mockRepo := new(MockDAGRepository)
-		engine := dag.NewEngine()
-		handler := handlers.NewDAGHandler(mockRepo, engine)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
 
-		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.DAG")).Return(nil)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(d *models.DAG) bool {
+			// Task definitions must be handed to the repository for persistence
+			return len(d.Tasks) == 1 && d.Tasks[0].ID == "task1" && d.Tasks[0].Retries == 3
+		})).Return(nil)
 
 		reqBody := dto.CreateDAGRequest{
 			Name:        "test_dag",
@@ -118,10 +139,71 @@ func TestCreateDAG(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("with retry policy", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
+
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(d *models.DAG) bool {
+			policy := d.Tasks[0].RetryPolicy
+			return policy != nil &&
+				policy.Strategy == models.RetryStrategyLinear &&
+				policy.BaseDelay == 10*time.Second &&
+				policy.MaxDelay == 2*time.Minute &&
+				len(policy.RetryOn) == 1 && policy.RetryOn[0] == "75"
+		})).Return(nil)
+
+		body := []byte(`{
+			"name": "retry_dag",
+			"start_date": "2024-01-01T00:00:00Z",
+			"tasks": [{
+				"id": "task1",
+				"name": "Task 1",
+				"type": "bash",
+				"command": "exit 75",
+				"retries": 3,
+				"retry": {"strategy": "linear", "base_delay": "10s", "max_delay": "2m", "retry_on": ["75"]}
+			}]
+		}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/dags", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.POST("/api/v1/dags", handler.CreateDAG)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"base_delay":"10s"`)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid retry strategy", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
+
+		body := []byte(`{
+			"name": "retry_dag",
+			"start_date": "2024-01-01T00:00:00Z",
+			"tasks": [{"id": "task1", "name": "Task 1", "type": "bash", "command": "echo hi", "retry": {"strategy": "random"}}]
+		}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/dags", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.POST("/api/v1/dags", handler.CreateDAG)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
 	t.Run("invalid request body", func(t *testing.T) {
 		mockRepo := new(MockDAGRepository)
-		engine := dag.NewEngine()
-		handler := handlers.NewDAGHandler(mockRepo, engine)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
 
 		req := httptest.NewRequest(http.MethodPost, "/api/v1/dags", bytes.NewReader([]byte("invalid json")))
 		req.Header.Set("Content-Type", "application/json")
@@ -140,8 +222,8 @@ func TestListDAGs(t *testing.T) {
 
 	t.Run("successful list", func(t *testing.T) {
 		mockRepo := new(MockDAGRepository)
-		engine := dag.NewEngine()
-		handler := handlers.NewDAGHandler(mockRepo, engine)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
 
 		dags := []*models.DAG{
 			{
@@ -176,8 +258,8 @@ func TestGetDAG(t *testing.T) {
 
 	t.Run("successful get", func(t *testing.T) {
 		mockRepo := new(MockDAGRepository)
-		engine := dag.NewEngine()
-		handler := handlers.NewDAGHandler(mockRepo, engine)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
 
 		dag := &models.DAG{
 			ID:          "dag1",
@@ -206,8 +288,8 @@ func TestGetDAG(t *testing.T) {
 
 	t.Run("DAG not found", func(t *testing.T) {
 		mockRepo := new(MockDAGRepository)
-		engine := dag.NewEngine()
-		handler := handlers.NewDAGHandler(mockRepo, engine)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
 
 		mockRepo.On("Get", mock.Anything, "nonexistent").Return(nil, assert.AnError)
 
@@ -223,13 +305,80 @@ func TestGetDAG(t *testing.T) {
 	})
 }
 
+func TestRenderDAG(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dagModel := &models.DAG{
+		ID:     "dag1",
+		Name:   "Test DAG",
+		Params: []models.Param{{Name: "table", Default: "events"}},
+		Tasks: []models.Task{
+			{ID: "load", Type: models.TaskTypeBash, Command: "load {{ .params.table }} --date {{ .ds }}"},
+			{ID: "broken", Type: models.TaskTypeBash, Command: "echo {{ .params.missing }}"},
+		},
+	}
+
+	t.Run("renders every task", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		handler := handlers.NewDAGHandler(mockRepo, dag.NewValidator())
+		mockRepo.On("Get", mock.Anything, "dag1").Return(dagModel, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dags/dag1/render?execution_date=2024-03-05", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.GET("/api/v1/dags/:id/render", handler.RenderDAG)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response dto.RenderDAGResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Len(t, response.Tasks, 2)
+		assert.Equal(t, "load events --date 2024-03-05", response.Tasks[0].RenderedCommand)
+		assert.Empty(t, response.Tasks[0].Error)
+		assert.NotEmpty(t, response.Tasks[1].Error)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown task", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		handler := handlers.NewDAGHandler(mockRepo, dag.NewValidator())
+		mockRepo.On("Get", mock.Anything, "dag1").Return(dagModel, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dags/dag1/render?task_id=missing", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.GET("/api/v1/dags/:id/render", handler.RenderDAG)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid execution date", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		handler := handlers.NewDAGHandler(mockRepo, dag.NewValidator())
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dags/dag1/render?execution_date=yesterday", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.GET("/api/v1/dags/:id/render", handler.RenderDAG)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
 func TestDeleteDAG(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	t.Run("successful delete", func(t *testing.T) {
 		mockRepo := new(MockDAGRepository)
-		engine := dag.NewEngine()
-		handler := handlers.NewDAGHandler(mockRepo, engine)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
 
 		mockRepo.On("Delete", mock.Anything, "dag1").Return(nil)
 
@@ -250,8 +399,8 @@ func TestPauseDAG(t *testing.T) {
 
 	t.Run("successful pause", func(t *testing.T) {
 		mockRepo := new(MockDAGRepository)
-		engine := dag.NewEngine()
-		handler := handlers.NewDAGHandler(mockRepo, engine)
+		validator := dag.NewValidator()
+		handler := handlers.NewDAGHandler(mockRepo, validator)
 
 		mockRepo.On("Pause", mock.Anything, "dag1").Return(nil)
 
@@ -266,3 +415,124 @@ func TestPauseDAG(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestDiffDAGVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	v1 := &models.DAG{
+		ID:       "dag1",
+		Name:     "Test DAG",
+		Schedule: "0 0 * * *",
+		Version:  1,
+		Tasks:    []models.Task{{ID: "task1", Type: models.TaskTypeBash, Command: "echo hello"}},
+	}
+	v2 := &models.DAG{
+		ID:       "dag1",
+		Name:     "Test DAG",
+		Schedule: "0 * * * *",
+		Version:  2,
+		Tasks: []models.Task{
+			{ID: "task1", Type: models.TaskTypeBash, Command: "echo hello"},
+			{ID: "task2", Type: models.TaskTypeBash, Command: "echo world", Dependencies: []string{"task1"}},
+		},
+	}
+
+	t.Run("successful diff", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		handler := handlers.NewDAGHandler(mockRepo, dag.NewValidator())
+
+		mockRepo.On("GetVersion", mock.Anything, "dag1", 1).Return(&models.DAGVersion{DAGID: "dag1", Version: 1, DAG: v1}, nil)
+		mockRepo.On("GetVersion", mock.Anything, "dag1", 2).Return(&models.DAGVersion{DAGID: "dag1", Version: 2, DAG: v2}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dags/dag1/diff?from=1&to=2", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.GET("/api/v1/dags/:id/diff", handler.DiffDAGVersions)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response dto.DAGDiffResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"schedule"}, response.ChangedFields)
+		assert.Equal(t, []string{"task2"}, response.AddedTasks)
+		assert.Empty(t, response.RemovedTasks)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		handler := handlers.NewDAGHandler(mockRepo, dag.NewValidator())
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dags/dag1/diff?from=abc", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.GET("/api/v1/dags/:id/diff", handler.DiffDAGVersions)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestRollbackDAG(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("restores an earlier definition", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		handler := handlers.NewDAGHandler(mockRepo, dag.NewValidator())
+
+		current := &models.DAG{
+			ID:       "dag1",
+			Name:     "Test DAG",
+			Schedule: "0 * * * *",
+			IsPaused: true,
+			Version:  3,
+			Tasks:    []models.Task{{ID: "task2", Type: models.TaskTypeBash, Command: "echo world"}},
+		}
+		v1 := &models.DAG{
+			ID:       "dag1",
+			Name:     "Test DAG",
+			Schedule: "0 0 * * *",
+			Version:  1,
+			Tasks:    []models.Task{{ID: "task1", Type: models.TaskTypeBash, Command: "echo hello"}},
+		}
+
+		mockRepo.On("Get", mock.Anything, "dag1").Return(current, nil)
+		mockRepo.On("GetVersion", mock.Anything, "dag1", 1).Return(&models.DAGVersion{DAGID: "dag1", Version: 1, DAG: v1}, nil)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(d *models.DAG) bool {
+			// The old definition is restored without touching the pause state
+			return d.Schedule == "0 0 * * *" && d.IsPaused && len(d.Tasks) == 1 && d.Tasks[0].ID == "task1"
+		})).Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/dags/dag1/versions/1/rollback", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.POST("/api/v1/dags/:id/versions/:version/rollback", handler.RollbackDAG)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("version not found", func(t *testing.T) {
+		mockRepo := new(MockDAGRepository)
+		handler := handlers.NewDAGHandler(mockRepo, dag.NewValidator())
+
+		mockRepo.On("Get", mock.Anything, "dag1").Return(&models.DAG{ID: "dag1", Version: 1}, nil)
+		mockRepo.On("GetVersion", mock.Anything, "dag1", 5).Return(nil, storage.ErrNotFound)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/dags/dag1/versions/5/rollback", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.POST("/api/v1/dags/:id/versions/:version/rollback", handler.RollbackDAG)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
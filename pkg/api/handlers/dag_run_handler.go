@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dag"
 	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
@@ -15,12 +19,18 @@ import (
 	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
 )
 
+// TaskCanceller interrupts a running task instance on whichever worker runs it, see executor.NATSTaskCanceller
+type TaskCanceller interface {
+	CancelTask(ctx context.Context, taskInstanceID string) error
+}
+
 // DAGRunHandler handles DAG run-related HTTP requests
 type DAGRunHandler struct {
 	dagRepo        storage.DAGRepository
 	dagRunRepo     storage.DAGRunRepository
 	taskInstanceRepo storage.TaskInstanceRepository
 	executor       executor.Executor
+	taskCanceller  TaskCanceller
 }
 
 // NewDAGRunHandler creates a new DAG run handler
@@ -38,6 +48,11 @@ func NewDAGRunHandler(
 	}
 }
 
+// SetTaskCanceller sets how running tasks of DAG runs scheduled by other processes are interrupted
+func (h *DAGRunHandler) SetTaskCanceller(canceller TaskCanceller) {
+	h.taskCanceller = canceller
+}
+
 // TriggerDAG handles POST /api/v1/dags/:id/trigger
 // @Summary Trigger a DAG run
 // @Description Manually trigger a DAG execution
@@ -54,21 +69,22 @@ func NewDAGRunHandler(
 func (h *DAGRunHandler) TriggerDAG(c *gin.Context) {
 	dagID := c.Param("id")
 
+	// Allow empty body, but not a malformed one, which would silently drop the conf
 	var req dto.TriggerDAGRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// Allow empty body
-		req = dto.TriggerDAGRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_JSON", err.Error())
+		return
 	}
 
 	// Get DAG
-	dag, err := h.dagRepo.Get(c.Request.Context(), dagID)
+	dagModel, err := h.dagRepo.Get(c.Request.Context(), dagID)
 	if err != nil {
 		middleware.AbortWithError(c, http.StatusNotFound, "DAG_NOT_FOUND", "DAG not found")
 		return
 	}
 
 	// Check if DAG is paused
-	if dag.IsPaused {
+	if dagModel.IsPaused {
 		middleware.AbortWithError(c, http.StatusBadRequest, "DAG_PAUSED", "Cannot trigger a paused DAG")
 		return
 	}
@@ -79,6 +95,12 @@ func (h *DAGRunHandler) TriggerDAG(c *gin.Context) {
 		executionDate = *req.ExecutionDate
 	}
 
+	// Check the conf against the DAG's parameters
+	if err := dag.ValidateConf(dagModel, req.Conf); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_CONF", err.Error())
+		return
+	}
+
 	// Create DAG run
 	dagRun := &models.DAGRun{
 		ID:              uuid.New().String(),
@@ -86,6 +108,8 @@ func (h *DAGRunHandler) TriggerDAG(c *gin.Context) {
 		ExecutionDate:   executionDate,
 		State:           models.StateQueued,
 		ExternalTrigger: true,
+		DAGVersion:      dagModel.Version,
+		Conf:            req.Conf,
 	}
 
 	if err := h.dagRunRepo.Create(c.Request.Context(), dagRun); err != nil {
@@ -96,7 +120,7 @@ func (h *DAGRunHandler) TriggerDAG(c *gin.Context) {
 	// Submit to executor (asynchronously)
 	go func() {
 		ctx := context.Background()
-		_ = h.executor.Execute(ctx, dagRun, dag)
+		_ = h.executor.Execute(ctx, dagRun, dagModel)
 	}()
 
 	response := dto.ToDAGRunResponse(dagRun)
@@ -113,6 +137,7 @@ func (h *DAGRunHandler) TriggerDAG(c *gin.Context) {
 // @Param dag_id query string false "Filter by DAG ID"
 // @Param state query string false "Filter by state"
 // @Success 200 {object} dto.DAGRunListResponse
+// @Failure 400 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /api/v1/dag-runs [get]
 func (h *DAGRunHandler) ListDAGRuns(c *gin.Context) {
@@ -139,6 +164,11 @@ func (h *DAGRunHandler) ListDAGRuns(c *gin.Context) {
 
 	if stateStr := c.Query("state"); stateStr != "" {
 		state := models.State(stateStr)
+		if !state.IsValid() {
+			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_STATE",
+				"Unknown state: "+stateStr)
+			return
+		}
 		filters.State = &state
 	}
 
@@ -208,7 +238,7 @@ func (h *DAGRunHandler) GetDAGRun(c *gin.Context) {
 
 // CancelDAGRun handles POST /api/v1/dag-runs/:id/cancel
 // @Summary Cancel DAG run
-// @Description Cancel a running DAG run
+// @Description Cancel a queued or running DAG run, interrupting its running tasks
 // @Tags dag-runs
 // @Param id path string true "DAG Run ID"
 // @Success 200 {object} dto.SuccessResponse
@@ -226,24 +256,54 @@ func (h *DAGRunHandler) CancelDAGRun(c *gin.Context) {
 	}
 
 	// Check if run is in a cancellable state
-	if dagRun.State == models.StateSuccess || dagRun.State == models.StateFailed {
+	if dagRun.State.IsTerminal() {
 		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_STATE",
 			"Cannot cancel a DAG run in terminal state")
 		return
 	}
 
-	// Update state to failed (cancel)
-	if err := h.dagRunRepo.UpdateState(c.Request.Context(), id, dagRun.State, models.StateFailed); err != nil {
+	// A run scheduled by this process is cancelled by its executor, which interrupts the running
+	// tasks and moves the run to cancelled once they stopped
+	if dagRun.State == models.StateRunning {
+		err := h.executor.Cancel(c.Request.Context(), id)
+		if err == nil {
+			c.JSON(http.StatusOK, dto.SuccessResponse{
+				Success: true,
+				Message: "DAG run cancellation requested",
+			})
+			return
+		}
+		if !errors.Is(err, executor.ErrDAGRunNotActive) {
+			middleware.AbortWithError(c, http.StatusInternalServerError, "CANCEL_FAILED", err.Error())
+			return
+		}
+	}
+
+	// Otherwise the run has not started or is scheduled by another process. The executor scheduling
+	// it notices the cancelled state when it next re-reads the run and interrupts its running tasks.
+	if err := h.dagRunRepo.UpdateState(c.Request.Context(), id, dagRun.State, models.StateCancelled); err != nil {
 		middleware.AbortWithError(c, http.StatusInternalServerError, "CANCEL_FAILED", err.Error())
 		return
 	}
 
-	// Cancel all running task instances
+	// Cancel the task instances that have not started. Running ones are left to the process running
+	// them, which reports them cancelled once they stopped; distributed workers are told right away.
 	taskInstances, err := h.taskInstanceRepo.ListByDAGRun(c.Request.Context(), id)
-	if err == nil {
-		for _, ti := range taskInstances {
-			if ti.State == models.StateRunning || ti.State == models.StateQueued {
-				_ = h.taskInstanceRepo.UpdateState(c.Request.Context(), ti.ID, ti.State, models.StateFailed)
+	if err != nil {
+		log.Printf("Failed to list task instances of cancelled DAG run %s: %v", id, err)
+	}
+	for _, ti := range taskInstances {
+		switch {
+		case ti.State == models.StateRunning:
+			if h.taskCanceller == nil {
+				continue
+			}
+			if err := h.taskCanceller.CancelTask(c.Request.Context(), ti.ID); err != nil {
+				log.Printf("Failed to cancel running task instance %s: %v", ti.ID, err)
+			}
+		case !ti.State.IsTerminal() && ti.State != models.StateUpstreamFailed:
+			if err := h.taskInstanceRepo.UpdateState(c.Request.Context(), ti.ID, ti.State, models.StateCancelled); err != nil {
+				log.Printf("Failed to cancel task instance %s: %v", ti.ID, err)
 			}
 		}
 	}
@@ -0,0 +1,211 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// fakeDAGRunRepository keeps the DAG runs created through it
+type fakeDAGRunRepository struct {
+	storage.DAGRunRepository
+	mu   sync.Mutex
+	runs []*models.DAGRun
+}
+
+func (r *fakeDAGRunRepository) Create(ctx context.Context, run *models.DAGRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func (r *fakeDAGRunRepository) Get(ctx context.Context, id string) (*models.DAGRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.ID == id {
+			copied := *run
+			return &copied, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *fakeDAGRunRepository) UpdateState(ctx context.Context, id string, oldState, newState models.State) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.ID == id && run.State == oldState {
+			run.State = newState
+			return nil
+		}
+	}
+	return fmt.Errorf("DAG run %s is not in state %s", id, oldState)
+}
+
+// runTaskInstanceRepository stores the task instances of DAG runs in memory
+type runTaskInstanceRepository struct {
+	storage.TaskInstanceRepository
+	instances []*models.TaskInstance
+}
+
+func (r *runTaskInstanceRepository) ListByDAGRun(ctx context.Context, dagRunID string) ([]*models.TaskInstance, error) {
+	var instances []*models.TaskInstance
+	for _, instance := range r.instances {
+		if instance.DAGRunID == dagRunID {
+			copied := *instance
+			instances = append(instances, &copied)
+		}
+	}
+	return instances, nil
+}
+
+func (r *runTaskInstanceRepository) UpdateState(ctx context.Context, id string, oldState, newState models.State) error {
+	for _, instance := range r.instances {
+		if instance.ID == id && instance.State == oldState {
+			instance.State = newState
+			return nil
+		}
+	}
+	return fmt.Errorf("task instance %s is not in state %s", id, oldState)
+}
+
+// fakeExecutor hands the DAG runs submitted to it to a channel and schedules no runs to cancel
+type fakeExecutor struct {
+	executor.Executor
+	submitted chan *models.DAGRun
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, dagRun *models.DAGRun, dag *models.DAG) error {
+	e.submitted <- dagRun
+	return nil
+}
+
+func (e *fakeExecutor) Cancel(ctx context.Context, dagRunID string) error {
+	return executor.ErrDAGRunNotActive
+}
+
+// fakeTaskCanceller records the task instances it was asked to interrupt
+type fakeTaskCanceller struct {
+	cancelled []string
+}
+
+func (c *fakeTaskCanceller) CancelTask(ctx context.Context, taskInstanceID string) error {
+	c.cancelled = append(c.cancelled, taskInstanceID)
+	return nil
+}
+
+func TestTriggerDAG(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dagModel := &models.DAG{
+		ID:   "dag1",
+		Name: "etl",
+		Params: []models.Param{
+			{Name: "table", Type: models.ParamTypeString, Default: "events"},
+			{Name: "mode", Enum: []interface{}{"full", "incremental"}, Default: "full"},
+			{Name: "limit", Type: models.ParamTypeInteger},
+		},
+	}
+	mockRepo := new(MockDAGRepository)
+	mockRepo.On("Get", mock.Anything, "dag1").Return(dagModel, nil)
+	runRepo := &fakeDAGRunRepository{}
+	exec := &fakeExecutor{submitted: make(chan *models.DAGRun, 10)}
+	handler := handlers.NewDAGRunHandler(mockRepo, runRepo, nil, exec)
+
+	router := gin.New()
+	router.POST("/api/v1/dags/:id/trigger", handler.TriggerDAG)
+	trigger := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/dags/dag1/trigger", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("triggers a run with a conf", func(t *testing.T) {
+		w := trigger(`{"conf": {"table": "users", "limit": 10}}`)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response dto.DAGRunResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, map[string]interface{}{"table": "users", "limit": float64(10)}, response.Conf)
+
+		submitted := <-exec.submitted
+		assert.Equal(t, "users", submitted.Conf["table"])
+	})
+
+	t.Run("triggers a run without a body", func(t *testing.T) {
+		w := trigger("")
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		submitted := <-exec.submitted
+		assert.Nil(t, submitted.Conf)
+	})
+
+	t.Run("rejects an invalid conf", func(t *testing.T) {
+		for _, body := range []string{
+			`{"conf": {"unknown": 1}}`,
+			`{"conf": {"table": 1}}`,
+			`{"conf": {"mode": "partial"}}`,
+			`{"conf": {"limit": 1.5}}`,
+		} {
+			w := trigger(body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+			assert.Contains(t, w.Body.String(), "INVALID_CONF", body)
+		}
+	})
+
+	t.Run("rejects a malformed body", func(t *testing.T) {
+		w := trigger(`{"conf": `)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_JSON")
+	})
+
+	// Only the two valid triggers created runs
+	assert.Len(t, runRepo.runs, 2)
+}
+
+func TestCancelDAGRun_ScheduledElsewhere(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	runRepo := &fakeDAGRunRepository{runs: []*models.DAGRun{{ID: "run1", DAGID: "dag1", State: models.StateRunning}}}
+	taskRepo := &runTaskInstanceRepository{instances: []*models.TaskInstance{
+		{ID: "ti1", DAGRunID: "run1", TaskID: "extract", State: models.StateRunning},
+		{ID: "ti2", DAGRunID: "run1", TaskID: "load", State: models.StateQueued},
+		{ID: "ti3", DAGRunID: "run1", TaskID: "setup", State: models.StateSuccess},
+	}}
+	canceller := &fakeTaskCanceller{}
+	handler := handlers.NewDAGRunHandler(nil, runRepo, taskRepo, &fakeExecutor{})
+	handler.SetTaskCanceller(canceller)
+
+	router := gin.New()
+	router.POST("/api/v1/dag-runs/:id/cancel", handler.CancelDAGRun)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/dag-runs/run1/cancel", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.StateCancelled, runRepo.runs[0].State)
+	// The running task is interrupted by the process running it, which reports it cancelled
+	assert.Equal(t, []string{"ti1"}, canceller.cancelled)
+	assert.Equal(t, models.StateRunning, taskRepo.instances[0].State)
+	assert.Equal(t, models.StateCancelled, taskRepo.instances[1].State)
+	assert.Equal(t, models.StateSuccess, taskRepo.instances[2].State)
+}
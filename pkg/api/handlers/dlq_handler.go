@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/middleware"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// maxBulkReplay is how many entries a bulk replay by DAG or task replays at most
+const maxBulkReplay = 100
+
+// DLQReplayer replays dead letter queue entries, see executor.Replayer
+type DLQReplayer interface {
+	Replay(ctx context.Context, id string) (*models.TaskInstance, error)
+	ReplayAll(ctx context.Context, ids []string) []executor.ReplayResult
+}
+
+// DLQHandler handles dead letter queue-related HTTP requests
+type DLQHandler struct {
+	queue    dlq.Queue
+	replayer DLQReplayer
+}
+
+// NewDLQHandler creates a new dead letter queue handler
+func NewDLQHandler(queue dlq.Queue, replayer DLQReplayer) *DLQHandler {
+	return &DLQHandler{
+		queue:    queue,
+		replayer: replayer,
+	}
+}
+
+// ListDLQEntries handles GET /api/v1/dlq
+// @Summary List dead letter queue entries
+// @Description Get a paginated list of task instances whose attempts are exhausted, most recent failures first
+// @Tags dlq
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Param dag_id query string false "Filter by DAG ID"
+// @Param task_id query string false "Filter by task ID"
+// @Param replayed query bool false "Filter by whether the entry was replayed"
+// @Param after query string false "Only entries that failed after this time (RFC3339)"
+// @Param before query string false "Only entries that failed before this time (RFC3339)"
+// @Success 200 {object} dto.DLQEntryListResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dlq [get]
+func (h *DLQHandler) ListDLQEntries(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	// Build filters
+	filters := &dlq.Filters{
+		DAGID:  c.Query("dag_id"),
+		TaskID: c.Query("task_id"),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	}
+
+	if value := c.Query("replayed"); value != "" {
+		replayed, err := strconv.ParseBool(value)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_QUERY",
+				"Invalid replayed filter, expected true or false: "+value)
+			return
+		}
+		filters.Replayed = &replayed
+	}
+
+	for name, filter := range map[string]**time.Time{"after": &filters.After, "before": &filters.Before} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_TIME",
+				"Invalid "+name+" time, expected RFC3339: "+value)
+			return
+		}
+		*filter = &t
+	}
+
+	// Get entries from the queue
+	entries, err := h.queue.List(c.Request.Context(), filters)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "LIST_FAILED", err.Error())
+		return
+	}
+
+	// Convert to response
+	entryResponses := make([]dto.DLQEntryResponse, len(entries))
+	for i, entry := range entries {
+		entryResponses[i] = dto.ToDLQEntryResponse(entry)
+	}
+
+	// TODO: Get total count for pagination
+	totalCount := int64(len(entryResponses))
+
+	response := dto.DLQEntryListResponse{
+		Entries:    entryResponses,
+		Pagination: dto.NewPaginationMeta(page, pageSize, totalCount),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDLQEntry handles GET /api/v1/dlq/:id
+// @Summary Get dead letter queue entry
+// @Description Get a dead letter queue entry by ID
+// @Tags dlq
+// @Produce json
+// @Param id path string true "Entry ID"
+// @Success 200 {object} dto.DLQEntryResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dlq/{id} [get]
+func (h *DLQHandler) GetDLQEntry(c *gin.Context) {
+	entry, err := h.queue.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithDLQError(c, err, "GET_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToDLQEntryResponse(entry))
+}
+
+// DeleteDLQEntry handles DELETE /api/v1/dlq/:id
+// @Summary Delete dead letter queue entry
+// @Description Remove an entry from the dead letter queue without replaying it
+// @Tags dlq
+// @Param id path string true "Entry ID"
+// @Success 204
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dlq/{id} [delete]
+func (h *DLQHandler) DeleteDLQEntry(c *gin.Context) {
+	if err := h.queue.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		abortWithDLQError(c, err, "DELETE_FAILED")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PurgeDLQ handles DELETE /api/v1/dlq
+// @Summary Purge dead letter queue
+// @Description Remove all entries from the dead letter queue
+// @Tags dlq
+// @Success 204
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dlq [delete]
+func (h *DLQHandler) PurgeDLQ(c *gin.Context) {
+	if err := h.queue.Purge(c.Request.Context()); err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "PURGE_FAILED", err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ReplayDLQEntry handles POST /api/v1/dlq/:id/replay
+// @Summary Replay dead letter queue entry
+// @Description Give the failed task instance of an entry a new try and resume its DAG run
+// @Tags dlq
+// @Produce json
+// @Param id path string true "Entry ID"
+// @Success 200 {object} dto.ReplayResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dlq/{id}/replay [post]
+func (h *DLQHandler) ReplayDLQEntry(c *gin.Context) {
+	id := c.Param("id")
+
+	taskInstance, err := h.replayer.Replay(c.Request.Context(), id)
+	if err != nil {
+		abortWithDLQError(c, err, "REPLAY_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ReplayResponse{
+		EntryID:        id,
+		TaskInstanceID: taskInstance.ID,
+		TryNumber:      taskInstance.TryNumber,
+	})
+}
+
+// ReplayDLQEntries handles POST /api/v1/dlq/replay
+// @Summary Replay dead letter queue entries
+// @Description Replay entries by ID, or the entries of a DAG or task that were not replayed yet (at most 100)
+// @Tags dlq
+// @Accept json
+// @Produce json
+// @Param request body dto.BulkReplayRequest true "Entries to replay"
+// @Success 200 {object} dto.BulkReplayResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/dlq/replay [post]
+func (h *DLQHandler) ReplayDLQEntries(c *gin.Context) {
+	var req dto.BulkReplayRequest
+	if !middleware.BindAndValidate(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	ids := req.IDs
+	if len(ids) == 0 {
+		pending := false
+		entries, err := h.queue.List(ctx, &dlq.Filters{
+			DAGID:    req.DAGID,
+			TaskID:   req.TaskID,
+			Replayed: &pending,
+			Limit:    maxBulkReplay,
+		})
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusInternalServerError, "LIST_FAILED", err.Error())
+			return
+		}
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+	}
+
+	response := dto.BulkReplayResponse{
+		Replayed: []dto.ReplayResponse{},
+		Failed:   []dto.ReplayFailure{},
+	}
+	for _, result := range h.replayer.ReplayAll(ctx, ids) {
+		if result.Err != nil {
+			response.Failed = append(response.Failed, dto.ReplayFailure{
+				EntryID: result.EntryID,
+				Error:   result.Err.Error(),
+			})
+			continue
+		}
+		response.Replayed = append(response.Replayed, dto.ReplayResponse{
+			EntryID:        result.EntryID,
+			TaskInstanceID: result.TaskInstance.ID,
+			TryNumber:      result.TaskInstance.TryNumber,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// abortWithDLQError responds to a failed dead letter queue operation
+func abortWithDLQError(c *gin.Context, err error, code string) {
+	switch {
+	case errors.Is(err, dlq.ErrNotFound):
+		middleware.AbortWithError(c, http.StatusNotFound, "DLQ_ENTRY_NOT_FOUND", "Dead letter queue entry not found")
+	case errors.Is(err, executor.ErrNotReplayable):
+		middleware.AbortWithError(c, http.StatusConflict, "NOT_REPLAYABLE", err.Error())
+	case errors.Is(err, state.ErrOptimisticLock):
+		middleware.AbortWithError(c, http.StatusConflict, "STATE_CHANGED",
+			"Task instance or DAG run was modified concurrently, try again")
+	default:
+		middleware.AbortWithError(c, http.StatusInternalServerError, code, err.Error())
+	}
+}
@@ -0,0 +1,181 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therealutkarshpriyadarshi/dag/internal/dlq"
+	"github.com/therealutkarshpriyadarshi/dag/internal/executor"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// fakeReplayer replays entries of a queue, refusing the ones listed in notReplayable
+type fakeReplayer struct {
+	queue         dlq.Queue
+	notReplayable map[string]bool
+	replayed      []string
+}
+
+func (r *fakeReplayer) Replay(ctx context.Context, id string) (*models.TaskInstance, error) {
+	result := r.ReplayAll(ctx, []string{id})[0]
+	return result.TaskInstance, result.Err
+}
+
+func (r *fakeReplayer) ReplayAll(ctx context.Context, ids []string) []executor.ReplayResult {
+	results := make([]executor.ReplayResult, len(ids))
+	for i, id := range ids {
+		results[i].EntryID = id
+		entry, err := r.queue.Get(ctx, id)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if r.notReplayable[id] {
+			results[i].Err = fmt.Errorf("%w: DAG run is running", executor.ErrNotReplayable)
+			continue
+		}
+		r.queue.Replay(ctx, id)
+		r.replayed = append(r.replayed, id)
+		results[i].TaskInstance = &models.TaskInstance{ID: entry.TaskInstanceID, TryNumber: entry.Attempts + 1}
+	}
+	return results
+}
+
+func newDLQTestRouter(t *testing.T) (*gin.Engine, dlq.Queue, *fakeReplayer) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	queue := dlq.NewMemoryQueue()
+	failureTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, entry := range []*dlq.Entry{
+		{ID: "ti1", TaskInstanceID: "ti1", TaskID: "extract", DAGRunID: "run1", DAGID: "dag1", Attempts: 3, FailureTime: failureTime},
+		{ID: "ti2", TaskInstanceID: "ti2", TaskID: "load", DAGRunID: "run1", DAGID: "dag1", Attempts: 1, FailureTime: failureTime.Add(time.Hour)},
+		{ID: "ti3", TaskInstanceID: "ti3", TaskID: "extract", DAGRunID: "run2", DAGID: "dag2", Attempts: 1, FailureTime: failureTime},
+	} {
+		require.NoError(t, queue.Add(context.Background(), entry))
+	}
+	replayer := &fakeReplayer{queue: queue, notReplayable: map[string]bool{"ti2": true}}
+
+	handler := handlers.NewDLQHandler(queue, replayer)
+	router := gin.New()
+	router.GET("/api/v1/dlq", handler.ListDLQEntries)
+	router.DELETE("/api/v1/dlq", handler.PurgeDLQ)
+	router.POST("/api/v1/dlq/replay", handler.ReplayDLQEntries)
+	router.GET("/api/v1/dlq/:id", handler.GetDLQEntry)
+	router.DELETE("/api/v1/dlq/:id", handler.DeleteDLQEntry)
+	router.POST("/api/v1/dlq/:id/replay", handler.ReplayDLQEntry)
+	return router, queue, replayer
+}
+
+func serveDLQ(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestListDLQEntries(t *testing.T) {
+	router, _, _ := newDLQTestRouter(t)
+
+	t.Run("filters entries", func(t *testing.T) {
+		w := serveDLQ(router, http.MethodGet, "/api/v1/dlq?dag_id=dag1&after=2024-01-01T00:30:00Z&replayed=false", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response dto.DLQEntryListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Entries, 1)
+		assert.Equal(t, "ti2", response.Entries[0].ID)
+		assert.Equal(t, "load", response.Entries[0].TaskID)
+	})
+
+	t.Run("rejects invalid filters", func(t *testing.T) {
+		w := serveDLQ(router, http.MethodGet, "/api/v1/dlq?replayed=maybe", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = serveDLQ(router, http.MethodGet, "/api/v1/dlq?before=yesterday", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_TIME")
+	})
+}
+
+func TestGetAndDeleteDLQEntry(t *testing.T) {
+	router, queue, _ := newDLQTestRouter(t)
+
+	w := serveDLQ(router, http.MethodGet, "/api/v1/dlq/ti1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var entry dto.DLQEntryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, 3, entry.Attempts)
+
+	assert.Equal(t, http.StatusNoContent, serveDLQ(router, http.MethodDelete, "/api/v1/dlq/ti1", "").Code)
+	assert.Equal(t, http.StatusNotFound, serveDLQ(router, http.MethodGet, "/api/v1/dlq/ti1", "").Code)
+	assert.Equal(t, http.StatusNotFound, serveDLQ(router, http.MethodDelete, "/api/v1/dlq/ti1", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, serveDLQ(router, http.MethodDelete, "/api/v1/dlq", "").Code)
+	count, _ := queue.Count(context.Background())
+	assert.Equal(t, 0, count)
+}
+
+func TestReplayDLQEntry(t *testing.T) {
+	router, _, replayer := newDLQTestRouter(t)
+
+	w := serveDLQ(router, http.MethodPost, "/api/v1/dlq/ti1/replay", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response dto.ReplayResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "ti1", response.TaskInstanceID)
+	assert.Equal(t, 4, response.TryNumber)
+	assert.Equal(t, []string{"ti1"}, replayer.replayed)
+
+	w = serveDLQ(router, http.MethodPost, "/api/v1/dlq/ti2/replay", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "NOT_REPLAYABLE")
+
+	assert.Equal(t, http.StatusNotFound, serveDLQ(router, http.MethodPost, "/api/v1/dlq/missing/replay", "").Code)
+}
+
+func TestReplayDLQEntries(t *testing.T) {
+	t.Run("replays by ID", func(t *testing.T) {
+		router, _, _ := newDLQTestRouter(t)
+
+		w := serveDLQ(router, http.MethodPost, "/api/v1/dlq/replay", `{"ids": ["ti1", "ti2", "missing"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response dto.BulkReplayResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Replayed, 1)
+		assert.Equal(t, "ti1", response.Replayed[0].EntryID)
+		require.Len(t, response.Failed, 2)
+		assert.Equal(t, "ti2", response.Failed[0].EntryID)
+		assert.Equal(t, "missing", response.Failed[1].EntryID)
+	})
+
+	t.Run("replays pending entries of a task", func(t *testing.T) {
+		router, queue, replayer := newDLQTestRouter(t)
+		require.NoError(t, queue.Replay(context.Background(), "ti3"))
+
+		w := serveDLQ(router, http.MethodPost, "/api/v1/dlq/replay", `{"task_id": "extract"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"ti1"}, replayer.replayed)
+	})
+
+	t.Run("requires a selection", func(t *testing.T) {
+		router, _, replayer := newDLQTestRouter(t)
+
+		w := serveDLQ(router, http.MethodPost, "/api/v1/dlq/replay", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, replayer.replayed)
+	})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/middleware"
+)
+
+// EventSubscriber delivers state change events as they are published
+type EventSubscriber interface {
+	// Events returns once subscribed; the channel is closed when ctx is done or the subscription ends
+	Events(ctx context.Context) (<-chan state.TransitionEvent, error)
+}
+
+// EventLog replays state change events that were published before a client reconnected
+type EventLog interface {
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]state.TransitionEvent, error)
+}
+
+// EventHandler streams state changes of DAG runs and task instances
+type EventHandler struct {
+	subscriber        EventSubscriber
+	eventLog          EventLog
+	keepAliveInterval time.Duration
+}
+
+// defaultKeepAliveInterval is how often an idle event stream sends a comment so that proxies keep it open
+const defaultKeepAliveInterval = 15 * time.Second
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(subscriber EventSubscriber, eventLog EventLog) *EventHandler {
+	return &EventHandler{
+		subscriber:        subscriber,
+		eventLog:          eventLog,
+		keepAliveInterval: defaultKeepAliveInterval,
+	}
+}
+
+// SetKeepAliveInterval sets how often an idle event stream sends a keep-alive comment
+func (h *EventHandler) SetKeepAliveInterval(interval time.Duration) {
+	h.keepAliveInterval = interval
+}
+
+// eventFilter selects the events a client asked for; empty fields match everything
+type eventFilter struct {
+	dagID      string
+	dagRunID   string
+	entityType string
+}
+
+// matches returns true if the filter selects the event
+func (f *eventFilter) matches(event *dto.StateChangeEvent) bool {
+	return (f.dagID == "" || event.DAGID == f.dagID) &&
+		(f.dagRunID == "" || event.DAGRunID == f.dagRunID) &&
+		(f.entityType == "" || event.EntityType == f.entityType)
+}
+
+// StreamEvents handles GET /api/v1/events
+// @Summary Stream state changes
+// @Description Stream state changes of DAG runs and task instances as Server-Sent Events. Clients that reconnect
+// @Description with the ID of the last event they received first get the events they missed.
+// @Tags events
+// @Produce text/event-stream
+// @Param dag_id query string false "Only changes of runs and tasks of this DAG"
+// @Param dag_run_id query string false "Only changes of this DAG run and its tasks"
+// @Param entity_type query string false "Only events of dag_run, task_instance or sla_miss entities"
+// @Param last_event_id query int false "Resume after this event ID"
+// @Param Last-Event-ID header string false "Resume after this event ID (takes precedence over last_event_id)"
+// @Success 200 {object} dto.StateChangeEvent
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
+// @Router /api/v1/events [get]
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	filter := &eventFilter{
+		dagID:      c.Query("dag_id"),
+		dagRunID:   c.Query("dag_run_id"),
+		entityType: c.Query("entity_type"),
+	}
+	switch filter.entityType {
+	case "", "dag_run", "task_instance", "sla_miss":
+	default:
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_ENTITY_TYPE",
+			"Entity type must be dag_run, task_instance or sla_miss")
+		return
+	}
+
+	lastEventIDStr := c.DefaultQuery("last_event_id", "0")
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		lastEventIDStr = lastEventID
+	}
+	lastEventID, err := strconv.ParseInt(lastEventIDStr, 10, 64)
+	if err != nil || lastEventID < 0 {
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_LAST_EVENT_ID",
+			"Last event ID must be a non-negative integer")
+		return
+	}
+
+	// Subscribe before replaying so that no event falls in between
+	ctx := c.Request.Context()
+	events, err := h.subscriber.Events(ctx)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "EVENTS_UNAVAILABLE", err.Error())
+		return
+	}
+
+	startSSE(c)
+
+	replayed := make(map[int64]bool)
+	if lastEventID > 0 {
+		if err := h.replayAfter(c, filter, lastEventID, replayed); err != nil {
+			writeSSE(c, "", "error", dto.ErrorResponse{Error: "stream failed", Message: err.Error(), Code: "STREAM_FAILED"})
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(h.keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// Replayed events may be published again by the outbox relay
+			if replayed[event.OutboxID] {
+				continue
+			}
+			if err := h.send(c, filter, &event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// replayAfter writes the stored events after lastEventID that match the filter and records their IDs
+func (h *EventHandler) replayAfter(c *gin.Context, filter *eventFilter, lastEventID int64, replayed map[int64]bool) error {
+	const pageSize = 500
+
+	for {
+		events, err := h.eventLog.ListAfter(c.Request.Context(), lastEventID, pageSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range events {
+			if err := h.send(c, filter, &events[i]); err != nil {
+				return err
+			}
+			replayed[events[i].OutboxID] = true
+			lastEventID = events[i].OutboxID
+		}
+
+		if len(events) < pageSize {
+			return nil
+		}
+	}
+}
+
+// send writes an event if it matches the filter
+func (h *EventHandler) send(c *gin.Context, filter *eventFilter, event *state.TransitionEvent) error {
+	response := toStateChangeEvent(event)
+	if !filter.matches(&response) {
+		return nil
+	}
+
+	id := ""
+	if response.ID > 0 {
+		id = strconv.FormatInt(response.ID, 10)
+	}
+	return writeSSE(c, id, "state_change", response)
+}
+
+// toStateChangeEvent converts a transition event to a response
+func toStateChangeEvent(event *state.TransitionEvent) dto.StateChangeEvent {
+	response := dto.StateChangeEvent{
+		ID:         event.OutboxID,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		OldState:   string(event.OldState),
+		NewState:   string(event.NewState),
+	}
+
+	if dagID, ok := event.Metadata[state.MetadataDAGID].(string); ok {
+		response.DAGID = dagID
+	}
+	if dagRunID, ok := event.Metadata[state.MetadataDAGRunID].(string); ok {
+		response.DAGRunID = dagRunID
+	}
+	if response.DAGRunID == "" && event.EntityType == "dag_run" {
+		response.DAGRunID = event.EntityID
+	}
+
+	return response
+}
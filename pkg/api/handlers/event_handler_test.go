@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// fakeEventSource publishes a fixed list of live events, then ends the subscription,
+// and replays a fixed list of stored events
+type fakeEventSource struct {
+	live   []state.TransitionEvent
+	stored []state.TransitionEvent
+}
+
+func (s *fakeEventSource) Events(ctx context.Context) (<-chan state.TransitionEvent, error) {
+	events := make(chan state.TransitionEvent, len(s.live))
+	for _, event := range s.live {
+		events <- event
+	}
+	close(events)
+	return events, nil
+}
+
+func (s *fakeEventSource) ListAfter(ctx context.Context, afterID int64, limit int) ([]state.TransitionEvent, error) {
+	var events []state.TransitionEvent
+	for _, event := range s.stored {
+		if event.OutboxID > afterID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func taskEvent(id int64, dagRunID string, newState models.State) state.TransitionEvent {
+	return state.TransitionEvent{
+		EntityType: "task_instance",
+		EntityID:   "ti-" + dagRunID,
+		NewState:   newState,
+		Metadata:   map[string]interface{}{state.MetadataDAGID: "dag1", state.MetadataDAGRunID: dagRunID},
+		OutboxID:   id,
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	stream := func(source *fakeEventSource, target string, header http.Header) *httptest.ResponseRecorder {
+		handler := handlers.NewEventHandler(source, source)
+
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		w := httptest.NewRecorder()
+
+		router := gin.New()
+		router.GET("/api/v1/events", handler.StreamEvents)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("forwards events matching the filters", func(t *testing.T) {
+		source := &fakeEventSource{live: []state.TransitionEvent{
+			taskEvent(1, "run1", models.StateRunning),
+			taskEvent(2, "run2", models.StateRunning),
+			{EntityType: "dag_run", EntityID: "run1", NewState: models.StateSuccess, OutboxID: 3},
+		}}
+
+		w := stream(source, "/api/v1/events?dag_run_id=run1", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+		body := w.Body.String()
+		assert.Contains(t, body, "id: 1\nevent: state_change\n")
+		assert.Contains(t, body, `"dag_id":"dag1","dag_run_id":"run1","new_state":"running"`)
+		assert.NotContains(t, body, "id: 2\n")
+		assert.Contains(t, body, "id: 3\nevent: state_change\n")
+	})
+
+	t.Run("replays missed events from Last-Event-ID", func(t *testing.T) {
+		source := &fakeEventSource{
+			stored: []state.TransitionEvent{
+				taskEvent(1, "run1", models.StateRunning),
+				taskEvent(2, "run1", models.StateSuccess),
+			},
+			live: []state.TransitionEvent{
+				taskEvent(2, "run1", models.StateSuccess),
+				taskEvent(3, "run1", models.StateFailed),
+			},
+		}
+
+		w := stream(source, "/api/v1/events?entity_type=task_instance", http.Header{"Last-Event-ID": []string{"1"}})
+
+		body := w.Body.String()
+		assert.NotContains(t, body, "id: 1\n")
+		assert.Equal(t, 1, strings.Count(body, "id: 2\n"), "a replayed event is not sent again")
+		assert.True(t, strings.Index(body, "id: 2\n") < strings.Index(body, "id: 3\n"), body)
+	})
+
+	t.Run("rejects unknown entity types", func(t *testing.T) {
+		w := stream(&fakeEventSource{}, "/api/v1/events?entity_type=dag", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("rejects invalid last event IDs", func(t *testing.T) {
+		w := stream(&fakeEventSource{}, "/api/v1/events?last_event_id=abc", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
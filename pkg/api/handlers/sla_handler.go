@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/middleware"
+)
+
+// SLAHandler handles SLA miss-related HTTP requests
+type SLAHandler struct {
+	slaMissRepo storage.SLAMissRepository
+}
+
+// NewSLAHandler creates a new SLA handler
+func NewSLAHandler(slaMissRepo storage.SLAMissRepository) *SLAHandler {
+	return &SLAHandler{
+		slaMissRepo: slaMissRepo,
+	}
+}
+
+// ListSLAMisses handles GET /api/v1/sla-misses
+// @Summary List SLA misses
+// @Description Get a paginated list of task instances and DAG runs that missed their SLA, most recent first
+// @Tags sla
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Param dag_id query string false "Filter by DAG ID"
+// @Param dag_run_id query string false "Filter by DAG run ID"
+// @Param task_id query string false "Filter by task ID"
+// @Param after query string false "Only misses detected after this time (RFC3339)"
+// @Param before query string false "Only misses detected before this time (RFC3339)"
+// @Success 200 {object} dto.SLAMissListResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/sla-misses [get]
+func (h *SLAHandler) ListSLAMisses(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	// Build filters
+	filters := storage.SLAMissFilters{
+		DAGID:    c.Query("dag_id"),
+		DAGRunID: c.Query("dag_run_id"),
+		TaskID:   c.Query("task_id"),
+		Limit:    pageSize,
+		Offset:   (page - 1) * pageSize,
+	}
+
+	for name, filter := range map[string]**time.Time{"after": &filters.After, "before": &filters.Before} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_TIME",
+				"Invalid "+name+" time, expected RFC3339: "+value)
+			return
+		}
+		*filter = &t
+	}
+
+	// Get SLA misses from database
+	misses, err := h.slaMissRepo.List(c.Request.Context(), filters)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "LIST_FAILED", err.Error())
+		return
+	}
+
+	// Convert to response
+	missResponses := make([]dto.SLAMissResponse, len(misses))
+	for i, miss := range misses {
+		missResponses[i] = dto.ToSLAMissResponse(miss)
+	}
+
+	// TODO: Get total count for pagination
+	totalCount := int64(len(missResponses))
+
+	response := dto.SLAMissListResponse{
+		SLAMisses:  missResponses,
+		Pagination: dto.NewPaginationMeta(page, pageSize, totalCount),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
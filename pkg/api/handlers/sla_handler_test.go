@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// fakeSLAMissRepository returns fixed misses and remembers the filters it was asked for
+type fakeSLAMissRepository struct {
+	storage.SLAMissRepository
+	misses  []*models.SLAMiss
+	filters storage.SLAMissFilters
+}
+
+func (r *fakeSLAMissRepository) List(ctx context.Context, filters storage.SLAMissFilters) ([]*models.SLAMiss, error) {
+	r.filters = filters
+	return r.misses, nil
+}
+
+func TestListSLAMisses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(repo storage.SLAMissRepository) *gin.Engine {
+		router := gin.New()
+		router.GET("/api/v1/sla-misses", handlers.NewSLAHandler(repo).ListSLAMisses)
+		return router
+	}
+
+	t.Run("passes filters and returns misses", func(t *testing.T) {
+		deadline := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+		repo := &fakeSLAMissRepository{misses: []*models.SLAMiss{{
+			ID:       "miss1",
+			DAGID:    "dag1",
+			DAGRunID: "run1",
+			TaskID:   "extract",
+			SLA:      time.Hour,
+			Deadline: deadline,
+			State:    models.StateRunning,
+		}}}
+
+		req := httptest.NewRequest(http.MethodGet,
+			"/api/v1/sla-misses?dag_id=dag1&task_id=extract&after=2024-01-01T00:00:00Z&page=2&page_size=10", nil)
+		w := httptest.NewRecorder()
+		newRouter(repo).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "dag1", repo.filters.DAGID)
+		assert.Equal(t, "extract", repo.filters.TaskID)
+		assert.Equal(t, 10, repo.filters.Limit)
+		assert.Equal(t, 10, repo.filters.Offset)
+		require.NotNil(t, repo.filters.After)
+		assert.True(t, repo.filters.After.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+		assert.Nil(t, repo.filters.Before)
+
+		var response dto.SLAMissListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.SLAMisses, 1)
+		assert.Equal(t, "miss1", response.SLAMisses[0].ID)
+		assert.Equal(t, "1h0m0s", response.SLAMisses[0].SLA)
+		assert.Equal(t, "running", response.SLAMisses[0].State)
+	})
+
+	t.Run("rejects invalid times", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/sla-misses?before=yesterday", nil)
+		w := httptest.NewRecorder()
+		newRouter(&fakeSLAMissRepository{}).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "INVALID_TIME")
+	})
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/internal/state"
 	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
 	"github.com/therealutkarshpriyadarshi/dag/pkg/api/middleware"
@@ -15,8 +19,13 @@ import (
 type TaskInstanceHandler struct {
 	taskInstanceRepo storage.TaskInstanceRepository
 	taskLogRepo      storage.TaskLogRepository
+	results          *results.Manager
+	logPollInterval  time.Duration
 }
 
+// defaultLogPollInterval is how often streamed logs are checked for new lines
+const defaultLogPollInterval = 500 * time.Millisecond
+
 // NewTaskInstanceHandler creates a new task instance handler
 func NewTaskInstanceHandler(
 	taskInstanceRepo storage.TaskInstanceRepository,
@@ -25,9 +34,20 @@ func NewTaskInstanceHandler(
 	return &TaskInstanceHandler{
 		taskInstanceRepo: taskInstanceRepo,
 		taskLogRepo:      taskLogRepo,
+		logPollInterval:  defaultLogPollInterval,
 	}
 }
 
+// SetLogPollInterval sets how often streamed logs are checked for new lines
+func (h *TaskInstanceHandler) SetLogPollInterval(interval time.Duration) {
+	h.logPollInterval = interval
+}
+
+// SetResults sets the result manager serving the values task instances pushed
+func (h *TaskInstanceHandler) SetResults(manager *results.Manager) {
+	h.results = manager
+}
+
 // ListTaskInstances handles GET /api/v1/task-instances
 // @Summary List task instances
 // @Description Get a paginated list of task instances with optional filters
@@ -39,6 +59,7 @@ func NewTaskInstanceHandler(
 // @Param task_id query string false "Filter by task ID"
 // @Param state query string false "Filter by state"
 // @Success 200 {object} dto.TaskInstanceListResponse
+// @Failure 400 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /api/v1/task-instances [get]
 func (h *TaskInstanceHandler) ListTaskInstances(c *gin.Context) {
@@ -69,6 +90,11 @@ func (h *TaskInstanceHandler) ListTaskInstances(c *gin.Context) {
 
 	if stateStr := c.Query("state"); stateStr != "" {
 		state := models.State(stateStr)
+		if !state.IsValid() {
+			middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_STATE",
+				"Unknown state: "+stateStr)
+			return
+		}
 		filters.State = &state
 	}
 
@@ -119,6 +145,83 @@ func (h *TaskInstanceHandler) GetTaskInstance(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetTaskInstanceResults handles GET /api/v1/task-instances/:id/results
+// @Summary Get task instance results
+// @Description Get the values a task instance pushed for downstream tasks, by key
+// @Tags task-instances
+// @Produce json
+// @Param id path string true "Task Instance ID"
+// @Success 200 {object} dto.TaskResultsResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/task-instances/{id}/results [get]
+func (h *TaskInstanceHandler) GetTaskInstanceResults(c *gin.Context) {
+	id := c.Param("id")
+
+	taskInstance, err := h.taskInstanceRepo.Get(c.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "TASK_INSTANCE_NOT_FOUND", "Task instance not found")
+		return
+	}
+
+	response := dto.TaskResultsResponse{Results: []dto.TaskResultResponse{}}
+	if h.results == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	taskResults, err := h.results.List(c.Request.Context(), taskInstance.DAGRunID, taskInstance.TaskID)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "GET_RESULTS_FAILED", "Failed to list task results")
+		return
+	}
+
+	for _, result := range taskResults {
+		response.Results = append(response.Results, dto.ToTaskResultResponse(result))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTaskInstanceResult handles GET /api/v1/task-instances/:id/results/:key
+// @Summary Get a task instance result
+// @Description Get the value a task instance pushed under a key
+// @Tags task-instances
+// @Produce json
+// @Param id path string true "Task Instance ID"
+// @Param key path string true "Result key"
+// @Success 200 {object} dto.TaskResultResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/v1/task-instances/{id}/results/{key} [get]
+func (h *TaskInstanceHandler) GetTaskInstanceResult(c *gin.Context) {
+	id := c.Param("id")
+	key := c.Param("key")
+
+	taskInstance, err := h.taskInstanceRepo.Get(c.Request.Context(), id)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "TASK_INSTANCE_NOT_FOUND", "Task instance not found")
+		return
+	}
+
+	if h.results == nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "RESULT_NOT_FOUND", "Task result not found")
+		return
+	}
+
+	result, err := h.results.Get(c.Request.Context(), taskInstance.DAGRunID, taskInstance.TaskID, key)
+	if err != nil {
+		if errors.Is(err, results.ErrNotFound) {
+			middleware.AbortWithError(c, http.StatusNotFound, "RESULT_NOT_FOUND", "Task result not found")
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "GET_RESULTS_FAILED", "Failed to get task result")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ToTaskResultResponse(result))
+}
+
 // GetTaskInstanceLogs handles GET /api/v1/task-instances/:id/logs
 // @Summary Get task instance logs
 // @Description Get logs for a specific task instance
@@ -154,12 +257,8 @@ func (h *TaskInstanceHandler) GetTaskInstanceLogs(c *gin.Context) {
 
 	// Convert to response
 	logResponses := make([]dto.TaskLogResponse, len(logs))
-	for i, log := range logs {
-		logResponses[i] = dto.TaskLogResponse{
-			ID:        log.ID.String(),
-			LogData:   log.LogData,
-			Timestamp: log.Timestamp,
-		}
+	for i := range logs {
+		logResponses[i] = toTaskLogResponse(&logs[i])
 	}
 
 	response := dto.TaskLogsResponse{
@@ -175,6 +274,114 @@ func (h *TaskInstanceHandler) GetTaskInstanceLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// StreamTaskInstanceLogs handles GET /api/v1/task-instances/:id/logs/stream
+// @Summary Stream task instance logs
+// @Description Stream logs of a task instance as Server-Sent Events. Stored lines after the offset are replayed first,
+// @Description then new lines are pushed as they are written. The stream ends with an "end" event once the task reaches a terminal state.
+// @Tags task-instances
+// @Produce text/event-stream
+// @Param id path string true "Task Instance ID"
+// @Param offset query int false "Sequence number of the last line already received" default(0)
+// @Param Last-Event-ID header string false "Resume after this event ID (takes precedence over offset)"
+// @Success 200 {object} dto.TaskLogResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /api/v1/task-instances/{id}/logs/stream [get]
+func (h *TaskInstanceHandler) StreamTaskInstanceLogs(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	if _, err := h.taskInstanceRepo.Get(ctx, id); err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "TASK_INSTANCE_NOT_FOUND", "Task instance not found")
+		return
+	}
+
+	offsetStr := c.DefaultQuery("offset", "0")
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		offsetStr = lastEventID
+	}
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil || offset < 0 {
+		middleware.AbortWithError(c, http.StatusBadRequest, "INVALID_OFFSET", "Offset must be a non-negative integer")
+		return
+	}
+
+	startSSE(c)
+
+	ticker := time.NewTicker(h.logPollInterval)
+	defer ticker.Stop()
+
+	finishing := false
+	for {
+		offset, err = h.sendLogsAfter(c, id, offset)
+		if err != nil {
+			writeSSE(c, "", "error", dto.ErrorResponse{Error: "stream failed", Message: err.Error(), Code: "STREAM_FAILED"})
+			return
+		}
+
+		taskInstance, err := h.taskInstanceRepo.Get(ctx, id)
+		if err != nil {
+			writeSSE(c, "", "error", dto.ErrorResponse{Error: "stream failed", Message: err.Error(), Code: "STREAM_FAILED"})
+			return
+		}
+
+		if isFinished(taskInstance.State) {
+			// Lines flushed just before the state change are picked up by one last read
+			if finishing {
+				writeSSE(c, "", "end", dto.TaskLogStreamEndEvent{TaskInstanceID: id, State: string(taskInstance.State)})
+				return
+			}
+			finishing = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendLogsAfter writes every stored log line after offset as an event and returns the new offset
+func (h *TaskInstanceHandler) sendLogsAfter(c *gin.Context, id string, offset int64) (int64, error) {
+	const pageSize = 500
+
+	for {
+		logs, err := h.taskLogRepo.ListAfter(c.Request.Context(), id, offset, pageSize)
+		if err != nil {
+			return offset, err
+		}
+
+		for i := range logs {
+			if err := writeSSE(c, strconv.FormatInt(logs[i].Seq, 10), "log", toTaskLogResponse(&logs[i])); err != nil {
+				return offset, err
+			}
+			offset = logs[i].Seq
+		}
+
+		if len(logs) < pageSize {
+			return offset, nil
+		}
+	}
+}
+
+// isFinished returns true if no more output can be written for a task instance in this state
+func isFinished(state models.State) bool {
+	return state.IsTerminal() || state == models.StateUpstreamFailed
+}
+
+// toTaskLogResponse converts a stored task log line to a response
+func toTaskLogResponse(log *storage.TaskLogModel) dto.TaskLogResponse {
+	return dto.TaskLogResponse{
+		ID:         log.ID.String(),
+		Seq:        log.Seq,
+		Stream:     log.Stream,
+		LineNumber: log.LineNumber,
+		LogData:    log.LogData,
+		Timestamp:  log.Timestamp,
+	}
+}
+
 // RetryTaskInstance handles POST /api/v1/task-instances/:id/retry
 // @Summary Retry task instance
 // @Description Manually retry a failed task instance
@@ -183,6 +390,7 @@ func (h *TaskInstanceHandler) GetTaskInstanceLogs(c *gin.Context) {
 // @Success 200 {object} dto.SuccessResponse
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /api/v1/task-instances/{id}/retry [post]
 func (h *TaskInstanceHandler) RetryTaskInstance(c *gin.Context) {
@@ -208,14 +416,31 @@ func (h *TaskInstanceHandler) RetryTaskInstance(c *gin.Context) {
 		return
 	}
 
-	// Update state to queued and increment try number
-	taskInstance.State = models.StateQueued
+	// A failed task is retried directly, a task whose upstream failed waits for its dependencies again
+	retryState := models.StateRetrying
+	if taskInstance.State == models.StateUpstreamFailed {
+		retryState = models.StateQueued
+	}
+
+	ctx := c.Request.Context()
+	if err := h.taskInstanceRepo.UpdateState(ctx, taskInstance.ID, taskInstance.State, retryState); err != nil {
+		if errors.Is(err, state.ErrOptimisticLock) {
+			middleware.AbortWithError(c, http.StatusConflict, "STATE_CHANGED",
+				"Task instance was modified concurrently, try again")
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "RETRY_FAILED", err.Error())
+		return
+	}
+
+	// Start the next attempt afresh
+	taskInstance.State = retryState
 	taskInstance.TryNumber++
 	taskInstance.ErrorMessage = ""
 	taskInstance.StartDate = nil
 	taskInstance.EndDate = nil
 
-	if err := h.taskInstanceRepo.Update(c.Request.Context(), taskInstance); err != nil {
+	if err := h.taskInstanceRepo.Update(ctx, taskInstance); err != nil {
 		middleware.AbortWithError(c, http.StatusInternalServerError, "RETRY_FAILED", err.Error())
 		return
 	}
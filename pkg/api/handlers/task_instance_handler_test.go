@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/therealutkarshpriyadarshi/dag/internal/results"
+	"github.com/therealutkarshpriyadarshi/dag/internal/storage"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/dto"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/api/handlers"
+	"github.com/therealutkarshpriyadarshi/dag/pkg/models"
+)
+
+// fakeTaskInstanceRepository returns a task instance whose state advances on every Get
+type fakeTaskInstanceRepository struct {
+	storage.TaskInstanceRepository
+	mu       sync.Mutex
+	id       string
+	taskID   string
+	dagRunID string
+	states   []models.State
+}
+
+func (r *fakeTaskInstanceRepository) Get(ctx context.Context, id string) (*models.TaskInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id != r.id {
+		return nil, storage.ErrNotFound
+	}
+
+	state := r.states[0]
+	if len(r.states) > 1 {
+		r.states = r.states[1:]
+	}
+	return &models.TaskInstance{ID: id, TaskID: r.taskID, DAGRunID: r.dagRunID, State: state}, nil
+}
+
+// fakeTaskLogRepository serves log lines from memory
+type fakeTaskLogRepository struct {
+	storage.TaskLogRepository
+	mu   sync.Mutex
+	logs []storage.TaskLogModel
+}
+
+func (r *fakeTaskLogRepository) append(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, storage.TaskLogModel{
+		ID:         uuid.New(),
+		Seq:        int64(len(r.logs) + 1),
+		Stream:     "stdout",
+		LineNumber: len(r.logs) + 1,
+		LogData:    text,
+		Timestamp:  time.Now(),
+	})
+}
+
+func (r *fakeTaskLogRepository) ListAfter(ctx context.Context, taskInstanceID string, afterSeq int64, limit int) ([]storage.TaskLogModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var logs []storage.TaskLogModel
+	for _, log := range r.logs {
+		if log.Seq > afterSeq && (limit <= 0 || len(logs) < limit) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func TestStreamTaskInstanceLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("replays stored lines and ends when the task finishes", func(t *testing.T) {
+		taskRepo := &fakeTaskInstanceRepository{
+			id:     "ti1",
+			states: []models.State{models.StateRunning, models.StateRunning, models.StateSuccess},
+		}
+		logRepo := &fakeTaskLogRepository{}
+		logRepo.append("first")
+		logRepo.append("second")
+		logRepo.append("third")
+
+		handler := handlers.NewTaskInstanceHandler(taskRepo, logRepo)
+		handler.SetLogPollInterval(time.Millisecond)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/task-instances/ti1/logs/stream?offset=1", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.New()
+		router.GET("/api/v1/task-instances/:id/logs/stream", handler.StreamTaskInstanceLogs)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+		body := w.Body.String()
+		assert.NotContains(t, body, `"log_data":"first"`)
+		assert.Contains(t, body, "id: 2\nevent: log\n")
+		assert.Contains(t, body, `"log_data":"third"`)
+		assert.True(t, strings.HasSuffix(body, "event: end\ndata: {\"task_instance_id\":\"ti1\",\"state\":\"success\"}\n\n"), body)
+	})
+
+	t.Run("resumes from Last-Event-ID", func(t *testing.T) {
+		taskRepo := &fakeTaskInstanceRepository{id: "ti1", states: []models.State{models.StateFailed}}
+		logRepo := &fakeTaskLogRepository{}
+		logRepo.append("first")
+		logRepo.append("second")
+
+		handler := handlers.NewTaskInstanceHandler(taskRepo, logRepo)
+		handler.SetLogPollInterval(time.Millisecond)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/task-instances/ti1/logs/stream", nil)
+		req.Header.Set("Last-Event-ID", "2")
+		w := httptest.NewRecorder()
+
+		router := gin.New()
+		router.GET("/api/v1/task-instances/:id/logs/stream", handler.StreamTaskInstanceLogs)
+		router.ServeHTTP(w, req)
+
+		assert.NotContains(t, w.Body.String(), "event: log")
+		assert.Contains(t, w.Body.String(), "event: end")
+	})
+
+	t.Run("task instance not found", func(t *testing.T) {
+		handler := handlers.NewTaskInstanceHandler(&fakeTaskInstanceRepository{id: "ti1"}, &fakeTaskLogRepository{})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/task-instances/missing/logs/stream", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.New()
+		router.GET("/api/v1/task-instances/:id/logs/stream", handler.StreamTaskInstanceLogs)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestGetTaskInstanceResults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := results.NewManager(results.NewMemoryStore(), nil)
+	ctx := context.Background()
+	assert.NoError(t, manager.Push(ctx, "run1", "extract", "rows", json.RawMessage(`42`)))
+	assert.NoError(t, manager.Push(ctx, "run1", "extract", "tables", json.RawMessage(`["events"]`)))
+	assert.NoError(t, manager.Push(ctx, "run1", "load", "rows", json.RawMessage(`40`)))
+
+	taskRepo := &fakeTaskInstanceRepository{id: "ti1", taskID: "extract", dagRunID: "run1", states: []models.State{models.StateSuccess}}
+	handler := handlers.NewTaskInstanceHandler(taskRepo, &fakeTaskLogRepository{})
+	handler.SetResults(manager)
+
+	router := gin.New()
+	router.GET("/api/v1/task-instances/:id/results", handler.GetTaskInstanceResults)
+	router.GET("/api/v1/task-instances/:id/results/:key", handler.GetTaskInstanceResult)
+
+	t.Run("lists the results of the task instance", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/task-instances/ti1/results", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.TaskResultsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response.Results, 2) {
+			assert.Equal(t, "rows", response.Results[0].Key)
+			assert.Equal(t, "number", response.Results[0].Type)
+			assert.JSONEq(t, `42`, string(response.Results[0].Value))
+			assert.Equal(t, "tables", response.Results[1].Key)
+			assert.Equal(t, "array", response.Results[1].Type)
+		}
+	})
+
+	t.Run("gets a result by key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/task-instances/ti1/results/tables", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response dto.TaskResultResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.JSONEq(t, `["events"]`, string(response.Value))
+		assert.False(t, response.Spilled)
+	})
+
+	t.Run("result not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/task-instances/ti1/results/missing", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "RESULT_NOT_FOUND")
+	})
+
+	t.Run("task instance not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/task-instances/missing/results", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
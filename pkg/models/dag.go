@@ -4,31 +4,174 @@ import "time"
 
 // DAG represents a Directed Acyclic Graph workflow definition
 type DAG struct {
-	ID          string     `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Schedule    string     `json:"schedule"` // Cron expression
-	Tasks       []Task     `json:"tasks"`
-	StartDate   time.Time  `json:"start_date"`
-	EndDate     *time.Time `json:"end_date,omitempty"`
-	Tags        []string   `json:"tags"`
-	IsPaused    bool       `json:"is_paused"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID             string                `json:"id"`
+	Name           string                `json:"name"`
+	Description    string                `json:"description"`
+	Schedule       string                `json:"schedule"` // Cron expression
+	Tasks          []Task                `json:"tasks"`
+	StartDate      time.Time             `json:"start_date"`
+	EndDate        *time.Time            `json:"end_date,omitempty"`
+	Tags           []string              `json:"tags"`
+	IsPaused       bool                  `json:"is_paused"`
+	Version        int                   `json:"version"`                   // Current definition version
+	SLA            time.Duration         `json:"sla,omitempty"`             // Time a DAG run may take before it misses its SLA; zero disables the check
+	Notifications  *NotificationRules    `json:"notifications,omitempty"`   // Notifiers told about runs and tasks of the DAG
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker,omitempty"` // Circuit breaker settings of tasks that do not set their own
+	Params         []Param               `json:"params,omitempty"`          // Parameters of the DAG's runs, set by the conf of triggered runs
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// TaskCircuitBreaker returns the circuit breaker settings of a task of the DAG:
+// its own if it has any, otherwise the DAG's
+func (d *DAG) TaskCircuitBreaker(task *Task) *CircuitBreakerConfig {
+	if task.CircuitBreaker != nil {
+		return task.CircuitBreaker
+	}
+	return d.CircuitBreaker
+}
+
+// ParamValues returns the values of the DAG's parameters in a run by name: those set by the run's conf,
+// which may be nil, and the defaults of the others
+func (d *DAG) ParamValues(conf map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(d.Params))
+	for _, param := range d.Params {
+		if value, ok := conf[param.Name]; ok {
+			values[param.Name] = value
+		} else {
+			values[param.Name] = param.Default
+		}
+	}
+	return values
+}
+
+// Param is a parameter of a DAG's runs, available to task command templates as {{ .params.<name> }}.
+// Triggered runs may set it in their conf; scheduled runs use the default.
+type Param struct {
+	Name        string        `json:"name"`
+	Type        ParamType     `json:"type,omitempty"` // Empty accepts any value
+	Default     interface{}   `json:"default,omitempty"`
+	Required    bool          `json:"required,omitempty"` // Runs must have a value other than null, from their conf or the default
+	Enum        []interface{} `json:"enum,omitempty"`     // Values the parameter may take; empty allows any
+	Description string        `json:"description,omitempty"`
+}
+
+// ParamType is the JSON type of a parameter's values
+type ParamType string
+
+const (
+	ParamTypeString  ParamType = "string"
+	ParamTypeInteger ParamType = "integer"
+	ParamTypeNumber  ParamType = "number"
+	ParamTypeBoolean ParamType = "boolean"
+	ParamTypeArray   ParamType = "array"
+	ParamTypeObject  ParamType = "object"
+)
+
+// IsValid returns true if the parameter type is empty or one of the known types
+func (t ParamType) IsValid() bool {
+	switch t {
+	case "", ParamTypeString, ParamTypeInteger, ParamTypeNumber, ParamTypeBoolean, ParamTypeArray, ParamTypeObject:
+		return true
+	}
+	return false
+}
+
+// NotificationRules names the notifiers told about each kind of event of a DAG.
+// Notifiers are configured by name in the scheduler and server.
+type NotificationRules struct {
+	OnFailure []string `json:"on_failure,omitempty"`  // A task or the DAG run failed
+	OnSuccess []string `json:"on_success,omitempty"`  // The DAG run succeeded
+	OnSLAMiss []string `json:"on_sla_miss,omitempty"` // A task or the DAG run missed its SLA
+	OnRetry   []string `json:"on_retry,omitempty"`    // A failed task is retried
+}
+
+// DAGVersion is an immutable snapshot of a DAG definition
+type DAGVersion struct {
+	DAGID       string    `json:"dag_id"`
+	Version     int       `json:"version"`
+	ContentHash string    `json:"content_hash"`
+	DAG         *DAG      `json:"dag"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // Task represents a single task within a DAG
 type Task struct {
-	ID           string        `json:"id"`
-	Name         string        `json:"name"`
-	Type         TaskType      `json:"type"`
-	Command      string        `json:"command"`
-	Dependencies []string      `json:"dependencies"`
-	Retries      int           `json:"retries"`
-	Timeout      time.Duration `json:"timeout"`
-	SLA          time.Duration `json:"sla"`
+	ID             string                `json:"id"`
+	Name           string                `json:"name"`
+	Type           TaskType              `json:"type"`
+	Command        string                `json:"command"`
+	Dependencies   []string              `json:"dependencies"`
+	Retries        int                   `json:"retries"`
+	Timeout        time.Duration         `json:"timeout"`
+	SLA            time.Duration         `json:"sla"`
+	RetryPolicy    *RetryPolicy          `json:"retry_policy,omitempty"`    // Overrides the executor's retry strategy when set
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker,omitempty"` // Overrides the DAG's circuit breaker settings when set
+	TriggerRule    TriggerRule           `json:"trigger_rule,omitempty"`    // When the task runs given the states of its dependencies; empty means all_success
+	Branch         *BranchConfig         `json:"branch,omitempty"`          // Makes the task a branch choosing which downstream tasks to follow
+	ResultPaths    map[string]string     `json:"result_paths,omitempty"`    // Response body fields HTTP tasks push as results, as dot-separated paths by result key
+}
+
+// BranchConfig makes a task a branch: its result names the immediate downstream tasks to follow, and the
+// others are skipped. Bash tasks name them on the last line of their output, Go tasks return them and HTTP
+// tasks name them in a field of the JSON response body, as one ID, comma-separated IDs or a list.
+type BranchConfig struct {
+	Targets []string `json:"targets"`         // Immediate downstream tasks the branch may follow
+	Field   string   `json:"field,omitempty"` // Dot-separated path of the response body field naming them, for HTTP tasks; defaults to "branches"
 }
 
+// TriggerRule decides whether a task runs once its dependencies progressed
+type TriggerRule string
+
+const (
+	// TriggerRuleAllSuccess runs the task once all dependencies succeeded
+	TriggerRuleAllSuccess TriggerRule = "all_success"
+	// TriggerRuleAllDone runs the task once all dependencies finished, whatever their state
+	TriggerRuleAllDone TriggerRule = "all_done"
+	// TriggerRuleOneFailed runs the task as soon as a dependency failed
+	TriggerRuleOneFailed TriggerRule = "one_failed"
+	// TriggerRuleNoneFailed runs the task once all dependencies succeeded or were skipped
+	TriggerRuleNoneFailed TriggerRule = "none_failed"
+	// TriggerRuleOneSuccess runs the task as soon as a dependency succeeded
+	TriggerRuleOneSuccess TriggerRule = "one_success"
+)
+
+// IsValid returns true if the trigger rule is empty or one of the known rules
+func (r TriggerRule) IsValid() bool {
+	switch r {
+	case "", TriggerRuleAllSuccess, TriggerRuleAllDone, TriggerRuleOneFailed, TriggerRuleNoneFailed, TriggerRuleOneSuccess:
+		return true
+	}
+	return false
+}
+
+// CircuitBreakerConfig configures the circuit breakers guarding the external dependencies of a task,
+// such as the host an HTTP task calls or the Docker daemon. Unset fields use the executor's defaults.
+type CircuitBreakerConfig struct {
+	MaxFailures int           `json:"max_failures,omitempty"` // Consecutive failures that open the breaker
+	Timeout     time.Duration `json:"timeout,omitempty"`      // How long the breaker stays open before a trial request
+	Disabled    bool          `json:"disabled,omitempty"`     // Runs the task without circuit breakers
+}
+
+// RetryPolicy describes how failed attempts of a task are retried
+type RetryPolicy struct {
+	Strategy  RetryStrategy `json:"strategy"`
+	BaseDelay time.Duration `json:"base_delay,omitempty"`
+	MaxDelay  time.Duration `json:"max_delay,omitempty"`
+	Jitter    bool          `json:"jitter,omitempty"`
+	RetryOn   []string      `json:"retry_on,omitempty"` // Error codes or exit codes that trigger a retry; empty retries every failure
+}
+
+// RetryStrategy names the backoff strategy used between retries
+type RetryStrategy string
+
+const (
+	RetryStrategyExponential RetryStrategy = "exponential"
+	RetryStrategyLinear      RetryStrategy = "linear"
+	RetryStrategyFixed       RetryStrategy = "fixed"
+	RetryStrategyNone        RetryStrategy = "none"
+)
+
 // TaskType defines the type of task executor to use
 type TaskType string
 
@@ -41,28 +184,49 @@ const (
 
 // DAGRun represents a single execution instance of a DAG
 type DAGRun struct {
-	ID              string     `json:"id"`
-	DAGID           string     `json:"dag_id"`
-	ExecutionDate   time.Time  `json:"execution_date"`
-	State           State      `json:"state"`
-	StartDate       *time.Time `json:"start_date,omitempty"`
-	EndDate         *time.Time `json:"end_date,omitempty"`
-	ExternalTrigger bool       `json:"external_trigger"`
+	ID              string                 `json:"id"`
+	DAGID           string                 `json:"dag_id"`
+	ExecutionDate   time.Time              `json:"execution_date"`
+	State           State                  `json:"state"`
+	StartDate       *time.Time             `json:"start_date,omitempty"`
+	EndDate         *time.Time             `json:"end_date,omitempty"`
+	ExternalTrigger bool                   `json:"external_trigger"`
+	DAGVersion      int                    `json:"dag_version"`                 // DAG definition version the run executes
+	LastHeartbeatAt *time.Time             `json:"last_heartbeat_at,omitempty"` // Last time the process scheduling the run reported it alive
+	Conf            map[string]interface{} `json:"conf,omitempty"`              // Parameter values the run was triggered with; others use their defaults
 }
 
 // TaskInstance represents a single execution instance of a task
 type TaskInstance struct {
-	ID           string        `json:"id"`
-	TaskID       string        `json:"task_id"`
-	DAGRunID     string        `json:"dag_run_id"`
-	State        State         `json:"state"`
-	TryNumber    int           `json:"try_number"`
-	MaxTries     int           `json:"max_tries"`
-	StartDate    *time.Time    `json:"start_date,omitempty"`
-	EndDate      *time.Time    `json:"end_date,omitempty"`
-	Duration     time.Duration `json:"duration"`
-	Hostname     string        `json:"hostname"`
-	ErrorMessage string        `json:"error_message,omitempty"`
+	ID              string        `json:"id"`
+	TaskID          string        `json:"task_id"`
+	DAGRunID        string        `json:"dag_run_id"`
+	State           State         `json:"state"`
+	TryNumber       int           `json:"try_number"`
+	MaxTries        int           `json:"max_tries"`
+	StartDate       *time.Time    `json:"start_date,omitempty"`
+	EndDate         *time.Time    `json:"end_date,omitempty"`
+	Duration        time.Duration `json:"duration"`
+	Hostname        string        `json:"hostname"`
+	ErrorMessage    string        `json:"error_message,omitempty"`
+	LastHeartbeatAt *time.Time    `json:"last_heartbeat_at,omitempty"` // Last time the executor running the task reported it alive
+	WorkerID        string        `json:"worker_id,omitempty"`         // Distributed worker running the current attempt
+	Branches        []string      `json:"branches,omitempty"`          // Downstream tasks a succeeded branch task follows
+	RenderedCommand string        `json:"rendered_command,omitempty"`  // Command of the current attempt after rendering its template
+}
+
+// SLAMiss records a task instance or DAG run that did not finish before its SLA deadline
+type SLAMiss struct {
+	ID             string        `json:"id"`
+	DAGID          string        `json:"dag_id"`
+	DAGRunID       string        `json:"dag_run_id"`
+	TaskID         string        `json:"task_id,omitempty"`          // Empty when the whole DAG run missed its SLA
+	TaskInstanceID string        `json:"task_instance_id,omitempty"` // Empty when the whole DAG run missed its SLA
+	ExecutionDate  time.Time     `json:"execution_date"`
+	SLA            time.Duration `json:"sla"`
+	Deadline       time.Time     `json:"deadline"`
+	State          State         `json:"state"` // State of the task instance or DAG run when the miss was detected
+	DetectedAt     time.Time     `json:"detected_at"`
 }
 
 // State represents the execution state of a DAG or task
@@ -76,9 +240,26 @@ const (
 	StateRetrying       State = "retrying"
 	StateSkipped        State = "skipped"
 	StateUpstreamFailed State = "upstream_failed"
+	StateCancelled      State = "cancelled"
+	// StateScheduled is a task waiting for a point in time before it is queued. The built-in executors keep
+	// tasks waiting for their retry delay in StateRetrying and do not produce it.
+	StateScheduled State = "scheduled"
+	// StateUpForReschedule is a sensor that released its worker slot until its next poke. No built-in task type
+	// is a sensor yet, so the executors do not produce it.
+	StateUpForReschedule State = "up_for_reschedule"
 )
 
+// IsValid returns true if the state is one of the known states
+func (s State) IsValid() bool {
+	switch s {
+	case StateQueued, StateRunning, StateSuccess, StateFailed, StateRetrying, StateSkipped,
+		StateUpstreamFailed, StateCancelled, StateScheduled, StateUpForReschedule:
+		return true
+	}
+	return false
+}
+
 // IsTerminal returns true if the state is a terminal state (no further transitions)
 func (s State) IsTerminal() bool {
-	return s == StateSuccess || s == StateFailed || s == StateSkipped
+	return s == StateSuccess || s == StateFailed || s == StateSkipped || s == StateCancelled
 }
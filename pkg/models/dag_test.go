@@ -14,10 +14,13 @@ func TestState_IsTerminal(t *testing.T) {
 		{"Success is terminal", StateSuccess, true},
 		{"Failed is terminal", StateFailed, true},
 		{"Skipped is terminal", StateSkipped, true},
+		{"Cancelled is terminal", StateCancelled, true},
 		{"Queued is not terminal", StateQueued, false},
 		{"Running is not terminal", StateRunning, false},
 		{"Retrying is not terminal", StateRetrying, false},
 		{"Upstream failed is not terminal", StateUpstreamFailed, false},
+		{"Scheduled is not terminal", StateScheduled, false},
+		{"Up for reschedule is not terminal", StateUpForReschedule, false},
 	}
 
 	for _, tt := range tests {
@@ -30,6 +33,28 @@ func TestState_IsTerminal(t *testing.T) {
 	}
 }
 
+func TestState_IsValid(t *testing.T) {
+	tests := []struct {
+		state    State
+		expected bool
+	}{
+		{StateQueued, true},
+		{StateScheduled, true},
+		{StateUpForReschedule, true},
+		{StateCancelled, true},
+		{"up_for_retry", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.state), func(t *testing.T) {
+			if got := tt.state.IsValid(); got != tt.expected {
+				t.Errorf("IsValid() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestDAG_Creation(t *testing.T) {
 	now := time.Now()
 	dag := &DAG{